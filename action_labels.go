@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+// Accessible names for note actions
+// Visible action labels are terse ("Like", "Quote") and repeat on every note, so each
+// button and link also gets an aria-label naming its target: "Like note by alice".
+// Known actions are phrased here; anything else falls back to "<action> note by <author>".
+
+// actionLabelFormats maps an action to its accessible label; %s is the author's name
+var actionLabelFormats = map[string]string{
+	"Reply":    "Reply to note by %s",
+	"Repost":   "Repost note by %s",
+	"Quote":    "Quote note by %s",
+	"Like":     "Like note by %s",
+	"Bookmark": "Bookmark note by %s",
+	"Label":    "Label note by %s",
+}
+
+// actionLabel returns the accessible label for an action on a note
+func actionLabel(action string, target any) string {
+	var item *HTMLEventItem
+	switch t := target.(type) {
+	case *HTMLEventItem:
+		item = t
+	case HTMLEventItem:
+		item = &t
+	}
+	author := "unknown author"
+	if item != nil {
+		author = actionAuthorName(item)
+	}
+	if format, ok := actionLabelFormats[action]; ok {
+		return fmt.Sprintf(format, author)
+	}
+	return action + " note by " + author
+}
+
+// actionAuthorName is the name screen readers hear for a note's author
+func actionAuthorName(item *HTMLEventItem) string {
+	if p := item.AuthorProfile; p != nil {
+		if p.DisplayName != "" {
+			return p.DisplayName
+		}
+		if p.Name != "" {
+			return p.Name
+		}
+	}
+	if item.NpubShort != "" {
+		return item.NpubShort
+	}
+	return "unknown author"
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Instance announcements
+// Operators publish an addressable event (ANNOUNCEMENT_PUBKEY + ANNOUNCEMENT_D, kind
+// ANNOUNCEMENT_KIND, default 30078) and the server shows its content as a banner on
+// every page until it expires (NIP-40 "expiration" tag) or the visitor dismisses it.
+
+const defaultAnnouncementKind = 30078 // NIP-78 application-specific data
+
+// announcementRefreshInterval is how often the announcement event is refetched
+const announcementRefreshInterval = 5 * time.Minute
+
+// announcementDismissCookie holds the ID of the last dismissed announcement
+const announcementDismissCookie = "announcement_dismissed"
+
+// Announcement is the current instance announcement, pre-rendered at fetch time
+type Announcement struct {
+	ID          string
+	CreatedAt   int64
+	ContentHTML RenderedContent
+	Expiration  int64 // unix seconds, 0 if it doesn't expire
+}
+
+// Expired reports whether the announcement's expiration has passed
+func (a *Announcement) Expired() bool {
+	return a.Expiration > 0 && time.Now().Unix() >= a.Expiration
+}
+
+// announcementSource is the configured announcement address
+type announcementSource struct {
+	Kind   int
+	Pubkey string
+	DTag   string
+}
+
+var currentAnnouncement atomic.Pointer[Announcement]
+
+// parseAnnouncementSource reads the announcement address from the environment
+// Returns nil when no announcement is configured or the configuration is invalid
+func parseAnnouncementSource() *announcementSource {
+	pubkey := strings.TrimSpace(os.Getenv("ANNOUNCEMENT_PUBKEY"))
+	dTag := strings.TrimSpace(os.Getenv("ANNOUNCEMENT_D"))
+	if pubkey == "" {
+		return nil
+	}
+	if strings.HasPrefix(pubkey, "npub1") {
+		decoded, err := decodeBech32Pubkey(pubkey)
+		if err != nil {
+			log.Printf("Announcements disabled: invalid ANNOUNCEMENT_PUBKEY: %v", err)
+			return nil
+		}
+		pubkey = decoded
+	}
+	if !isValidEventID(pubkey) {
+		log.Printf("Announcements disabled: ANNOUNCEMENT_PUBKEY must be hex or npub")
+		return nil
+	}
+
+	kind := defaultAnnouncementKind
+	if v := os.Getenv("ANNOUNCEMENT_KIND"); v != "" {
+		k, err := strconv.Atoi(v)
+		if err != nil || k < 30000 || k > 39999 {
+			log.Printf("Announcements disabled: ANNOUNCEMENT_KIND must be an addressable kind (30000-39999)")
+			return nil
+		}
+		kind = k
+	}
+	return &announcementSource{Kind: kind, Pubkey: pubkey, DTag: dTag}
+}
+
+// initAnnouncements starts the announcement poller if one is configured
+func initAnnouncements() {
+	src := parseAnnouncementSource()
+	if src == nil {
+		return
+	}
+	log.Printf("Announcements from %d:%s:%s, refreshed every %v", src.Kind, src.Pubkey[:16], src.DTag, announcementRefreshInterval)
+	go func() {
+		for {
+			refreshAnnouncement(src)
+			time.Sleep(announcementRefreshInterval)
+		}
+	}()
+}
+
+// refreshAnnouncement fetches the announcement event and swaps in the result
+// A failed fetch keeps the last good banner, since a relay timeout looks the same as a
+// missing event; the operator removes the banner by replacing the event with empty
+// content. Older versions are ignored, and a panic is logged and swallowed so a bad
+// event can never take the server down
+func refreshAnnouncement(src *announcementSource) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Announcement refresh panicked: %v", rec)
+		}
+	}()
+
+	relays := append(relaysFor(RelayPurposeRead), relaysFor(RelayPurposeMetadata)...)
+	evt := fetchReplaceableEvent(relays, src.Kind, src.Pubkey, src.DTag)
+	if evt == nil {
+		if currentAnnouncement.Load() != nil {
+			log.Printf("Announcement not found, keeping the current banner")
+		}
+		return
+	}
+	if evt.PubKey != src.Pubkey || !verifyEvent(evt) {
+		log.Printf("Announcement %s has an invalid ID or signature, ignoring", shortID(evt.ID))
+		return
+	}
+	if prev := currentAnnouncement.Load(); prev != nil && evt.CreatedAt < prev.CreatedAt {
+		return
+	}
+	if strings.TrimSpace(evt.Content) == "" {
+		if currentAnnouncement.Swap(nil) != nil {
+			log.Printf("Announcement removed, banner cleared")
+		}
+		return
+	}
+
+	a := &Announcement{
+		ID:          evt.ID,
+		CreatedAt:   evt.CreatedAt,
+		ContentHTML: processContentToHTML(evt.Content),
+	}
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "expiration" {
+			a.Expiration, _ = strconv.ParseInt(tag[1], 10, 64)
+		}
+	}
+	if prev := currentAnnouncement.Swap(a); prev == nil || prev.ID != a.ID {
+		log.Printf("Announcement updated: %s", a.ID)
+	}
+}
+
+// announcementFor returns the banner to show on this request, or nil when there's
+// no announcement, it has expired, or the visitor dismissed it
+func announcementFor(r *http.Request) *Announcement {
+	a := currentAnnouncement.Load()
+	if a == nil || a.Expired() {
+		return nil
+	}
+	if cookie, err := r.Cookie(announcementDismissCookie); err == nil && cookie.Value == a.ID {
+		return nil
+	}
+	return a
+}
+
+// htmlDismissAnnouncementHandler remembers the dismissed announcement ID in a cookie
+func htmlDismissAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20", http.StatusSeeOther)
+		return
+	}
+
+	if id := strings.TrimSpace(r.FormValue("id")); isValidEventID(id) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     announcementDismissCookie,
+			Value:    id,
+			Path:     "/",
+			MaxAge:   365 * 24 * 60 * 60,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	http.Redirect(w, r, refererReturnURL(r), http.StatusSeeOther)
+}
+
+// announcementTemplate is parsed into every page template
+// Use {{template "announcement-styles"}} inside <style> and {{template "announcement" .Announcement}} after <body>
+var announcementTemplate = `{{define "announcement-styles"}}
+    .announcement-banner {
+      display: flex;
+      align-items: flex-start;
+      gap: 12px;
+      padding: 10px 16px;
+      background: var(--bg-secondary, #f5f5f5);
+      border-bottom: 1px solid var(--border-color, #ddd);
+      font-size: 14px;
+    }
+    .announcement-content {
+      flex: 1;
+      min-width: 0;
+      overflow-wrap: anywhere;
+    }
+    .announcement-content img {
+      max-height: 120px;
+    }
+    .announcement-dismiss {
+      background: none;
+      border: none;
+      cursor: pointer;
+      font-size: 18px;
+      line-height: 1;
+      color: var(--text-muted, #666);
+    }
+{{end}}{{define "announcement"}}{{with .}}
+  <div class="announcement-banner" role="status">
+    <div class="announcement-content">{{.ContentHTML.HTML}}</div>
+    <form method="POST" action="/html/announcement/dismiss" class="inline-form">
+      <input type="hidden" name="id" value="{{.ID}}">
+      <button type="submit" class="announcement-dismiss" aria-label="Dismiss announcement">&times;</button>
+    </form>
+  </div>
+{{end}}{{end}}`
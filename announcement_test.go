@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestRefreshAnnouncement(t *testing.T) {
+	s := startTestServer(t)
+	t.Cleanup(func() { currentAnnouncement.Store(nil) })
+
+	operator := testutil.NewKeypair("announcement-operator")
+	src := &announcementSource{Kind: defaultAnnouncementKind, Pubkey: operator.PubKey, DTag: "banner"}
+	now := time.Now().Unix()
+	announce := func(createdAt int64, content string) testutil.Event {
+		return testutil.MustSign(operator, testutil.Event{Kind: src.Kind, CreatedAt: createdAt, Content: content, Tags: [][]string{{"d", "banner"}}})
+	}
+	current := func() string {
+		if a := currentAnnouncement.Load(); a != nil {
+			return a.ID
+		}
+		return ""
+	}
+
+	first := announce(now-300, "Maintenance tonight")
+	s.Relay.Publish(first)
+	refreshAnnouncement(src)
+	if current() != first.ID {
+		t.Fatalf("banner = %q, want %s", current(), shortID(first.ID))
+	}
+
+	// Relays that don't answer keep the banner
+	s.Relay.Reset()
+	refreshAnnouncement(src)
+	if current() != first.ID {
+		t.Errorf("banner cleared after a failed fetch")
+	}
+
+	// A relay replaying a newer ID and signature over other content is ignored
+	second := announce(now-200, "Maintenance moved to Friday")
+	forged := second
+	forged.Content = "Send your nsec to evil.example"
+	s.Relay.Publish(forged)
+	refreshAnnouncement(src)
+	if current() != first.ID {
+		t.Errorf("banner = %q after a forged announcement, want %s", current(), shortID(first.ID))
+	}
+
+	// A newer version replaces the banner, and an older one can't bring the old text back
+	s.Relay.Reset()
+	s.Relay.Publish(second)
+	refreshAnnouncement(src)
+	if current() != second.ID {
+		t.Errorf("banner = %q, want the newer announcement %s", current(), shortID(second.ID))
+	}
+	s.Relay.Reset()
+	s.Relay.Publish(first)
+	refreshAnnouncement(src)
+	if current() != second.ID {
+		t.Errorf("banner = %q after an older version, want %s", current(), shortID(second.ID))
+	}
+
+	// Replacing the event with empty content removes the banner
+	s.Relay.Reset()
+	s.Relay.Publish(announce(now-100, ""))
+	refreshAnnouncement(src)
+	if current() != "" {
+		t.Errorf("banner = %q, want it removed", current())
+	}
+}
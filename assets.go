@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Self-hosted fonts and icons
+// The branding config can declare font files and an SVG icon sprite kept in static/.
+// They are referenced by fingerprinted URLs (/static/fonts/inter.woff2?v=<hash>) that
+// are cached for a year, so nothing is loaded from a CDN and the CSP stays 'self'.
+//
+//	"font_family": "Inter, system-ui, sans-serif",
+//	"fonts": [{"family": "Inter", "file": "fonts/inter.woff2", "weight": "100 900"}],
+//	"icon_sprite": "icons.svg"
+//
+// The sprite holds <symbol id="icon-bell"> etc.; templates call {{icon "bell" "🔔"}},
+// which falls back to the emoji when there's no sprite or it lacks that symbol.
+
+// staticDir is where /static/ is served from
+const staticDir = "./static"
+
+// BrandingFont is a self-hosted font face
+type BrandingFont struct {
+	Family string `json:"family"`
+	File   string `json:"file"`   // Path under static/, .woff2 or .woff
+	Weight string `json:"weight"` // e.g. "400", "100 900" for variable fonts (default: normal)
+	Style  string `json:"style"`  // "normal" or "italic" (default: normal)
+
+	// Derived on load
+	URL  string `json:"-"` // Fingerprinted /static/ URL
+	Type string `json:"-"` // MIME type for the preload link
+}
+
+var (
+	fontFamilyRegex = regexp.MustCompile(`^[A-Za-z0-9 ,'"-]+$`)
+	fontNameRegex   = regexp.MustCompile(`^[A-Za-z0-9 -]+$`)
+	fontWeightRegex = regexp.MustCompile(`^(normal|bold|[1-9]00( [1-9]00)?)$`)
+)
+
+// fontTypes maps the allowed font file extensions to their preload type and @font-face format
+var fontTypes = map[string][2]string{
+	".woff2": {"font/woff2", "woff2"},
+	".woff":  {"font/woff", "woff"},
+}
+
+// Format returns the @font-face format() hint
+func (f BrandingFont) Format() string {
+	return fontTypes[path.Ext(f.File)][1]
+}
+
+// validateAssets checks the font and icon settings and fingerprints the files
+func (b *Branding) validateAssets() error {
+	if b.FontFamily != "" {
+		if !fontFamilyRegex.MatchString(b.FontFamily) {
+			return fmt.Errorf("font_family %q may only contain font names, quotes and commas", b.FontFamily)
+		}
+		b.FontStack = template.CSS(b.FontFamily)
+	}
+
+	for i := range b.Fonts {
+		f := &b.Fonts[i]
+		if !fontNameRegex.MatchString(f.Family) {
+			return fmt.Errorf("font %d: family %q must be letters, digits, spaces or hyphens", i, f.Family)
+		}
+		types, ok := fontTypes[path.Ext(f.File)]
+		if !ok {
+			return fmt.Errorf("font %s: file %q must be .woff2 or .woff", f.Family, f.File)
+		}
+		f.Type = types[0]
+		if f.Weight == "" {
+			f.Weight = "normal"
+		}
+		if !fontWeightRegex.MatchString(f.Weight) {
+			return fmt.Errorf("font %s: weight %q must be normal, bold, or e.g. 400 or 100 900", f.Family, f.Weight)
+		}
+		if f.Style == "" {
+			f.Style = "normal"
+		}
+		if f.Style != "normal" && f.Style != "italic" {
+			return fmt.Errorf("font %s: style must be normal or italic", f.Family)
+		}
+		url, _, err := fingerprintStaticAsset(f.File)
+		if err != nil {
+			return fmt.Errorf("font %s: %v", f.Family, err)
+		}
+		f.URL = url
+	}
+
+	if b.IconSprite != "" {
+		if path.Ext(b.IconSprite) != ".svg" {
+			return fmt.Errorf("icon_sprite %q must be an .svg file", b.IconSprite)
+		}
+		url, data, err := fingerprintStaticAsset(b.IconSprite)
+		if err != nil {
+			return fmt.Errorf("icon_sprite: %v", err)
+		}
+		// Served from our origin, so it gets the same checks as an inline logo
+		if err := checkLogoSVG(string(data)); err != nil {
+			return fmt.Errorf("icon_sprite: %v", err)
+		}
+		b.iconSpriteURL = url
+		b.iconIDs = spriteSymbolIDs(data)
+	}
+	return nil
+}
+
+// fingerprintStaticAsset reads a file under static/ and returns its URL with a content hash
+func fingerprintStaticAsset(name string) (string, []byte, error) {
+	clean := path.Clean("/" + name)[1:]
+	if clean != name || strings.HasPrefix(name, ".") {
+		return "", nil, fmt.Errorf("%q must be a plain path under static/", name)
+	}
+	data, err := os.ReadFile(filepath.Join(staticDir, filepath.FromSlash(clean)))
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(data)
+	return "/static/" + clean + "?v=" + hex.EncodeToString(sum[:6]), data, nil
+}
+
+// spriteSymbolIDs returns the ids of the sprite's <symbol> elements
+func spriteSymbolIDs(data []byte) map[string]bool {
+	ids := make(map[string]bool)
+	dec := xml.NewDecoder(strings.NewReader(string(data)))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ids
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Local == "symbol" {
+			for _, attr := range el.Attr {
+				if attr.Name.Local == "id" {
+					ids[attr.Value] = true
+				}
+			}
+		}
+	}
+}
+
+// icon renders a sprite icon, or the fallback text when the sprite doesn't have it
+// Used as a template func: {{icon "bell" "🔔"}} references <symbol id="icon-bell">
+func icon(name, fallback string) template.HTML {
+	b := branding()
+	id := "icon-" + name
+	if b.iconSpriteURL == "" || !b.iconIDs[id] {
+		return template.HTML(template.HTMLEscapeString(fallback))
+	}
+	return template.HTML(`<svg class="icon" aria-hidden="true"><use href="` +
+		template.HTMLEscapeString(b.iconSpriteURL+"#"+id) + `"></use></svg>`)
+}
+
+// staticHandler serves static/, caching fingerprinted (?v=) requests for a year
+func staticHandler() http.Handler {
+	fs := http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("v") {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		fs.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// Instance branding
+// BRANDING_CONFIG points at a JSON file that renames the instance and sets its logo,
+// accent colour and footer links. Every page template parses brandingTemplate, so the
+// values reach the <title>, og:site_name and theme-color meta tags, the nav and the
+// footer without touching page data. The file is reloaded on SIGHUP like RELAY_CONFIG.
+//
+//	{
+//	  "site_name": "Example Social",
+//	  "tagline": "A zero-JS Nostr client",
+//	  "logo": "/static/logo.png",
+//	  "accent_color": "#0f766e",
+//	  "footer_links": [{"label": "Rules", "url": "/static/rules.html"}]
+//	}
+//
+// "logo_svg" takes inline SVG instead of "logo"; it is checked on load and rendered as an
+// <img> data URI, so even a hostile file can't script the page.
+
+const defaultSiteName = "Nostr Hypermedia"
+const defaultTagline = "Zero-JS Hypermedia Browser"
+
+// maxLogoSVGSize caps inline SVG logos
+const maxLogoSVGSize = 32 * 1024
+
+// BrandingLink is a footer link
+type BrandingLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Branding is the instance's name, logo, accent colour and footer links
+type Branding struct {
+	SiteName    string         `json:"site_name"`
+	Tagline     string         `json:"tagline"`
+	Logo        string         `json:"logo"`
+	LogoSVG     string         `json:"logo_svg"`
+	AccentColor string         `json:"accent_color"`
+	FooterLinks []BrandingLink `json:"footer_links"`
+	FontFamily  string         `json:"font_family"` // CSS font-family stack for page text
+	Fonts       []BrandingFont `json:"fonts"`       // Self-hosted faces, see assets.go
+	IconSprite  string         `json:"icon_sprite"` // SVG sprite under static/
+
+	// Derived on load
+	LogoSrc       template.URL `json:"-"` // Logo, or LogoSVG as a data URI
+	AccentHover   string       `json:"-"` // AccentColor darkened for hover states
+	FontStack     template.CSS `json:"-"` // FontFamily, checked for CSS output
+	iconSpriteURL string
+	iconIDs       map[string]bool
+}
+
+var defaultBranding = &Branding{SiteName: defaultSiteName, Tagline: defaultTagline}
+
+var currentBranding atomic.Pointer[Branding]
+
+func init() {
+	currentBranding.Store(defaultBranding)
+}
+
+// branding returns the active branding; used as a template func
+func branding() *Branding {
+	return currentBranding.Load()
+}
+
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate checks the configuration and fills in defaults and derived fields
+func (b *Branding) Validate() error {
+	b.SiteName = strings.TrimSpace(b.SiteName)
+	if b.SiteName == "" {
+		b.SiteName = defaultSiteName
+	}
+	if len(b.SiteName) > 64 {
+		return errors.New("site_name is longer than 64 characters")
+	}
+	b.Tagline = strings.TrimSpace(b.Tagline)
+	if b.Tagline == "" {
+		b.Tagline = defaultTagline
+	}
+
+	if b.AccentColor != "" {
+		if !hexColorRegex.MatchString(b.AccentColor) {
+			return fmt.Errorf("accent_color %q must be a hex colour like #0f766e", b.AccentColor)
+		}
+		b.AccentHover = darkenHexColor(b.AccentColor, 0.85)
+	}
+
+	switch {
+	case b.Logo != "" && b.LogoSVG != "":
+		return errors.New("set logo or logo_svg, not both")
+	case b.Logo != "":
+		if !isBrandingURL(b.Logo) {
+			return fmt.Errorf("logo %q must be a site path (/static/...) or an https URL", b.Logo)
+		}
+		b.LogoSrc = template.URL(b.Logo)
+	case b.LogoSVG != "":
+		if len(b.LogoSVG) > maxLogoSVGSize {
+			return fmt.Errorf("logo_svg is larger than %d bytes", maxLogoSVGSize)
+		}
+		if err := checkLogoSVG(b.LogoSVG); err != nil {
+			return fmt.Errorf("logo_svg: %v", err)
+		}
+		b.LogoSrc = template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(b.LogoSVG)))
+	}
+
+	for i, link := range b.FooterLinks {
+		if strings.TrimSpace(link.Label) == "" {
+			return fmt.Errorf("footer link %d has no label", i)
+		}
+		if !isBrandingURL(link.URL) {
+			return fmt.Errorf("footer link %q must be a site path or an https URL", link.Label)
+		}
+	}
+	return b.validateAssets()
+}
+
+// isBrandingURL accepts site-relative paths and https URLs
+func isBrandingURL(s string) bool {
+	if strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// logoSVGAllowedElements are the SVG elements a logo or icon sprite may use; anything that can carry
+// script, load external resources or embed HTML is rejected
+var logoSVGAllowedElements = map[string]bool{
+	"svg": true, "g": true, "path": true, "circle": true, "ellipse": true, "rect": true,
+	"line": true, "polyline": true, "polygon": true, "text": true, "tspan": true,
+	"title": true, "desc": true, "defs": true, "linearGradient": true, "radialGradient": true,
+	"stop": true, "clipPath": true, "mask": true, "symbol": true, "use": true,
+}
+
+// checkLogoSVG rejects SVG that isn't well-formed, isn't rooted at <svg>, or uses
+// elements outside the allowlist, event handler attributes or external references
+func checkLogoSVG(svg string) error {
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("not well-formed: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 && t.Name.Local != "svg" {
+				return errors.New("root element must be <svg>")
+			}
+			if !logoSVGAllowedElements[t.Name.Local] {
+				return fmt.Errorf("element <%s> is not allowed", t.Name.Local)
+			}
+			for _, attr := range t.Attr {
+				name := strings.ToLower(attr.Name.Local)
+				value := strings.ToLower(strings.TrimSpace(attr.Value))
+				if strings.HasPrefix(name, "on") {
+					return fmt.Errorf("attribute %s is not allowed", attr.Name.Local)
+				}
+				if name == "href" && !strings.HasPrefix(value, "#") {
+					return errors.New("only local (#id) references are allowed")
+				}
+				if strings.Contains(value, "url(") && !strings.Contains(value, "url(#") {
+					return errors.New("only local url(#id) references are allowed")
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				return errors.New("processing instructions are not allowed")
+			}
+		case xml.Directive:
+			return errors.New("DOCTYPE and entity declarations are not allowed")
+		}
+	}
+	if depth != 0 {
+		return errors.New("unbalanced elements")
+	}
+	return nil
+}
+
+// darkenHexColor scales each channel of a #rgb or #rrggbb colour by factor
+func darkenHexColor(color string, factor float64) string {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	var out bytes.Buffer
+	out.WriteByte('#')
+	for i := 0; i < 6; i += 2 {
+		v, _ := strconv.ParseUint(hex[i:i+2], 16, 8)
+		fmt.Fprintf(&out, "%02x", int(float64(v)*factor))
+	}
+	return out.String()
+}
+
+// loadBranding reads and validates a branding configuration file
+func loadBranding(path string) (*Branding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Branding
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid branding config %s: %v", path, err)
+	}
+	return &b, nil
+}
+
+// initBranding loads BRANDING_CONFIG at startup and reloads it on SIGHUP
+// An invalid file at startup is fatal; an invalid file on reload keeps the previous branding
+func initBranding() {
+	path := os.Getenv("BRANDING_CONFIG")
+	if path == "" {
+		return
+	}
+
+	b, err := loadBranding(path)
+	if err != nil {
+		log.Fatalf("Failed to load branding config: %v", err)
+	}
+	currentBranding.Store(b)
+	log.Printf("Loaded branding from %s (%s)", path, b.SiteName)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			b, err := loadBranding(path)
+			if err != nil {
+				log.Printf("Branding reload failed, keeping previous branding: %v", err)
+				continue
+			}
+			currentBranding.Store(b)
+			log.Printf("Reloaded branding from %s (%s)", path, b.SiteName)
+		}
+	}()
+}
+
+// brandingTemplate is parsed into every page template, alongside announcementTemplate
+// Use {{template "branding-head"}} in <head>, {{template "branding-styles"}} at the end of
+// <style>, {{template "branding-logo"}} at the start of <nav> and {{template "branding-footer"}}
+// in <footer>. Templates parsing it need the "branding" and "icon" funcs
+var brandingTemplate = `{{define "branding-head"}}{{with branding}}
+  <meta property="og:site_name" content="{{.SiteName}}">
+  {{if .AccentColor}}<meta name="theme-color" content="{{.AccentColor}}">{{end}}
+  {{range .Fonts}}<link rel="preload" href="{{.URL}}" as="font" type="{{.Type}}" crossorigin>
+  {{end}}
+{{end}}{{end}}{{define "branding-styles"}}{{with branding}}{{if .AccentColor}}
+    :root, :root:not(.light), html.dark, html.light {
+      --accent: {{.AccentColor}};
+      --accent-hover: {{.AccentHover}};
+    }
+{{end}}{{if .LogoSrc}}
+    .brand-logo { display: flex; align-items: center; padding: 0 8px 0 0; }
+{{end}}{{range .Fonts}}
+    @font-face {
+      font-family: "{{.Family}}";
+      src: url("{{.URL}}") format("{{.Format}}");
+      font-weight: {{.Weight}};
+      font-style: {{.Style}};
+      font-display: swap;
+    }
+{{end}}{{if .FontStack}}
+    body { font-family: {{.FontStack}}; }
+{{end}}{{if .IconSprite}}
+    .icon { width: 1.1em; height: 1.1em; fill: currentColor; vertical-align: -0.15em; }
+{{end}}{{end}}{{end}}{{define "branding-logo"}}{{with branding}}{{if .LogoSrc}}
+      <a href="/" class="brand-logo"><img src="{{.LogoSrc}}" alt="{{.SiteName}}" height="28"></a>
+{{end}}{{end}}{{end}}{{define "branding-footer"}}{{with branding}}{{.Tagline}}{{range .FooterLinks}} · <a href="{{.URL}}" class="text-link">{{.Label}}</a>{{end}}{{end}}{{end}}`
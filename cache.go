@@ -92,6 +92,21 @@ func (c *ProfileCache) GetMultiple(pubkeys []string) (found map[string]*ProfileI
 	return found, missing
 }
 
+// Len returns the number of cached profiles, including expired entries not yet evicted
+func (c *ProfileCache) Len() int {
+	return syncMapLen(&c.profiles)
+}
+
+// syncMapLen counts the entries in a sync.Map
+func syncMapLen(m *sync.Map) int {
+	n := 0
+	m.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
 // EventCache provides in-memory caching for relay queries
 type EventCache struct {
 	mu      sync.RWMutex
@@ -154,6 +169,26 @@ func buildEventCacheKey(relays []string, filter Filter) string {
 	if filter.Until != nil {
 		sb.WriteString(fmt.Sprintf("|until:%d", *filter.Until))
 	}
+	if len(filter.IDs) > 0 {
+		sortedIDs := append([]string(nil), filter.IDs...)
+		sort.Strings(sortedIDs)
+		sb.WriteString("|ids:")
+		sb.WriteString(strings.Join(sortedIDs, ","))
+	}
+	if len(filter.PTags) > 0 {
+		sb.WriteString("|#p:")
+		sb.WriteString(strings.Join(filter.PTags, ","))
+	}
+	// Tag filters in name order so map iteration doesn't change the key
+	tagNames := make([]string, 0, len(filter.Tags))
+	for name := range filter.Tags {
+		tagNames = append(tagNames, name)
+	}
+	sort.Strings(tagNames)
+	for _, name := range tagNames {
+		sb.WriteString("|#" + name + ":")
+		sb.WriteString(strings.Join(filter.Tags[name], ","))
+	}
 
 	// Hash the key to keep it short
 	hash := sha256.Sum256([]byte(sb.String()))
@@ -265,6 +300,28 @@ func (c *EventCache) cleanup() {
 	}
 }
 
+// DeleteAuthor drops every cached query that returned an event by pubkey
+func (c *EventCache) DeleteAuthor(pubkey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		for _, evt := range entry.Events {
+			if evt.PubKey == pubkey {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// Len returns the number of cached queries
+func (c *EventCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
 // ContactCache stores contact lists with short TTL
 type ContactCache struct {
 	contacts sync.Map
@@ -305,6 +362,16 @@ func (c *ContactCache) Set(pubkey string, contacts []string) {
 	})
 }
 
+// Delete removes a contact list from the cache
+func (c *ContactCache) Delete(pubkey string) {
+	c.contacts.Delete(pubkey)
+}
+
+// Len returns the number of cached contact lists
+func (c *ContactCache) Len() int {
+	return syncMapLen(&c.contacts)
+}
+
 // RelayListCache stores relay lists with TTL
 type RelayListCache struct {
 	relayLists sync.Map
@@ -353,6 +420,81 @@ func (c *RelayListCache) Set(pubkey string, relayList *RelayList) {
 	})
 }
 
+// Delete removes a relay list from the cache
+func (c *RelayListCache) Delete(pubkey string) {
+	c.relayLists.Delete(pubkey)
+}
+
+// Len returns the number of cached relay lists, including not-found entries
+func (c *RelayListCache) Len() int {
+	return syncMapLen(&c.relayLists)
+}
+
+// ReactionCache stores per-event reaction summaries with a short TTL
+// A nil summary means the event was checked and has no reactions
+type ReactionCache struct {
+	reactions sync.Map
+	ttl       time.Duration
+}
+
+type cachedReactions struct {
+	summary   *ReactionsSummary
+	fetchedAt time.Time
+}
+
+// Global reaction cache - 1 minute TTL (counts change quickly)
+var reactionCache = &ReactionCache{
+	ttl: 1 * time.Minute,
+}
+
+// GetMultiple retrieves reaction summaries, returning found ones and list of missing event IDs
+func (c *ReactionCache) GetMultiple(eventIDs []string) (found map[string]*ReactionsSummary, missing []string) {
+	found = make(map[string]*ReactionsSummary)
+	now := time.Now()
+
+	for _, id := range eventIDs {
+		val, ok := c.reactions.Load(id)
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		cached := val.(*cachedReactions)
+		if now.Sub(cached.fetchedAt) > c.ttl {
+			c.reactions.Delete(id)
+			missing = append(missing, id)
+			continue
+		}
+
+		if cached.summary != nil {
+			found[id] = cached.summary
+		}
+	}
+
+	return found, missing
+}
+
+// SetMultiple stores the summaries for every fetched event ID, including ones without reactions
+func (c *ReactionCache) SetMultiple(eventIDs []string, reactions map[string]*ReactionsSummary) {
+	now := time.Now()
+	for _, id := range eventIDs {
+		c.reactions.Store(id, &cachedReactions{
+			summary:   reactions[id],
+			fetchedAt: now,
+		})
+	}
+}
+
+// Delete removes an event's reactions from the cache
+func (c *ReactionCache) Delete(eventID string) {
+	c.reactions.Delete(eventID)
+}
+
+// Len returns the number of cached reaction summaries
+func (c *ReactionCache) Len() int {
+	return syncMapLen(&c.reactions)
+}
+
 // LinkPreview holds Open Graph metadata for a URL
 type LinkPreview struct {
 	URL         string
@@ -438,3 +580,120 @@ func (c *LinkPreviewCache) GetMultiple(urls []string) (found map[string]*LinkPre
 
 	return found, missing
 }
+
+// Len returns the number of cached link previews
+func (c *LinkPreviewCache) Len() int {
+	return syncMapLen(&c.previews)
+}
+
+// EventSighting records a relay delivering an event to us
+type EventSighting struct {
+	Relay  string
+	SeenAt time.Time
+}
+
+// SeenEvent is an event as ingested from relays, with where and when we saw it
+type SeenEvent struct {
+	Event     Event
+	FirstSeen time.Time
+	Sightings []EventSighting
+	Protected bool // NIP-70: has the "-" tag, so must not be re-broadcast
+}
+
+// maxEventSightings bounds the relays recorded per event; later relays aren't listed
+const maxEventSightings = 32
+
+// SeenEventCache keeps recently ingested events and their relay sightings
+// Bounded FIFO: the oldest ingested events are dropped first
+type SeenEventCache struct {
+	mu      sync.Mutex
+	events  map[string]*SeenEvent
+	order   []string
+	maxSize int
+}
+
+// Global ingestion cache - last 10,000 distinct events
+var seenEventCache = NewSeenEventCache(10000)
+
+// NewSeenEventCache creates an ingestion cache holding up to maxSize events
+func NewSeenEventCache(maxSize int) *SeenEventCache {
+	return &SeenEventCache{
+		events:  make(map[string]*SeenEvent),
+		maxSize: maxSize,
+	}
+}
+
+// Record notes that relayURL delivered the event
+// Returns true the first time an event is seen
+func (c *SeenEventCache) Record(evt Event, relayURL string) bool {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seen, ok := c.events[evt.ID]; ok {
+		if len(seen.Sightings) >= maxEventSightings {
+			return false
+		}
+		for _, s := range seen.Sightings {
+			if s.Relay == relayURL {
+				return false
+			}
+		}
+		seen.Sightings = append(seen.Sightings, EventSighting{Relay: relayURL, SeenAt: now})
+		return false
+	}
+
+	stored := evt
+	stored.RelaysSeen = nil
+	c.events[evt.ID] = &SeenEvent{
+		Event:     stored,
+		FirstSeen: now,
+		Sightings: []EventSighting{{Relay: relayURL, SeenAt: now}},
+		Protected: isProtectedEvent(evt.Tags),
+	}
+	c.order = append(c.order, evt.ID)
+
+	for len(c.order) > c.maxSize {
+		delete(c.events, c.order[0])
+		c.order = c.order[1:]
+	}
+	return true
+}
+
+// Get returns a copy of the ingestion record for an event ID
+func (c *SeenEventCache) Get(id string) (*SeenEvent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen, ok := c.events[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *seen
+	copied.Sightings = append([]EventSighting(nil), seen.Sightings...)
+	return &copied, true
+}
+
+// DeleteAuthor drops every ingested event by pubkey
+func (c *SeenEventCache) DeleteAuthor(pubkey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := c.order[:0]
+	for _, id := range c.order {
+		if c.events[id].Event.PubKey == pubkey {
+			delete(c.events, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	c.order = kept
+}
+
+// Len returns the number of events currently held
+func (c *SeenEventCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.events)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Canonical URLs
+// Notes canonicalize on /html/thread/{hex id} and profiles on /html/profile/{hex pubkey}.
+// NIP-19 forms (note1, nevent1, npub1, nprofile1) are accepted on those routes and at the
+// site root, and 301 to the canonical path so share links and caches converge on one URL.
+// naddr has no canonical route; pages link addressable events by the ID of the version shown.
+
+// canonicalThreadPath returns the canonical path for a note
+func canonicalThreadPath(eventID string) string {
+	return "/html/thread/" + eventID
+}
+
+// canonicalProfilePath returns the canonical path for a profile
+func canonicalProfilePath(pubkey string) string {
+	return "/html/profile/" + pubkey
+}
+
+// canonicalPathForIdentifier maps a NIP-19 identifier to its canonical path
+// nevent relay hints are kept as ?hint= parameters
+// Returns "" for identifiers that don't decode or have no canonical route (naddr)
+func canonicalPathForIdentifier(identifier string) string {
+	switch {
+	case strings.HasPrefix(identifier, "note1"):
+		if id, err := DecodeNote(identifier); err == nil {
+			return canonicalThreadPath(id)
+		}
+	case strings.HasPrefix(identifier, "nevent1"):
+		if ne, err := DecodeNEvent(identifier); err == nil {
+			// Relay hints ride along so a miss on our relays can try them
+			path := canonicalThreadPath(ne.EventID)
+			hints := url.Values{}
+			for _, hint := range ne.RelayHints[:min(len(ne.RelayHints), maxThreadHintRelays)] {
+				hints.Add("hint", hint)
+			}
+			if len(hints) > 0 {
+				path += "?" + hints.Encode()
+			}
+			return path
+		}
+	case strings.HasPrefix(identifier, "npub1"):
+		if pk, err := decodeBech32Pubkey(identifier); err == nil {
+			return canonicalProfilePath(pk)
+		}
+	case strings.HasPrefix(identifier, "nprofile1"):
+		if np, err := DecodeNProfile(identifier); err == nil {
+			return canonicalProfilePath(np.Pubkey)
+		}
+	}
+	return ""
+}
+
+// redirectToCanonical sends a 301 to path, keeping the request's query string
+func redirectToCanonical(w http.ResponseWriter, r *http.Request, path string) {
+	if r.URL.RawQuery != "" {
+		if strings.Contains(path, "?") {
+			path += "&" + r.URL.RawQuery
+		} else {
+			path += "?" + r.URL.RawQuery
+		}
+	}
+	http.Redirect(w, r, path, http.StatusMovedPermanently)
+}
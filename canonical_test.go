@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// encodeTLV builds a NIP-19 TLV identifier: the 32-byte special entry, then relay hints
+func encodeTLV(t *testing.T, hrp, hexValue string, relays ...string) string {
+	t.Helper()
+	value, err := hex.DecodeString(hexValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte{0, byte(len(value))}, value...)
+	for _, relay := range relays {
+		data = append(data, 1, byte(len(relay)))
+		data = append(data, relay...)
+	}
+	words, err := bech32ConvertBits(data, 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := bech32Encode(hrp, words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestCanonicalRedirectsKeepQueryParams(t *testing.T) {
+	eventID := "5c83da77af1dec6d7289834998ad7aafbd9e2191396d75ec3cc27f5a77226f36"
+	pubkey := "3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d"
+	note, err := encodeBech32EventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	npub, err := encodeBech32Pubkey(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nevent := encodeTLV(t, "nevent", eventID, "wss://relay.example.com")
+	nprofile := encodeTLV(t, "nprofile", pubkey, "wss://relay.example.com")
+	thread, profile := canonicalThreadPath(eventID), canonicalProfilePath(pubkey)
+
+	tests := []struct {
+		name      string
+		path      string
+		wantPath  string
+		wantQuery url.Values
+	}{
+		{"note alias", "/html/thread/" + note + "?reply=1&fast=1", thread, url.Values{"reply": {"1"}, "fast": {"1"}}},
+		{"note at root", "/" + note + "?fast=1", thread, url.Values{"fast": {"1"}}},
+		{"nevent keeps hints and params", "/html/thread/" + nevent + "?fast=1", thread, url.Values{"hint": {"wss://relay.example.com"}, "fast": {"1"}}},
+		{"nevent at root", "/" + nevent + "?theme=dark", thread, url.Values{"hint": {"wss://relay.example.com"}, "theme": {"dark"}}},
+		{"npub alias", "/html/profile/" + npub + "?tab=replies&until=1700000000", profile, url.Values{"tab": {"replies"}, "until": {"1700000000"}}},
+		{"npub at root", "/" + npub + "?limit=10", profile, url.Values{"limit": {"10"}}},
+		{"nprofile alias", "/html/profile/" + nprofile + "?kinds=1&kinds=6", profile, url.Values{"kinds": {"1", "6"}}},
+		{"escaped values", "/" + npub + "?q=a%26b%3Dc+d", profile, url.Values{"q": {"a&b=c d"}}},
+		{"no params", "/html/profile/" + npub, profile, url.Values{}},
+	}
+
+	handler := traceRequests(newRouter())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusMovedPermanently {
+				t.Fatalf("status %d, want 301", rec.Code)
+			}
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if location.Path != tt.wantPath {
+				t.Errorf("redirected to %s, want %s", location.Path, tt.wantPath)
+			}
+			if got := location.Query(); got.Encode() != tt.wantQuery.Encode() {
+				t.Errorf("query = %v, want %v", got, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestArticleLinksUseCanonicalPath(t *testing.T) {
+	article := &Event{
+		ID:        "5c83da77af1dec6d7289834998ad7aafbd9e2191396d75ec3cc27f5a77226f36",
+		PubKey:    "3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d",
+		CreatedAt: 1700000000,
+		Kind:      30023,
+		Tags:      [][]string{{"d", "my-article"}, {"title", "My Article"}},
+		Content:   "Long-form content",
+	}
+	naddr, err := EncodeNAddr(30023, article.PubKey, "my-article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// naddr has no canonical route, so nothing may link to one
+	if path := canonicalPathForIdentifier(naddr); path != "" {
+		t.Errorf("canonicalPathForIdentifier(naddr) = %q, want none", path)
+	}
+
+	quoted := renderQuotedNote(article, nil)
+	assertContains(t, quoted, `href="`+canonicalThreadPath(article.ID)+`"`)
+	if strings.Contains(quoted, naddr) {
+		t.Errorf("quoted article links to its naddr:\n%s", quoted)
+	}
+}
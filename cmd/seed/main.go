@@ -0,0 +1,265 @@
+// Command seed populates a relay with a realistic, reproducible mix of
+// Nostr events for local development.
+//
+// Usage:
+//
+//	go run ./cmd/seed -relay ws://localhost:7777
+//	go run ./cmd/seed -serve :7777   # start an in-memory relay and seed it
+//
+// Keypairs and content are derived from -seed, and timestamps from -base,
+// so the same flags always produce the same event IDs.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"nostr-hypermedia/testutil"
+)
+
+var (
+	firstNames = []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi",
+		"ivan", "judy", "mallory", "niaj", "olivia", "peggy", "rupert", "sybil",
+		"trent", "uma", "victor", "walter", "xena", "yusuf", "zoe", "quinn"}
+	topics = []string{"nostr", "bitcoin", "hypermedia", "gardening", "coffee", "music", "rust", "golang"}
+	notes  = []string{
+		"Just shipped a new version of my relay. Faster than ever.",
+		"Zero-JS web apps are underrated. HTML forms still work great.",
+		"Anyone else running their own relay at home?",
+		"Morning coffee and a good book. Perfect start.",
+		"Hot take: pagination links beat infinite scroll.",
+		"Reading up on NIP-46 remote signing today.",
+		"Planted tomatoes this weekend 🍅",
+		"What's everyone's favorite nostr client and why?",
+		"Learning Go has been a blast. The standard library is so good.",
+		"The best UI is the one you don't notice.",
+		"GM nostr! ☀️",
+		"Long thread incoming about decentralized identity...",
+	}
+	replies = []string{
+		"Totally agree!", "Interesting, tell me more.", "I'm not so sure about that.",
+		"This is the way.", "Great point 👏", "Source?", "Same here!", "Love this.",
+	}
+)
+
+func main() {
+	relayURL := flag.String("relay", "", "relay websocket URL to publish to (e.g. ws://localhost:7777)")
+	serve := flag.String("serve", "", "start an in-memory relay on this address, seed it, and keep serving")
+	seed := flag.Int64("seed", 1, "seed for keypairs and content selection")
+	users := flag.Int("users", 24, "number of users to generate")
+	base := flag.Int64("base", 0, "base unix timestamp for created_at (default: current hour)")
+	flag.Parse()
+
+	if *relayURL == "" && *serve == "" {
+		fmt.Fprintln(os.Stderr, "seed: one of -relay or -serve is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseTime := *base
+	if baseTime == 0 {
+		baseTime = time.Now().Truncate(time.Hour).Unix()
+	}
+
+	events := generate(*seed, *users, baseTime)
+
+	if *serve != "" {
+		serveAndSeed(*serve, events)
+		return
+	}
+
+	published, err := publish(*relayURL, events)
+	if err != nil {
+		log.Fatalf("seed: %v", err)
+	}
+	log.Printf("Published %d/%d events to %s", published, len(events), *relayURL)
+}
+
+// generate builds the full set of signed seed events
+func generate(seed int64, userCount int, base int64) []testutil.Event {
+	rng := rand.New(rand.NewSource(seed))
+	if userCount < 4 {
+		userCount = 4
+	}
+
+	keys := make([]*testutil.Keypair, userCount)
+	var events []testutil.Event
+
+	// Profiles
+	for i := range keys {
+		name := firstNames[i%len(firstNames)]
+		if i >= len(firstNames) {
+			name += strconv.Itoa(i / len(firstNames))
+		}
+		keys[i] = testutil.NewKeypair(fmt.Sprintf("seed-%d-user-%d", seed, i))
+		metadata, _ := json.Marshal(map[string]string{
+			"name":         name,
+			"display_name": strings.ToUpper(name[:1]) + name[1:],
+			"about":        fmt.Sprintf("Interested in #%s and #%s.", topics[rng.Intn(len(topics))], topics[rng.Intn(len(topics))]),
+			"picture":      fmt.Sprintf("https://robohash.org/%s.png?size=128x128", name),
+			"nip05":        name + "@example.com",
+		})
+		events = append(events, testutil.Profile(keys[i], base-86400, string(metadata)))
+	}
+
+	// Follows among themselves
+	for i, kp := range keys {
+		var follows []string
+		for j, other := range keys {
+			if i != j && rng.Intn(3) == 0 {
+				follows = append(follows, other.PubKey)
+			}
+		}
+		events = append(events, testutil.ContactList(kp, base-86000, follows...))
+	}
+
+	// Notes, each with a nested reply thread, reactions and occasional reposts
+	ts := base - 3600*12
+	for i := 0; i < userCount*2; i++ {
+		author := keys[rng.Intn(userCount)]
+		topic := topics[rng.Intn(len(topics))]
+		ts += int64(60 + rng.Intn(900))
+		root := testutil.Note(author, ts, notes[rng.Intn(len(notes))]+" #"+topic, []string{"t", topic})
+		events = append(events, root)
+
+		parent := root
+		depth := rng.Intn(4)
+		for d := 0; d < depth; d++ {
+			replier := keys[rng.Intn(userCount)]
+			reply := testutil.Reply(replier, ts+int64(d+1)*120, replies[rng.Intn(len(replies))], root, parent)
+			events = append(events, reply)
+			parent = reply
+		}
+
+		for r := rng.Intn(5); r > 0; r-- {
+			reactor := keys[rng.Intn(userCount)]
+			content := "+"
+			if rng.Intn(3) == 0 {
+				content = "🔥"
+			}
+			events = append(events, testutil.Reaction(reactor, ts+int64(r*30), content, root))
+		}
+
+		if rng.Intn(5) == 0 {
+			events = append(events, testutil.Repost(keys[rng.Intn(userCount)], ts+600, root))
+		}
+	}
+
+	events = append(events, specialKinds(keys, base)...)
+	return events
+}
+
+// specialKinds builds one example of each non-note kind the client renders
+func specialKinds(keys []*testutil.Keypair, base int64) []testutil.Event {
+	ts := strconv.FormatInt
+	return []testutil.Event{
+		// Long-form article (NIP-23)
+		testutil.MustSign(keys[0], testutil.Event{
+			Kind: 30023, CreatedAt: base - 7200,
+			Content: "# Building zero-JS clients\n\nHypermedia controls let the server drive the UI.\n\n" +
+				"## Why forms\n\nForms are the original **actions**. They work everywhere.",
+			Tags: [][]string{{"d", "zero-js-clients"}, {"title", "Building zero-JS clients"},
+				{"summary", "Why hypermedia controls still matter"}, {"published_at", ts(base-7200, 10)}, {"t", "hypermedia"}},
+		}),
+		// Poll (NIP-88)
+		testutil.MustSign(keys[1], testutil.Event{
+			Kind: 1068, CreatedAt: base - 5400, Content: "Which page should load fastest?",
+			Tags: [][]string{{"option", "a", "Timeline"}, {"option", "b", "Threads"}, {"option", "c", "Profiles"},
+				{"polltype", "singlechoice"}, {"endsAt", ts(base+86400, 10)}},
+		}),
+		// Calendar event (NIP-52)
+		testutil.MustSign(keys[2], testutil.Event{
+			Kind: 31923, CreatedAt: base - 5000, Content: "Monthly meetup for relay operators.",
+			Tags: [][]string{{"d", "relay-meetup"}, {"title", "Relay Operators Meetup"},
+				{"start", ts(base+7*86400, 10)}, {"end", ts(base+7*86400+7200, 10)}, {"location", "Online"}},
+		}),
+		// Classified listing (NIP-99)
+		testutil.MustSign(keys[3], testutil.Event{
+			Kind: 30402, CreatedAt: base - 4000, Content: "Lightly used hardware signer, works perfectly.",
+			Tags: [][]string{{"d", "hw-signer"}, {"title", "Hardware signer for sale"},
+				{"summary", "Like new"}, {"price", "50000", "SAT"}, {"location", "Berlin"}, {"status", "active"}},
+		}),
+		// Torrent (NIP-35)
+		testutil.MustSign(keys[1], testutil.Event{
+			Kind: 2003, CreatedAt: base - 3000, Content: "Public domain footage of the first relay meetup.",
+			Tags: [][]string{{"title", "Relay Meetup 2024 (1080p)"}, {"x", "c9e15763f722f23e98a29decdfae341b98d53056"},
+				{"file", "meetup-2024/meetup.mkv", "1468006400"}, {"file", "meetup-2024/slides.pdf", "2411520"},
+				{"tracker", "udp://tracker.example.org:1337"}, {"tracker", "https://tracker.example.com/announce"},
+				{"t", "video"}},
+		}),
+		// Live event (NIP-53)
+		testutil.MustSign(keys[0], testutil.Event{
+			Kind: 30311, CreatedAt: base - 1800, Content: "",
+			Tags: [][]string{{"d", "live-coding"}, {"title", "Live coding a relay"},
+				{"summary", "Building a relay from scratch"}, {"status", "live"},
+				{"streaming", "https://example.com/live/relay.m3u8"}, {"starts", ts(base-1800, 10)},
+				{"current_participants", "42"}, {"p", keys[1].PubKey, "", "Host"}, {"t", "golang"}},
+		}),
+	}
+}
+
+// publish sends each event to the relay and waits for its OK response
+func publish(relayURL string, events []testutil.Event) (int, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(relayURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("connect %s: %w", relayURL, err)
+	}
+	defer conn.Close()
+
+	published := 0
+	for _, evt := range events {
+		if err := conn.WriteJSON([]interface{}{"EVENT", evt}); err != nil {
+			return published, fmt.Errorf("send event: %w", err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			var msg []json.RawMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return published, fmt.Errorf("read response: %w", err)
+			}
+			var msgType string
+			json.Unmarshal(msg[0], &msgType)
+			if msgType != "OK" || len(msg) < 3 {
+				continue
+			}
+			var accepted bool
+			json.Unmarshal(msg[2], &accepted)
+			if accepted {
+				published++
+			} else if len(msg) > 3 {
+				var reason string
+				json.Unmarshal(msg[3], &reason)
+				log.Printf("Relay rejected %s (kind %d): %s", evt.ID[:12], evt.Kind, reason)
+			}
+			break
+		}
+	}
+	return published, nil
+}
+
+// serveAndSeed runs an in-memory relay on addr, preloaded with the events,
+// until interrupted
+func serveAndSeed(addr string, events []testutil.Event) {
+	relay, err := testutil.NewRelayAt(addr)
+	if err != nil {
+		log.Fatalf("seed: listen %s: %v", addr, err)
+	}
+	defer relay.Close()
+	relay.Publish(events...)
+	log.Printf("Serving %d seeded events at %s", len(events), relay.URL())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
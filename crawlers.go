@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Crawler handling
+// Search engines and link unfurlers (Googlebot, Slackbot, Discordbot, ...) fetch thread
+// and profile URLs far more often than people do, and every uncached hit would fan out
+// to the read relays. Requests with a known crawler User-Agent never reach the relays:
+// they get the page as last rendered for a logged-out visitor, kept for 10 minutes so the
+// canonical, oEmbed and og: tags are exactly what people get, or else a minimal page
+// built from the ingestion and profile caches. If neither has anything, the answer is a
+// 503 with Retry-After rather than a relay query. Page views on these routes are counted
+// by visitor type for the stats page.
+
+const (
+	crawlerPageTTL     = 10 * time.Minute
+	crawlerPageMaxSize = 500
+	crawlerPageMaxBody = 1 << 20 // Larger pages aren't kept
+)
+
+// crawlerUserAgents are lowercase User-Agent substrings of common crawlers and unfurlers
+var crawlerUserAgents = []string{
+	"googlebot", "bingbot", "duckduckbot", "yandexbot", "baiduspider", "applebot",
+	"slackbot", "slack-imgproxy", "discordbot", "twitterbot", "facebookexternalhit",
+	"linkedinbot", "telegrambot", "whatsapp", "embedly", "redditbot", "mastodon",
+	"ahrefsbot", "semrushbot", "petalbot", "gptbot", "ccbot", "crawler", "spider",
+}
+
+// isCrawler reports whether the request comes from a known crawler or unfurler
+func isCrawler(r *http.Request) bool {
+	ua := strings.ToLower(r.UserAgent())
+	if ua == "" {
+		return false
+	}
+	for _, token := range crawlerUserAgents {
+		if strings.Contains(ua, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrafficStats counts page views on crawler-handled routes
+type TrafficStats struct {
+	Human           atomic.Int64
+	Crawler         atomic.Int64
+	CrawlerCached   atomic.Int64 // Served a page rendered for a person
+	CrawlerFallback atomic.Int64 // Served the minimal page or a 503
+}
+
+var trafficStats = &TrafficStats{}
+
+type cachedCrawlerPage struct {
+	html       []byte
+	renderedAt time.Time
+}
+
+// CrawlerPageCache holds pages recently rendered for logged-out visitors, by request URI
+type CrawlerPageCache struct {
+	mu    sync.Mutex
+	pages map[string]cachedCrawlerPage
+}
+
+var crawlerPageCache = &CrawlerPageCache{pages: make(map[string]cachedCrawlerPage)}
+
+// Get returns a page rendered within crawlerPageTTL
+func (c *CrawlerPageCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.pages[key]
+	if !ok || time.Since(cached.renderedAt) >= crawlerPageTTL {
+		return nil, false
+	}
+	return cached.html, true
+}
+
+// Set stores a rendered page, dropping expired ones when the cache is full
+func (c *CrawlerPageCache) Set(key string, html []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.pages[key]; !exists && len(c.pages) >= crawlerPageMaxSize {
+		for k, cached := range c.pages {
+			if time.Since(cached.renderedAt) >= crawlerPageTTL {
+				delete(c.pages, k)
+			}
+		}
+		for k := range c.pages {
+			if len(c.pages) < crawlerPageMaxSize {
+				break
+			}
+			delete(c.pages, k) // Arbitrary eviction is fine for a short-lived cache
+		}
+	}
+	c.pages[key] = cachedCrawlerPage{html: html, renderedAt: time.Now()}
+}
+
+// Len returns the number of pages held, including expired ones not yet dropped
+func (c *CrawlerPageCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pages)
+}
+
+// crawlerPageTarget returns which page a request is for ("thread" or "profile") and its
+// hex ID, or "" for requests crawlers may run as usual (redirects, summaries, errors)
+func crawlerPageTarget(r *http.Request) (page, id string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", ""
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/html/thread/"); ok && isValidEventID(id) {
+		return "thread", id
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/html/profile/"); ok && isValidEventID(id) {
+		return "profile", id
+	}
+	return "", ""
+}
+
+// crawlerCacheable reports whether the page rendered for this visitor is the one a
+// crawler would get: logged out, default theme, no flash message
+func crawlerCacheable(r *http.Request) bool {
+	if session := getSessionFromRequest(r); session != nil && session.Connected {
+		return false
+	}
+	if themeClass, _ := getThemeFromRequest(r); themeClass != "" {
+		return false
+	}
+	q := r.URL.Query()
+	return q.Get("success") == "" && q.Get("error") == ""
+}
+
+// crawlerPages serves crawlers from the page cache instead of running next, and keeps
+// what next renders for logged-out visitors for them
+func crawlerPages(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, id := crawlerPageTarget(r)
+		if page == "" {
+			next(w, r)
+			return
+		}
+		key := r.URL.RequestURI()
+
+		if isCrawler(r) {
+			trafficStats.Crawler.Add(1)
+			if html, ok := crawlerPageCache.Get(key); ok {
+				trafficStats.CrawlerCached.Add(1)
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Header().Set("Cache-Control", "public, max-age=300")
+				w.Write(html)
+				return
+			}
+			trafficStats.CrawlerFallback.Add(1)
+			renderCrawlerFallback(w, r, page, id)
+			return
+		}
+
+		trafficStats.Human.Add(1)
+		if !crawlerCacheable(r) {
+			next(w, r)
+			return
+		}
+		rec := &crawlerPageRecorder{ResponseWriter: w}
+		next(rec, r)
+		if rec.cacheable() {
+			crawlerPageCache.Set(key, bytes.Clone(rec.body.Bytes()))
+		}
+	}
+}
+
+// crawlerPageRecorder copies a successful HTML response while passing it through
+type crawlerPageRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *crawlerPageRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *crawlerPageRecorder) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(b) > crawlerPageMaxBody {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes through to the underlying writer so streamed pages still flush
+func (w *crawlerPageRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *crawlerPageRecorder) cacheable() bool {
+	return !w.overflow && w.body.Len() > 0 &&
+		(w.status == 0 || w.status == http.StatusOK) &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "text/html")
+}
+
+// PreviewMeta is the Open Graph and Twitter card metadata unfurlers read. Thread and
+// profile pages carry it, so a crawler served from the page cache gets it too, and the
+// minimal crawler page renders the same block
+type PreviewMeta struct {
+	Type        string // og:type: "article" or "profile"
+	Title       string
+	Description string
+	URL         string // Absolute canonical URL
+	Image       string // http(s) image URL, "" for none
+	LargeImage  bool   // Image is the note's own picture, not an avatar
+}
+
+// notePreview builds the preview metadata for a note: its first picture, or else the
+// author's avatar
+func notePreview(origin string, evt *Event, profile *ProfileInfo) *PreviewMeta {
+	p := &PreviewMeta{
+		Type:        "article",
+		Title:       "Note by " + strings.TrimPrefix(getUserDisplayName(evt.PubKey), "@"),
+		Description: firstLine(evt.Content, 200),
+		URL:         origin + canonicalThreadPath(evt.ID),
+	}
+	if img := noteImage(evt.Content, evt.Tags); img != "" {
+		p.Image, p.LargeImage = img, true
+	} else if profile != nil && isSafeMediaURL(profile.Picture) {
+		p.Image = profile.Picture
+	}
+	return p
+}
+
+// profilePreview builds the preview metadata for a profile
+func profilePreview(origin, pubkey string, profile *ProfileInfo) *PreviewMeta {
+	p := &PreviewMeta{
+		Type:  "profile",
+		Title: strings.TrimPrefix(getUserDisplayName(pubkey), "@"),
+		URL:   origin + canonicalProfilePath(pubkey),
+	}
+	if profile != nil {
+		p.Description = firstLine(profile.About, 200)
+		if isSafeMediaURL(profile.Picture) {
+			p.Image = profile.Picture
+		}
+	}
+	return p
+}
+
+// noteImage returns a note's first picture: an imeta url, or else an image link in the content
+func noteImage(content string, tags [][]string) string {
+	for _, tag := range tags {
+		if img := parseImetaTag(tag); img != nil && isSafeMediaURL(img.URL) {
+			return img.URL
+		}
+	}
+	for _, u := range urlRegex.FindAllString(content, -1) {
+		if imageExtRegex.MatchString(u) && isSafeMediaURL(u) {
+			return u
+		}
+	}
+	return ""
+}
+
+// HTMLCrawlerPageData is the data for the minimal page crawlers get on a cache miss
+type HTMLCrawlerPageData struct {
+	HTMLPageChrome
+	CanonicalURL string
+	Found        bool   // The note or profile was in the caches
+	Noun         string // "note" or "profile"
+	Text         string // Note content or bio, plain text
+	Preview      *PreviewMeta
+}
+
+func init() {
+	registerPageTemplate("crawler", htmlCrawlerContent)
+}
+
+// renderCrawlerFallback renders a note or profile from the ingestion and profile caches
+// Nothing cached is a 503 so crawlers come back later rather than index an empty page
+func renderCrawlerFallback(w http.ResponseWriter, r *http.Request, page, id string) {
+	data := HTMLCrawlerPageData{Noun: "note", CanonicalURL: canonicalThreadPath(id)}
+	title := "Note"
+	if page == "profile" {
+		data.Noun, data.CanonicalURL, title = "profile", canonicalProfilePath(id), "Profile"
+		if profile, ok := profileCache.Get(id); ok && profile != nil {
+			data.Found = true
+			data.Text = profile.About
+			data.Preview = profilePreview(requestOrigin(r), id, profile)
+		}
+	} else if seen, ok := seenEventCache.Get(id); ok {
+		data.Found = true
+		data.Text = seen.Event.Content
+		profile, _ := profileCache.Get(seen.Event.PubKey)
+		data.Preview = notePreview(requestOrigin(r), &seen.Event, profile)
+	}
+	if data.Preview != nil {
+		title = data.Preview.Title
+	}
+	data.HTMLPageChrome = newPageChrome(r, title)
+
+	if !data.Found {
+		w.Header().Set("Retry-After", "600")
+		w.Header().Set("Cache-Control", "no-store")
+		html, err := renderPageHTML("crawler", data)
+		if err != nil {
+			http.Error(w, "Error rendering page", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(html))
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	renderPage(w, "crawler", data)
+}
+
+// previewMetaTemplate is parsed into the thread, profile and page templates
+// Use {{template "preview-meta" .Preview}} inside <head>; a nil Preview renders nothing
+var previewMetaTemplate = `{{define "preview-meta"}}{{with .}}
+  <meta property="og:type" content="{{.Type}}">
+  <meta property="og:title" content="{{.Title}}">
+  {{if .Description}}<meta property="og:description" content="{{.Description}}">{{end}}
+  <meta property="og:url" content="{{.URL}}">
+  {{if .Image}}<meta property="og:image" content="{{.Image}}">{{end}}
+  <meta name="twitter:card" content="{{if .LargeImage}}summary_large_image{{else}}summary{{end}}">
+  <meta name="twitter:title" content="{{.Title}}">
+  {{if .Description}}<meta name="twitter:description" content="{{.Description}}">{{end}}
+  {{if .Image}}<meta name="twitter:image" content="{{.Image}}">{{end}}
+{{end}}{{end}}`
+
+var htmlCrawlerContent = `{{define "head"}}
+  <link rel="canonical" href="{{.CanonicalURL}}">
+  {{template "preview-meta" .Preview}}
+{{end}}
+{{define "content"}}
+<h1>{{.Title}}</h1>
+{{if .Found}}
+{{if .Text}}<p class="crawler-text">{{.Text}}</p>{{end}}
+{{else}}
+<p class="text-muted">This {{.Noun}} hasn't been loaded on this instance recently. Please try again later.</p>
+{{end}}
+{{end}}
+{{define "styles"}}
+    .crawler-text { white-space: pre-wrap; overflow-wrap: anywhere; }
+{{end}}`
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+// getAsCrawler fetches path with an unfurler's User-Agent
+func getAsCrawler(t *testing.T, s *testServer, path string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s as a crawler: status %d", path, resp.StatusCode)
+	}
+	return string(body)
+}
+
+func TestCrawlerPreviewMeta(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("crawler-preview-alice")
+	now := time.Now().Unix()
+	picture := "https://img.example/alice.png"
+	s.Relay.Publish(testutil.MustSign(alice, testutil.Event{Kind: 0, CreatedAt: now - 600,
+		Content: `{"name":"Alice","about":"Cat person","picture":"` + picture + `"}`}))
+	withImage := testutil.Note(alice, now-120, "Look at this cat https://img.example/cat.jpg")
+	textOnly := testutil.Note(alice, now-60, "No pictures today")
+	s.Relay.Publish(withImage, textOnly)
+
+	tests := []struct {
+		name      string
+		path      string
+		cached    bool // A logged-out visitor rendered the page first
+		wantURL   string
+		wantImage string
+		wantCard  string
+	}{
+		{"cached note with a picture", "/html/thread/" + withImage.ID, true,
+			"/html/thread/" + withImage.ID, "https://img.example/cat.jpg", "summary_large_image"},
+		{"cached note falls back to the avatar", "/html/thread/" + textOnly.ID, true,
+			"/html/thread/" + textOnly.ID, picture, "summary"},
+		{"cached profile", "/html/profile/" + alice.PubKey, true,
+			"/html/profile/" + alice.PubKey, picture, "summary"},
+		{"fallback note with a picture", "/html/thread/" + withImage.ID, false,
+			"/html/thread/" + withImage.ID, "https://img.example/cat.jpg", "summary_large_image"},
+		{"fallback profile", "/html/profile/" + alice.PubKey, false,
+			"/html/profile/" + alice.PubKey, picture, "summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawlerPageCache.mu.Lock()
+			crawlerPageCache.pages = make(map[string]cachedCrawlerPage)
+			crawlerPageCache.mu.Unlock()
+			if tt.cached {
+				s.get(t, nil, tt.path)
+			} else {
+				// Loads the note and profile into the caches without caching this page
+				s.get(t, nil, tt.path+"?ref=warm")
+			}
+
+			body := getAsCrawler(t, s, tt.path)
+			assertContains(t, body,
+				`<meta property="og:url" content="`+s.URL+tt.wantURL+`">`,
+				`<meta property="og:image" content="`+tt.wantImage+`">`,
+				`<meta name="twitter:card" content="`+tt.wantCard+`">`,
+				`<meta name="twitter:image" content="`+tt.wantImage+`">`,
+			)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// New-key embargo
+// Spam waves come from thousands of freshly created keys. With NEW_KEY_EMBARGO set (a
+// duration such as "72h"), the global feed hides events from pubkeys whose oldest event
+// this instance has seen is newer than that, unless the pubkey is vouched for: one of
+// the EMBARGO_ANCHORS (comma-separated npubs or hex pubkeys), someone an anchor follows,
+// or someone followed by a person an anchor follows. Follow and profile feeds, threads
+// and hashtag follows are unaffected. The policy is shown on /about/stats along with
+// how many events it has hidden.
+//
+// Oldest-event times are kept in memory, fed by the relay pool read loop, so they
+// restart empty; since they use created_at rather than when we received the event, an
+// established account reappears as old as soon as we see any of its older events.
+
+// maxEmbargoAuthors caps the oldest-event table; past it, unseen pubkeys aren't embargoed
+const maxEmbargoAuthors = 500000
+
+// maxEmbargoTrusted caps the vouched-for set built from the anchors' follows
+const maxEmbargoTrusted = 500000
+
+// embargoTrustRefreshInterval is how often the anchors' follow graph is refetched
+const embargoTrustRefreshInterval = time.Hour
+
+// embargoContactBatch is how many authors' contact lists are requested per query
+const embargoContactBatch = 200
+
+// NewKeyEmbargo hides events from recently created pubkeys in public feeds
+type NewKeyEmbargo struct {
+	MinAge  time.Duration // Zero when disabled
+	Anchors []string      // Hex pubkeys whose follow graph vouches for new keys
+
+	mu         sync.Mutex
+	oldest     map[string]int64 // pubkey -> oldest created_at seen
+	trusted    map[string]bool
+	refreshed  time.Time
+	suppressed atomic.Int64
+}
+
+// newKeyEmbargo is configured by initNewKeyEmbargo; disabled by default
+var newKeyEmbargo = &NewKeyEmbargo{oldest: make(map[string]int64), trusted: make(map[string]bool)}
+
+// Enabled reports whether the embargo is configured
+func (e *NewKeyEmbargo) Enabled() bool {
+	return e.MinAge > 0
+}
+
+// Record notes an ingested event's created_at as its author's oldest, if it is
+func (e *NewKeyEmbargo) Record(evt Event) {
+	if !e.Enabled() {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	oldest, ok := e.oldest[evt.PubKey]
+	if !ok && len(e.oldest) >= maxEmbargoAuthors {
+		return
+	}
+	if !ok || evt.CreatedAt < oldest {
+		e.oldest[evt.PubKey] = evt.CreatedAt
+	}
+}
+
+// hides reports whether pubkey's events are embargoed (caller holds mu)
+func (e *NewKeyEmbargo) hides(pubkey string, cutoff int64) bool {
+	oldest, ok := e.oldest[pubkey]
+	return ok && oldest > cutoff && !e.trusted[pubkey]
+}
+
+// Filter drops embargoed events and counts them
+func (e *NewKeyEmbargo) Filter(events []Event) []Event {
+	if !e.Enabled() {
+		return events
+	}
+	cutoff := time.Now().Add(-e.MinAge).Unix()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	filtered := make([]Event, 0, len(events))
+	for _, evt := range events {
+		if e.hides(evt.PubKey, cutoff) {
+			continue
+		}
+		filtered = append(filtered, evt)
+	}
+	if hidden := len(events) - len(filtered); hidden > 0 {
+		e.suppressed.Add(int64(hidden))
+	}
+	return filtered
+}
+
+// EmbargoSnapshot is the embargo's state for the stats page
+type EmbargoSnapshot struct {
+	MinAge     time.Duration
+	Anchors    int
+	Trusted    int
+	Tracked    int
+	Suppressed int64
+	Refreshed  time.Time
+}
+
+// Snapshot returns the embargo's counters
+func (e *NewKeyEmbargo) Snapshot() EmbargoSnapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return EmbargoSnapshot{
+		MinAge:     e.MinAge,
+		Anchors:    len(e.Anchors),
+		Trusted:    len(e.trusted),
+		Tracked:    len(e.oldest),
+		Suppressed: e.suppressed.Load(),
+		Refreshed:  e.refreshed,
+	}
+}
+
+// refreshTrusted rebuilds the vouched-for set: the anchors, their follows, and their follows' follows
+func (e *NewKeyEmbargo) refreshTrusted() {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Embargo trust refresh panicked: %v", rec)
+		}
+	}()
+
+	relays := append(relaysFor(RelayPurposeRead), relaysFor(RelayPurposeMetadata)...)
+	trusted := make(map[string]bool)
+	var firstHop []string
+	for _, anchor := range e.Anchors {
+		trusted[anchor] = true
+		for _, pk := range fetchContactList(relays, anchor) {
+			if !trusted[pk] {
+				trusted[pk] = true
+				firstHop = append(firstHop, pk)
+			}
+		}
+	}
+
+	for start := 0; start < len(firstHop) && len(trusted) < maxEmbargoTrusted; start += embargoContactBatch {
+		batch := firstHop[start:min(start+embargoContactBatch, len(firstHop))]
+		events, _ := fetchEventsFromRelaysWithTimeout(relays, Filter{
+			Authors: batch,
+			Kinds:   []int{3},
+			Limit:   len(batch),
+		}, 5*time.Second)
+		for _, evt := range events {
+			for _, tag := range evt.Tags {
+				if len(tag) >= 2 && tag[0] == "p" && len(trusted) < maxEmbargoTrusted {
+					trusted[tag[1]] = true
+				}
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.trusted = trusted
+	e.refreshed = time.Now()
+	e.mu.Unlock()
+	log.Printf("Embargo: %d pubkeys vouched for by %d anchors (%d first-hop follows)", len(trusted), len(e.Anchors), len(firstHop))
+}
+
+// formatEmbargoAge formats the embargo age as "3 days", "12 hours" or a plain duration
+func formatEmbargoAge(d time.Duration) string {
+	unit, name := 24*time.Hour, "day"
+	if d%unit != 0 {
+		unit, name = time.Hour, "hour"
+	}
+	if d < unit || d%unit != 0 {
+		return d.String()
+	}
+	n := int(d / unit)
+	if n == 1 {
+		return "1 " + name
+	}
+	return strconv.Itoa(n) + " " + name + "s"
+}
+
+// parsePubkeyList parses a comma-separated list of npubs or hex pubkeys from the env
+// variable name, logging and skipping invalid entries
+func parsePubkeyList(name, value string) []string {
+	var pubkeys []string
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.HasPrefix(s, "npub1") {
+			decoded, err := decodeBech32Pubkey(s)
+			if err != nil {
+				log.Printf("%s: ignoring invalid npub %q: %v", name, s, err)
+				continue
+			}
+			s = decoded
+		}
+		if !isValidEventID(s) {
+			log.Printf("%s: ignoring %q, must be hex or npub", name, s)
+			continue
+		}
+		pubkeys = append(pubkeys, s)
+	}
+	return pubkeys
+}
+
+// initNewKeyEmbargo reads NEW_KEY_EMBARGO and EMBARGO_ANCHORS and starts the trust refresher
+func initNewKeyEmbargo() {
+	value := strings.TrimSpace(os.Getenv("NEW_KEY_EMBARGO"))
+	if value == "" {
+		return
+	}
+	minAge, err := time.ParseDuration(value)
+	if err != nil || minAge <= 0 {
+		log.Printf("New-key embargo disabled: NEW_KEY_EMBARGO must be a positive duration like 72h")
+		return
+	}
+	newKeyEmbargo.Anchors = parsePubkeyList("EMBARGO_ANCHORS", os.Getenv("EMBARGO_ANCHORS"))
+	newKeyEmbargo.MinAge = minAge
+	log.Printf("New-key embargo: hiding pubkeys newer than %v from the global feed (%d anchors)", minAge, len(newKeyEmbargo.Anchors))
+
+	if len(newKeyEmbargo.Anchors) == 0 {
+		return
+	}
+	go func() {
+		for {
+			newKeyEmbargo.refreshTrusted()
+			time.Sleep(embargoTrustRefreshInterval)
+		}
+	}()
+}
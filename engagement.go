@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Engagement ranking
+// Reactions (kind 7), reposts (kind 6) and zap receipts (kind 9735) ingested by the relay
+// pool are counted per target event in hourly buckets. ?sort=top orders a feed by weighted
+// engagement within ?window=; ?sort=trending also halves the weight of engagement every
+// quarter window. Only events that have flowed through the instance are ranked, so this
+// never triggers relay scans - just an ID lookup for ranked events no longer in memory.
+
+// Feed sort modes; chronological ("") is the default
+const (
+	FeedSortTop      = "top"
+	FeedSortTrending = "trending"
+)
+
+// feedSortWindows are the accepted ?window= values, in hours
+var feedSortWindows = map[string]int64{
+	"6h":  6,
+	"24h": 24,
+	"7d":  7 * 24,
+}
+
+const defaultFeedSortWindow = "24h"
+
+// engagementRetentionHours is how long engagement is kept (the longest window)
+const engagementRetentionHours = 7 * 24
+
+// maxRankedEvents caps the ranking computed for one sort and window
+const maxRankedEvents = 500
+
+// rankingCacheTTL is how long a computed ranking is reused
+const rankingCacheTTL = 3 * time.Minute
+
+// EngagementWeights are the score contributions of each kind of engagement
+type EngagementWeights struct {
+	Reaction float64
+	Repost   float64
+	Zap      float64
+}
+
+var defaultEngagementWeights = EngagementWeights{Reaction: 1, Repost: 3, Zap: 5}
+
+// engagementWeights is parsed once from ENGAGEMENT_WEIGHTS, e.g. "reaction=1,repost=3,zap=5"
+var engagementWeights = parseEngagementWeights(os.Getenv("ENGAGEMENT_WEIGHTS"))
+
+// parseEngagementWeights overrides the default weights with any valid entries in value
+func parseEngagementWeights(value string) EngagementWeights {
+	weights := defaultEngagementWeights
+	for _, part := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || w < 0 || math.IsInf(w, 0) {
+			log.Printf("ENGAGEMENT_WEIGHTS: ignoring invalid weight %q", part)
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "reaction":
+			weights.Reaction = w
+		case "repost":
+			weights.Repost = w
+		case "zap":
+			weights.Zap = w
+		default:
+			log.Printf("ENGAGEMENT_WEIGHTS: unknown engagement %q", name)
+		}
+	}
+	return weights
+}
+
+// parseFeedSort reads ?sort= and ?window=, returning "" for chronological feeds
+func parseFeedSort(sortParam, windowParam string) (mode, window string) {
+	switch sortParam {
+	case FeedSortTop, FeedSortTrending:
+	default:
+		return "", ""
+	}
+	if _, ok := feedSortWindows[windowParam]; !ok {
+		windowParam = defaultFeedSortWindow
+	}
+	return sortParam, windowParam
+}
+
+// engagementCounts is one hour of engagement with an event
+type engagementCounts struct {
+	Reactions int
+	Reposts   int
+	Zaps      int
+}
+
+func (c *engagementCounts) score(w EngagementWeights) float64 {
+	return float64(c.Reactions)*w.Reaction + float64(c.Reposts)*w.Repost + float64(c.Zaps)*w.Zap
+}
+
+// EngagementStats counts engagement per target event and unix hour
+type EngagementStats struct {
+	mu        sync.Mutex
+	targets   map[string]map[int64]*engagementCounts
+	lastPrune int64 // unix hour of the last prune
+	rankings  map[string]cachedRanking
+}
+
+type cachedRanking struct {
+	ids     []string
+	expires time.Time
+}
+
+// Global engagement counters, fed by the relay pool read loop
+var engagementStats = &EngagementStats{
+	targets:  make(map[string]map[int64]*engagementCounts),
+	rankings: make(map[string]cachedRanking),
+}
+
+// engagementTarget returns the event a reaction, repost or zap receipt points at
+func engagementTarget(evt Event) string {
+	var target string
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "e" && isValidEventID(tag[1]) {
+			target = tag[1]
+			if evt.Kind == 6 {
+				break // reposts reference the reposted event first
+			}
+		}
+	}
+	return target // reactions and zaps: the last e tag (NIP-25)
+}
+
+// Record counts a newly ingested event if it is engagement
+func (s *EngagementStats) Record(evt Event) {
+	if evt.Kind != 6 && evt.Kind != 7 && evt.Kind != 9735 {
+		return
+	}
+	target := engagementTarget(evt)
+	if target == "" {
+		return
+	}
+	hour := time.Now().Unix() / 3600
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hour != s.lastPrune {
+		s.lastPrune = hour
+		s.pruneLocked(hour)
+	}
+	hours := s.targets[target]
+	if hours == nil {
+		hours = make(map[int64]*engagementCounts)
+		s.targets[target] = hours
+	}
+	c := hours[hour]
+	if c == nil {
+		c = &engagementCounts{}
+		hours[hour] = c
+	}
+	switch evt.Kind {
+	case 7:
+		c.Reactions++
+	case 6:
+		c.Reposts++
+	case 9735:
+		c.Zaps++
+	}
+}
+
+// pruneLocked drops engagement older than the retention window
+func (s *EngagementStats) pruneLocked(hour int64) {
+	for id, hours := range s.targets {
+		for h := range hours {
+			if hour-h >= engagementRetentionHours {
+				delete(hours, h)
+			}
+		}
+		if len(hours) == 0 {
+			delete(s.targets, id)
+		}
+	}
+}
+
+// Len returns the number of events with recorded engagement
+func (s *EngagementStats) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.targets)
+}
+
+// Ranking returns event IDs ordered by score for the sort mode and window, highest first
+// The result is shared across viewers and reused for rankingCacheTTL
+func (s *EngagementStats) Ranking(mode, window string) []string {
+	key := mode + ":" + window
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.rankings[key]; ok && now.Before(cached.expires) {
+		return cached.ids
+	}
+
+	hour := now.Unix() / 3600
+	windowHours := feedSortWindows[window]
+	halfLife := float64(windowHours) / 4
+
+	type scored struct {
+		id    string
+		score float64
+	}
+	var ranked []scored
+	for id, hours := range s.targets {
+		var score float64
+		for h, c := range hours {
+			age := hour - h
+			if age >= windowHours {
+				continue
+			}
+			points := c.score(engagementWeights)
+			if mode == FeedSortTrending {
+				points *= math.Pow(0.5, float64(age)/halfLife)
+			}
+			score += points
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{id, score})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].id < ranked[j].id
+	})
+	if len(ranked) > maxRankedEvents {
+		ranked = ranked[:maxRankedEvents]
+	}
+
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.id
+	}
+	s.rankings[key] = cachedRanking{ids: ids, expires: now.Add(rankingCacheTTL)}
+	return ids
+}
+
+// rankedPageURL builds the URL of a ranked feed page; a limit of 0 is left out
+func rankedPageURL(path string, kinds []int, limit int, feedMode, mode, window string, offset int) string {
+	return fmt.Sprintf("%s?kinds=%s%s&feed=%s&sort=%s&window=%s&offset=%d",
+		path, joinKinds(kinds), limitParam(limit), feedMode, mode, window, offset)
+}
+
+// joinKinds formats kinds as a comma-separated query value
+func joinKinds(kinds []int) string {
+	kindsStr := make([]string, len(kinds))
+	for i, k := range kinds {
+		kindsStr[i] = strconv.Itoa(k)
+	}
+	return strings.Join(kindsStr, ",")
+}
+
+// fetchRankedEvents returns up to limit events from the ranking starting at offset that
+// match the feed's authors, kinds and reply filter, plus the offset of the next page
+// (0 when the ranking is exhausted). Events are taken from the ingestion cache and only
+// looked up by ID on relays when they've been evicted from it
+func fetchRankedEvents(ctx context.Context, relays []string, ranking []string, offset, limit int, authors []string, kinds []int, noReplies bool) ([]Event, int) {
+	authorSet := make(map[string]bool, len(authors))
+	for _, a := range authors {
+		authorSet[a] = true
+	}
+	kindSet := make(map[int]bool, len(kinds))
+	for _, k := range kinds {
+		kindSet[k] = true
+	}
+
+	var events []Event
+	pos := offset
+	for pos < len(ranking) && len(events) < limit {
+		end := min(pos+limit*3, len(ranking))
+		chunk := ranking[pos:end]
+
+		found := make(map[string]Event, len(chunk))
+		var missing []string
+		for _, id := range chunk {
+			if seen, ok := seenEventCache.Get(id); ok {
+				found[id] = seen.Event
+			} else {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			fetched, _ := fetchEventsFromRelaysCachedCtx(ctx, relays, Filter{IDs: missing, Limit: len(missing)})
+			for _, evt := range fetched {
+				found[evt.ID] = evt
+			}
+		}
+
+		for _, id := range chunk {
+			pos++
+			evt, ok := found[id]
+			if !ok {
+				continue
+			}
+			if len(authorSet) > 0 && !authorSet[evt.PubKey] {
+				continue
+			}
+			if len(kindSet) > 0 && !kindSet[evt.Kind] {
+				continue
+			}
+			if noReplies && isReply(evt) && evt.Kind != 6 {
+				continue
+			}
+			events = append(events, evt)
+			if len(events) == limit {
+				break
+			}
+		}
+	}
+
+	if pos >= len(ranking) {
+		return events, 0
+	}
+	return events, pos
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Geo-tagged events
+// Notes, classifieds (NIP-99) and calendar events (NIP-52) can carry "g" geohash tags,
+// often several at different precisions, and a free-text "location" tag. We decode the
+// most precise geohash to coordinates rounded to what the hash actually pins down, link
+// it to OpenStreetMap, and let the timeline filter by geohash prefix (?g=) or by the
+// location text (?location=). No tiles or scripts: just decoding and links.
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// maxGeohashLength is the longest geohash we accept (12 chars is ~4cm)
+const maxGeohashLength = 12
+
+// maxLocationLength caps location names from tags and query strings
+const maxLocationLength = 100
+
+// GeoPoint is the centre of a geohash cell and its half-size in degrees
+type GeoPoint struct {
+	Lat, Lon       float64
+	LatErr, LonErr float64
+}
+
+// normalizeGeohash lowercases a geohash, returning "" if it isn't a valid one
+func normalizeGeohash(hash string) string {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash == "" || len(hash) > maxGeohashLength {
+		return ""
+	}
+	for _, c := range hash {
+		if !strings.ContainsRune(geohashAlphabet, c) {
+			return ""
+		}
+	}
+	return hash
+}
+
+// decodeGeohash returns the cell a geohash covers
+func decodeGeohash(hash string) (GeoPoint, bool) {
+	hash = normalizeGeohash(hash)
+	if hash == "" {
+		return GeoPoint{}, false
+	}
+	latMin, latMax := -90.0, 90.0
+	lonMin, lonMax := -180.0, 180.0
+	even := true // Bits alternate longitude, latitude, starting with longitude
+	for _, c := range hash {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		for bit := 4; bit >= 0; bit-- {
+			on := idx>>bit&1 == 1
+			if even {
+				mid := (lonMin + lonMax) / 2
+				if on {
+					lonMin = mid
+				} else {
+					lonMax = mid
+				}
+			} else {
+				mid := (latMin + latMax) / 2
+				if on {
+					latMin = mid
+				} else {
+					latMax = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return GeoPoint{
+		Lat:    (latMin + latMax) / 2,
+		Lon:    (lonMin + lonMax) / 2,
+		LatErr: (latMax - latMin) / 2,
+		LonErr: (lonMax - lonMin) / 2,
+	}, true
+}
+
+// decimalsFor returns how many decimal places are meaningful for an error of err degrees
+func decimalsFor(err float64) int {
+	if err <= 0 {
+		return 6
+	}
+	return min(max(int(math.Ceil(-math.Log10(err))), 0), 6)
+}
+
+// String formats the point as "lat, lon" with only the meaningful decimals
+func (p GeoPoint) String() string {
+	return strconv.FormatFloat(p.Lat, 'f', decimalsFor(p.LatErr), 64) + ", " +
+		strconv.FormatFloat(p.Lon, 'f', decimalsFor(p.LonErr), 64)
+}
+
+// MapURL returns an OpenStreetMap link centred on the point, zoomed to the cell size
+func (p GeoPoint) MapURL(precision int) string {
+	zoom := min(2+precision*2, 18)
+	lat := strconv.FormatFloat(p.Lat, 'f', decimalsFor(p.LatErr), 64)
+	lon := strconv.FormatFloat(p.Lon, 'f', decimalsFor(p.LonErr), 64)
+	return fmt.Sprintf("https://www.openstreetmap.org/?mlat=%s&mlon=%s#map=%d/%s/%s", lat, lon, zoom, lat, lon)
+}
+
+// HTMLLocation is an event's location, for display
+type HTMLLocation struct {
+	Name      string // From the "location" tag
+	Geohash   string // Most precise "g" tag
+	Coords    string // Decoded Geohash, e.g. "52.52, 13.40"
+	MapURL    string // OpenStreetMap link
+	NearbyURL string // Timeline of events in the surrounding cell
+}
+
+// nearbyGeohashLength is the prefix used for "nearby" links (~5km cells)
+const nearbyGeohashLength = 5
+
+// eventLocation extracts the location from an event's g and location tags, or nil
+func eventLocation(tags [][]string) *HTMLLocation {
+	var loc HTMLLocation
+	for _, tag := range tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "g":
+			if hash := normalizeGeohash(tag[1]); len(hash) > len(loc.Geohash) {
+				loc.Geohash = hash
+			}
+		case "location":
+			if loc.Name == "" {
+				loc.Name = truncateString(strings.TrimSpace(tag[1]), maxLocationLength)
+			}
+		}
+	}
+	if loc.Geohash == "" && loc.Name == "" {
+		return nil
+	}
+	if p, ok := decodeGeohash(loc.Geohash); ok {
+		loc.Coords = p.String()
+		loc.MapURL = p.MapURL(len(loc.Geohash))
+		loc.NearbyURL = "/html/timeline?kinds=1&limit=20&feed=global&g=" + url.QueryEscape(loc.Geohash[:min(len(loc.Geohash), nearbyGeohashLength)])
+	}
+	return &loc
+}
+
+// matchesLocation reports whether an event is inside the geohash prefix cell (when set)
+// and has a location tag containing name (when set)
+func matchesLocation(evt Event, geohashPrefix, name string) bool {
+	geoOK, nameOK := geohashPrefix == "", name == ""
+	name = strings.ToLower(name)
+	for _, tag := range evt.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "g":
+			if !geoOK && strings.HasPrefix(normalizeGeohash(tag[1]), geohashPrefix) {
+				geoOK = true
+			}
+		case "location":
+			if !nameOK && strings.Contains(strings.ToLower(tag[1]), name) {
+				nameOK = true
+			}
+		}
+	}
+	return geoOK && nameOK
+}
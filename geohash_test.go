@@ -0,0 +1,203 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeGeohash(t *testing.T) {
+	tests := []struct {
+		hash           string
+		lat, lon       float64
+		latErr, lonErr float64
+		coords         string
+	}{
+		{"s", 22.5, 22.5, 22.5, 22.5, "22, 22"}, // ±22.5° leaves no meaningful decimals
+		{"ezs42", 42.60498046875, -5.60302734375, 0.02197265625, 0.02197265625, "42.60, -5.60"},
+		{"EZS42", 42.60498046875, -5.60302734375, 0.02197265625, 0.02197265625, "42.60, -5.60"},
+		{"u33db2m", 52.51670837402344, 13.377914428710938, 0.0006866455078125, 0.0006866455078125, "52.5167, 13.3779"},
+		{"9q8yyk8ytpxr", 37.774899965152144, -122.41939986124635, 8.381903171539307e-08, 1.6763806343078613e-07, "37.774900, -122.419400"},
+		// Just below the equator and west of Greenwich, where the signs flip
+		{"7zzzzzzzzzzz", -8.381903171539307e-08, -1.6763806343078613e-07, 8.381903171539307e-08, 1.6763806343078613e-07, "-0.000000, -0.000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			p, ok := decodeGeohash(tt.hash)
+			if !ok {
+				t.Fatal("not decoded")
+			}
+			if p.Lat != tt.lat || p.Lon != tt.lon || p.LatErr != tt.latErr || p.LonErr != tt.lonErr {
+				t.Errorf("decoded %+v, want lat %v lon %v ±%v/%v", p, tt.lat, tt.lon, tt.latErr, tt.lonErr)
+			}
+			if got := p.String(); got != tt.coords {
+				t.Errorf("String() = %q, want %q", got, tt.coords)
+			}
+		})
+	}
+}
+
+func TestNormalizeGeohash(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"u33db2m", "u33db2m"},
+		{"  U33DB2M ", "u33db2m"},
+		{"u33dc0cppjs7", "u33dc0cppjs7"},
+		{"u33dc0cppjs7x", ""}, // 13 chars, past maxGeohashLength
+		{"", ""},
+		{"u33a", ""}, // a, i, l and o aren't in the alphabet
+		{"u33i", ""},
+		{"u33l", ""},
+		{"u33o", ""},
+		{"u3 3d", ""},
+		{"u33d<", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeGeohash(tt.in); got != tt.want {
+			t.Errorf("normalizeGeohash(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestGeohashPrecisionTruncation checks that every prefix of a geohash is a cell that
+// contains the full hash's cell, and that each character narrows it
+func TestGeohashPrecisionTruncation(t *testing.T) {
+	for _, tt := range []struct {
+		hash     string
+		lat, lon float64
+	}{
+		{"u33dc0cppjs7", 52.52, 13.405},      // Berlin
+		{"r3gx2f77b", -33.8688, 151.2093},    // Sydney
+		{"dr5regw", 40.7128, -74.006},        // New York
+		{"9q8yyk8ytpxr", 37.7749, -122.4194}, // San Francisco
+	} {
+		t.Run(tt.hash, func(t *testing.T) {
+			prev := GeoPoint{LatErr: 90, LonErr: 180}
+			for n := 1; n <= len(tt.hash); n++ {
+				p, ok := decodeGeohash(tt.hash[:n])
+				if !ok {
+					t.Fatalf("%s not decoded", tt.hash[:n])
+				}
+				if math.Abs(p.Lat-tt.lat) > p.LatErr || math.Abs(p.Lon-tt.lon) > p.LonErr {
+					t.Errorf("%s cell %v does not contain %v, %v", tt.hash[:n], p, tt.lat, tt.lon)
+				}
+				if math.Abs(p.Lat-prev.Lat) > prev.LatErr || math.Abs(p.Lon-prev.Lon) > prev.LonErr {
+					t.Errorf("%s cell centre is outside its parent cell", tt.hash[:n])
+				}
+				if p.LatErr >= prev.LatErr && p.LonErr >= prev.LonErr {
+					t.Errorf("%s cell is no smaller than its parent", tt.hash[:n])
+				}
+				prev = p
+			}
+		})
+	}
+}
+
+func TestDecimalsFor(t *testing.T) {
+	tests := []struct {
+		err  float64
+		want int
+	}{
+		{45, 0},
+		{0.5, 1},
+		{0.022, 2},
+		{0.0007, 4},
+		{1e-9, 6},
+		{0, 6},
+	}
+	for _, tt := range tests {
+		if got := decimalsFor(tt.err); got != tt.want {
+			t.Errorf("decimalsFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestEventLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags       [][]string
+		want       *HTMLLocation
+		wantNearby string
+	}{
+		{
+			name:       "most precise g tag wins and nearby is cut to five characters",
+			tags:       [][]string{{"g", "u3"}, {"g", "u33db2m"}, {"g", "u33d"}, {"location", " Berlin "}},
+			want:       &HTMLLocation{Name: "Berlin", Geohash: "u33db2m", Coords: "52.5167, 13.3779"},
+			wantNearby: "g=u33db",
+		},
+		{
+			name:       "short geohash is used whole",
+			tags:       [][]string{{"g", "U33"}},
+			want:       &HTMLLocation{Geohash: "u33", Coords: "52.7, 13.4"},
+			wantNearby: "g=u33",
+		},
+		{
+			name: "invalid geohash keeps the name only",
+			tags: [][]string{{"g", "not-a-hash"}, {"location", "Somewhere"}},
+			want: &HTMLLocation{Name: "Somewhere"},
+		},
+		{
+			name: "location name is truncated",
+			tags: [][]string{{"location", strings.Repeat("x", maxLocationLength+20)}},
+			want: &HTMLLocation{Name: truncateString(strings.Repeat("x", maxLocationLength+20), maxLocationLength)},
+		},
+		{name: "no location tags", tags: [][]string{{"t", "nostr"}, {"g"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eventLocation(tt.tags)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("got %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil")
+			}
+			if got.Name != tt.want.Name || got.Geohash != tt.want.Geohash || got.Coords != tt.want.Coords {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if tt.wantNearby == "" {
+				if got.NearbyURL != "" || got.MapURL != "" {
+					t.Errorf("links %q %q for a location without a geohash", got.NearbyURL, got.MapURL)
+				}
+			} else if !strings.HasSuffix(got.NearbyURL, "&"+tt.wantNearby) {
+				t.Errorf("NearbyURL = %q, want it to end with %s", got.NearbyURL, tt.wantNearby)
+			}
+		})
+	}
+}
+
+func TestMatchesLocation(t *testing.T) {
+	berlin := Event{Tags: [][]string{{"g", "u33"}, {"g", "U33DB2M"}, {"location", "Berlin, Germany"}}}
+	tests := []struct {
+		name     string
+		evt      Event
+		geohash  string
+		location string
+		want     bool
+	}{
+		{"no filter", berlin, "", "", true},
+		{"exact hash", berlin, "u33db2m", "", true},
+		{"prefix of the precise tag", berlin, "u33d", "", true},
+		{"prefix of the coarse tag", berlin, "u3", "", true},
+		{"longer than any tag", berlin, "u33db2mx", "", false},
+		// u33e borders u33d but is a different cell
+		{"neighbouring cell", berlin, "u33e", "", false},
+		{"neighbour sharing no prefix", Event{Tags: [][]string{{"g", "gcpvj"}}}, "u10hb", "", false},
+		{"name is case-insensitive substring", berlin, "", "germany", true},
+		{"name and hash both required", berlin, "u33d", "paris", false},
+		{"name and hash both match", berlin, "u33d", "berlin", true},
+		{"untagged event", Event{}, "u33", "", false},
+		{"invalid tag never matches", Event{Tags: [][]string{{"g", "u33!"}}}, "u33", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesLocation(tt.evt, tt.geohash, tt.location); got != tt.want {
+				t.Errorf("matchesLocation(%q, %q) = %v, want %v", tt.geohash, tt.location, got, tt.want)
+			}
+		})
+	}
+}
@@ -14,9 +14,16 @@ import (
 )
 
 type TimelineResponse struct {
-	Items []EventItem        `json:"items"`
-	Page  PageInfo           `json:"page"`
-	Meta  MetaInfo           `json:"meta"`
+	Items    []EventItem `json:"items"`
+	Page     PageInfo    `json:"page"`
+	Meta     MetaInfo    `json:"meta"`
+	Label    *EventLabel `json:"label,omitempty"`    // Set for /labels/{namespace}/{label} feeds
+	Hashtag  string      `json:"hashtag,omitempty"`  // Set for ?t= hashtag views
+	Sort     string      `json:"sort,omitempty"`     // "top" or "trending" for engagement-ranked feeds
+	Window   string      `json:"window,omitempty"`   // Ranking window for Sort, e.g. "24h"
+	Geohash  string      `json:"geohash,omitempty"`  // Set when filtering by ?g= geohash prefix
+	Location string      `json:"location,omitempty"` // Set when filtering by ?location= name
+	Gap      *TimelineGap `json:"-"`                  // Unread posts between this page and the last visit (HTML only)
 }
 
 type EventItem struct {
@@ -30,7 +37,10 @@ type EventItem struct {
 	RelaysSeen    []string          `json:"relays_seen"`
 	AuthorProfile *ProfileInfo      `json:"author_profile,omitempty"`
 	Reactions     *ReactionsSummary `json:"reactions,omitempty"`
+	ReactionsPending bool           `json:"reactions_pending,omitempty"` // Reactions still loading; see /html/reactions
 	ReplyCount    int               `json:"reply_count"`
+	Labels        []EventLabel      `json:"labels,omitempty"`
+	FromTag       string            `json:"from_tag,omitempty"` // Followed hashtag this item was included for
 }
 
 type ProfileInfo struct {
@@ -90,13 +100,7 @@ func timelineHandler(w http.ResponseWriter, r *http.Request) {
 
 	relays := parseStringList(q.Get("relays"))
 	if len(relays) == 0 {
-		relays = []string{
-			"wss://relay.damus.io",
-			"wss://relay.nostr.band",
-			"wss://relay.primal.net",
-			"wss://nos.lol",
-			"wss://nostr.mom",
-		}
+		relays = relaysFor(RelayPurposeRead)
 	}
 
 	authors := parseStringList(q.Get("authors"))
@@ -373,13 +377,7 @@ func threadHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	relays := parseStringList(q.Get("relays"))
 	if len(relays) == 0 {
-		relays = []string{
-			"wss://relay.damus.io",
-			"wss://relay.nostr.band",
-			"wss://relay.primal.net",
-			"wss://nos.lol",
-			"wss://nostr.mom",
-		}
+		relays = relaysFor(RelayPurposeRead)
 	}
 
 	log.Printf("Fetching thread for event: %s", eventID)
@@ -509,7 +507,9 @@ func buildPaginationURL(path string, relays []string, authors []string, kinds []
 		}
 		parts = append(parts, "kinds="+strings.Join(kindsStr, ","))
 	}
-	parts = append(parts, "limit="+strconv.Itoa(limit))
+	if limit > 0 {
+		parts = append(parts, "limit="+strconv.Itoa(limit))
+	}
 	parts = append(parts, "until="+strconv.FormatInt(until, 10))
 
 	return strings.Join(parts, "&")
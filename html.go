@@ -14,8 +14,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/yuin/goldmark"
 )
 
 // Cached compiled templates - initialized at startup via init()
@@ -108,28 +106,46 @@ func initTemplates() {
 		"gt": func(a, b int) bool {
 			return a > b
 		},
+		"devMode": func() bool {
+			return devModeEnabled
+		},
+		"actionLabel": actionLabel,
+		"branding":    branding,
+		"icon":        icon,
+		"relayInfoURL": relayInfoURL,
+		"relayPurposes": func(relayURL string) string {
+			for _, r := range getRelayConfig().Relays {
+				if r.URL == relayURL {
+					return strings.Join(r.Purposes, ", ")
+				}
+			}
+			return ""
+		},
 	}
 
 	var err error
 
 	// Compile main HTML template
-	cachedHTMLTemplate, err = template.New("html").Funcs(templateFuncMap).Parse(htmlTemplate)
+	cachedHTMLTemplate, err = template.New("html").Funcs(templateFuncMap).Parse(htmlTemplate + deckTemplate + notificationStylesTemplate + densityStylesTemplate + timelineGapTemplate + announcementTemplate + brandingTemplate)
 	if err != nil {
 		log.Fatalf("Failed to compile HTML template: %v", err)
 	}
 
 	// Compile thread template
-	cachedThreadTemplate, err = template.New("thread").Funcs(templateFuncMap).Parse(htmlThreadTemplate)
+	cachedThreadTemplate, err = template.New("thread").Funcs(templateFuncMap).Parse(htmlThreadTemplate + densityStylesTemplate + announcementTemplate + brandingTemplate + previewMetaTemplate)
 	if err != nil {
 		log.Fatalf("Failed to compile thread template: %v", err)
 	}
 
 	// Compile profile template
-	cachedProfileTemplate, err = template.New("profile").Funcs(templateFuncMap).Parse(htmlProfileTemplate)
+	cachedProfileTemplate, err = template.New("profile").Funcs(templateFuncMap).Parse(htmlProfileTemplate + densityStylesTemplate + announcementTemplate + brandingTemplate + previewMetaTemplate)
 	if err != nil {
 		log.Fatalf("Failed to compile profile template: %v", err)
 	}
 
+	// Compile secondary page templates (shared layout)
+	initPageTemplates()
+
 	log.Printf("All HTML templates compiled successfully")
 }
 
@@ -156,9 +172,11 @@ var htmlTemplate = `<!DOCTYPE html>
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>{{.Title}} - Nostr Hypermedia</title>
+  <title>{{.Title}} - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
   <link rel="icon" href="/static/favicon.ico" />
   <style>
+{{template "announcement-styles"}}
     :root {
       --bg-page: #f5f5f5;
       --bg-container: #ffffff;
@@ -427,6 +445,17 @@ var htmlTemplate = `<!DOCTYPE html>
     .post-form:focus-within button[type="submit"] {
       display: block;
     }
+    .post-options {
+      display: none;
+      align-items: center;
+      gap: 8px;
+      flex-wrap: wrap;
+      margin-bottom: 10px;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .post-form:focus-within .post-options { display: flex; }
+    .post-options select { max-width: 100%; }
     .nav-tab {
       padding: 8px 16px;
       background: var(--bg-badge);
@@ -560,6 +589,92 @@ var htmlTemplate = `<!DOCTYPE html>
       color: var(--text-muted);
       margin-bottom: 8px;
     }
+    .torrent-title {
+      margin: 0 0 6px;
+      font-size: 17px;
+      color: var(--text-content);
+    }
+    .torrent-meta {
+      display: flex;
+      gap: 12px;
+      font-size: 13px;
+      color: var(--text-muted);
+      margin-bottom: 8px;
+    }
+    .torrent-magnet {
+      display: flex;
+      gap: 8px;
+      align-items: center;
+      margin: 8px 0;
+      font-size: 13px;
+    }
+    .torrent-magnet input {
+      flex: 1;
+      min-width: 0;
+      padding: 4px 8px;
+      font-family: monospace;
+      font-size: 12px;
+      border: 1px solid var(--border-color);
+      border-radius: 4px;
+      background: var(--bg-secondary);
+      color: var(--text-secondary);
+    }
+    .torrent-files summary {
+      cursor: pointer;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .torrent-files ul {
+      margin: 6px 0 0;
+      padding-left: 20px;
+      font-size: 13px;
+    }
+    .torrent-file-name {
+      word-break: break-all;
+    }
+    .from-tag {
+      font-size: 12px;
+      color: var(--text-muted);
+      margin-bottom: 8px;
+    }
+    .hashtag-header {
+      display: flex;
+      align-items: center;
+      justify-content: space-between;
+      padding: 12px 0;
+      margin-bottom: 12px;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .hashtag-header h2 {
+      margin: 0;
+      font-size: 20px;
+    }
+    .follow-btn {
+      padding: 8px 20px;
+      font-size: 14px;
+      font-weight: 600;
+      border-radius: 20px;
+      cursor: pointer;
+      transition: all 0.2s;
+      border: none;
+    }
+    .follow-btn.follow {
+      background: var(--accent);
+      color: white;
+    }
+    .follow-btn.follow:hover {
+      background: var(--accent-hover);
+    }
+    .follow-btn.unfollow {
+      background: var(--bg-tertiary);
+      color: var(--text-secondary);
+      border: 1px solid var(--border-color);
+    }
+    .follow-btn.unfollow:hover {
+      background: #fee2e2;
+      color: #dc2626;
+      border-color: #dc2626;
+    }
     .reposted-note {
       border: 1px solid var(--border-color);
       border-radius: 8px;
@@ -1444,6 +1559,11 @@ var htmlTemplate = `<!DOCTYPE html>
       margin-right: 6px;
       color: var(--text-secondary);
     }
+    .source-link {
+      font-size: 11px;
+      color: var(--text-secondary);
+      margin-left: 6px;
+    }
     .note-footer {
       display: flex;
       gap: 16px;
@@ -1491,6 +1611,57 @@ var htmlTemplate = `<!DOCTYPE html>
       background: var(--bg-reply-badge);
       color: var(--accent);
     }
+    .note-location {
+      margin: 8px 0 0;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .note-protected {
+      margin: 8px 0 0;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .note-labels {
+      display: flex;
+      gap: 6px;
+      flex-wrap: wrap;
+      margin: 8px 0 0;
+    }
+    .label-badge {
+      display: inline-block;
+      padding: 2px 8px;
+      border: 1px solid var(--border-color);
+      border-radius: 4px;
+      font-size: 12px;
+      color: var(--text-secondary);
+      text-decoration: none;
+    }
+    .label-badge:hover {
+      color: var(--accent);
+      border-color: var(--accent);
+    }
+    .label-action summary {
+      cursor: pointer;
+      list-style: none;
+    }
+    .label-action summary::-webkit-details-marker {
+      display: none;
+    }
+    .label-form {
+      display: flex;
+      gap: 6px;
+      margin-top: 6px;
+    }
+    .label-form input[type="text"] {
+      width: 140px;
+      padding: 2px 6px;
+      font-size: 13px;
+    }
+    .label-feed-header {
+      padding: 12px 0;
+      margin-bottom: 12px;
+      border-bottom: 1px solid var(--border-color);
+    }
     .note-meta {
       display: flex;
       gap: 16px;
@@ -1843,6 +2014,10 @@ var htmlTemplate = `<!DOCTYPE html>
       font-size: 11px;
       color: var(--text-secondary);
     }
+    .relay-purposes {
+      font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+      color: var(--text-muted);
+    }
     footer {
       text-align: center;
       padding: 20px;
@@ -1880,26 +2055,34 @@ var htmlTemplate = `<!DOCTYPE html>
     .skip-link:focus {
       transform: translateY(0);
     }
+{{if .Deck}}{{template "notification-styles"}}{{template "deck-styles"}}{{end}}
+{{if .Gap}}{{template "timeline-gap-styles"}}{{end}}
+{{template "density-styles"}}
+{{template "branding-styles"}}
   </style>
 </head>
-<body>
+<body{{if .BodyClass}} class="{{.BodyClass}}"{{end}}>
   <a href="#main-content" class="skip-link">Skip to main content</a>
-  <div id="top" class="container">
+  {{template "announcement" .Announcement}}
+  <div id="top" class="container{{if .Deck}} deck-container{{end}}">
     <div class="sticky-section">
       <nav>
+        {{template "branding-logo"}}
         {{if .LoggedIn}}
-        <a href="?kinds=1&limit=20&feed=follows{{if not .ShowReactions}}&fast=1{{end}}" class="nav-tab{{if eq .FeedMode "follows"}} active{{end}}">Follows</a>
+        <a href="/html/timeline?kinds=1&limit=20&feed=follows{{if not .ShowReactions}}&fast=1{{end}}" class="nav-tab{{if eq .FeedMode "follows"}} active{{end}}">Follows</a>
         {{end}}
-        <a href="?kinds=1&limit=20&feed=global{{if not .ShowReactions}}&fast=1{{end}}" class="nav-tab{{if or (eq .FeedMode "global") (not .LoggedIn)}} active{{end}}">Global</a>
+        <a href="/html/timeline?kinds=1&limit=20&feed=global{{if not .ShowReactions}}&fast=1{{end}}" class="nav-tab{{if or (eq .FeedMode "global") (not .LoggedIn)}} active{{end}}">Global</a>
         {{if .LoggedIn}}
-        <a href="?kinds=1&limit=20&feed=me{{if not .ShowReactions}}&fast=1{{end}}" class="nav-tab{{if eq .FeedMode "me"}} active{{end}}">Me</a>
+        <a href="/html/timeline?kinds=1&limit=20&feed=me{{if not .ShowReactions}}&fast=1{{end}}" class="nav-tab{{if eq .FeedMode "me"}} active{{end}}">Me</a>
+        <a href="/html/deck" class="nav-tab{{if .Deck}} active{{end}}">Deck</a>
         {{end}}
         <div class="ml-auto flex-center gap-md">
           {{if .LoggedIn}}
-          <a href="/html/notifications" class="notification-bell" title="Notifications">🔔{{if .HasUnreadNotifications}}<span class="notification-badge"></span>{{end}}</a>
+          <a href="/html/messages" class="notification-bell" title="Messages">{{icon "mail" "✉️"}}</a>
+          <a href="/html/notifications" class="notification-bell" title="Notifications">{{icon "bell" "🔔"}}{{if .HasUnreadNotifications}}<span class="notification-badge"></span>{{end}}</a>
           {{end}}
           <details class="settings-dropdown">
-            <summary class="settings-toggle" title="Settings">⚙️</summary>
+            <summary class="settings-toggle" title="Settings">{{icon "settings" "⚙️"}}</summary>
             <div class="settings-menu">
               <div class="settings-item">
                 <a href="?kinds=1,6&limit=20&feed={{.FeedMode}}{{if .ShowReactions}}&fast=1{{end}}" class="checkbox-link">
@@ -1915,7 +2098,8 @@ var htmlTemplate = `<!DOCTYPE html>
               {{if .ActiveRelays}}
               <div class="settings-divider">
                 <div class="settings-item">{{len .ActiveRelays}} relay{{if gt (len .ActiveRelays) 1}}s{{end}}:</div>
-                {{range .ActiveRelays}}<div class="relay-item">{{.}}</div>{{end}}
+                {{range .ActiveRelays}}<div class="relay-item">{{.}}{{with relayPurposes .}} <span class="relay-purposes">({{.}})</span>{{end}}</div>{{end}}
+                <div class="settings-item"><a href="/html/relays" class="text-link text-xs">Relay info →</a></div>
               </div>
               {{end}}
             </div>
@@ -1927,21 +2111,38 @@ var htmlTemplate = `<!DOCTYPE html>
           {{end}}
         </div>
       </nav>
+      {{if not .Deck}}
       <div class="kind-filter">
-        <a href="/html/timeline?kinds=1,6,20,30023,9802,30311&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "all"}}active{{end}}">All</a>
-        <a href="/html/timeline?kinds=1&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "notes"}}active{{end}}">Notes</a>
-        <a href="/html/timeline?kinds=20&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "photos"}}active{{end}}">Photos</a>
-        <a href="/html/timeline?kinds=30023&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "reads"}}active{{end}}">Longform</a>
+        <a href="/html/timeline?kinds=1,6,20,30023,9802,30311&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}{{if .SortMode}}&sort={{.SortMode}}&window={{.SortWindow}}{{end}}" class="{{if eq .KindFilter "all"}}active{{end}}">All</a>
+        <a href="/html/timeline?kinds=1&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}{{if .SortMode}}&sort={{.SortMode}}&window={{.SortWindow}}{{end}}" class="{{if eq .KindFilter "notes"}}active{{end}}">Notes</a>
+        <a href="/html/timeline?kinds=20&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}{{if .SortMode}}&sort={{.SortMode}}&window={{.SortWindow}}{{end}}" class="{{if eq .KindFilter "photos"}}active{{end}}">Photos</a>
+        <a href="/html/timeline?kinds=30023&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}{{if .SortMode}}&sort={{.SortMode}}&window={{.SortWindow}}{{end}}" class="{{if eq .KindFilter "reads"}}active{{end}}">Longform</a>
         {{if eq .FeedMode "me"}}<a href="/html/timeline?kinds=10003&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "bookmarks"}}active{{end}}">Bookmarks</a>{{end}}
-        <a href="/html/timeline?kinds=9802&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "highlights"}}active{{end}}">Highlights</a>
-        <a href="/html/timeline?kinds=30311&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if eq .KindFilter "livestreams"}}active{{end}}">Livestreams</a>
+        <a href="/html/timeline?kinds=9802&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}{{if .SortMode}}&sort={{.SortMode}}&window={{.SortWindow}}{{end}}" class="{{if eq .KindFilter "highlights"}}active{{end}}">Highlights</a>
+        <a href="/html/timeline?kinds=30311&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}{{if .SortMode}}&sort={{.SortMode}}&window={{.SortWindow}}{{end}}" class="{{if eq .KindFilter "livestreams"}}active{{end}}">Livestreams</a>
         {{if eq .FeedMode "me"}}<span class="kind-filter-spacer"></span><a href="/html/profile/edit" class="edit-profile-link">Edit Profile</a>{{end}}
       </div>
+      {{end}}
+      {{if and (not .Deck) (not .HashtagView) (not .LabelFeed) (ne .KindFilter "bookmarks")}}
+      <div class="kind-filter" aria-label="Sort">
+        <a href="/html/timeline?kinds={{.KindsParam}}&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="{{if not .SortMode}}active{{end}}">Latest</a>
+        <a href="/html/timeline?kinds={{.KindsParam}}&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}&sort=top&window=24h" class="{{if and (eq .SortMode "top") (eq .SortWindow "24h")}}active{{end}}">Top today</a>
+        <a href="/html/timeline?kinds={{.KindsParam}}&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}&sort=top&window=7d" class="{{if and (eq .SortMode "top") (eq .SortWindow "7d")}}active{{end}}">Top this week</a>
+        <a href="/html/timeline?kinds={{.KindsParam}}&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}&sort=trending&window=24h" class="{{if eq .SortMode "trending"}}active{{end}}">Trending</a>
+      </div>
+      {{end}}
       {{if .LoggedIn}}
       <form method="POST" action="/html/post" class="post-form">
         <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
         <label for="post-content" class="sr-only">Write a new note</label>
-        <textarea id="post-content" name="content" placeholder="What's on your mind?" required></textarea>
+        <textarea id="post-content" name="content" placeholder="What's on your mind?" required>{{.ComposerDraft}}</textarea>
+        {{if .ComposerRelays}}
+        <div class="post-options">
+          <label><input type="checkbox" name="relay_only" value="1"> This relay only</label>
+          <label for="post-relay" class="sr-only">Relay for a relay-only note</label>
+          <select id="post-relay" name="relay">{{range .ComposerRelays}}<option value="{{.}}">{{.}}</option>{{end}}</select>
+        </div>
+        {{end}}
         <button type="submit">Post</button>
       </form>
       {{end}}
@@ -1949,18 +2150,61 @@ var htmlTemplate = `<!DOCTYPE html>
 
     <main id="main-content">
       {{if .Error}}
-      <div class="error-box">{{.Error}}</div>
+      <div class="error-box" role="alert">{{.Error}}</div>
       {{end}}
       {{if .Success}}
-      <div class="flash-message">{{.Success}}</div>
+      <div class="flash-message" role="status">{{.Success}}</div>
+      {{end}}
+      {{if .PendingCount}}
+      <div class="error-box" role="status">{{.PendingCount}} unsent post{{if ne .PendingCount 1}}s{{end}}. <a href="/html/pending">Review and retry</a></div>
+      {{end}}
+      {{if .HashtagView}}
+      <div class="hashtag-header">
+        <h2>#{{.HashtagView}}</h2>
+        {{if .LoggedIn}}
+        <form method="POST" action="/html/follow-tag" class="inline-form">
+          <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+          <input type="hidden" name="tag" value="{{.HashtagView}}">
+          <input type="hidden" name="return_url" value="{{.CurrentURL}}">
+          {{if .HashtagFollowed}}
+          <input type="hidden" name="action" value="unfollow">
+          <button type="submit" class="follow-btn unfollow">Following</button>
+          {{else}}
+          <input type="hidden" name="action" value="follow">
+          <button type="submit" class="follow-btn follow">Follow</button>
+          {{end}}
+        </form>
+        {{end}}
+      </div>
+      {{end}}
+      {{if .LocationView}}
+      <div class="label-feed-header">
+        Posts near <strong>{{.LocationView}}</strong>
+        <a href="/html/timeline?kinds=1&limit=20&feed={{.FeedMode}}" class="text-link text-sm">Clear</a>
+      </div>
+      {{end}}
+      {{if .SortMode}}
+      <div class="label-feed-header">
+        {{if eq .SortMode "trending"}}Trending{{else}}Top{{end}} over the last {{.SortWindow}}
+        <span class="text-muted text-sm">ranked by reactions, reposts and zaps seen by this instance &middot; <a href="/html/timeline?kinds={{.KindsParam}}&limit=20&feed={{.FeedMode}}{{if not .ShowReactions}}&fast=1{{end}}" class="text-link">back to latest</a></span>
+      </div>
+      {{end}}
+      {{with .LabelFeed}}
+      <div class="label-feed-header">
+        Events labeled <span class="label-badge" title="{{.Namespace}}">{{.Value}}</span>
+        <span class="text-muted text-sm">in {{.Namespace}}, {{if $.LoggedIn}}by you and people you follow{{else}}by anyone{{end}}</span>
+      </div>
       {{end}}
 
+      {{if .Deck}}
+      {{template "deck" .}}
+      {{else}}{{block "timeline-items" .}}
       {{range .Items}}
       {{$item := .}}
       {{if eq .Kind 9735}}
       <article class="note zap-receipt">
         <div class="zap-content">
-          <span class="zap-icon">⚡</span>
+          <span class="zap-icon">{{icon "zap" "⚡"}}</span>
           <div class="zap-info">
             <div class="zap-header">
               <a href="/html/profile/{{.ZapSenderNpub}}" class="zap-sender">
@@ -2056,7 +2300,7 @@ var htmlTemplate = `<!DOCTYPE html>
       {{else if eq .Kind 10003}}
       <article class="note bookmarks">
         <div class="bookmarks-header">
-          <span class="bookmarks-icon">🔖</span>
+          <span class="bookmarks-icon">{{icon "bookmark" "🔖"}}</span>
           <span class="bookmarks-title">Bookmarks</span>
           <span class="bookmarks-count">{{.BookmarkCount}} items</span>
         </div>
@@ -2165,7 +2409,10 @@ var htmlTemplate = `<!DOCTYPE html>
         </div>
       </article>
       {{else}}
-      <article class="note">
+      <article class="note" id="note-{{.ID}}">
+        {{if .FromTag}}
+        <div class="from-tag">from <a href="/html/timeline?kinds=1&limit=20&feed=global&t={{.FromTag}}">#{{.FromTag}}</a> you follow</div>
+        {{end}}
         <div class="note-author">
           <a href="/html/profile/{{.Npub}}" class="text-muted">
           {{if and .AuthorProfile .AuthorProfile.Picture}}
@@ -2175,7 +2422,7 @@ var htmlTemplate = `<!DOCTYPE html>
           {{end}}
           </a>
           <div class="author-info">
-            <a href="/html/profile/{{.Npub}}" class="text-muted">
+            <a href="/html/profile/{{.Npub}}/summary?return_url={{$.CurrentURL}}%23note-{{.ID}}" data-fragment="/html/profile/{{.Npub}}/summary?fragment=1" class="text-muted">
             {{if .AuthorProfile}}
             {{if or .AuthorProfile.DisplayName .AuthorProfile.Name}}
             <span class="author-name">{{if .AuthorProfile.DisplayName}}{{.AuthorProfile.DisplayName}}{{else}}{{.AuthorProfile.Name}}{{end}}</span>
@@ -2189,7 +2436,7 @@ var htmlTemplate = `<!DOCTYPE html>
             <span class="pubkey" title="{{.Pubkey}}">{{.NpubShort}}</span>
             {{end}}
             </a>
-            <span class="author-time">{{formatTime .CreatedAt}}</span>
+            <span class="author-time">{{formatTime .CreatedAt}}</span> {{if devMode}}<a href="/event/{{.ID}}/inspect" class="source-link" title="View event source">source</a>{{end}}
           </div>
         </div>
         {{if eq .Kind 6}}
@@ -2224,11 +2471,11 @@ var htmlTemplate = `<!DOCTYPE html>
           {{if eq .RepostedEvent.Kind 20}}
           <div class="picture-note">
             {{if .RepostedEvent.Title}}<div class="picture-title">{{.RepostedEvent.Title}}</div>{{end}}
-            <div class="picture-gallery">{{.RepostedEvent.ImagesHTML}}</div>
-            {{if .RepostedEvent.Content}}<div class="picture-caption">{{.RepostedEvent.ContentHTML}}</div>{{end}}
+            <div class="picture-gallery">{{.RepostedEvent.ImagesHTML.HTML}}</div>
+            {{if .RepostedEvent.Content}}<div class="picture-caption">{{.RepostedEvent.ContentHTML.HTML}}</div>{{end}}
           </div>
           {{else}}
-          <div class="note-content">{{.RepostedEvent.ContentHTML}}</div>
+          <div class="note-content">{{.RepostedEvent.ContentHTML.HTML}}</div>
           {{end}}
           <a href="/html/thread/{{.RepostedEvent.ID}}" class="view-note-link">View note &rarr;</a>
         </div>
@@ -2238,8 +2485,36 @@ var htmlTemplate = `<!DOCTYPE html>
         {{else if eq .Kind 20}}
         <div class="picture-note">
           {{if .Title}}<div class="picture-title">{{.Title}}</div>{{end}}
-          <div class="picture-gallery">{{.ImagesHTML}}</div>
-          {{if .Content}}<div class="picture-caption">{{.ContentHTML}}</div>{{end}}
+          <div class="picture-gallery">{{.ImagesHTML.HTML}}</div>
+          {{if .Content}}<div class="picture-caption">{{.ContentHTML.HTML}}</div>{{end}}
+        </div>
+        {{else if eq .Kind 2003}}
+        <div class="torrent">
+          <h3 class="torrent-title">{{if .TorrentTitle}}{{.TorrentTitle}}{{else}}Untitled torrent{{end}}</h3>
+          <div class="torrent-meta">
+            {{if .TorrentSize}}<span>{{.TorrentSize}}</span>{{end}}
+            {{if .TorrentFileCount}}<span>{{.TorrentFileCount}} file{{if ne .TorrentFileCount 1}}s{{end}}</span>{{end}}
+            <span>{{.TorrentTrackerCount}} tracker{{if ne .TorrentTrackerCount 1}}s{{end}}</span>
+          </div>
+          {{if .Content}}<div class="note-content">{{.ContentHTML.HTML}}</div>{{end}}
+          {{if .TorrentMagnet}}
+          <div class="torrent-magnet">
+            <label for="magnet-{{.ID}}" class="sr-only">Magnet link</label>
+            <input type="text" id="magnet-{{.ID}}" value="{{.TorrentMagnet}}" readonly>
+            <a href="{{.TorrentMagnetHref}}" class="text-link" rel="nofollow">Open magnet</a>
+          </div>
+          {{else}}
+          <div class="torrent-magnet text-muted">No valid info hash</div>
+          {{end}}
+          {{if .TorrentFiles}}
+          <details class="torrent-files">
+            <summary>Files</summary>
+            <ul>
+              {{range .TorrentFiles}}<li><span class="torrent-file-name">{{.Name}}</span>{{if .Size}} <span class="text-muted">{{.Size}}</span>{{end}}</li>{{end}}
+            </ul>
+            {{if gt .TorrentMoreFiles 0}}<p class="text-muted">and {{.TorrentMoreFiles}} more</p>{{end}}
+          </details>
+          {{end}}
         </div>
         {{else if eq .Kind 30023}}
         <div class="article-preview">
@@ -2248,7 +2523,11 @@ var htmlTemplate = `<!DOCTYPE html>
           {{if .Summary}}<p class="article-preview-summary">{{.Summary}}</p>{{end}}
         </div>
         {{else}}
-        <div class="note-content">{{.ContentHTML}}</div>
+        <div class="note-content">{{.ContentHTML.HTML}}</div>
+        {{with .Location}}
+        <div class="note-location">{{icon "location" "📍"}} {{with .Name}}<a href="/html/timeline?kinds=1&limit=20&feed=global&location={{.}}" class="text-link">{{.}}</a>{{end}}{{if .MapURL}} <a href="{{.MapURL}}" class="text-link" target="_blank" rel="noopener">{{.Coords}}</a> · <a href="{{.NearbyURL}}" class="text-link">Nearby</a>{{end}}</div>
+        {{end}}
+        {{if .Protected}}<div class="note-protected" title="NIP-70 protected event">{{icon "protected" "🔒"}} Posted to a specific relay</div>{{end}}
         {{if .QuotedEvent}}
         <div class="quoted-note">
           <div class="quoted-author">
@@ -2274,7 +2553,7 @@ var htmlTemplate = `<!DOCTYPE html>
           {{if .QuotedEvent.Summary}}<div class="quoted-article-summary">{{.QuotedEvent.Summary}}</div>{{end}}
           <a href="/html/thread/{{.QuotedEvent.ID}}" class="view-note-link">Read article &rarr;</a>
           {{else}}
-          <div class="note-content">{{.QuotedEvent.ContentHTML}}</div>
+          <div class="note-content">{{.QuotedEvent.ContentHTML.HTML}}</div>
           <a href="/html/thread/{{.QuotedEvent.ID}}" class="view-note-link">View quoted note &rarr;</a>
           {{end}}
         </div>
@@ -2284,28 +2563,35 @@ var htmlTemplate = `<!DOCTYPE html>
         </div>
         {{end}}
         {{end}}
+        {{if .Labels}}
+        <div class="note-labels">
+          {{range .Labels}}<a href="{{.URL}}" class="label-badge" title="{{.Namespace}}">{{.Value}}</a>{{end}}
+        </div>
+        {{end}}
         <div class="note-footer">
           <div class="note-footer-actions">
           {{if $.LoggedIn}}
             {{if eq .Kind 6}}
             {{/* For reposts, actions target the reposted note */}}
             {{if .RepostedEvent}}
-            <a href="/html/thread/{{.RepostedEvent.ID}}" class="text-link">Reply{{if gt .RepostedEvent.ReplyCount 0}} {{.RepostedEvent.ReplyCount}}{{end}}</a>
+            <a href="/html/thread/{{.RepostedEvent.ID}}" class="text-link" aria-label="{{actionLabel "Reply" .RepostedEvent}}">Reply{{if gt .RepostedEvent.ReplyCount 0}} {{.RepostedEvent.ReplyCount}}{{end}}</a>
+            {{if not .RepostedEvent.Protected}}
             <form method="POST" action="/html/repost" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
               <input type="hidden" name="event_id" value="{{.RepostedEvent.ID}}">
               <input type="hidden" name="event_pubkey" value="{{.RepostedEvent.Pubkey}}">
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
-              <button type="submit" class="text-link">Repost</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Repost" .RepostedEvent}}">Repost</button>
             </form>
-            <a href="/html/quote/{{.RepostedEvent.ID}}" class="text-link">Quote</a>
+            {{end}}
+            <a href="/html/quote/{{.RepostedEvent.ID}}" class="text-link" aria-label="{{actionLabel "Quote" .RepostedEvent}}">Quote</a>
             <form method="POST" action="/html/react" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
               <input type="hidden" name="event_id" value="{{.RepostedEvent.ID}}">
               <input type="hidden" name="event_pubkey" value="{{.RepostedEvent.Pubkey}}">
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
               <input type="hidden" name="reaction" value="❤️">
-              <button type="submit" class="text-link">Like</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Like" .RepostedEvent}}">Like</button>
             </form>
             <form method="POST" action="/html/bookmark" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
@@ -2313,30 +2599,32 @@ var htmlTemplate = `<!DOCTYPE html>
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
               {{if .RepostedEvent.IsBookmarked}}
               <input type="hidden" name="action" value="remove">
-              <button type="submit" class="text-link" title="Remove bookmark">Unbookmark</button>
+              <button type="submit" class="text-link" title="Remove bookmark" aria-label="{{actionLabel "Bookmark" .RepostedEvent}}" aria-pressed="true">Unbookmark</button>
               {{else}}
               <input type="hidden" name="action" value="add">
-              <button type="submit" class="text-link" title="Add bookmark">Bookmark</button>
+              <button type="submit" class="text-link" title="Add bookmark" aria-label="{{actionLabel "Bookmark" .RepostedEvent}}" aria-pressed="false">Bookmark</button>
               {{end}}
             </form>
             {{end}}
             {{else if ne .Kind 30023}}
-            <a href="/html/thread/{{.ID}}" class="text-link">Reply{{if gt .ReplyCount 0}} {{.ReplyCount}}{{end}}</a>
+            <a href="/html/thread/{{.ID}}" class="text-link" aria-label="{{actionLabel "Reply" .}}">Reply{{if gt .ReplyCount 0}} {{.ReplyCount}}{{end}}</a>
+            {{if not .Protected}}
             <form method="POST" action="/html/repost" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
               <input type="hidden" name="event_id" value="{{.ID}}">
               <input type="hidden" name="event_pubkey" value="{{.Pubkey}}">
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
-              <button type="submit" class="text-link">Repost</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Repost" .}}">Repost</button>
             </form>
-            <a href="/html/quote/{{.ID}}" class="text-link">Quote</a>
+            {{end}}
+            <a href="/html/quote/{{.ID}}" class="text-link" aria-label="{{actionLabel "Quote" .}}">Quote</a>
             <form method="POST" action="/html/react" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
               <input type="hidden" name="event_id" value="{{$item.ID}}">
               <input type="hidden" name="event_pubkey" value="{{$item.Pubkey}}">
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
               <input type="hidden" name="reaction" value="❤️">
-              <button type="submit" class="text-link">Like</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Like" $item}}">Like</button>
             </form>
             <form method="POST" action="/html/bookmark" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
@@ -2344,12 +2632,25 @@ var htmlTemplate = `<!DOCTYPE html>
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
               {{if .IsBookmarked}}
               <input type="hidden" name="action" value="remove">
-              <button type="submit" class="text-link" title="Remove bookmark">Unbookmark</button>
+              <button type="submit" class="text-link" title="Remove bookmark" aria-label="{{actionLabel "Bookmark" $item}}" aria-pressed="true">Unbookmark</button>
               {{else}}
               <input type="hidden" name="action" value="add">
-              <button type="submit" class="text-link" title="Add bookmark">Bookmark</button>
+              <button type="submit" class="text-link" title="Add bookmark" aria-label="{{actionLabel "Bookmark" $item}}" aria-pressed="false">Bookmark</button>
               {{end}}
             </form>
+            <details class="label-action">
+              <summary class="text-link" aria-label="{{actionLabel "Label" .}}">Label</summary>
+              <form method="POST" action="/html/label" class="label-form">
+                <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+                <input type="hidden" name="event_id" value="{{$item.ID}}">
+                <input type="hidden" name="event_pubkey" value="{{$item.Pubkey}}">
+                <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
+                <input type="hidden" name="namespace" value="{{$.LabelNamespace}}">
+                <label for="label-{{$item.ID}}" class="sr-only">Label in {{$.LabelNamespace}}</label>
+                <input type="text" id="label-{{$item.ID}}" name="label" placeholder="{{$.LabelNamespace}} label" maxlength="64" required>
+                <button type="submit" class="text-link">Add</button>
+              </form>
+            </details>
             {{else}}
             <a href="/html/thread/{{.ID}}" class="text-link">Read article</a>
             {{end}}
@@ -2365,8 +2666,11 @@ var htmlTemplate = `<!DOCTYPE html>
             {{end}}
           {{end}}
           </div>
-          {{if or (and .Reactions (gt .Reactions.Total 0)) (and (not $.LoggedIn) (gt .ReplyCount 0))}}
-          <div class="note-footer-reactions">
+          {{if .ReactionsPending}}
+          {{/* Placeholder with a stable id; hypermedia clients can swap in /html/reactions?ids= */}}
+          <div class="note-footer-reactions" id="reactions-{{.ID}}" data-fragment="/html/reactions?ids={{.ID}}" aria-busy="true"></div>
+          {{else if or (and .Reactions (gt .Reactions.Total 0)) (and (not $.LoggedIn) (gt .ReplyCount 0))}}
+          <div class="note-footer-reactions" id="reactions-{{.ID}}">
             {{if and .Reactions (gt .Reactions.Total 0)}}
             {{range $type, $count := .Reactions.ByType}}
             <span class="reaction-badge">{{$type}} {{$count}}</span>
@@ -2388,11 +2692,12 @@ var htmlTemplate = `<!DOCTYPE html>
       {{end}}{{/* end if eq .Kind 9735 else */}}
       {{else}}
       <div class="empty-state">
-        <div class="empty-state-icon">📭</div>
+        <div class="empty-state-icon">{{icon "inbox" "📭"}}</div>
         <p>No notes found</p>
         <p class="empty-state-hint">Try adjusting your filters or check back later.</p>
       </div>
-      {{end}}
+      {{end}}{{end}}{{end}}
+      {{with .Gap}}{{template "timeline-gap" .}}{{end}}
 
       {{if .Pagination}}
       <div class="pagination">
@@ -2426,7 +2731,7 @@ var htmlTemplate = `<!DOCTYPE html>
     </main>
 
     <footer>
-      <p>{{if .Meta}}Generated: {{.Meta.GeneratedAt.Format "15:04:05"}} · {{end}}Zero-JS Hypermedia Browser</p>
+      <p>{{if .Meta}}Generated: {{.Meta.GeneratedAt.Format "15:04:05"}} · {{end}}{{template "branding-footer"}}</p>
     </footer>
   </div>
   <a href="#top" class="scroll-top" aria-label="Scroll to top">↑</a>
@@ -2443,18 +2748,33 @@ type HTMLPageData struct {
 	Links                  []string
 	LoggedIn               bool
 	UserPubKey             string
-	UserDisplayName        string   // Display name from profile (falls back to @npubShort)
+	UserDisplayName        string // Display name from profile (falls back to @npubShort)
 	Error                  string
 	Success                string
-	ShowReactions          bool     // Whether reactions are being fetched (slow mode)
-	FeedMode               string   // "follows" or "global"
-	KindFilter             string   // Current kind filter: "all", "notes", "photos", "reads", "streams"
-	ActiveRelays           []string // Relays being used for this request
-	CurrentURL             string   // Current page URL for reaction redirects
-	ThemeClass             string   // "dark", "light", or "" for system default
-	ThemeLabel             string   // Label for theme toggle button
-	CSRFToken              string   // CSRF token for form submission
-	HasUnreadNotifications bool     // Whether there are notifications newer than last seen
+	ShowReactions          bool        // Whether reactions are being fetched (slow mode)
+	FeedMode               string      // "follows" or "global"
+	KindFilter             string      // Current kind filter: "all", "notes", "photos", "reads", "streams"
+	ActiveRelays           []string    // Relays being used for this request
+	CurrentURL             string      // Current page URL for reaction redirects
+	ThemeClass             string      // "dark", "light", or "" for system default
+	ThemeLabel             string      // Label for theme toggle button
+	BodyClass              string      // "density-compact" or "" (html_appearance.go)
+	CSRFToken              string      // CSRF token for form submission
+	HasUnreadNotifications bool        // Whether there are notifications newer than last seen
+	Gap                    *TimelineGap // Unread posts between this page and the last visit (html_gap.go)
+	LabelFeed              *EventLabel // Set when showing a /labels/{namespace}/{label} feed
+	LabelNamespace         string      // Namespace offered in the label form
+	HashtagView            string      // Set when showing a ?t= hashtag view
+	HashtagFollowed        bool        // Whether the user follows HashtagView
+	LocationView           string      // Set when filtering by ?g= or ?location=
+	SortMode               string      // "top" or "trending" for engagement-ranked feeds, "" for latest
+	SortWindow             string      // Ranking window for SortMode, e.g. "24h"
+	KindsParam             string      // Current kinds filter as a query value, for sort links
+	Announcement           *Announcement // Instance announcement banner, nil if none
+	Deck                   *HTMLDeck     // Set on /html/deck; the page shows columns instead of Items
+	ComposerRelays         []string      // Write relays offered for a relay-only (NIP-70) note
+	ComposerDraft          string        // Rejected note text to refill the composer with
+	PendingCount           int           // Unsent events kept for retry (pending_events.go)
 }
 
 type HTMLEventItem struct {
@@ -2465,8 +2785,8 @@ type HTMLEventItem struct {
 	NpubShort     string // Short display format (npub1abc...xyz)
 	CreatedAt     int64
 	Content       string
-	ContentHTML   template.HTML
-	ImagesHTML    template.HTML // Pre-rendered images from imeta tags (kind 20)
+	ContentHTML   RenderedContent
+	ImagesHTML    RenderedContent // Pre-rendered images from imeta tags (kind 20)
 	Title         string        // Title from title tag (kind 20, 30023)
 	Summary       string        // Summary from summary tag (kind 30023)
 	HeaderImage   string        // Header image URL from image tag (kind 30023)
@@ -2475,11 +2795,17 @@ type HTMLEventItem struct {
 	Links         []string
 	AuthorProfile *ProfileInfo
 	Reactions     *ReactionsSummary
+	ReactionsPending bool        // Reactions still loading; rendered as a placeholder
 	ReplyCount    int
+	Labels        []EventLabel   // NIP-32 labels from people the viewer follows
+	FromTag       string         // Followed hashtag this item was included in the feed for
+	Location      *HTMLLocation  // From g (geohash) and location tags
+	Protected     bool           // NIP-70 "-" tag: meant to stay on the relays it was posted to
 	ParentID      string         // ID of parent event if this is a reply
 	RepostedEvent  *HTMLEventItem // For kind 6 reposts: the embedded original event
 	QuotedEvent    *HTMLEventItem // For quote posts: the quoted note (from q tag)
 	QuotedEventID  string         // Event ID from q tag (used to fetch quoted event)
+	Receipt        *PublishReceipt // Per-relay publish results, set only on the viewer's own posts
 	// Kind 9735 zap receipt fields
 	ZapSenderPubkey    string       // Pubkey of who sent the zap
 	ZapSenderNpub      string       // Npub of sender
@@ -2507,6 +2833,15 @@ type HTMLEventItem struct {
 	LiveHashtags      []string            // Hashtags for the event
 	LiveDTag          string              // d-tag identifier for addressable events
 	LiveEmbedURL      string              // Embed URL for iframe (e.g., zap.stream)
+	// Kind 2003 torrent fields
+	TorrentTitle        string        // Title from title tag
+	TorrentMagnet       string        // Validated magnet URI (for the copy field)
+	TorrentMagnetHref   template.URL  // Same URI, marked safe for the magnet: anchor
+	TorrentSize         string        // Total size of all files, human-readable
+	TorrentFiles        []TorrentFile // Files (capped at maxTorrentFiles)
+	TorrentFileCount    int           // Number of file tags
+	TorrentMoreFiles    int           // Files left out of TorrentFiles
+	TorrentTrackerCount int           // Number of valid tracker URLs
 	// Kind 9802 highlight fields
 	HighlightContext    string        // Surrounding context text
 	HighlightComment    string        // User's comment on the highlight
@@ -2681,16 +3016,16 @@ func parseImetaTag(tag []string) *ImetaImage {
 }
 
 // extractImetaImages extracts all imeta tags from event tags and renders them as HTML
-func extractImetaImages(tags [][]string) template.HTML {
+func extractImetaImages(tags [][]string) RenderedContent {
 	var images []*ImetaImage
 	for _, tag := range tags {
-		if img := parseImetaTag(tag); img != nil {
+		if img := parseImetaTag(tag); img != nil && isSafeMediaURL(img.URL) {
 			images = append(images, img)
 		}
 	}
 
 	if len(images) == 0 {
-		return ""
+		return RenderedContent{}
 	}
 
 	var sb strings.Builder
@@ -2706,7 +3041,7 @@ func extractImetaImages(tags [][]string) template.HTML {
 		sb.WriteString(`" loading="lazy" class="picture-image">`)
 	}
 
-	return template.HTML(sb.String())
+	return renderedContent(sb.String())
 }
 
 // extractTitle extracts the title tag value from event tags
@@ -2762,13 +3097,14 @@ func extractDTag(tags [][]string) string {
 }
 
 // renderMarkdown converts markdown content to HTML using goldmark
-func renderMarkdown(content string) template.HTML {
+// goldmark's defaults (no WithUnsafe) omit raw HTML; safeMarkdownLinks drops unsafe URLs
+func renderMarkdown(content string) RenderedContent {
 	var buf bytes.Buffer
-	if err := goldmark.Convert([]byte(content), &buf); err != nil {
+	if err := markdownRenderer.Convert([]byte(content), &buf); err != nil {
 		// Fallback to escaped plain text if markdown parsing fails
-		return template.HTML(html.EscapeString(content))
+		return renderedContent(html.EscapeString(content))
 	}
-	return template.HTML(buf.String())
+	return renderedContent(buf.String())
 }
 
 // parseRepostedEvent parses the embedded event JSON from a kind 6 repost's content field
@@ -2802,6 +3138,7 @@ func parseRepostedEvent(content string, relays []string, resolvedRefs map[string
 		Content:       embeddedEvent.Content,
 		ContentHTML:   processContentToHTMLFull(embeddedEvent.Content, relays, resolvedRefs, linkPreviews),
 		AuthorProfile: profiles[embeddedEvent.PubKey],
+		Protected:     isProtectedEvent(embeddedEvent.Tags),
 	}
 
 	// Handle kind 20 (picture notes) within reposts
@@ -3021,7 +3358,7 @@ func parseBookmarks(tags [][]string) *BookmarkInfo {
 
 // processContentToHTML converts plain text content to HTML with images and links
 // This version does not resolve nostr: references (for backward compatibility)
-func processContentToHTML(content string) template.HTML {
+func processContentToHTML(content string) RenderedContent {
 	return processContentToHTMLFull(content, nil, nil, nil)
 }
 
@@ -3029,22 +3366,22 @@ func processContentToHTML(content string) template.HTML {
 // and resolved nostr: references (quoted notes, profiles)
 // NOTE: This function resolves references synchronously - use processContentToHTMLFull
 // with pre-resolved refs for better performance when processing multiple items
-func processContentToHTMLWithRelays(content string, relays []string) template.HTML {
+func processContentToHTMLWithRelays(content string, relays []string) RenderedContent {
 	return processContentToHTMLFull(content, relays, nil, nil)
 }
 
 // processContentToHTMLWithResolved converts plain text content to HTML with images, links,
 // and pre-resolved nostr: references. If resolvedRefs is provided, it uses those instead
 // of fetching from relays (much faster for batch processing).
-func processContentToHTMLWithResolved(content string, relays []string, resolvedRefs map[string]string) template.HTML {
+func processContentToHTMLWithResolved(content string, relays []string, resolvedRefs map[string]string) RenderedContent {
 	return processContentToHTMLFull(content, relays, resolvedRefs, nil)
 }
 
 // processContentToHTMLFull converts plain text content to HTML with images, links,
 // pre-resolved nostr: references, and link previews.
-func processContentToHTMLFull(content string, relays []string, resolvedRefs map[string]string, linkPreviews map[string]*LinkPreview) template.HTML {
-	// Trim leading/trailing whitespace
-	content = strings.TrimSpace(content)
+func processContentToHTMLFull(content string, relays []string, resolvedRefs map[string]string, linkPreviews map[string]*LinkPreview) RenderedContent {
+	// Trim leading/trailing whitespace, and drop NULs so content can't forge a placeholder
+	content = strings.ReplaceAll(strings.TrimSpace(content), "\x00", "")
 
 	// Collapse multiple newlines before media URLs to just a single newline
 	content = mediaURLRegex.ReplaceAllString(content, "\n$2")
@@ -3122,7 +3459,7 @@ func processContentToHTMLFull(content string, relays []string, resolvedRefs map[
 	// Wrap consecutive images in a gallery div for better layout
 	result = wrapConsecutiveImages(result)
 
-	return template.HTML(result)
+	return renderedContent(result)
 }
 
 // renderLinkPreview creates an HTML preview card for a URL
@@ -3134,7 +3471,7 @@ func renderLinkPreview(url string, preview *LinkPreview) string {
 	sb.WriteString(`" target="_blank" rel="noopener" class="link-preview">`)
 
 	// Image on the left (if available)
-	if preview.Image != "" {
+	if preview.Image != "" && isSafeMediaURL(preview.Image) {
 		sb.WriteString(`<img src="`)
 		sb.WriteString(html.EscapeString(preview.Image))
 		sb.WriteString(`" alt="" class="link-preview-image" loading="lazy">`)
@@ -3421,6 +3758,9 @@ func fetchReplaceableEvent(relays []string, kind int, author string, dTag string
 		Kinds:   []int{kind},
 		Limit:   1,
 	}
+	if dTag != "" {
+		filter.Tags = map[string][]string{"d": {dTag}}
+	}
 
 	events, _ := fetchEventsFromRelays(relays, filter)
 
@@ -3471,18 +3811,8 @@ func renderQuotedNote(event *Event, relays []string) string {
 			summary = content
 		}
 
-		// Get d-tag for naddr link
-		dTag := extractDTag(event.Tags)
-		var linkURL string
-		if dTag != "" {
-			naddr, err := EncodeNAddr(30023, event.PubKey, dTag)
-			if err == nil {
-				linkURL = "/html/thread/" + naddr
-			}
-		}
-		if linkURL == "" {
-			linkURL = "/html/thread/" + event.ID
-		}
+		// Articles have no naddr route, so link this version by ID
+		linkURL := canonicalThreadPath(event.ID)
 
 		return fmt.Sprintf(`<div class="quoted-note quoted-article">%s<div class="quoted-article-title">%s</div><div class="quoted-article-summary">%s</div><div class="quoted-meta"><span>%s</span> · <a href="%s">Read article →</a></div></div>`,
 			authorHTML,
@@ -3548,7 +3878,7 @@ func computeKindFilter(kinds []int) string {
 	return "all" // Unknown filter pattern, default to all
 }
 
-func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds []int, limit int, session *BunkerSession, errorMsg, successMsg string, showReactions bool, feedMode string, currentURL string, themeClass, themeLabel string, csrfToken string, hasUnreadNotifs bool) (string, error) {
+func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds []int, limit int, session *BunkerSession, errorMsg, successMsg string, showReactions bool, feedMode string, currentURL string, themeClass, themeLabel string, csrfToken string, hasUnreadNotifs bool, announcement *Announcement, fragment bool) (string, error) {
 	// Pre-fetch all nostr: references in parallel for much faster rendering
 	contents := make([]string, len(resp.Items))
 	for i, item := range resp.Items {
@@ -3564,7 +3894,7 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 	}
 	linkPreviews := FetchLinkPreviews(allURLs)
 
-	// Pre-fetch profiles for live event participants from purplepag.es
+	// Pre-fetch profiles for live event participants from the metadata relays
 	liveParticipantPubkeys := make(map[string]bool)
 	for _, item := range resp.Items {
 		if item.Kind == 30311 {
@@ -3582,8 +3912,8 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 		for pk := range liveParticipantPubkeys {
 			pubkeys = append(pubkeys, pk)
 		}
-		// Fetch from purplepag.es for better profile coverage
-		liveParticipantProfiles = fetchProfiles([]string{"wss://purplepag.es"}, pubkeys)
+		// Fetch from the metadata relays for better profile coverage
+		liveParticipantProfiles = fetchProfiles(relaysFor(RelayPurposeMetadata), pubkeys)
 	}
 
 	// Pre-fetch quoted events for quote posts (notes with q tag)
@@ -3626,7 +3956,7 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 			for pk := range pubkeys {
 				pks = append(pks, pk)
 			}
-			quotedEventProfiles = fetchProfiles([]string{"wss://purplepag.es"}, pks)
+			quotedEventProfiles = fetchProfiles(relaysFor(RelayPurposeMetadata), pks)
 		}
 	}
 
@@ -3649,7 +3979,12 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 			Links:         []string{},
 			AuthorProfile: item.AuthorProfile,
 			Reactions:     item.Reactions,
+			ReactionsPending: item.ReactionsPending,
 			ReplyCount:    item.ReplyCount,
+			Labels:        item.Labels,
+			FromTag:       item.FromTag,
+			Location:      eventLocation(item.Tags),
+			Protected:     isProtectedEvent(item.Tags),
 		}
 
 		// Extract imeta images and title for kind 20 (picture notes)
@@ -3776,7 +4111,7 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 					}
 				}
 
-				// Build participant list with profiles from purplepag.es
+				// Build participant list with profiles from the metadata relays
 				participants := make([]LiveParticipant, 0, len(liveInfo.ParticipantPubkeys))
 				for _, pk := range liveInfo.ParticipantPubkeys {
 					npub, _ := encodeBech32Pubkey(pk)
@@ -3793,6 +4128,19 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 			}
 		}
 
+		// Parse torrent for kind 2003
+		if item.Kind == 2003 {
+			torrentInfo := parseTorrent(item.Tags)
+			items[i].TorrentTitle = torrentInfo.Title
+			items[i].TorrentMagnet = torrentInfo.Magnet
+			items[i].TorrentMagnetHref = magnetHref(torrentInfo.Magnet)
+			items[i].TorrentSize = torrentInfo.TotalSize
+			items[i].TorrentFiles = torrentInfo.Files
+			items[i].TorrentFileCount = torrentInfo.FileCount
+			items[i].TorrentMoreFiles = torrentInfo.FileCount - len(torrentInfo.Files)
+			items[i].TorrentTrackerCount = len(torrentInfo.Trackers)
+		}
+
 		// Parse highlight for kind 9802
 		if item.Kind == 9802 {
 			highlightInfo := parseHighlight(item.Tags)
@@ -3849,7 +4197,32 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 		CurrentURL:    currentURL,
 		ThemeClass:    themeClass,
 		ThemeLabel:    themeLabel,
+		BodyClass:     densityClass(session),
 		CSRFToken:     csrfToken,
+		LabelFeed:     resp.Label,
+		LabelNamespace: labelNamespace,
+		SortMode:      resp.Sort,
+		SortWindow:    resp.Window,
+		Gap:           resp.Gap,
+		KindsParam:    joinKinds(kinds),
+		Announcement:  announcement,
+	}
+	if resp.Label != nil {
+		data.Title = "Labeled " + resp.Label.Value
+	}
+	if resp.Geohash != "" || resp.Location != "" {
+		data.LocationView = strings.TrimSpace(resp.Location + " " + resp.Geohash)
+		data.Title = "Near " + data.LocationView
+	}
+	if resp.Hashtag != "" {
+		data.Title = "#" + resp.Hashtag
+		data.HashtagView = resp.Hashtag
+		for _, t := range followedTagsOf(session) {
+			if t == resp.Hashtag {
+				data.HashtagFollowed = true
+				break
+			}
+		}
 	}
 
 	// Add session info if logged in
@@ -3859,10 +4232,22 @@ func renderHTML(resp TimelineResponse, relays []string, authors []string, kinds
 		data.UserPubKey = pubkeyHex
 		data.UserDisplayName = getUserDisplayName(pubkeyHex)
 		data.HasUnreadNotifications = hasUnreadNotifs
+		data.ComposerRelays = composerRelays(session)
+		data.PendingCount = len(pendingEvents(session))
+		if !fragment {
+			data.ComposerDraft = takeComposerDraft(session, "note", "")
+		}
 	}
 
 	// Use cached template for better performance
+	// Fragment requests (deck columns) render just the feed items
 	var buf strings.Builder
+	if fragment {
+		if err := cachedHTMLTemplate.ExecuteTemplate(&buf, "timeline-items", data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
 	if err := cachedHTMLTemplate.Execute(&buf, data); err != nil {
 		return "", err
 	}
@@ -3875,9 +4260,14 @@ var htmlThreadTemplate = `<!DOCTYPE html>
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>Thread - Nostr Hypermedia</title>
+  <title>Thread - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
+  {{if .CanonicalURL}}<link rel="canonical" href="{{.CanonicalURL}}">
+  <link rel="alternate" type="application/json+oembed" href="/oembed?url={{.CanonicalURL}}">{{end}}
+  {{template "preview-meta" .Preview}}
   <link rel="icon" href="/static/favicon.ico" />
   <style>
+{{template "announcement-styles"}}
     :root {
       --bg-page: #f5f5f5;
       --bg-container: #ffffff;
@@ -5176,7 +5566,32 @@ var htmlThreadTemplate = `<!DOCTYPE html>
       font-size: 16px;
       margin-bottom: 16px;
     }
+    .thread-summary {
+      margin-bottom: 16px;
+      padding: 12px 16px;
+      background: var(--bg-secondary);
+      border: 1px solid var(--border-color);
+      border-radius: 8px;
+      font-size: 14px;
+    }
+    .thread-summary-participants {
+      display: flex;
+      flex-wrap: wrap;
+      gap: 6px;
+      margin: 8px 0;
+    }
+    .thread-summary-participants img {
+      width: 32px;
+      height: 32px;
+      border-radius: 50%;
+      object-fit: cover;
+    }
+    .thread-summary-links {
+      display: flex;
+      gap: 16px;
+    }
     .reply {
+      scroll-margin-top: 16px;
       margin-left: 20px;
       border-left: 3px solid var(--border-color);
       padding-left: 16px;
@@ -5244,6 +5659,24 @@ var htmlThreadTemplate = `<!DOCTYPE html>
       flex-wrap: wrap;
       margin-left: auto;
     }
+    .note-location {
+      margin: 8px 0 0;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .note-protected {
+      margin: 8px 0 0;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .publish-receipt {
+      margin-top: 8px;
+      font-size: 12px;
+      color: var(--text-muted);
+    }
+    .publish-receipt summary { cursor: pointer; }
+    .publish-receipt ul { margin: 6px 0 6px 20px; }
+    .receipt-relay { font-family: monospace; }
     .text-muted { color: var(--text-secondary); text-decoration: none; }
     .text-sm { font-size: 13px; }
     .text-xs { font-size: 12px; }
@@ -5453,11 +5886,15 @@ var htmlThreadTemplate = `<!DOCTYPE html>
       border-top: 1px solid var(--border-color);
       border-radius: 0 0 8px 8px;
     }
+{{template "density-styles"}}
+{{template "branding-styles"}}
   </style>
 </head>
-<body>
+<body{{if .BodyClass}} class="{{.BodyClass}}"{{end}}>
+  {{template "announcement" .Announcement}}
   <div id="top" class="container">
     <nav>
+      {{template "branding-logo"}}
       {{if .LoggedIn}}
       <a href="/html/timeline?kinds=1&limit=20&feed=follows" class="nav-tab">Follows</a>
       {{end}}
@@ -5468,10 +5905,11 @@ var htmlThreadTemplate = `<!DOCTYPE html>
       <div class="ml-auto flex-center gap-md">
         <span class="text-xs text-muted">{{len .Replies}} repl{{if eq (len .Replies) 1}}y{{else}}ies{{end}}</span>
         {{if .LoggedIn}}
-        <a href="/html/notifications" class="notification-bell" title="Notifications">🔔{{if .HasUnreadNotifications}}<span class="notification-badge"></span>{{end}}</a>
+        <a href="/html/messages" class="notification-bell" title="Messages">{{icon "mail" "✉️"}}</a>
+        <a href="/html/notifications" class="notification-bell" title="Notifications">{{icon "bell" "🔔"}}{{if .HasUnreadNotifications}}<span class="notification-badge"></span>{{end}}</a>
         {{end}}
         <details class="settings-dropdown">
-          <summary class="settings-toggle" title="Settings">⚙️</summary>
+          <summary class="settings-toggle" title="Settings">{{icon "settings" "⚙️"}}</summary>
           <div class="settings-menu">
             <div class="settings-item">
               <form method="POST" action="/html/theme" class="inline-form">
@@ -5489,8 +5927,11 @@ var htmlThreadTemplate = `<!DOCTYPE html>
     </nav>
 
     <main>
+      {{if .Error}}
+      <div class="error-box" role="alert">{{.Error}}</div>
+      {{end}}
       {{if .Success}}
-      <div class="flash-message">{{.Success}}</div>
+      <div class="flash-message" role="status">{{.Success}}</div>
       {{end}}
 
       {{if .Root}}
@@ -5504,7 +5945,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
           {{end}}
           </a>
           <div class="author-info">
-            <a href="/html/profile/{{.Root.Npub}}" class="text-link">
+            <a href="/html/profile/{{.Root.Npub}}/summary?return_url={{.CurrentURL}}" data-fragment="/html/profile/{{.Root.Npub}}/summary?fragment=1" class="text-link">
             {{if .Root.AuthorProfile}}
             {{if or .Root.AuthorProfile.DisplayName .Root.AuthorProfile.Name}}
             <span class="author-name">{{if .Root.AuthorProfile.DisplayName}}{{.Root.AuthorProfile.DisplayName}}{{else}}{{.Root.AuthorProfile.Name}}{{end}}</span>
@@ -5518,7 +5959,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
             <span class="pubkey" title="{{.Root.Pubkey}}">{{.Root.NpubShort}}</span>
             {{end}}
             </a>
-            <span class="author-time">{{formatTime .Root.CreatedAt}}</span>
+            <span class="author-time">{{formatTime .Root.CreatedAt}}</span> {{if devMode}}<a href="/event/{{.Root.ID}}/inspect" class="source-link" title="View event source">source</a>{{end}}
           </div>
         </div>
         {{if eq .Root.Kind 30023}}
@@ -5527,11 +5968,15 @@ var htmlThreadTemplate = `<!DOCTYPE html>
           {{if .Root.Title}}<h2 class="article-title">{{.Root.Title}}</h2>{{end}}
           {{if .Root.Summary}}<p class="article-summary">{{.Root.Summary}}</p>{{end}}
           {{if .Root.PublishedAt}}<div class="article-published">Published: {{formatTime .Root.PublishedAt}}</div>{{end}}
-          <div class="article-content">{{.Root.ContentHTML}}</div>
+          <div class="article-content">{{.Root.ContentHTML.HTML}}</div>
         </article>
         {{else}}
-        <div class="note-content">{{.Root.ContentHTML}}</div>
+        <div class="note-content">{{.Root.ContentHTML.HTML}}</div>
         {{end}}
+        {{with .Root.Location}}
+        <div class="note-location">{{icon "location" "📍"}} {{with .Name}}<a href="/html/timeline?kinds=1&limit=20&feed=global&location={{.}}" class="text-link">{{.}}</a>{{end}}{{if .MapURL}} <a href="{{.MapURL}}" class="text-link" target="_blank" rel="noopener">{{.Coords}}</a> · <a href="{{.NearbyURL}}" class="text-link">Nearby</a>{{end}}</div>
+        {{end}}
+        {{if .Root.Protected}}<div class="note-protected" title="NIP-70 protected event">{{icon "protected" "🔒"}} Posted to a specific relay</div>{{end}}
         {{if .Root.QuotedEvent}}
         <div class="quoted-note">
           <div class="quoted-author">
@@ -5555,7 +6000,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
           {{if .Root.QuotedEvent.Summary}}<div class="quoted-article-summary">{{.Root.QuotedEvent.Summary}}</div>{{end}}
           <a href="/html/thread/{{.Root.QuotedEvent.ID}}" class="view-note-link">Read article &rarr;</a>
           {{else}}
-          <div class="quoted-content">{{.Root.QuotedEvent.ContentHTML}}</div>
+          <div class="quoted-content">{{.Root.QuotedEvent.ContentHTML.HTML}}</div>
           <a href="/html/thread/{{.Root.QuotedEvent.ID}}" class="view-note-link">View quoted note &rarr;</a>
           {{end}}
         </div>
@@ -5567,21 +6012,23 @@ var htmlThreadTemplate = `<!DOCTYPE html>
         <div class="note-footer">
           <div class="note-footer-actions">
           {{if $.LoggedIn}}
+          {{if not .Root.Protected}}
           <form method="POST" action="/html/repost" class="inline-form">
             <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
             <input type="hidden" name="event_id" value="{{.Root.ID}}">
             <input type="hidden" name="event_pubkey" value="{{.Root.Pubkey}}">
             <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
-            <button type="submit" class="text-link">Repost</button>
+            <button type="submit" class="text-link" aria-label="{{actionLabel "Repost" .Root}}">Repost</button>
           </form>
-          <a href="/html/quote/{{.Root.ID}}" class="text-link">Quote</a>
+          {{end}}
+          <a href="/html/quote/{{.Root.ID}}" class="text-link" aria-label="{{actionLabel "Quote" .Root}}">Quote</a>
           <form method="POST" action="/html/react" class="inline-form">
             <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
             <input type="hidden" name="event_id" value="{{.Root.ID}}">
             <input type="hidden" name="event_pubkey" value="{{.Root.Pubkey}}">
             <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
             <input type="hidden" name="reaction" value="❤️">
-            <button type="submit" class="text-link">Like</button>
+            <button type="submit" class="text-link" aria-label="{{actionLabel "Like" .Root}}">Like</button>
           </form>
           <form method="POST" action="/html/bookmark" class="inline-form">
             <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
@@ -5589,10 +6036,10 @@ var htmlThreadTemplate = `<!DOCTYPE html>
             <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
             {{if .Root.IsBookmarked}}
             <input type="hidden" name="action" value="remove">
-            <button type="submit" class="text-link">Unbookmark</button>
+            <button type="submit" class="text-link" aria-label="{{actionLabel "Bookmark" .Root}}" aria-pressed="true">Unbookmark</button>
             {{else}}
             <input type="hidden" name="action" value="add">
-            <button type="submit" class="text-link">Bookmark</button>
+            <button type="submit" class="text-link" aria-label="{{actionLabel "Bookmark" .Root}}" aria-pressed="false">Bookmark</button>
             {{end}}
           </form>
           {{end}}
@@ -5608,6 +6055,34 @@ var htmlThreadTemplate = `<!DOCTYPE html>
           </div>
           {{end}}
         </div>
+        {{with .Root.Receipt}}
+        <details class="publish-receipt">
+          <summary>Accepted by {{.AcceptedCount}}/{{len .Results}} relays{{with .PendingCount}}, {{.}} pending{{end}}</summary>
+          <ul>
+            {{range .Results}}
+            <li><span class="receipt-relay">{{.Relay}}</span> {{if .Pending}}pending{{else if .Accepted}}accepted{{else}}failed{{with .Message}}: {{.}}{{end}}{{end}}</li>
+            {{end}}
+          </ul>
+          {{if .MissingRelays}}
+          <form method="POST" action="/html/republish" class="inline-form">
+            <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+            <input type="hidden" name="event_id" value="{{.Event.ID}}">
+            <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
+            <button type="submit" class="text-link">Re-publish to missing relays</button>
+          </form>
+          {{end}}
+        </details>
+        {{end}}
+        {{if .RootSeenOn}}
+        <details class="publish-receipt">
+          <summary>Seen on {{len .RootSeenOn}} relay{{if gt (len .RootSeenOn) 1}}s{{end}}</summary>
+          <ul>
+            {{range .RootSeenOn}}
+            <li><a href="{{relayInfoURL .Relay}}" class="receipt-relay">{{.Relay}}</a> at {{.SeenAt}} UTC{{if ne .After "0s"}} (+{{.After}}){{end}}</li>
+            {{end}}
+          </ul>
+        </details>
+        {{end}}
       </article>
 
       {{if .LoggedIn}}
@@ -5619,7 +6094,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
           Replying as: <span class="reply-author">{{.UserDisplayName}}</span>
         </div>
         <label for="reply-content" class="sr-only">Write a reply</label>
-        <textarea id="reply-content" name="content" placeholder="Write a reply..." required></textarea>
+        <textarea id="reply-content" name="content" placeholder="Write a reply..." required>{{.ComposerDraft}}</textarea>
         <button type="submit">Reply</button>
       </form>
       {{else}}
@@ -5631,9 +6106,26 @@ var htmlThreadTemplate = `<!DOCTYPE html>
       {{if .Replies}}
       <div class="replies-section">
         <h3>Replies ({{len .Replies}})</h3>
+        {{with .Summary}}
+        <nav class="thread-summary" aria-label="Thread summary">
+          <div>{{.ReplyCount}} replies from {{.ParticipantCount}} participant{{if gt .ParticipantCount 1}}s{{end}}</div>
+          <div class="thread-summary-participants">
+            {{range .TopParticipants}}
+            {{$name := .NpubShort}}{{if .Profile}}{{if .Profile.DisplayName}}{{$name = .Profile.DisplayName}}{{else if .Profile.Name}}{{$name = .Profile.Name}}{{end}}{{end}}
+            <a href="#reply-{{.FirstReplyID}}" title="{{$name}} ({{.ReplyCount}} repl{{if eq .ReplyCount 1}}y{{else}}ies{{end}})">
+              <img src="{{if and .Profile .Profile.Picture}}{{.Profile.Picture}}{{else}}/static/avatar.jpg{{end}}" alt="{{$name}}'s first reply">
+            </a>
+            {{end}}
+          </div>
+          <div class="thread-summary-links">
+            <a href="#reply-{{.NewestReplyID}}" class="text-link">Jump to newest</a>
+            {{if .FirstOPReplyID}}<a href="#reply-{{.FirstOPReplyID}}" class="text-link">Jump to OP's replies</a>{{end}}
+          </div>
+        </nav>
+        {{end}}
         {{range .Replies}}
         {{$reply := .}}
-        <article class="note reply">
+        <article class="note reply" id="reply-{{.ID}}">
           <div class="note-author">
             <a href="/html/profile/{{.Npub}}" class="text-link">
             {{if and .AuthorProfile .AuthorProfile.Picture}}
@@ -5643,7 +6135,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
             {{end}}
             </a>
             <div class="author-info">
-              <a href="/html/profile/{{.Npub}}" class="text-link">
+              <a href="/html/profile/{{.Npub}}/summary?return_url={{$.CurrentURL}}%23reply-{{.ID}}" data-fragment="/html/profile/{{.Npub}}/summary?fragment=1" class="text-link">
               {{if .AuthorProfile}}
               {{if or .AuthorProfile.DisplayName .AuthorProfile.Name}}
               <span class="author-name">{{if .AuthorProfile.DisplayName}}{{.AuthorProfile.DisplayName}}{{else}}{{.AuthorProfile.Name}}{{end}}</span>
@@ -5657,10 +6149,14 @@ var htmlThreadTemplate = `<!DOCTYPE html>
               <span class="pubkey" title="{{.Pubkey}}">{{.NpubShort}}</span>
               {{end}}
               </a>
-              <span class="author-time">{{formatTime .CreatedAt}}</span>
+              <span class="author-time">{{formatTime .CreatedAt}}</span> {{if devMode}}<a href="/event/{{.ID}}/inspect" class="source-link" title="View event source">source</a>{{end}}
             </div>
           </div>
-          <div class="note-content">{{.ContentHTML}}</div>
+          <div class="note-content">{{.ContentHTML.HTML}}</div>
+          {{with .Location}}
+          <div class="note-location">{{icon "location" "📍"}} {{with .Name}}<a href="/html/timeline?kinds=1&limit=20&feed=global&location={{.}}" class="text-link">{{.}}</a>{{end}}{{if .MapURL}} <a href="{{.MapURL}}" class="text-link" target="_blank" rel="noopener">{{.Coords}}</a> · <a href="{{.NearbyURL}}" class="text-link">Nearby</a>{{end}}</div>
+          {{end}}
+          {{if .Protected}}<div class="note-protected" title="NIP-70 protected event">{{icon "protected" "🔒"}} Posted to a specific relay</div>{{end}}
           {{if .QuotedEvent}}
           <div class="quoted-note">
             <div class="quoted-author">
@@ -5684,7 +6180,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
             {{if .QuotedEvent.Summary}}<div class="quoted-article-summary">{{.QuotedEvent.Summary}}</div>{{end}}
             <a href="/html/thread/{{.QuotedEvent.ID}}" class="view-note-link">Read article &rarr;</a>
             {{else}}
-            <div class="quoted-content">{{.QuotedEvent.ContentHTML}}</div>
+            <div class="quoted-content">{{.QuotedEvent.ContentHTML.HTML}}</div>
             <a href="/html/thread/{{.QuotedEvent.ID}}" class="view-note-link">View quoted note &rarr;</a>
             {{end}}
           </div>
@@ -5696,22 +6192,24 @@ var htmlThreadTemplate = `<!DOCTYPE html>
           <div class="note-footer">
             <div class="note-footer-actions">
             {{if $.LoggedIn}}
-            <a href="/html/thread/{{.ID}}" class="text-link">Reply</a>
+            <a href="/html/thread/{{.ID}}" class="text-link" aria-label="{{actionLabel "Reply" .}}">Reply</a>
+            {{if not $reply.Protected}}
             <form method="POST" action="/html/repost" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
               <input type="hidden" name="event_id" value="{{$reply.ID}}">
               <input type="hidden" name="event_pubkey" value="{{$reply.Pubkey}}">
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
-              <button type="submit" class="text-link">Repost</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Repost" $reply}}">Repost</button>
             </form>
-            <a href="/html/quote/{{.ID}}" class="text-link">Quote</a>
+            {{end}}
+            <a href="/html/quote/{{.ID}}" class="text-link" aria-label="{{actionLabel "Quote" .}}">Quote</a>
             <form method="POST" action="/html/react" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
               <input type="hidden" name="event_id" value="{{$reply.ID}}">
               <input type="hidden" name="event_pubkey" value="{{$reply.Pubkey}}">
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
               <input type="hidden" name="reaction" value="❤️">
-              <button type="submit" class="text-link">Like</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Like" $reply}}">Like</button>
             </form>
             <form method="POST" action="/html/bookmark" class="inline-form">
               <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
@@ -5719,10 +6217,10 @@ var htmlThreadTemplate = `<!DOCTYPE html>
               <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
               {{if .IsBookmarked}}
               <input type="hidden" name="action" value="remove">
-              <button type="submit" class="text-link">Unbookmark</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Bookmark" $reply}}" aria-pressed="true">Unbookmark</button>
               {{else}}
               <input type="hidden" name="action" value="add">
-              <button type="submit" class="text-link">Bookmark</button>
+              <button type="submit" class="text-link" aria-label="{{actionLabel "Bookmark" $reply}}" aria-pressed="false">Bookmark</button>
               {{end}}
             </form>
             {{end}}
@@ -5738,13 +6236,31 @@ var htmlThreadTemplate = `<!DOCTYPE html>
             </div>
             {{end}}
           </div>
+          {{with .Receipt}}
+          <details class="publish-receipt">
+            <summary>Accepted by {{.AcceptedCount}}/{{len .Results}} relays{{with .PendingCount}}, {{.}} pending{{end}}</summary>
+            <ul>
+              {{range .Results}}
+              <li><span class="receipt-relay">{{.Relay}}</span> {{if .Pending}}pending{{else if .Accepted}}accepted{{else}}failed{{with .Message}}: {{.}}{{end}}{{end}}</li>
+              {{end}}
+            </ul>
+            {{if .MissingRelays}}
+            <form method="POST" action="/html/republish" class="inline-form">
+              <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+              <input type="hidden" name="event_id" value="{{.Event.ID}}">
+              <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
+              <button type="submit" class="text-link">Re-publish to missing relays</button>
+            </form>
+            {{end}}
+          </details>
+          {{end}}
         </article>
         {{end}}
       </div>
       {{end}}
       {{else}}
       <div class="empty-state">
-        <div class="empty-state-icon">🔍</div>
+        <div class="empty-state-icon">{{icon "search" "🔍"}}</div>
         <p>Event not found</p>
         <p class="empty-state-hint">This note may have been deleted or may not exist on the relays we checked.</p>
       </div>
@@ -5752,7 +6268,7 @@ var htmlThreadTemplate = `<!DOCTYPE html>
     </main>
 
     <footer>
-      <p>{{if .Meta}}Generated: {{.Meta.GeneratedAt.Format "15:04:05"}} · {{end}}Zero-JS Hypermedia Browser</p>
+      <p>{{if .Meta}}Generated: {{.Meta.GeneratedAt.Format "15:04:05"}} · {{end}}{{template "branding-footer"}}</p>
     </footer>
   </div>
   <a href="#top" class="scroll-top" aria-label="Scroll to top">↑</a>
@@ -5765,15 +6281,23 @@ type HTMLThreadData struct {
 	Meta                   *MetaInfo
 	Root                   *HTMLEventItem
 	Replies                []HTMLEventItem
+	Summary                *ThreadSummary // Participation summary for large threads, nil otherwise
 	LoggedIn               bool
 	UserPubKey             string
 	UserDisplayName        string
 	CurrentURL             string
+	CanonicalURL           string // Canonical path for <link rel="canonical">, empty if the root wasn't found
+	Preview                *PreviewMeta // og: and twitter: tags, nil if the root wasn't found
 	ThemeClass             string // "dark", "light", or "" for system default
 	ThemeLabel             string // Label for theme toggle button
+	BodyClass              string // "density-compact" or ""
+	Error                  string
 	Success                string
+	ComposerDraft          string // Rejected reply text to refill the reply form with
 	CSRFToken              string // CSRF token for form submission
 	HasUnreadNotifications bool   // Whether there are notifications newer than last seen
+	Announcement           *Announcement // Instance announcement banner, nil if none
+	RootSeenOn             []HTMLInspectSighting // Relays the root was received from
 }
 
 // extractParentID extracts the parent event ID from the "e" tags
@@ -5793,7 +6317,7 @@ func extractParentID(tags [][]string) string {
 	return parentID
 }
 
-func renderThreadHTML(resp ThreadResponse, relays []string, session *BunkerSession, currentURL string, themeClass, themeLabel, successMsg, csrfToken string, hasUnreadNotifs bool) (string, error) {
+func renderThreadHTML(resp ThreadResponse, relays []string, session *BunkerSession, currentURL string, themeClass, themeLabel, errorMsg, successMsg, csrfToken string, hasUnreadNotifs bool, announcement *Announcement, preview *PreviewMeta) (string, error) {
 	// Pre-fetch all nostr: references in parallel for much faster rendering
 	contents := make([]string, 1+len(resp.Replies))
 	contents[0] = resp.Root.Content
@@ -5874,6 +6398,8 @@ func renderThreadHTML(resp ThreadResponse, relays []string, session *BunkerSessi
 		AuthorProfile: resp.Root.AuthorProfile,
 		ReplyCount:    resp.Root.ReplyCount,
 		ParentID:      extractParentID(resp.Root.Tags),
+		Location:      eventLocation(resp.Root.Tags),
+		Protected:     isProtectedEvent(resp.Root.Tags),
 	}
 
 	// Handle kind 30023 (long-form articles) - extract metadata and render markdown
@@ -5937,6 +6463,8 @@ func renderThreadHTML(resp ThreadResponse, relays []string, session *BunkerSessi
 			AuthorProfile: item.AuthorProfile,
 			ReplyCount:    item.ReplyCount,
 			ParentID:      extractParentID(item.Tags),
+			Location:      eventLocation(item.Tags),
+			Protected:     isProtectedEvent(item.Tags),
 		}
 
 		// Handle quote posts for replies (kind 1 with q tag)
@@ -5983,8 +6511,19 @@ func renderThreadHTML(resp ThreadResponse, relays []string, session *BunkerSessi
 		CurrentURL: currentURL,
 		ThemeClass: themeClass,
 		ThemeLabel: themeLabel,
+		BodyClass:  densityClass(session),
+		Error:      errorMsg,
 		Success:    successMsg,
 		CSRFToken:  csrfToken,
+		Announcement: announcement,
+	}
+	if root != nil {
+		data.CanonicalURL = canonicalThreadPath(root.ID)
+		data.Preview = preview
+		data.Summary = buildThreadSummary(root.Pubkey, replies)
+		if seen, ok := seenEventCache.Get(root.ID); ok {
+			data.RootSeenOn = eventSightings(seen)
+		}
 	}
 
 	// Add session info
@@ -5994,6 +6533,13 @@ func renderThreadHTML(resp ThreadResponse, relays []string, session *BunkerSessi
 		data.UserPubKey = pubkeyHex
 		data.UserDisplayName = getUserDisplayName(pubkeyHex)
 		data.HasUnreadNotifications = hasUnreadNotifs
+		if root != nil {
+			data.ComposerDraft = takeComposerDraft(session, "reply", root.ID)
+		}
+		attachPublishReceipts(pubkeyHex, root)
+		for i := range replies {
+			attachPublishReceipts(pubkeyHex, &replies[i])
+		}
 	}
 
 	// Use cached template for better performance
@@ -6010,9 +6556,13 @@ var htmlProfileTemplate = `<!DOCTYPE html>
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>{{.Title}} - Nostr Hypermedia</title>
+  <title>{{.Title}} - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
+  <link rel="canonical" href="{{.CanonicalURL}}">
+  {{template "preview-meta" .Preview}}
   <link rel="icon" href="/static/favicon.ico" />
   <style>
+{{template "announcement-styles"}}
     :root {
       --bg-page: #f5f5f5;
       --bg-container: #ffffff;
@@ -7494,6 +8044,24 @@ var htmlProfileTemplate = `<!DOCTYPE html>
       flex-wrap: wrap;
       margin-left: auto;
     }
+    .note-location {
+      margin: 8px 0 0;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .note-protected {
+      margin: 8px 0 0;
+      font-size: 13px;
+      color: var(--text-secondary);
+    }
+    .publish-receipt {
+      margin-top: 8px;
+      font-size: 12px;
+      color: var(--text-muted);
+    }
+    .publish-receipt summary { cursor: pointer; }
+    .publish-receipt ul { margin: 6px 0 6px 20px; }
+    .receipt-relay { font-family: monospace; }
     .note-author {
       display: flex;
       align-items: flex-start;
@@ -7557,11 +8125,15 @@ var htmlProfileTemplate = `<!DOCTYPE html>
 		input:checked + span + span {
 			transform: translateX(20px);
 		}
+{{template "density-styles"}}
+{{template "branding-styles"}}
   </style>
 </head>
-<body>
+<body{{if .BodyClass}} class="{{.BodyClass}}"{{end}}>
+  {{template "announcement" .Announcement}}
   <div id="top" class="container">
     <nav>
+      {{template "branding-logo"}}
       {{if .LoggedIn}}
       <a href="/html/timeline?kinds=1&limit=20&feed=follows" class="nav-tab">Follows</a>
       {{end}}
@@ -7571,10 +8143,11 @@ var htmlProfileTemplate = `<!DOCTYPE html>
       {{end}}
       <div class="ml-auto flex-center gap-md">
         {{if .LoggedIn}}
-        <a href="/html/notifications" class="notification-bell" title="Notifications">🔔{{if .HasUnreadNotifications}}<span class="notification-badge"></span>{{end}}</a>
+        <a href="/html/messages" class="notification-bell" title="Messages">{{icon "mail" "✉️"}}</a>
+        <a href="/html/notifications" class="notification-bell" title="Notifications">{{icon "bell" "🔔"}}{{if .HasUnreadNotifications}}<span class="notification-badge"></span>{{end}}</a>
         {{end}}
         <details class="settings-dropdown">
-          <summary class="settings-toggle" title="Settings">⚙️</summary>
+          <summary class="settings-toggle" title="Settings">{{icon "settings" "⚙️"}}</summary>
           <div class="settings-menu">
             <div class="settings-item">
               <form method="POST" action="/html/theme" class="inline-form">
@@ -7622,6 +8195,7 @@ var htmlProfileTemplate = `<!DOCTYPE html>
               <button type="submit" class="follow-btn follow">Follow</button>
               {{end}}
             </form>
+            <a href="/html/messages/{{.Pubkey}}" class="edit-profile-btn">Message</a>
             {{end}}
             {{if and .LoggedIn .IsSelf}}
             <a href="/html/profile/edit" class="edit-profile-btn">Edit Profile</a>
@@ -7644,7 +8218,7 @@ var htmlProfileTemplate = `<!DOCTYPE html>
         <div class="edit-form-error">{{.Error}}</div>
         {{end}}
         {{if .Success}}
-        <div class="flash-message">{{.Success}}</div>
+        <div class="flash-message" role="status">{{.Success}}</div>
         {{end}}
         <form method="POST" action="/html/profile/edit">
           <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
@@ -7689,6 +8263,8 @@ var htmlProfileTemplate = `<!DOCTYPE html>
             <a href="/html/profile/{{.Npub}}" class="edit-form-btn edit-form-btn-secondary">Cancel</a>
           </div>
         </form>
+        <p class="edit-form-hint">Notes per page and compact layout: <a href="/html/settings/appearance" class="text-link">Appearance settings</a></p>
+        <p class="edit-form-hint">Leaving, or key compromised? <a href="/html/settings/danger" class="text-link">Deactivate your account</a></p>
       </div>
       {{else}}
       <div class="notes-section">
@@ -7708,31 +8284,37 @@ var htmlProfileTemplate = `<!DOCTYPE html>
               <span class="author-npub">{{$.NpubShort}}</span>
               {{end}}
               </a>
-              <span class="author-time">{{formatTime .CreatedAt}}</span>
+              <span class="author-time">{{formatTime .CreatedAt}}</span> {{if devMode}}<a href="/event/{{.ID}}/inspect" class="source-link" title="View event source">source</a>{{end}}
             </div>
           </div>
-          <div class="note-content">{{.ContentHTML}}</div>
+          <div class="note-content">{{.ContentHTML.HTML}}</div>
+          {{with .Location}}
+          <div class="note-location">{{icon "location" "📍"}} {{with .Name}}<a href="/html/timeline?kinds=1&limit=20&feed=global&location={{.}}" class="text-link">{{.}}</a>{{end}}{{if .MapURL}} <a href="{{.MapURL}}" class="text-link" target="_blank" rel="noopener">{{.Coords}}</a> · <a href="{{.NearbyURL}}" class="text-link">Nearby</a>{{end}}</div>
+          {{end}}
+          {{if .Protected}}<div class="note-protected" title="NIP-70 protected event">{{icon "protected" "🔒"}} Posted to a specific relay</div>{{end}}
           <div class="note-footer">
             <div class="note-footer-actions">
             {{if $.LoggedIn}}
               {{if ne .Kind 30023}}
-              <a href="/html/thread/{{.ID}}" class="text-link">Reply{{if gt .ReplyCount 0}} {{.ReplyCount}}{{end}}</a>
+              <a href="/html/thread/{{.ID}}" class="text-link" aria-label="{{actionLabel "Reply" .}}">Reply{{if gt .ReplyCount 0}} {{.ReplyCount}}{{end}}</a>
               {{end}}
+              {{if not .Protected}}
               <form method="POST" action="/html/repost" class="inline-form">
                 <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
                 <input type="hidden" name="event_id" value="{{.ID}}">
                 <input type="hidden" name="event_pubkey" value="{{.Pubkey}}">
                 <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
-                <button type="submit" class="text-link">Repost</button>
+                <button type="submit" class="text-link" aria-label="{{actionLabel "Repost" .}}">Repost</button>
               </form>
-              <a href="/html/quote/{{.ID}}" class="text-link">Quote</a>
+              {{end}}
+              <a href="/html/quote/{{.ID}}" class="text-link" aria-label="{{actionLabel "Quote" .}}">Quote</a>
               <form method="POST" action="/html/react" class="inline-form">
                 <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
                 <input type="hidden" name="event_id" value="{{.ID}}">
                 <input type="hidden" name="event_pubkey" value="{{.Pubkey}}">
                 <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
                 <input type="hidden" name="reaction" value="❤️">
-                <button type="submit" class="text-link">Like</button>
+                <button type="submit" class="text-link" aria-label="{{actionLabel "Like" .}}">Like</button>
               </form>
               <form method="POST" action="/html/bookmark" class="inline-form">
                 <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
@@ -7740,10 +8322,10 @@ var htmlProfileTemplate = `<!DOCTYPE html>
                 <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
                 {{if .IsBookmarked}}
                 <input type="hidden" name="action" value="remove">
-                <button type="submit" class="text-link" title="Remove bookmark">Unbookmark</button>
+                <button type="submit" class="text-link" title="Remove bookmark" aria-label="{{actionLabel "Bookmark" .}}" aria-pressed="true">Unbookmark</button>
                 {{else}}
                 <input type="hidden" name="action" value="add">
-                <button type="submit" class="text-link" title="Add bookmark">Bookmark</button>
+                <button type="submit" class="text-link" title="Add bookmark" aria-label="{{actionLabel "Bookmark" .}}" aria-pressed="false">Bookmark</button>
                 {{end}}
               </form>
             {{else}}
@@ -7755,11 +8337,29 @@ var htmlProfileTemplate = `<!DOCTYPE html>
             {{end}}
             </div>
           </div>
+          {{with .Receipt}}
+          <details class="publish-receipt">
+            <summary>Accepted by {{.AcceptedCount}}/{{len .Results}} relays{{with .PendingCount}}, {{.}} pending{{end}}</summary>
+            <ul>
+              {{range .Results}}
+              <li><span class="receipt-relay">{{.Relay}}</span> {{if .Pending}}pending{{else if .Accepted}}accepted{{else}}failed{{with .Message}}: {{.}}{{end}}{{end}}</li>
+              {{end}}
+            </ul>
+            {{if .MissingRelays}}
+            <form method="POST" action="/html/republish" class="inline-form">
+              <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+              <input type="hidden" name="event_id" value="{{.Event.ID}}">
+              <input type="hidden" name="return_url" value="{{$.CurrentURL}}">
+              <button type="submit" class="text-link">Re-publish to missing relays</button>
+            </form>
+            {{end}}
+          </details>
+          {{end}}
         </article>
         {{end}}
         {{if not .Items}}
         <div class="empty-state">
-          <div class="empty-state-icon">📝</div>
+          <div class="empty-state-icon">{{icon "note" "📝"}}</div>
           <p>No notes yet</p>
           <p class="empty-state-hint">This user hasn't posted any notes.</p>
         </div>
@@ -7777,7 +8377,7 @@ var htmlProfileTemplate = `<!DOCTYPE html>
     </main>
 
     <footer>
-      <p>{{if .Meta}}Generated: {{.Meta.GeneratedAt.Format "15:04:05"}} · {{end}}Zero-JS Hypermedia Browser</p>
+      <p>{{if .Meta}}Generated: {{.Meta.GeneratedAt.Format "15:04:05"}} · {{end}}{{template "branding-footer"}}</p>
     </footer>
   </div>
   <a href="#top" class="scroll-top" aria-label="Scroll to top">↑</a>
@@ -7796,8 +8396,11 @@ type HTMLProfileData struct {
 	Meta                   *MetaInfo
 	ThemeClass             string // "dark", "light", or "" for system default
 	ThemeLabel             string // Label for theme toggle button
+	BodyClass              string // "density-compact" or ""
 	LoggedIn               bool
 	CurrentURL             string
+	CanonicalURL           string // Canonical path for <link rel="canonical">
+	Preview                *PreviewMeta // og: and twitter: tags
 	CSRFToken              string // CSRF token for form submission
 	IsFollowing            bool   // Whether logged-in user follows this profile
 	IsSelf                 bool   // Whether this is the logged-in user's own profile
@@ -7807,9 +8410,10 @@ type HTMLProfileData struct {
 	RawContent string // JSON of raw profile content (for preserving unknown fields)
 	Error      string // Error message for edit form
 	Success    string // Success message for edit form
+	Announcement *Announcement // Instance announcement banner, nil if none
 }
 
-func renderProfileHTML(resp ProfileResponse, relays []string, limit int, themeClass, themeLabel string, loggedIn bool, currentURL, csrfToken string, isFollowing, isSelf, hasUnreadNotifs bool) (string, error) {
+func renderProfileHTML(resp ProfileResponse, relays []string, limit int, themeClass, themeLabel, bodyClass string, loggedIn bool, currentURL, csrfToken string, isFollowing, isSelf, hasUnreadNotifs bool, announcement *Announcement, preview *PreviewMeta) (string, error) {
 	// Pre-fetch all nostr: references in parallel for much faster rendering
 	contents := make([]string, len(resp.Notes.Items))
 	for i, item := range resp.Notes.Items {
@@ -7840,6 +8444,8 @@ func renderProfileHTML(resp ProfileResponse, relays []string, limit int, themeCl
 			ContentHTML:   processContentToHTMLFull(item.Content, relays, resolvedRefs, linkPreviews),
 			RelaysSeen:    item.RelaysSeen,
 			AuthorProfile: item.AuthorProfile,
+			Location:      eventLocation(item.Tags),
+			Protected:     isProtectedEvent(item.Tags),
 		}
 	}
 
@@ -7872,12 +8478,21 @@ func renderProfileHTML(resp ProfileResponse, relays []string, limit int, themeCl
 		Meta:                   &resp.Notes.Meta,
 		ThemeClass:             themeClass,
 		ThemeLabel:             themeLabel,
+		BodyClass:              bodyClass,
 		LoggedIn:               loggedIn,
 		CurrentURL:             currentURL,
+		CanonicalURL:           canonicalProfilePath(resp.Pubkey),
+		Preview:                preview,
 		CSRFToken:              csrfToken,
 		IsFollowing:            isFollowing,
 		IsSelf:                 isSelf,
 		HasUnreadNotifications: hasUnreadNotifs,
+		Announcement:           announcement,
+	}
+	if isSelf {
+		for i := range items {
+			attachPublishReceipts(resp.Pubkey, &items[i])
+		}
 	}
 
 	// Use cached template for better performance
@@ -7896,11 +8511,11 @@ type HTMLNotificationItem struct {
 	TypeLabel         string // Human-readable label: "replied", "mentioned", "reacted", "reposted"
 	TypeIcon          string // Emoji icon for the notification type
 	TargetEventID     string
-	TargetContentHTML template.HTML // Content of the target event (for reactions/reposts to show what was reacted to)
+	TargetPreview     string // Content of the target event (for reactions/reposts to show what was reacted to)
 	AuthorProfile     *ProfileInfo
 	AuthorNpub        string
 	AuthorNpubShort   string
-	ContentHTML       template.HTML
+	ContentPreview    string
 	TimeAgo           string
 }
 
@@ -7909,11 +8524,13 @@ type HTMLNotificationsData struct {
 	Title           string
 	ThemeClass      string
 	ThemeLabel      string
+	BodyClass       string // "density-compact" or ""
 	UserDisplayName string
 	UserPubKey      string
 	Items           []HTMLNotificationItem
 	GeneratedAt     time.Time
 	Pagination      *HTMLPagination
+	Announcement    *Announcement
 }
 
 var htmlNotificationsTemplate = `<!DOCTYPE html>
@@ -7921,9 +8538,11 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>{{.Title}} - Nostr Hypermedia</title>
+  <title>{{.Title}} - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
   <link rel="icon" href="/static/favicon.ico" />
   <style>
+{{template "announcement-styles"}}
     :root {
       --bg-page: #f5f5f5;
       --bg-container: #ffffff;
@@ -8265,83 +8884,7 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
     .post-form:focus-within button[type="submit"] {
       display: block;
     }
-    /* Notification items */
-    .notification-list {
-      display: flex;
-      flex-direction: column;
-      gap: 1px;
-    }
-    .notification-item {
-      background: var(--bg-card);
-      padding: 16px;
-      border: 1px solid var(--border-color);
-      border-radius: 6px;
-      margin-bottom: 12px;
-      transition: box-shadow 0.2s;
-    }
-    .notification-item:hover {
-      box-shadow: 0 2px 8px var(--shadow);
-    }
-    .notification-header {
-      display: flex;
-      align-items: flex-start;
-      gap: 12px;
-      margin-bottom: 8px;
-    }
-    .notification-icon {
-      font-size: 1.5rem;
-      flex-shrink: 0;
-    }
-    .notification-meta { flex: 1; }
-    .notification-author {
-      font-weight: 600;
-      color: var(--text-primary);
-      text-decoration: none;
-    }
-    .notification-author:hover { text-decoration: underline; }
-    .notification-action { color: var(--text-secondary); }
-    .notification-time {
-      color: var(--text-muted);
-      font-size: 0.85rem;
-      margin-left: 8px;
-    }
-    .notification-content {
-      margin-left: 44px;
-      padding: 12px;
-      background: var(--bg-secondary);
-      border-radius: 6px;
-      color: var(--text-secondary);
-      font-size: 0.95rem;
-      overflow: hidden;
-      text-overflow: ellipsis;
-    }
-    .notification-content a {
-      color: var(--accent);
-      text-decoration: none;
-    }
-    .notification-content a:hover { text-decoration: underline; }
-    .notification-target-content {
-      margin-left: 44px;
-      margin-top: 8px;
-      padding: 10px 12px;
-      background: var(--bg-card);
-      border-left: 3px solid var(--accent);
-      border-radius: 4px;
-      color: var(--text-muted);
-      font-size: 0.9rem;
-      font-style: italic;
-      word-break: break-word;
-      overflow-wrap: break-word;
-    }
-    .notification-link {
-      display: block;
-      margin-left: 44px;
-      margin-top: 8px;
-      color: var(--accent);
-      text-decoration: none;
-      font-size: 0.9rem;
-    }
-    .notification-link:hover { text-decoration: underline; }
+{{template "notification-styles"}}
     .empty-state {
       text-align: center;
       padding: 60px 20px;
@@ -8355,19 +8898,24 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
       margin-top: 8px;
       font-size: 0.9rem;
     }
+{{template "density-styles"}}
+{{template "branding-styles"}}
   </style>
 </head>
-<body>
+<body{{if .BodyClass}} class="{{.BodyClass}}"{{end}}>
+  {{template "announcement" .Announcement}}
   <div id="top" class="container">
     <div class="sticky-section">
       <nav>
+        {{template "branding-logo"}}
         <a href="/html/timeline?kinds=1&limit=20&feed=follows" class="nav-tab">Follows</a>
         <a href="/html/timeline?kinds=1&limit=20&feed=global" class="nav-tab">Global</a>
         <a href="/html/timeline?kinds=1&limit=20&feed=me" class="nav-tab active">Me</a>
         <div class="ml-auto flex-center gap-md">
-          <a href="/html/notifications" class="notification-bell" title="Notifications">🔔</a>
+          <a href="/html/messages" class="notification-bell" title="Messages">{{icon "mail" "✉️"}}</a>
+          <a href="/html/notifications" class="notification-bell" title="Notifications">{{icon "bell" "🔔"}}</a>
           <details class="settings-dropdown">
-            <summary class="settings-toggle" title="Settings">⚙️</summary>
+            <summary class="settings-toggle" title="Settings">{{icon "settings" "⚙️"}}</summary>
             <div class="settings-menu">
               <div class="settings-item">
                 <form method="POST" action="/html/theme" class="inline-form">
@@ -8399,7 +8947,7 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
     </form>
 
     <main>
-      {{if .Items}}
+      {{block "notification-items" .}}{{if .Items}}
       <div class="notification-list">
         {{range .Items}}
         <div class="notification-item">
@@ -8417,11 +8965,11 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
               <span class="notification-time">{{.TimeAgo}}</span>
             </div>
           </div>
-          {{if .ContentHTML}}
-          <div class="notification-content">{{.ContentHTML}}</div>
+          {{if .ContentPreview}}
+          <div class="notification-content">{{.ContentPreview}}</div>
           {{end}}
-          {{if .TargetContentHTML}}
-          <div class="notification-target-content">{{.TargetContentHTML}}</div>
+          {{if .TargetPreview}}
+          <div class="notification-target-content">{{.TargetPreview}}</div>
           {{end}}
           {{if .TargetEventID}}
           <a href="/html/thread/{{.TargetEventID}}" class="notification-link">View thread →</a>
@@ -8433,11 +8981,11 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
       </div>
       {{else}}
       <div class="empty-state">
-        <div class="empty-state-icon">🔔</div>
+        <div class="empty-state-icon">{{icon "bell" "🔔"}}</div>
         <p>No notifications yet</p>
         <p class="empty-state-hint">When people mention you, reply to you, or react to your notes, you'll see it here.</p>
       </div>
-      {{end}}
+      {{end}}{{end}}
       {{if .Pagination}}
       <div class="pagination">
         {{if .Pagination.Next}}
@@ -8448,24 +8996,105 @@ var htmlNotificationsTemplate = `<!DOCTYPE html>
     </main>
 
     <footer>
-      <p>Generated: {{.GeneratedAt.Format "15:04:05"}} · Zero-JS Hypermedia Browser</p>
+      <p>Generated: {{.GeneratedAt.Format "15:04:05"}} · {{template "branding-footer"}}</p>
     </footer>
   </div>
   <a href="#top" class="scroll-top" title="Back to top">↑</a>
 </body>
 </html>`
 
+// notificationStylesTemplate is shared by the notifications page and the deck's notifications column
+var notificationStylesTemplate = `{{define "notification-styles"}}
+    /* Notification items */
+    .notification-list {
+      display: flex;
+      flex-direction: column;
+      gap: 1px;
+    }
+    .notification-item {
+      background: var(--bg-card);
+      padding: 16px;
+      border: 1px solid var(--border-color);
+      border-radius: 6px;
+      margin-bottom: 12px;
+      transition: box-shadow 0.2s;
+    }
+    .notification-item:hover {
+      box-shadow: 0 2px 8px var(--shadow);
+    }
+    .notification-header {
+      display: flex;
+      align-items: flex-start;
+      gap: 12px;
+      margin-bottom: 8px;
+    }
+    .notification-icon {
+      font-size: 1.5rem;
+      flex-shrink: 0;
+    }
+    .notification-meta { flex: 1; }
+    .notification-author {
+      font-weight: 600;
+      color: var(--text-primary);
+      text-decoration: none;
+    }
+    .notification-author:hover { text-decoration: underline; }
+    .notification-action { color: var(--text-secondary); }
+    .notification-time {
+      color: var(--text-muted);
+      font-size: 0.85rem;
+      margin-left: 8px;
+    }
+    .notification-content {
+      margin-left: 44px;
+      padding: 12px;
+      background: var(--bg-secondary);
+      border-radius: 6px;
+      color: var(--text-secondary);
+      font-size: 0.95rem;
+      overflow: hidden;
+      text-overflow: ellipsis;
+    }
+    .notification-content a {
+      color: var(--accent);
+      text-decoration: none;
+    }
+    .notification-content a:hover { text-decoration: underline; }
+    .notification-target-content {
+      margin-left: 44px;
+      margin-top: 8px;
+      padding: 10px 12px;
+      background: var(--bg-card);
+      border-left: 3px solid var(--accent);
+      border-radius: 4px;
+      color: var(--text-muted);
+      font-size: 0.9rem;
+      font-style: italic;
+      word-break: break-word;
+      overflow-wrap: break-word;
+    }
+    .notification-link {
+      display: block;
+      margin-left: 44px;
+      margin-top: 8px;
+      color: var(--accent);
+      text-decoration: none;
+      font-size: 0.9rem;
+    }
+    .notification-link:hover { text-decoration: underline; }
+{{end}}`
+
 var cachedNotificationsTemplate *template.Template
 
 func initNotificationsTemplate() {
 	var err error
-	cachedNotificationsTemplate, err = template.New("notifications").Parse(htmlNotificationsTemplate)
+	cachedNotificationsTemplate, err = template.New("notifications").Funcs(template.FuncMap{"branding": branding, "icon": icon}).Parse(htmlNotificationsTemplate + notificationStylesTemplate + densityStylesTemplate + announcementTemplate + brandingTemplate)
 	if err != nil {
 		log.Fatalf("Failed to compile notifications template: %v", err)
 	}
 }
 
-func renderNotificationsHTML(notifications []Notification, profiles map[string]*ProfileInfo, targetEvents map[string]*Event, themeClass, themeLabel, userDisplayName, userPubKey string, pagination *HTMLPagination) (string, error) {
+func renderNotificationsHTML(notifications []Notification, profiles map[string]*ProfileInfo, targetEvents map[string]*Event, themeClass, themeLabel, bodyClass, userDisplayName, userPubKey string, pagination *HTMLPagination, announcement *Announcement, fragment bool) (string, error) {
 	// Initialize template if not done
 	if cachedNotificationsTemplate == nil {
 		initNotificationsTemplate()
@@ -8502,24 +9131,22 @@ func renderNotificationsHTML(notifications []Notification, profiles map[string]*
 		}
 
 		// Truncate content for preview (skip for reactions since the emoji is shown as the icon)
-		var contentHTML template.HTML
+		var contentPreview string
 		if notif.Type != NotificationReaction {
-			content := notif.Event.Content
-			if len(content) > 200 {
-				content = content[:200] + "..."
+			contentPreview = notif.Event.Content
+			if len(contentPreview) > 200 {
+				contentPreview = contentPreview[:200] + "..."
 			}
-			contentHTML = template.HTML(html.EscapeString(content))
 		}
 
 		// For reactions/reposts, show a preview of the target note content
-		var targetContentHTML template.HTML
+		var targetPreview string
 		if (notif.Type == NotificationReaction || notif.Type == NotificationRepost) && notif.TargetEventID != "" {
 			if targetEvent, ok := targetEvents[notif.TargetEventID]; ok {
-				targetContent := targetEvent.Content
-				if len(targetContent) > 150 {
-					targetContent = targetContent[:150] + "..."
+				targetPreview = targetEvent.Content
+				if len(targetPreview) > 150 {
+					targetPreview = targetPreview[:150] + "..."
 				}
-				targetContentHTML = template.HTML(html.EscapeString(targetContent))
 			}
 		}
 
@@ -8529,11 +9156,11 @@ func renderNotificationsHTML(notifications []Notification, profiles map[string]*
 			TypeLabel:         typeLabel,
 			TypeIcon:          typeIcon,
 			TargetEventID:     notif.TargetEventID,
-			TargetContentHTML: targetContentHTML,
+			TargetPreview:     targetPreview,
 			AuthorProfile:     profile,
 			AuthorNpub:        npub,
 			AuthorNpubShort:   formatNpubShort(npub),
-			ContentHTML:       contentHTML,
+			ContentPreview:    contentPreview,
 			TimeAgo:           formatTimeAgo(notif.Event.CreatedAt),
 		}
 	}
@@ -8542,14 +9169,22 @@ func renderNotificationsHTML(notifications []Notification, profiles map[string]*
 		Title:           "Notifications",
 		ThemeClass:      themeClass,
 		ThemeLabel:      themeLabel,
+		BodyClass:       bodyClass,
 		UserDisplayName: userDisplayName,
 		UserPubKey:      userPubKey,
 		Items:           items,
 		GeneratedAt:     time.Now(),
 		Pagination:      pagination,
+		Announcement:    announcement,
 	}
 
 	var buf strings.Builder
+	if fragment {
+		if err := cachedNotificationsTemplate.ExecuteTemplate(&buf, "notification-items", data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
 	if err := cachedNotificationsTemplate.Execute(&buf, data); err != nil {
 		return "", err
 	}
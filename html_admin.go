@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/hex"
+	"log"
+	"net/http"
+	"os"
+)
+
+// Admin status page
+// ADMIN_PUBKEYS (comma-separated npubs or hex pubkeys) names the operators who may see
+// /html/admin/status: operational details that don't belong on the public stats page,
+// such as webhook delivery results. Other users get a 404.
+
+// adminPubkeys holds the operators' hex pubkeys, parsed once at startup
+var adminPubkeys = make(map[string]bool)
+
+// initAdmins reads ADMIN_PUBKEYS
+func initAdmins() {
+	for _, pk := range parsePubkeyList("ADMIN_PUBKEYS", os.Getenv("ADMIN_PUBKEYS")) {
+		adminPubkeys[pk] = true
+	}
+	if len(adminPubkeys) > 0 {
+		log.Printf("Admin status page enabled for %d pubkeys", len(adminPubkeys))
+	}
+}
+
+// isAdminSession reports whether the session belongs to one of the operators
+func isAdminSession(session *BunkerSession) bool {
+	if session == nil || !session.Connected {
+		return false
+	}
+	return adminPubkeys[hex.EncodeToString(session.UserPubKey)]
+}
+
+// HTMLAdminWebhook is one row of the recent webhook deliveries table
+type HTMLAdminWebhook struct {
+	Endpoint   string
+	Event      string
+	Delivered  bool
+	StatusCode int
+	Attempts   int
+	Error      string
+	When       string
+}
+
+// HTMLAdminStatusData is the data for the admin status page
+type HTMLAdminStatusData struct {
+	HTMLPageChrome
+	HasWebhooks bool // webhooks are configured
+	Webhooks    []HTMLAdminWebhook
+}
+
+func init() {
+	registerPageTemplate("admin-status", htmlAdminStatusContent)
+}
+
+// htmlAdminStatusHandler serves the operators' status page
+func htmlAdminStatusHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login", http.StatusSeeOther)
+		return
+	}
+	if !isAdminSession(session) {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := HTMLAdminStatusData{HTMLPageChrome: newPageChrome(r, "Instance status")}
+	if d := webhooks; d != nil {
+		data.HasWebhooks = true
+		for _, delivery := range d.Recent() {
+			data.Webhooks = append(data.Webhooks, HTMLAdminWebhook{
+				Endpoint:   delivery.Endpoint,
+				Event:      delivery.Event,
+				Delivered:  delivery.Delivered,
+				StatusCode: delivery.StatusCode,
+				Attempts:   delivery.Attempts,
+				Error:      delivery.Error,
+				When:       formatRelativeTime(delivery.At.Unix()),
+			})
+		}
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	renderPage(w, "admin-status", data)
+}
+
+var htmlAdminStatusContent = `{{define "content"}}
+<h1>Instance status</h1>
+<p class="text-muted text-sm">Only visible to the operators listed in ADMIN_PUBKEYS. Public numbers are on <a href="/about/stats" class="text-link">the stats page</a>.</p>
+
+<h2>Webhook deliveries</h2>
+{{if not .HasWebhooks}}
+<p class="text-muted text-sm">No webhooks configured (WEBHOOK_CONFIG).</p>
+{{else if .Webhooks}}
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Endpoint</th><th scope="col">Event</th><th scope="col">Result</th><th scope="col">Attempts</th><th scope="col">When</th></tr>
+  </thead>
+  <tbody>
+    {{range .Webhooks}}
+    <tr>
+      <td>{{.Endpoint}}</td>
+      <td class="mono text-sm">{{.Event}}</td>
+      <td>{{if .Delivered}}<span class="badge accent">delivered</span>{{else}}<span class="badge" title="{{.Error}}">failed{{if .StatusCode}} ({{.StatusCode}}){{end}}</span>{{end}}</td>
+      <td>{{.Attempts}}</td>
+      <td class="text-sm">{{.When}}</td>
+    </tr>
+    {{end}}
+  </tbody>
+</table>
+{{else}}
+<p class="text-muted text-sm">No webhook deliveries yet.</p>
+{{end}}
+{{end}}`
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestAdminStatusShowsWebhookDeliveries(t *testing.T) {
+	s := startTestServer(t)
+	admin := testutil.NewKeypair("admin-status-admin")
+	user := testutil.NewKeypair("admin-status-user")
+
+	adminPubkeys[admin.PubKey] = true
+	previous := webhooks
+	webhooks = &WebhookDispatcher{}
+	webhooks.record(WebhookDelivery{Endpoint: "ops-chat-hook", Event: WebhookReportReceived, StatusCode: 500, Attempts: 4, Error: "server error"})
+	t.Cleanup(func() {
+		delete(adminPubkeys, admin.PubKey)
+		webhooks = previous
+	})
+
+	adminClient, _ := s.login(t, admin)
+	page := s.get(t, adminClient, "/html/admin/status")
+	assertContains(t, page, "ops-chat-hook", WebhookReportReceived, "failed (500)")
+
+	userClient, _ := s.login(t, user)
+	resp, err := userClient.Get(s.URL + "/html/admin/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("non-admin: status %d, want 404", resp.StatusCode)
+	}
+
+	anonymous := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = anonymous.Get(s.URL + "/html/admin/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther || !strings.HasPrefix(resp.Header.Get("Location"), "/html/login") {
+		t.Errorf("anonymous: %d to %q, want a redirect to the login page", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// Deliveries stay off the public stats page, even for the admin
+	for _, client := range []*http.Client{nil, adminClient} {
+		if strings.Contains(s.get(t, client, "/about/stats"), "ops-chat-hook") {
+			t.Error("stats page shows webhook deliveries")
+		}
+	}
+}
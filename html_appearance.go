@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Appearance settings
+// Logged-in users can set how many notes a feed page shows and a compact layout at
+// /html/settings/appearance. Both are kept in the session. When a page size is set, the
+// timeline, profile and notifications handlers use it in place of ?limit= and leave
+// limit out of the pagination links they build: paging is by until/offset cursors, so
+// those keep working after a change, and a shared "Older" link doesn't carry the
+// sharer's page size.
+
+// Bounds for the feed page size preference
+const (
+	minFeedPageSize = 10
+	maxFeedPageSize = 100
+)
+
+// Density values; "comfortable" is the default and adds no class
+const (
+	densityComfortable = "comfortable"
+	densityCompact     = "compact"
+)
+
+// HTMLAppearanceData is the data for the appearance settings page
+type HTMLAppearanceData struct {
+	HTMLPageChrome
+	PageSize int // 0 when unset
+	Density  string
+	MinSize  int
+	MaxSize  int
+}
+
+func init() {
+	registerPageTemplate("appearance", htmlAppearanceContent)
+}
+
+// feedPageSizeOf returns the session's page size preference, 0 if unset
+func feedPageSizeOf(session *BunkerSession) int {
+	if session == nil || !session.Connected {
+		return 0
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.FeedPageSize
+}
+
+// feedLimit returns the page size for a feed request: the viewer's preference, else
+// ?limit=, else def. linkLimit is the limit to put in pagination links, 0 to leave it out
+func feedLimit(session *BunkerSession, q url.Values, def int) (limit, linkLimit int) {
+	if size := feedPageSizeOf(session); size > 0 {
+		return size, 0
+	}
+	limit = parseLimit(q.Get("limit"), def)
+	return limit, limit
+}
+
+// limitParam formats a limit for a query string, "" when linkLimit is 0
+func limitParam(linkLimit int) string {
+	if linkLimit <= 0 {
+		return ""
+	}
+	return "&limit=" + strconv.Itoa(linkLimit)
+}
+
+// densityClass returns the <body> class for the session's density preference
+func densityClass(session *BunkerSession) string {
+	if session == nil || !session.Connected {
+		return ""
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.Density == densityCompact {
+		return "density-compact"
+	}
+	return ""
+}
+
+// htmlAppearanceHandler shows (GET) and saves (POST) the appearance settings
+func htmlAppearanceHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		session.mu.Lock()
+		data := HTMLAppearanceData{
+			HTMLPageChrome: newPageChrome(r, "Appearance"),
+			PageSize:       session.FeedPageSize,
+			Density:        session.Density,
+			MinSize:        minFeedPageSize,
+			MaxSize:        maxFeedPageSize,
+		}
+		session.mu.Unlock()
+		if data.Density == "" {
+			data.Density = densityComfortable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		renderPage(w, "appearance", data)
+		return
+	}
+
+	if !validateCSRFToken(session.ID, r.FormValue("csrf_token")) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+
+	// Empty page size means "use each feed's default"
+	pageSize := 0
+	if value := r.FormValue("page_size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minFeedPageSize || n > maxFeedPageSize {
+			http.Redirect(w, r, "/html/settings/appearance?error="+escapeURLParam("Page size must be between "+strconv.Itoa(minFeedPageSize)+" and "+strconv.Itoa(maxFeedPageSize)), http.StatusSeeOther)
+			return
+		}
+		pageSize = n
+	}
+	density := r.FormValue("density")
+	if density != densityCompact {
+		density = densityComfortable
+	}
+
+	session.mu.Lock()
+	session.FeedPageSize = pageSize
+	session.Density = density
+	session.mu.Unlock()
+
+	http.Redirect(w, r, "/html/settings/appearance?success=Appearance+saved", http.StatusSeeOther)
+}
+
+var htmlAppearanceContent = `{{define "content"}}
+<h1>Appearance</h1>
+<p class="text-muted text-sm">Saved for this login session. The light/dark theme is set separately from the menu.</p>
+<form method="POST" action="/html/settings/appearance" class="card appearance-form">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+  <label for="page-size">Notes per page</label>
+  <input type="number" id="page-size" name="page_size" min="{{.MinSize}}" max="{{.MaxSize}}" value="{{if .PageSize}}{{.PageSize}}{{end}}" placeholder="Default">
+  <p class="text-muted text-sm">{{.MinSize}} to {{.MaxSize}}, used by the timeline, profiles and notifications. Leave empty for each feed's default.</p>
+  <fieldset>
+    <legend>Density</legend>
+    <label class="confirm-label"><input type="radio" name="density" value="comfortable"{{if eq .Density "comfortable"}} checked{{end}}> Comfortable - roomy cards</label>
+    <label class="confirm-label"><input type="radio" name="density" value="compact"{{if eq .Density "compact"}} checked{{end}}> Compact - tighter spacing, more notes on screen</label>
+  </fieldset>
+  <button type="submit" class="btn">Save</button>
+</form>
+{{end}}
+{{define "styles"}}
+    .appearance-form fieldset { border: none; padding: 0; margin: 16px 0; }
+    .appearance-form legend { font-weight: 600; margin-bottom: 8px; }
+    .confirm-label { display: flex; gap: 8px; align-items: flex-start; color: var(--text-primary); margin-bottom: 10px; }
+{{end}}`
+
+// densityStylesTemplate is parsed into the timeline, thread, profile and notifications
+// templates; use {{template "density-styles"}} at the end of <style>, before branding
+var densityStylesTemplate = `{{define "density-styles"}}
+    body.density-compact .note { padding: 10px 12px; margin: 6px 0; }
+    body.density-compact .note-content { font-size: 14px; line-height: 1.45; margin: 6px 0; }
+    body.density-compact .note-footer { margin-top: 6px; padding-top: 6px; gap: 12px; }
+    body.density-compact .author-avatar { width: 32px; height: 32px; }
+    body.density-compact .notification-item { padding: 10px 12px; margin-bottom: 6px; }
+{{end}}`
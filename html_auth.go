@@ -13,6 +13,8 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/skip2/go-qrcode"
@@ -27,6 +29,12 @@ func sanitizeErrorForUser(context string, err error) string {
 	// Log the full error for debugging
 	log.Printf("%s: %v", context, err)
 
+	// Limit violations are about the user's own input, so say which limit
+	var limitErr *EventLimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.Reason
+	}
+
 	// Return generic messages based on context
 	errStr := err.Error()
 	switch {
@@ -60,13 +68,15 @@ func initAuthTemplates() {
 		"formatTime": func(ts int64) string {
 			return formatRelativeTime(ts)
 		},
-	}).Parse(htmlQuoteTemplate)
+		"branding": branding,
+		"icon":     icon,
+	}).Parse(htmlQuoteTemplate + announcementTemplate + brandingTemplate)
 	if err != nil {
 		log.Fatalf("Failed to compile quote template: %v", err)
 	}
 
 	// Compile login template
-	cachedLoginTemplate, err = template.New("login").Parse(htmlLoginTemplate)
+	cachedLoginTemplate, err = template.New("login").Funcs(template.FuncMap{"branding": branding, "icon": icon}).Parse(htmlLoginTemplate + announcementTemplate + brandingTemplate)
 	if err != nil {
 		log.Fatalf("Failed to compile login template: %v", err)
 	}
@@ -173,6 +183,7 @@ func htmlLoginHandler(w http.ResponseWriter, r *http.Request) {
 		QRCodeDataURL   template.URL
 		ServerPubKey    string
 		ThemeClass      string
+		Announcement    *Announcement
 	}{
 		Title:           "Login with Nostr Connect",
 		NostrConnectURL: nostrConnectURL,
@@ -180,6 +191,7 @@ func htmlLoginHandler(w http.ResponseWriter, r *http.Request) {
 		QRCodeDataURL:   template.URL(qrCodeDataURL),
 		ServerPubKey:    serverPubKey,
 		ThemeClass:      themeClass,
+		Announcement:    announcementFor(r),
 	}
 
 	// Check for error/success messages in query params
@@ -237,6 +249,7 @@ func htmlLoginSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	// Prefetch user profile and contact list in background so they're ready for display
 	prefetchUserProfile(hex.EncodeToString(session.UserPubKey), session.Relays)
 	prefetchUserContactList(session, session.Relays)
+	prefetchUserInterests(session, relaysFor(RelayPurposeMetadata))
 
 	http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&success=Logged+in+successfully", http.StatusSeeOther)
 }
@@ -273,6 +286,7 @@ func htmlCheckConnectionHandler(w http.ResponseWriter, r *http.Request) {
 	// Prefetch user profile and contact list in background so they're ready for display
 	prefetchUserProfile(hex.EncodeToString(session.UserPubKey), session.Relays)
 	prefetchUserContactList(session, session.Relays)
+	prefetchUserInterests(session, relaysFor(RelayPurposeMetadata))
 
 	http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&success=Logged+in+successfully", http.StatusSeeOther)
 }
@@ -332,6 +346,7 @@ func htmlReconnectHandler(w http.ResponseWriter, r *http.Request) {
 	// Prefetch user profile and contact list in background so they're ready for display
 	prefetchUserProfile(hex.EncodeToString(session.UserPubKey), session.Relays)
 	prefetchUserContactList(session, session.Relays)
+	prefetchUserInterests(session, relaysFor(RelayPurposeMetadata))
 
 	http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&success=Reconnected+successfully", http.StatusSeeOther)
 }
@@ -568,6 +583,12 @@ func htmlPostNoteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// "This relay only": a NIP-70 protected note to one relay (nip70.go)
+	if r.FormValue("relay_only") == "1" {
+		htmlPostRelayOnly(w, r, session, content)
+		return
+	}
+
 	// Create unsigned event
 	event := UnsignedEvent{
 		Kind:      1,
@@ -576,27 +597,23 @@ func htmlPostNoteHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now().Unix(),
 	}
 
-	// Sign via bunker
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Sign via bunker and publish to relays; failures are kept for retry
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 	defer cancel()
 
-	signedEvent, err := session.SignEvent(ctx, event)
+	draft := PendingEvent{
+		Composer:  "note",
+		Event:     event,
+		Relays:    relaysFor(RelayPurposeWrite),
+		ReturnURL: "/html/timeline?kinds=1&limit=20",
+	}
+	signedEvent, err := signAndPublish(ctx, session, draft)
 	if err != nil {
 		log.Printf("Failed to sign event: %v", err)
-		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&error="+escapeURLParam(sanitizeErrorForUser("Sign event", err)), http.StatusSeeOther)
+		http.Redirect(w, r, signFailureURL(session, draft, "/html/timeline?kinds=1&limit=20", content, err), http.StatusSeeOther)
 		return
 	}
 
-	// Publish to relays
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://relay.primal.net",
-		"wss://nos.lol",
-	}
-
-	publishEvent(ctx, relays, signedEvent)
-
 	log.Printf("Published note: %s", signedEvent.ID)
 	http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&success=Note+published", http.StatusSeeOther)
 }
@@ -663,27 +680,24 @@ func htmlReplyHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now().Unix(),
 	}
 
-	// Sign via bunker
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Sign via bunker and publish to relays; failures are kept for retry
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 	defer cancel()
 
-	signedEvent, err := session.SignEvent(ctx, event)
+	draft := PendingEvent{
+		Composer:  "reply",
+		Event:     event,
+		Target:    replyTo,
+		Relays:    relaysFor(RelayPurposeWrite),
+		ReturnURL: "/html/thread/" + replyTo,
+	}
+	signedEvent, err := signAndPublish(ctx, session, draft)
 	if err != nil {
 		log.Printf("Failed to sign reply: %v", err)
-		http.Redirect(w, r, "/html/thread/"+replyTo+"?error="+escapeURLParam(sanitizeErrorForUser("Sign event", err)), http.StatusSeeOther)
+		http.Redirect(w, r, signFailureURL(session, draft, "/html/thread/"+replyTo, content, err), http.StatusSeeOther)
 		return
 	}
 
-	// Publish to relays
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://relay.primal.net",
-		"wss://nos.lol",
-	}
-
-	publishEvent(ctx, relays, signedEvent)
-
 	log.Printf("Published reply: %s (to %s)", signedEvent.ID, replyTo)
 	http.Redirect(w, r, "/html/thread/"+replyTo+"?success=Reply+published", http.StatusSeeOther)
 }
@@ -740,7 +754,7 @@ func htmlReactHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sign via bunker
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 	defer cancel()
 
 	signedEvent, err := session.SignEvent(ctx, event)
@@ -755,16 +769,11 @@ func htmlReactHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get relays to publish to
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://nos.lol",
-	}
-	if session.UserRelayList != nil && len(session.UserRelayList.Write) > 0 {
-		relays = session.UserRelayList.Write
-	}
+	relays := session.writeRelays()
 
 	publishEvent(ctx, relays, signedEvent)
+	// Drop cached counts so the new reaction shows on return
+	reactionCache.Delete(eventID)
 
 	log.Printf("Published reaction %s to event %s", reaction, eventID)
 	http.Redirect(w, r, returnURL, http.StatusSeeOther)
@@ -799,6 +808,16 @@ func htmlRepostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// NIP-70: protected events are meant to stay on the relays their author chose
+	if eventIsProtected(relaysFor(RelayPurposeRead), eventID) {
+		separator := "?"
+		if strings.Contains(returnURL, "?") {
+			separator = "&"
+		}
+		http.Redirect(w, r, returnURL+separator+"error="+escapeURLParam("This note was posted to a specific relay and its author asked that it not be shared elsewhere, so it can't be reposted. You can quote it instead."), http.StatusSeeOther)
+		return
+	}
+
 	// Build tags for repost (NIP-18)
 	// e tag for the reposted event, p tag to mention the original author
 	tags := [][]string{
@@ -817,7 +836,7 @@ func htmlRepostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sign via bunker
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 	defer cancel()
 
 	signedEvent, err := session.SignEvent(ctx, event)
@@ -832,15 +851,7 @@ func htmlRepostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get relays to publish to
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://relay.primal.net",
-		"wss://nos.lol",
-	}
-	if session.UserRelayList != nil && len(session.UserRelayList.Write) > 0 {
-		relays = session.UserRelayList.Write
-	}
+	relays := session.writeRelays()
 
 	publishEvent(ctx, relays, signedEvent)
 
@@ -888,20 +899,13 @@ func htmlBookmarkHandler(w http.ResponseWriter, r *http.Request) {
 		action = "add"
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 	defer cancel()
 
 	userPubkey := hex.EncodeToString(session.UserPubKey)
 
 	// Get relays
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://nos.lol",
-	}
-	if session.UserRelayList != nil && len(session.UserRelayList.Write) > 0 {
-		relays = session.UserRelayList.Write
-	}
+	relays := session.writeRelays()
 
 	// Fetch user's current bookmark list (kind 10003)
 	existingTags := [][]string{}
@@ -1040,43 +1044,34 @@ func htmlQuoteHandler(w http.ResponseWriter, r *http.Request) {
 			CreatedAt: time.Now().Unix(),
 		}
 
-		// Sign via bunker
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		// Relays to publish to
+		relays := session.writeRelays()
+
+		// Sign via bunker and publish; failures are kept for retry
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 		defer cancel()
 
-		signedEvent, err := session.SignEvent(ctx, event)
+		draft := PendingEvent{
+			Composer:  "quote",
+			Event:     event,
+			Target:    eventID,
+			Relays:    relays,
+			ReturnURL: "/html/timeline?kinds=1&limit=20",
+		}
+		signedEvent, err := signAndPublish(ctx, session, draft)
 		if err != nil {
 			log.Printf("Failed to sign quote: %v", err)
-			http.Redirect(w, r, "/html/quote/"+eventID+"?error="+escapeURLParam(sanitizeErrorForUser("Sign event", err)), http.StatusSeeOther)
+			http.Redirect(w, r, signFailureURL(session, draft, "/html/quote/"+eventID, content, err), http.StatusSeeOther)
 			return
 		}
 
-		// Publish to relays
-		relays := []string{
-			"wss://relay.damus.io",
-			"wss://relay.nostr.band",
-			"wss://relay.primal.net",
-			"wss://nos.lol",
-		}
-		if session.UserRelayList != nil && len(session.UserRelayList.Write) > 0 {
-			relays = session.UserRelayList.Write
-		}
-
-		publishEvent(ctx, relays, signedEvent)
-
 		log.Printf("Published quote: %s (quoting %s)", signedEvent.ID, eventID)
 		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&success=Quote+published", http.StatusSeeOther)
 		return
 	}
 
 	// GET: Show quote form with preview of the quoted note
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://relay.primal.net",
-		"wss://nos.lol",
-		"wss://nostr.mom",
-	}
+	relays := relaysFor(RelayPurposeRead)
 
 	// Fetch the event to be quoted
 	events := fetchEventByID(relays, eventID)
@@ -1132,6 +1127,8 @@ func htmlQuoteHandler(w http.ResponseWriter, r *http.Request) {
 		Error           string
 		GeneratedAt     time.Time
 		CSRFToken       string
+		Announcement    *Announcement
+		ComposerDraft   string
 	}{
 		Title:           "Quote Note",
 		ThemeClass:      themeClass,
@@ -1144,6 +1141,10 @@ func htmlQuoteHandler(w http.ResponseWriter, r *http.Request) {
 		Error:           r.URL.Query().Get("error"),
 		GeneratedAt:     time.Now(),
 		CSRFToken:       csrfToken,
+		Announcement:    announcementFor(r),
+	}
+	if loggedIn {
+		data.ComposerDraft = takeComposerDraft(session, "quote", eventID)
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -1185,57 +1186,77 @@ func escapeURLParam(s string) string {
 }
 
 // publishEvent publishes a signed event to relays
-func publishEvent(ctx context.Context, relays []string, event *Event) {
+// The returned channel receives how many relays accepted it once they have all answered
+func publishEvent(ctx context.Context, relays []string, event *Event) <-chan int {
+	track := publishReceiptKinds[event.Kind]
+	if track {
+		publishReceipts.Start(event, relays)
+	}
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
 	for _, relay := range relays {
+		wg.Add(1)
 		go func(relayURL string) {
-			if err := publishToRelay(ctx, relayURL, event); err != nil {
+			defer wg.Done()
+			// Relays that answer after the handler returns still get their answer recorded
+			relayCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 15*time.Second)
+			defer cancel()
+			err := publishToRelay(relayCtx, relayURL, event)
+			if err != nil {
 				log.Printf("Failed to publish to %s: %v", relayURL, err)
 			} else {
 				log.Printf("Published to %s", relayURL)
+				accepted.Add(1)
+			}
+			if track {
+				publishReceipts.Record(event.ID, relayURL, err)
 			}
 		}(relay)
 	}
+	done := make(chan int, 1)
+	go func() {
+		wg.Wait()
+		done <- int(accepted.Load())
+	}()
 	// Give relays a moment to receive
 	time.Sleep(500 * time.Millisecond)
+	return done
 }
 
-func publishToRelay(ctx context.Context, relayURL string, event *Event) error {
-	pc, err := relayPool.Get(ctx, relayURL)
-	if err != nil {
-		return err
-	}
-	defer relayPool.Release(pc)
+// RelayPublishResult is the outcome of publishing an event to one relay
+type RelayPublishResult struct {
+	Relay string
+	Err   error
+}
 
-	req := []interface{}{"EVENT", event}
-	if err := pc.WriteJSON(req); err != nil {
-		relayPool.Close(pc)
-		return fmt.Errorf("write failed: %v", err)
+// publishEventWithResults publishes to all relays in parallel and waits for each OK
+func publishEventWithResults(ctx context.Context, relays []string, event *Event) []RelayPublishResult {
+	results := make([]RelayPublishResult, len(relays))
+	var wg sync.WaitGroup
+	for i, relay := range relays {
+		wg.Add(1)
+		go func(i int, relayURL string) {
+			defer wg.Done()
+			err := publishToRelay(ctx, relayURL, event)
+			if err != nil {
+				log.Printf("Failed to publish to %s: %v", relayURL, err)
+			}
+			results[i] = RelayPublishResult{Relay: relayURL, Err: err}
+		}(i, relay)
 	}
+	wg.Wait()
+	return results
+}
 
-	// Wait for OK response
-	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
-	var msg []interface{}
-	if err := pc.ReadJSON(&msg); err != nil {
-		relayPool.Close(pc)
-		return fmt.Errorf("read OK failed: %v", err)
+func publishToRelay(ctx context.Context, relayURL string, event *Event) error {
+	ack, err := relayPool.Publish(ctx, relayURL, event)
+	if err != nil {
+		return err
 	}
-
-	if len(msg) >= 4 {
-		msgType, _ := msg[0].(string)
-		if msgType == "OK" {
-			eventID, _ := msg[1].(string)
-			success, _ := msg[2].(bool)
-			reason := ""
-			if len(msg) > 3 {
-				reason, _ = msg[3].(string)
-			}
-			if !success {
-				return fmt.Errorf("relay rejected event %s: %s", eventID, reason)
-			}
-			log.Printf("Relay %s accepted event %s", relayURL, eventID[:16])
-		}
+	if !ack.Accepted {
+		return fmt.Errorf("relay rejected event %s: %s", event.ID, ack.Message)
 	}
-
+	log.Printf("Relay %s accepted event %s", relayURL, event.ID[:16])
 	return nil
 }
 
@@ -1244,9 +1265,11 @@ var htmlQuoteTemplate = `<!DOCTYPE html>
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>{{.Title}} - Nostr Hypermedia</title>
+  <title>{{.Title}} - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
   <link rel="icon" href="/static/favicon.ico" />
   <style>
+{{template "announcement-styles"}}
     :root {
       --bg-page: #f5f5f5;
       --bg-container: #ffffff;
@@ -1497,11 +1520,14 @@ var htmlQuoteTemplate = `<!DOCTYPE html>
       font-size: 13px;
       border-top: 1px solid var(--border-light);
     }
+{{template "branding-styles"}}
   </style>
 </head>
 <body>
+  {{template "announcement" .Announcement}}
   <div class="container">
     <nav>
+      {{template "branding-logo"}}
       {{if .LoggedIn}}
       <a href="/html/timeline?kinds=1&limit=20&feed=follows" class="nav-tab">Follows</a>
       {{end}}
@@ -1511,7 +1537,8 @@ var htmlQuoteTemplate = `<!DOCTYPE html>
       {{end}}
       <div class="ml-auto flex-center gap-md">
         {{if .LoggedIn}}
-        <a href="/html/notifications" class="text-muted text-sm" title="Notifications">🔔</a>
+        <a href="/html/messages" class="text-muted text-sm" title="Messages">{{icon "mail" "✉️"}}</a>
+        <a href="/html/notifications" class="text-muted text-sm" title="Notifications">{{icon "bell" "🔔"}}</a>
         <form method="POST" action="/html/logout" class="inline-form">
           <button type="submit" class="ghost-btn text-muted text-sm">Logout</button>
         </form>
@@ -1523,7 +1550,7 @@ var htmlQuoteTemplate = `<!DOCTYPE html>
 
     <main>
       {{if .Error}}
-      <div class="error-message">{{.Error}}</div>
+      <div class="error-message" role="alert">{{.Error}}</div>
       {{end}}
 
       <div class="quoted-note">
@@ -1557,7 +1584,7 @@ var htmlQuoteTemplate = `<!DOCTYPE html>
         <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
         <input type="hidden" name="quoted_pubkey" value="{{.QuotedEvent.PubKey}}">
         <div class="form-label">Quoting as: <strong>{{.UserDisplayName}}</strong></div>
-        <textarea name="content" placeholder="Add your commentary..." required autofocus></textarea>
+        <textarea name="content" placeholder="Add your commentary..." required autofocus>{{.ComposerDraft}}</textarea>
         <button type="submit" class="submit-btn">Post Commentary</button>
       </form>
       {{else}}
@@ -1568,7 +1595,7 @@ var htmlQuoteTemplate = `<!DOCTYPE html>
     </main>
 
     <footer>
-      <p>Generated: {{.GeneratedAt.Format "15:04:05"}} · Zero-JS Hypermedia Browser</p>
+      <p>Generated: {{.GeneratedAt.Format "15:04:05"}} · {{template "branding-footer"}}</p>
     </footer>
   </div>
 </body>
@@ -1580,9 +1607,11 @@ var htmlLoginTemplate = `<!DOCTYPE html>
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>{{.Title}} - Nostr Hypermedia</title>
+  <title>{{.Title}} - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
   <link rel="icon" href="/static/favicon.ico" />
   <style>
+{{template "announcement-styles"}}
     :root {
       --bg-page: #f5f5f5;
       --bg-container: #ffffff;
@@ -1901,15 +1930,18 @@ var htmlLoginTemplate = `<!DOCTYPE html>
       border-top: 1px solid var(--border-color);
       border-radius: 0 0 8px 8px;
     }
+{{template "branding-styles"}}
   </style>
 </head>
 <body>
+  {{template "announcement" .Announcement}}
   <div class="container">
     <header>
       <h1>{{.Title}}</h1>
     </header>
 
     <nav>
+      {{template "branding-logo"}}
       <a href="/html/timeline?kinds=1&limit=20&fast=1">Timeline</a>
     </nav>
 
@@ -2004,7 +2036,7 @@ var htmlLoginTemplate = `<!DOCTYPE html>
     </main>
 
     <footer>
-      <p>Zero-JS Hypermedia Browser</p>
+      <p>{{template "branding-footer"}}</p>
     </footer>
   </div>
 </body>
@@ -2057,18 +2089,11 @@ func htmlFollowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
 	defer cancel()
 
 	// Get relays to use
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://nos.lol",
-	}
-	if session.UserRelayList != nil && len(session.UserRelayList.Write) > 0 {
-		relays = session.UserRelayList.Write
-	}
+	relays := session.writeRelays()
 
 	// Fetch user's current contact list (kind 3)
 	existingTags := [][]string{}
@@ -2242,6 +2267,7 @@ func htmlProfileEditHandler(w http.ResponseWriter, r *http.Request) {
 			RawContent: string(rawContentJSON),
 			Error:      r.URL.Query().Get("error"),
 			Success:    r.URL.Query().Get("success"),
+			Announcement: announcementFor(r),
 		}
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -2338,17 +2364,6 @@ func htmlProfileEditHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now().Unix(),
 	}
 
-	// Sign via bunker
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	signedEvent, err := session.SignEvent(ctx, event)
-	if err != nil {
-		log.Printf("Failed to sign profile update: %v", err)
-		http.Redirect(w, r, "/html/profile/edit?error="+escapeURLParam(sanitizeErrorForUser("Sign profile", err)), http.StatusSeeOther)
-		return
-	}
-
 	// Get relays
 	var relays []string
 	session.mu.Lock()
@@ -2363,8 +2378,22 @@ func htmlProfileEditHandler(w http.ResponseWriter, r *http.Request) {
 		relays = defaultNostrConnectRelays
 	}
 
-	// Publish to relays
-	publishEvent(ctx, relays, signedEvent)
+	// Sign via bunker and publish; failures are kept for retry
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	draft := PendingEvent{
+		Composer:  "profile",
+		Event:     event,
+		Relays:    relays,
+		ReturnURL: "/html/profile/" + userPubKeyHex,
+	}
+	signedEvent, err := signAndPublish(ctx, session, draft)
+	if err != nil {
+		log.Printf("Failed to sign profile update: %v", err)
+		http.Redirect(w, r, signFailureURL(session, draft, "/html/profile/edit", "", err), http.StatusSeeOther)
+		return
+	}
 
 	// Invalidate cached profile
 	profileCache.Delete(userPubKeyHex)
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestPostNoteSignsWithSigner(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("auth-post-alice")
+	client, signer := s.login(t, alice)
+
+	location := s.post(t, client, "/html/post", url.Values{
+		"csrf_token": {s.csrfToken(t, client)},
+		"content":    {"signed by the mock signer"},
+	})
+	if !strings.Contains(location, "success=") {
+		t.Fatalf("post redirected to %s", location)
+	}
+	if !slices.Contains(signer.Requests(), "sign_event") {
+		t.Errorf("signer got %v, want a sign_event request", signer.Requests())
+	}
+
+	note := waitForEvent(t, s.Relay, testutil.Filter{Authors: []string{alice.PubKey}, Kinds: []int{1}})
+	if note.Content != "signed by the mock signer" {
+		t.Errorf("relay has %q", note.Content)
+	}
+}
+
+func TestReact(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("auth-react-alice")
+	bob := testutil.NewKeypair("auth-react-bob")
+	note := testutil.Note(bob, time.Now().Unix()-60, "react to me")
+	s.Relay.Publish(note)
+	client, _ := s.login(t, alice)
+
+	returnURL := "/html/thread/" + note.ID
+	location := s.post(t, client, "/html/react", url.Values{
+		"csrf_token":   {s.csrfToken(t, client)},
+		"event_id":     {note.ID},
+		"event_pubkey": {bob.PubKey},
+		"reaction":     {"🤙"},
+		"return_url":   {returnURL},
+	})
+	if location != returnURL {
+		t.Errorf("react redirected to %s, want %s", location, returnURL)
+	}
+
+	reaction := waitForEvent(t, s.Relay, testutil.Filter{Authors: []string{alice.PubKey}, Kinds: []int{7}})
+	if reaction.Content != "🤙" || reaction.TagValue("e") != note.ID || reaction.TagValue("p") != bob.PubKey {
+		t.Errorf("reaction = %q %v", reaction.Content, reaction.Tags)
+	}
+}
+
+func TestFollowKeepsExistingContacts(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("auth-follow-alice")
+	bob := testutil.NewKeypair("auth-follow-bob")
+	carol := testutil.NewKeypair("auth-follow-carol")
+	before := testutil.ContactList(alice, time.Now().Unix()-3600, carol.PubKey)
+	s.Relay.Publish(before)
+	client, _ := s.login(t, alice)
+
+	s.post(t, client, "/html/follow", url.Values{
+		"csrf_token": {s.csrfToken(t, client)},
+		"pubkey":     {bob.PubKey},
+		"action":     {"follow"},
+		"return_url": {"/html/profile/" + bob.PubKey},
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		contacts := s.Relay.Query(testutil.Filter{Authors: []string{alice.PubKey}, Kinds: []int{3}})
+		if len(contacts) == 1 && contacts[0].ID != before.ID {
+			var follows []string
+			for _, tag := range contacts[0].Tags {
+				follows = append(follows, tag[1])
+			}
+			if !slices.Contains(follows, carol.PubKey) || !slices.Contains(follows, bob.PubKey) {
+				t.Errorf("contact list follows %v, want carol and bob", follows)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("contact list was not replaced")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSignerPermissionDenied(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("auth-denied-alice")
+	bob := testutil.NewKeypair("auth-denied-bob")
+	note := testutil.Note(bob, time.Now().Unix()-60, "denied target")
+	s.Relay.Publish(note)
+	client, signer := s.login(t, alice)
+	csrf := s.csrfToken(t, client)
+	signer.FailMethod("sign_event", "permission denied")
+
+	location := s.post(t, client, "/html/post", url.Values{
+		"csrf_token": {csrf},
+		"content":    {"never signed"},
+	})
+	if !strings.HasPrefix(location, "/html/pending?error=") {
+		t.Errorf("denied post redirected to %s, want the pending page", location)
+	}
+	assertContains(t, s.get(t, client, "/html/pending"), "never signed")
+
+	returnURL := "/html/thread/" + note.ID
+	location = s.post(t, client, "/html/react", url.Values{
+		"csrf_token":   {csrf},
+		"event_id":     {note.ID},
+		"event_pubkey": {bob.PubKey},
+		"return_url":   {returnURL},
+	})
+	if !strings.HasPrefix(location, returnURL+"?error=") {
+		t.Errorf("denied reaction redirected to %s, want an error on %s", location, returnURL)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if events := s.Relay.Query(testutil.Filter{Authors: []string{alice.PubKey}, Kinds: []int{1, 7}}); len(events) > 0 {
+		t.Errorf("relay got %d events the signer refused", len(events))
+	}
+}
+
+func TestOversizedPostReturnsToComposer(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("auth-oversized-alice")
+	bob := testutil.NewKeypair("auth-oversized-bob")
+	note := testutil.Note(bob, time.Now().Unix()-60, "reply to me")
+	s.Relay.Publish(note)
+	client, signer := s.login(t, alice)
+	csrf := s.csrfToken(t, client)
+	oversized := "too long " + strings.Repeat("x", limitsForKind(1).MaxContent)
+
+	tests := []struct {
+		name     string
+		path     string
+		form     url.Values
+		composer string // where the error should land
+	}{
+		{"note", "/html/post", url.Values{"content": {oversized}}, "/html/timeline?"},
+		{"reply", "/html/reply", url.Values{"content": {oversized}, "reply_to": {note.ID}, "reply_to_pubkey": {bob.PubKey}}, "/html/thread/" + note.ID + "?"},
+		{"quote", "/html/quote/" + note.ID, url.Values{"content": {oversized}, "quoted_pubkey": {bob.PubKey}}, "/html/quote/" + note.ID + "?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.form.Set("csrf_token", csrf)
+			location := s.post(t, client, tt.path, tt.form)
+			if !strings.HasPrefix(location, tt.composer) || !strings.Contains(location, "error=") {
+				t.Fatalf("oversized %s redirected to %s, want an error on %s", tt.name, location, tt.composer)
+			}
+			page := s.get(t, client, location)
+			assertContains(t, page, "Content is too long")
+			assertContains(t, page, ">"+oversized+"</textarea>")
+
+			// The text is refilled once, not on every later visit
+			if strings.Contains(s.get(t, client, location), oversized) {
+				t.Error("composer refilled the rejected text a second time")
+			}
+		})
+	}
+
+	if slices.Contains(signer.Requests(), "sign_event") {
+		t.Error("an oversized event reached the signer")
+	}
+	if pending := s.get(t, client, "/html/pending"); strings.Contains(pending, "too long x") {
+		t.Error("an oversized event was kept for retry")
+	}
+}
+
+func TestAuthenticatedActionsRequireLogin(t *testing.T) {
+	s := startTestServer(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	for _, path := range []string{"/html/post", "/html/react", "/html/follow"} {
+		location := s.post(t, client, path, url.Values{"content": {"anonymous"}})
+		if !strings.HasPrefix(location, "/html/login") {
+			t.Errorf("%s redirected to %s, want the login page", path, location)
+		}
+	}
+}
+
+func TestAuthenticatedActionsRequireCSRFToken(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("auth-csrf-alice")
+	client, signer := s.login(t, alice)
+
+	resp, err := client.PostForm(s.URL+"/html/post", url.Values{
+		"csrf_token": {"forged"},
+		"content":    {"forged post"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("forged CSRF token: status %d, want 403", resp.StatusCode)
+	}
+	if slices.Contains(signer.Requests(), "sign_event") {
+		t.Error("signer was asked to sign a forged request")
+	}
+}
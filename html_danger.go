@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Account deactivation ("danger zone")
+// A guided path for users leaving or whose key is compromised, at /html/settings/danger.
+// Each step is its own confirmed form submission:
+//  1. replace the profile (kind 0) with a deactivation notice
+//  2. publish NIP-09 deletion requests for recent events, one batch per submission
+//  3. optionally publish an empty contact list (kind 3) and relay list (kind 10002)
+//  4. end every session for the pubkey and drop its cached data on this instance
+// Progress lives in a per-pubkey job record so the batches can resume after a
+// failed submission, a new login or a different device.
+
+const (
+	wipeBatchSize  = 100 // events per deletion request
+	maxWipeBatches = 20  // stop after this many batches ("recent" events only)
+	wipeJobTTL     = 7 * 24 * time.Hour
+)
+
+// deactivatedProfileContent replaces the user's kind 0
+const deactivatedProfileContent = `{"name":"deactivated","display_name":"Deactivated account","about":"This account has been deactivated.","deleted":true}`
+
+// AccountWipeJob tracks a user's progress through the danger zone steps
+type AccountWipeJob struct {
+	Pubkey        string
+	Deactivated   bool
+	DeleteUntil   int64 // created_at cursor for the next deletion batch; 0 = start from now
+	Batches       int
+	DeletedEvents int
+	DeletionsDone bool
+	ClearedLists  []string // "contact list", "relay list"
+	UpdatedAt     time.Time
+}
+
+// AccountWipeStore holds danger zone job records by pubkey
+type AccountWipeStore struct {
+	mu   sync.Mutex
+	jobs map[string]*AccountWipeJob
+}
+
+var accountWipeJobs = &AccountWipeStore{jobs: make(map[string]*AccountWipeJob)}
+
+// Get returns a copy of the pubkey's job, or a fresh one
+func (s *AccountWipeStore) Get(pubkey string) AccountWipeJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[pubkey]
+	if !ok || time.Since(job.UpdatedAt) > wipeJobTTL {
+		return AccountWipeJob{Pubkey: pubkey}
+	}
+	cp := *job
+	cp.ClearedLists = append([]string(nil), job.ClearedLists...)
+	return cp
+}
+
+// Update applies fn to the pubkey's job and saves it
+func (s *AccountWipeStore) Update(pubkey string, fn func(*AccountWipeJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[pubkey]
+	if !ok || time.Since(job.UpdatedAt) > wipeJobTTL {
+		job = &AccountWipeJob{Pubkey: pubkey}
+		s.jobs[pubkey] = job
+	}
+	fn(job)
+	job.UpdatedAt = time.Now()
+}
+
+// Delete removes the pubkey's job
+func (s *AccountWipeStore) Delete(pubkey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, pubkey)
+}
+
+// HTMLDangerData is the data for the danger zone page
+type HTMLDangerData struct {
+	HTMLPageChrome
+	Job         AccountWipeJob
+	MaxBatches  int
+	BatchSize   int
+	CurrentNpub string
+}
+
+func init() {
+	registerPageTemplate("danger", htmlDangerContent)
+}
+
+// htmlDangerHandler shows the danger zone steps (GET) and runs one step per POST
+func htmlDangerHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+	pubkey := hex.EncodeToString(session.UserPubKey)
+
+	if r.Method != http.MethodPost {
+		data := HTMLDangerData{
+			HTMLPageChrome: newPageChrome(r, "Deactivate account"),
+			Job:            accountWipeJobs.Get(pubkey),
+			MaxBatches:     maxWipeBatches,
+			BatchSize:      wipeBatchSize,
+		}
+		data.CurrentNpub, _ = encodeBech32Pubkey(pubkey)
+		w.Header().Set("Cache-Control", "no-store")
+		renderPage(w, "danger", data)
+		return
+	}
+
+	if !validateCSRFToken(session.ID, r.FormValue("csrf_token")) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("confirm") != "yes" {
+		http.Redirect(w, r, "/html/settings/danger?error=Tick+the+confirmation+box+to+continue", http.StatusSeeOther)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 60*time.Second)
+	defer cancel()
+
+	relays := session.writeRelays()
+
+	var msg string
+	var err error
+	switch r.FormValue("step") {
+	case "deactivate":
+		msg, err = wipeDeactivateProfile(ctx, session, pubkey, relays)
+	case "delete-batch":
+		msg, err = wipeDeleteBatch(ctx, session, pubkey, relays)
+	case "clear-lists":
+		contacts, relayList := r.FormValue("contacts") == "yes", r.FormValue("relays") == "yes"
+		if !contacts && !relayList {
+			http.Redirect(w, r, "/html/settings/danger?error=Choose+at+least+one+list+to+clear", http.StatusSeeOther)
+			return
+		}
+		msg, err = wipeClearLists(ctx, session, pubkey, relays, contacts, relayList)
+	case "destroy":
+		removed := forgetPubkey(pubkey)
+		log.Printf("Danger zone: removed %d sessions and cached data for %s", removed, shortID(pubkey))
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+		http.Redirect(w, r, "/html/login?success=Your+sessions+and+cached+data+were+removed", http.StatusSeeOther)
+		return
+	default:
+		http.Redirect(w, r, "/html/settings/danger?error=Unknown+step", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Redirect(w, r, "/html/settings/danger?error="+escapeURLParam(sanitizeErrorForUser("Danger zone", err)), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/html/settings/danger?success="+escapeURLParam(msg), http.StatusSeeOther)
+}
+
+// wipeSignAndPublish signs an event and publishes it, failing if no relay accepted it
+func wipeSignAndPublish(ctx context.Context, session *BunkerSession, relays []string, event UnsignedEvent) (*Event, error) {
+	signed, err := session.SignEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	accepted := 0
+	for _, res := range publishEventWithResults(ctx, relays, signed) {
+		if res.Err == nil {
+			accepted++
+		}
+	}
+	if accepted == 0 {
+		return nil, fmt.Errorf("no relay accepted kind %d event", signed.Kind)
+	}
+	return signed, nil
+}
+
+// wipeDeactivateProfile replaces the profile with a deactivation notice
+func wipeDeactivateProfile(ctx context.Context, session *BunkerSession, pubkey string, relays []string) (string, error) {
+	_, err := wipeSignAndPublish(ctx, session, relays, UnsignedEvent{
+		Kind:      0,
+		Content:   deactivatedProfileContent,
+		Tags:      [][]string{},
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	profileCache.Delete(pubkey)
+	accountWipeJobs.Update(pubkey, func(job *AccountWipeJob) { job.Deactivated = true })
+	log.Printf("Danger zone: deactivated profile for %s", shortID(pubkey))
+	return "Profile replaced with a deactivation notice", nil
+}
+
+// wipeDeleteBatch requests deletion of the next batch of the user's events, newest first
+func wipeDeleteBatch(ctx context.Context, session *BunkerSession, pubkey string, relays []string) (string, error) {
+	job := accountWipeJobs.Get(pubkey)
+	if job.DeletionsDone {
+		return "Deletion requests already sent", nil
+	}
+
+	filter := Filter{Authors: []string{pubkey}, Limit: wipeBatchSize}
+	if job.DeleteUntil > 0 {
+		until := job.DeleteUntil
+		filter.Until = &until
+	}
+	queryRelays := append(append([]string(nil), relays...), relaysFor(RelayPurposeRead)...)
+	events, _ := fetchEventsFromRelays(queryRelays, filter)
+
+	// Keep the deactivation notice and earlier deletion requests; ephemeral events
+	// (signer traffic) aren't stored by well-behaved relays, so skip them too
+	var ids []string
+	kinds := make(map[int]bool)
+	oldest := job.DeleteUntil
+	for _, evt := range events {
+		if oldest == 0 || evt.CreatedAt < oldest {
+			oldest = evt.CreatedAt
+		}
+		if evt.PubKey != pubkey || evt.Kind == 0 || evt.Kind == 5 || (evt.Kind >= 20000 && evt.Kind < 30000) {
+			continue
+		}
+		ids = append(ids, evt.ID)
+		kinds[evt.Kind] = true
+	}
+
+	if len(ids) > 0 {
+		tags := make([][]string, 0, len(ids)+len(kinds))
+		for _, id := range ids {
+			tags = append(tags, []string{"e", id})
+		}
+		kindList := make([]int, 0, len(kinds))
+		for k := range kinds {
+			kindList = append(kindList, k)
+		}
+		sort.Ints(kindList)
+		for _, k := range kindList {
+			tags = append(tags, []string{"k", strconv.Itoa(k)})
+		}
+		if _, err := wipeSignAndPublish(ctx, session, relays, UnsignedEvent{
+			Kind:      5,
+			Content:   "Account deactivated",
+			Tags:      tags,
+			CreatedAt: time.Now().Unix(),
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	var done bool
+	accountWipeJobs.Update(pubkey, func(job *AccountWipeJob) {
+		job.Batches++
+		job.DeletedEvents += len(ids)
+		job.DeleteUntil = oldest - 1
+		job.DeletionsDone = len(events) == 0 || job.Batches >= maxWipeBatches
+		done = job.DeletionsDone
+	})
+	eventCache.DeleteAuthor(pubkey)
+	log.Printf("Danger zone: deletion batch for %s covered %d events", shortID(pubkey), len(ids))
+
+	if done {
+		return fmt.Sprintf("Requested deletion of %d events; no more batches to send", len(ids)), nil
+	}
+	return fmt.Sprintf("Requested deletion of %d events; send the next batch to continue", len(ids)), nil
+}
+
+// wipeClearLists publishes an empty contact list and/or relay list
+func wipeClearLists(ctx context.Context, session *BunkerSession, pubkey string, relays []string, contacts, relayList bool) (string, error) {
+	var cleared []string
+	if contacts {
+		if _, err := wipeSignAndPublish(ctx, session, relays, UnsignedEvent{
+			Kind: 3, Content: "", Tags: [][]string{}, CreatedAt: time.Now().Unix(),
+		}); err != nil {
+			return "", err
+		}
+		contactCache.Delete(pubkey)
+		cleared = append(cleared, "contact list")
+	}
+	if relayList {
+		if _, err := wipeSignAndPublish(ctx, session, relays, UnsignedEvent{
+			Kind: 10002, Content: "", Tags: [][]string{}, CreatedAt: time.Now().Unix(),
+		}); err != nil {
+			return "", err
+		}
+		relayListCache.Delete(pubkey)
+		cleared = append(cleared, "relay list")
+	}
+	accountWipeJobs.Update(pubkey, func(job *AccountWipeJob) {
+		job.ClearedLists = append(job.ClearedLists, cleared...)
+	})
+	return "Published an empty " + joinWithAnd(cleared), nil
+}
+
+// forgetPubkey ends every session for pubkey and drops what this instance cached about it
+func forgetPubkey(pubkey string) int {
+	removed := bunkerSessions.DeleteByPubkey(pubkey)
+	profileCache.Delete(pubkey)
+	contactCache.Delete(pubkey)
+	relayListCache.Delete(pubkey)
+	eventCache.DeleteAuthor(pubkey)
+	seenEventCache.DeleteAuthor(pubkey)
+	publishReceipts.DeleteAuthor(pubkey)
+	accountWipeJobs.Delete(pubkey)
+	return removed
+}
+
+// joinWithAnd joins ["a", "b"] as "a and b"
+func joinWithAnd(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	out := items[0]
+	for _, item := range items[1 : len(items)-1] {
+		out += ", " + item
+	}
+	return out + " and " + items[len(items)-1]
+}
+
+var htmlDangerContent = `{{define "content"}}
+<h1>Deactivate account</h1>
+<p>Use these steps if you're leaving, or if your key has been compromised. Each step publishes signed events through your signer and <strong>cannot be undone</strong>: relays and other clients may keep copies, and deletion requests are requests, not guarantees.</p>
+<p class="text-muted text-sm">Signed in as <span class="mono">{{.CurrentNpub}}</span>. Progress is saved on this instance, so you can stop and come back.</p>
+
+<section class="card danger-step">
+  <h2>1. Replace your profile</h2>
+  <p class="text-sm">Publishes a new profile (kind 0) with your name, picture and bio removed and a note that the account is deactivated.</p>
+  {{if .Job.Deactivated}}<p class="text-sm"><span class="badge">done</span></p>{{end}}
+  <form method="POST" action="/html/settings/danger">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="step" value="deactivate">
+    <label class="confirm-label"><input type="checkbox" name="confirm" value="yes" required> I understand my current profile will be replaced and can't be restored from here</label>
+    <button type="submit" class="btn danger">Replace profile</button>
+  </form>
+</section>
+
+<section class="card danger-step">
+  <h2>2. Request deletion of your notes</h2>
+  <p class="text-sm">Publishes NIP-09 deletion requests for your events, newest first, {{.BatchSize}} per submission and up to {{.MaxBatches}} batches.</p>
+  <p class="text-sm">{{if .Job.Batches}}{{.Job.Batches}} of up to {{.MaxBatches}} batches sent, covering {{.Job.DeletedEvents}} events.{{else}}No batches sent yet.{{end}}
+    {{if .Job.DeletionsDone}}<span class="badge">done</span>{{end}}</p>
+  {{if not .Job.DeletionsDone}}
+  <form method="POST" action="/html/settings/danger">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="step" value="delete-batch">
+    <label class="confirm-label"><input type="checkbox" name="confirm" value="yes" required> I understand these events will be requested deleted from every relay</label>
+    <button type="submit" class="btn danger">{{if .Job.Batches}}Send next batch{{else}}Send first batch{{end}}</button>
+  </form>
+  {{end}}
+</section>
+
+<section class="card danger-step">
+  <h2>3. Clear your lists (optional)</h2>
+  <p class="text-sm">Publishes empty replacements so other clients stop following people or using relays on your behalf.</p>
+  {{if .Job.ClearedLists}}<p class="text-sm">Cleared: {{join .Job.ClearedLists ", "}}</p>{{end}}
+  <form method="POST" action="/html/settings/danger">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="step" value="clear-lists">
+    <label class="confirm-label"><input type="checkbox" name="contacts" value="yes"> Empty contact list (kind 3) - you will follow no one</label>
+    <label class="confirm-label"><input type="checkbox" name="relays" value="yes"> Empty relay list (kind 10002)</label>
+    <label class="confirm-label"><input type="checkbox" name="confirm" value="yes" required> I understand the selected lists will be replaced with empty ones</label>
+    <button type="submit" class="btn danger">Clear selected lists</button>
+  </form>
+</section>
+
+<section class="card danger-step">
+  <h2>4. Remove your data from this instance</h2>
+  <p class="text-sm">Logs out every session for this key on this instance, on all devices, and drops cached profiles, lists, events and this progress record. Nothing is published. Do this last.</p>
+  <form method="POST" action="/html/settings/danger">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="step" value="destroy">
+    <label class="confirm-label"><input type="checkbox" name="confirm" value="yes" required> I understand I will be logged out everywhere and this progress will be lost</label>
+    <button type="submit" class="btn danger">Log out everywhere and forget me</button>
+  </form>
+</section>
+{{end}}
+{{define "styles"}}
+    .danger-step h2 { margin-top: 0; }
+    .danger-step form { margin-top: 12px; }
+    .confirm-label { display: flex; gap: 8px; align-items: flex-start; color: var(--text-primary); margin-bottom: 10px; }
+{{end}}`
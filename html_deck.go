@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Deck view
+// /html/deck shows two to four feeds side by side on wide screens. Each column is an
+// existing feed handler (timeline or notifications) run against a copy of the request
+// with fragment=1, which renders only the feed items and returns the next page in a
+// Link header, so the deck has no feed logic of its own. Columns page independently
+// through ?u{column}=<until>. The layout is kept in the session and edited with the
+// form at the top of the page; below the breakpoint the columns stack, and browsers
+// without CSS show them one after another.
+
+const (
+	minDeckColumns = 2
+	maxDeckColumns = 4
+)
+
+// DeckColumn is one column of a user's deck
+type DeckColumn struct {
+	Type string // One of deckColumnTypes
+	Tag  string // Hashtag, for "hashtag" columns
+}
+
+// deckColumnTypes are the feeds a column can show, in settings form order
+var deckColumnTypes = []HTMLDeckOption{
+	{Value: "follows", Label: "Following"},
+	{Value: "notifications", Label: "Notifications"},
+	{Value: "global", Label: "Global"},
+	{Value: "me", Label: "My notes"},
+	{Value: "bookmarks", Label: "Bookmarks"},
+	{Value: "hashtag", Label: "Hashtag"},
+}
+
+// defaultDeckColumns is the layout until the user saves their own
+var defaultDeckColumns = []DeckColumn{{Type: "follows"}, {Type: "notifications"}, {Type: "global"}}
+
+// Title returns the column heading
+func (c DeckColumn) Title() string {
+	if c.Type == "hashtag" {
+		return "#" + c.Tag
+	}
+	for _, opt := range deckColumnTypes {
+		if opt.Value == c.Type {
+			return opt.Label
+		}
+	}
+	return c.Type
+}
+
+// Source returns the URL of the full page the column is a fragment of
+func (c DeckColumn) Source() string {
+	switch c.Type {
+	case "notifications":
+		return "/html/notifications"
+	case "bookmarks":
+		return "/html/timeline?kinds=10003&limit=20&feed=me"
+	case "hashtag":
+		return "/html/timeline?kinds=1&limit=20&feed=global&t=" + url.QueryEscape(c.Tag)
+	default:
+		return "/html/timeline?kinds=1&limit=20&feed=" + c.Type
+	}
+}
+
+// handler returns the feed handler that renders the column
+func (c DeckColumn) handler() http.HandlerFunc {
+	if c.Type == "notifications" {
+		return htmlNotificationsHandler
+	}
+	return htmlTimelineHandler
+}
+
+// HTMLDeck is the deck page's data, set as HTMLPageData.Deck
+type HTMLDeck struct {
+	Columns []HTMLDeckColumn
+	Slots   []HTMLDeckSlot // Settings form rows, one per possible column
+}
+
+// HTMLDeckColumn is a rendered deck column
+type HTMLDeckColumn struct {
+	Index     int
+	Title     string
+	SourceURL string
+	HTML      template.HTML // Fragment from the column's feed handler
+	Error     string
+	NewestURL string // Back to the first page, when paged
+	OlderURL  string
+}
+
+// HTMLDeckSlot is a row of the deck settings form
+type HTMLDeckSlot struct {
+	Index   int
+	Number  int
+	Tag     string
+	Options []HTMLDeckOption
+}
+
+// HTMLDeckOption is a column type choice
+type HTMLDeckOption struct {
+	Value    string
+	Label    string
+	Selected bool
+}
+
+// deckColumnsOf returns a copy of the session's deck layout, or the default
+func deckColumnsOf(session *BunkerSession) []DeckColumn {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if len(session.DeckColumns) == 0 {
+		return defaultDeckColumns
+	}
+	return append([]DeckColumn(nil), session.DeckColumns...)
+}
+
+// fragmentRecorder captures a feed handler's response for embedding in the deck
+type fragmentRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (f *fragmentRecorder) Header() http.Header { return f.header }
+
+func (f *fragmentRecorder) WriteHeader(status int) {
+	if f.status == 0 {
+		f.status = status
+	}
+}
+
+func (f *fragmentRecorder) Write(p []byte) (int, error) {
+	if f.status == 0 {
+		f.status = http.StatusOK
+	}
+	return f.body.Write(p)
+}
+
+// renderDeckFragment runs the column's feed handler in fragment mode with the deck request's cookies
+func renderDeckFragment(r *http.Request, col DeckColumn, until, returnURL string) *fragmentRecorder {
+	target := col.Source()
+	if strings.Contains(target, "?") {
+		target += "&"
+	} else {
+		target += "?"
+	}
+	target += "fragment=1&return_url=" + url.QueryEscape(returnURL)
+	if until != "" {
+		target += "&until=" + until
+	}
+
+	u, _ := url.Parse(target)
+	req := r.Clone(r.Context())
+	req.Method = http.MethodGet
+	req.URL = u
+	req.RequestURI = u.RequestURI()
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	req.Form, req.PostForm = nil, nil
+
+	rec := &fragmentRecorder{header: make(http.Header)}
+	col.handler()(rec, req)
+	return rec
+}
+
+// nextUntilFromLink extracts the until cursor from a fragment's Link: <...>; rel="next" header
+func nextUntilFromLink(link string) string {
+	target, ok := strings.CutPrefix(link, "<")
+	if !ok {
+		return ""
+	}
+	target, _, ok = strings.Cut(target, ">")
+	if !ok {
+		return ""
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("until")
+}
+
+// deckPageURL returns the deck URL for query, or /html/deck when it's empty
+func deckPageURL(query url.Values) string {
+	if len(query) == 0 {
+		return "/html/deck"
+	}
+	return "/html/deck?" + query.Encode()
+}
+
+// htmlDeckHandler shows the deck (GET) and saves its column layout (POST)
+func htmlDeckHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		saveDeckColumns(w, r, session)
+		return
+	}
+
+	q := r.URL.Query()
+	columns := deckColumnsOf(session)
+
+	// Per-column page cursors; anything else in the query is dropped from page links
+	cursors := url.Values{}
+	for i := range columns {
+		key := "u" + strconv.Itoa(i)
+		if _, err := strconv.ParseInt(q.Get(key), 10, 64); err == nil {
+			cursors.Set(key, q.Get(key))
+		}
+	}
+	returnURL := deckPageURL(cursors)
+
+	// Render the columns concurrently; each one queries its own relays
+	recs := make([]*fragmentRecorder, len(columns))
+	var wg sync.WaitGroup
+	for i, col := range columns {
+		wg.Add(1)
+		go func(i int, col DeckColumn) {
+			defer wg.Done()
+			recs[i] = renderDeckFragment(r, col, cursors.Get("u"+strconv.Itoa(i)), returnURL)
+		}(i, col)
+	}
+	wg.Wait()
+
+	deck := &HTMLDeck{Slots: deckSlots(columns)}
+	for i, col := range columns {
+		rec := recs[i]
+		key := "u" + strconv.Itoa(i)
+		column := HTMLDeckColumn{
+			Index:     i,
+			Title:     col.Title(),
+			SourceURL: col.Source(),
+		}
+		if rec.status == http.StatusOK {
+			column.HTML = handlerFragment(rec.body.String())
+		} else {
+			log.Printf("Deck column %s returned status %d", col.Title(), rec.status)
+			column.Error = "Couldn't load this column."
+		}
+		if cursors.Has(key) {
+			newest := cloneURLValues(cursors)
+			newest.Del(key)
+			column.NewestURL = deckPageURL(newest)
+		}
+		if until := nextUntilFromLink(rec.header.Get("Link")); until != "" {
+			older := cloneURLValues(cursors)
+			older.Set(key, until)
+			column.OlderURL = deckPageURL(older)
+		}
+		// Pass on cookies such as the notifications last-seen time
+		for _, cookie := range rec.header.Values("Set-Cookie") {
+			w.Header().Add("Set-Cookie", cookie)
+		}
+		deck.Columns = append(deck.Columns, column)
+	}
+
+	themeClass, themeLabel := getThemeFromRequest(r)
+	pubkeyHex := hex.EncodeToString(session.UserPubKey)
+	data := HTMLPageData{
+		Title:                  "Deck",
+		LoggedIn:               true,
+		UserPubKey:             pubkeyHex,
+		UserDisplayName:        getUserDisplayName(pubkeyHex),
+		Error:                  q.Get("error"),
+		Success:                q.Get("success"),
+		ShowReactions:          true,
+		CurrentURL:             returnURL,
+		ThemeClass:             themeClass,
+		ThemeLabel:             themeLabel,
+		CSRFToken:              generateCSRFToken(session.ID),
+		HasUnreadNotifications: checkUnreadNotifications(r, session, relaysFor(RelayPurposeRead)),
+		Announcement:           announcementFor(r),
+		Deck:                   deck,
+	}
+
+	var buf strings.Builder
+	if err := cachedHTMLTemplate.Execute(&buf, data); err != nil {
+		log.Printf("Error rendering deck: %v", err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(buf.String()))
+}
+
+// cloneURLValues returns a copy of v
+func cloneURLValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vals := range v {
+		out[k] = append([]string(nil), vals...)
+	}
+	return out
+}
+
+// deckSlots builds the settings form rows for the current layout
+func deckSlots(columns []DeckColumn) []HTMLDeckSlot {
+	slots := make([]HTMLDeckSlot, maxDeckColumns)
+	for i := range slots {
+		var col DeckColumn
+		if i < len(columns) {
+			col = columns[i]
+		}
+		slots[i] = HTMLDeckSlot{Index: i, Number: i + 1, Tag: col.Tag}
+		for _, opt := range deckColumnTypes {
+			opt.Selected = opt.Value == col.Type
+			slots[i].Options = append(slots[i].Options, opt)
+		}
+	}
+	return slots
+}
+
+// saveDeckColumns validates the settings form and stores the layout in the session
+func saveDeckColumns(w http.ResponseWriter, r *http.Request, session *BunkerSession) {
+	if !validateCSRFToken(session.ID, r.FormValue("csrf_token")) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+
+	var columns []DeckColumn
+	seen := make(map[DeckColumn]bool)
+	for i := 0; i < maxDeckColumns; i++ {
+		colType := r.FormValue("type_" + strconv.Itoa(i))
+		if colType == "" {
+			continue
+		}
+		col := DeckColumn{Type: colType}
+		if !isDeckColumnType(colType) {
+			http.Redirect(w, r, "/html/deck?error=Unknown+column+type", http.StatusSeeOther)
+			return
+		}
+		if colType == "hashtag" {
+			col.Tag = normalizeHashtag(r.FormValue("tag_" + strconv.Itoa(i)))
+			if col.Tag == "" {
+				msg := fmt.Sprintf("Column %d needs a hashtag", i+1)
+				http.Redirect(w, r, "/html/deck?error="+escapeURLParam(msg), http.StatusSeeOther)
+				return
+			}
+		}
+		if seen[col] {
+			http.Redirect(w, r, "/html/deck?error="+escapeURLParam(col.Title()+" is already a column"), http.StatusSeeOther)
+			return
+		}
+		seen[col] = true
+		columns = append(columns, col)
+	}
+	if len(columns) < minDeckColumns {
+		msg := fmt.Sprintf("Choose at least %d columns", minDeckColumns)
+		http.Redirect(w, r, "/html/deck?error="+escapeURLParam(msg), http.StatusSeeOther)
+		return
+	}
+
+	session.mu.Lock()
+	session.DeckColumns = columns
+	session.mu.Unlock()
+
+	http.Redirect(w, r, "/html/deck?success=Deck+saved", http.StatusSeeOther)
+}
+
+// isDeckColumnType reports whether t is a known column type
+func isDeckColumnType(t string) bool {
+	for _, opt := range deckColumnTypes {
+		if opt.Value == t {
+			return true
+		}
+	}
+	return false
+}
+
+// deckTemplate is parsed into the timeline template, which renders the deck when .Deck is set
+var deckTemplate = `{{define "deck-styles"}}
+    .deck-container { max-width: none; }
+    .deck-settings {
+      margin: 0 0 12px;
+      font-size: 0.9rem;
+    }
+    .deck-settings summary { cursor: pointer; color: var(--text-secondary); }
+    .deck-settings-form {
+      display: flex;
+      flex-wrap: wrap;
+      align-items: flex-end;
+      gap: 12px;
+      padding: 12px 0;
+    }
+    .deck-settings-row {
+      display: flex;
+      flex-direction: column;
+      gap: 4px;
+    }
+    .deck-settings-row input { width: 10em; }
+    .deck-column {
+      min-width: 0;
+      margin-bottom: 24px;
+    }
+    .deck-column-title {
+      font-size: 1rem;
+      margin: 0 0 8px;
+      padding-bottom: 8px;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .deck-column-title a { color: var(--text-primary); text-decoration: none; }
+    .deck-column-error { color: var(--text-muted); }
+    @media (min-width: 1000px) {
+      .deck { display: grid; gap: 16px; align-items: start; }
+      .deck-cols-2 { grid-template-columns: repeat(2, minmax(0, 1fr)); }
+      .deck-cols-3 { grid-template-columns: repeat(3, minmax(0, 1fr)); }
+      .deck-cols-4 { grid-template-columns: repeat(4, minmax(0, 1fr)); }
+      .deck-column {
+        max-height: calc(100vh - 160px);
+        overflow-y: auto;
+        margin-bottom: 0;
+      }
+    }
+{{end}}{{define "deck"}}{{with .Deck}}
+      <details class="deck-settings">
+        <summary>Columns</summary>
+        <form method="POST" action="/html/deck" class="deck-settings-form">
+          <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+          {{range .Slots}}
+          <div class="deck-settings-row">
+            <label for="deck-type-{{.Index}}">Column {{.Number}}</label>
+            <select id="deck-type-{{.Index}}" name="type_{{.Index}}">
+              <option value="">None</option>
+              {{range .Options}}<option value="{{.Value}}"{{if .Selected}} selected{{end}}>{{.Label}}</option>
+              {{end}}
+            </select>
+            <label for="deck-tag-{{.Index}}" class="sr-only">Hashtag for column {{.Number}}</label>
+            <input type="text" id="deck-tag-{{.Index}}" name="tag_{{.Index}}" value="{{.Tag}}" placeholder="hashtag">
+          </div>
+          {{end}}
+          <button type="submit">Save</button>
+        </form>
+      </details>
+      <div class="deck deck-cols-{{len .Columns}}">
+        {{range .Columns}}
+        <section class="deck-column" aria-labelledby="deck-column-{{.Index}}">
+          <h2 class="deck-column-title" id="deck-column-{{.Index}}"><a href="{{.SourceURL}}">{{.Title}}</a></h2>
+          {{if .Error}}
+          <p class="deck-column-error">{{.Error}}</p>
+          {{else}}
+          {{.HTML}}
+          {{end}}
+          {{if or .NewestURL .OlderURL}}
+          <div class="pagination">
+            {{if .NewestURL}}<a href="{{.NewestURL}}" class="link">← Newest</a>{{end}}
+            {{if .OlderURL}}<a href="{{.OlderURL}}" class="link">Older →</a>{{end}}
+          </div>
+          {{end}}
+        </section>
+        {{end}}
+      </div>
+{{end}}{{end}}`
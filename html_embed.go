@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Note embeds
+// GET /embed/{id} renders one note as a small self-contained page for <iframe> embeds on
+// other sites: inline CSS, no nav, no session and no cookies, and framing is allowed from
+// anywhere. GET /oembed?url=... answers oEmbed (https://oembed.com) requests for thread
+// and embed URLs with the iframe markup; thread pages link to it for discovery.
+// Notes can't change once published, so both responses are cacheable for a day.
+
+// embedCacheControl is used for found notes; misses are cached briefly
+const embedCacheControl = "public, max-age=86400, stale-while-revalidate=604800"
+
+// embedHintCacheControl is used for notes found only on relays the link's hints named:
+// anyone can make those up, so shared caches mustn't keep the page under this domain
+const embedHintCacheControl = "private, max-age=300"
+
+// Default iframe size suggested by /oembed, shrunk to fit maxwidth/maxheight
+const (
+	embedDefaultWidth  = 550
+	embedDefaultHeight = 360
+)
+
+// HTMLEmbedData is the data for the embed page
+type HTMLEmbedData struct {
+	SiteName    string
+	NotFound    bool
+	ThreadURL   string
+	Author      HTMLDMParticipant
+	AuthorURL   string
+	ContentHTML RenderedContent
+	Date        string // Absolute, since the page is cached
+	Datetime    string // RFC 3339 for <time datetime>
+}
+
+// oEmbedResponse is a "rich" oEmbed response
+type oEmbedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	AuthorName   string `json:"author_name,omitempty"`
+	AuthorURL    string `json:"author_url,omitempty"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+var cachedEmbedTemplate *template.Template
+
+func init() {
+	var err error
+	cachedEmbedTemplate, err = template.New("embed").Parse(htmlEmbedTemplate)
+	if err != nil {
+		log.Fatalf("Failed to compile embed template: %v", err)
+	}
+}
+
+// embedHeaders is securityHeaders for embeds: any site may frame the page, scripts are off
+func embedHeaders(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// No X-Frame-Options: it has no "allow all" value, frame-ancestors covers it
+		csp := "default-src 'self'; " +
+			"img-src * data:; " +
+			"media-src *; " +
+			"frame-src https://www.youtube.com https://www.youtube-nocookie.com; " +
+			"style-src 'unsafe-inline'; " +
+			"script-src 'none'; " +
+			"frame-ancestors *"
+		w.Header().Set("Content-Security-Policy", csp)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		next(w, r)
+	}
+}
+
+// parseEmbedID accepts a hex event id, note1 or nevent1, returning the id and any relay hints
+func parseEmbedID(identifier string) (string, []string) {
+	switch {
+	case strings.HasPrefix(identifier, "note1"):
+		if id, err := DecodeNote(identifier); err == nil {
+			return id, nil
+		}
+	case strings.HasPrefix(identifier, "nevent1"):
+		if ne, err := DecodeNEvent(identifier); err == nil {
+			return ne.EventID, ne.RelayHints
+		}
+	case isValidEventID(identifier):
+		return identifier, nil
+	}
+	return "", nil
+}
+
+// fetchEmbedNote fetches a text note and its author's profile, trying relay hints on a miss
+// Returns a nil event if the note can't be found, isn't a text note or doesn't verify;
+// fromHints reports that only a hinted relay had it
+func fetchEmbedNote(eventID string, hints []string) (evt *Event, profile *ProfileInfo, fromHints bool) {
+	relays := relaysFor(RelayPurposeRead)
+	evt = findEmbedNote(fetchEventByID(relays, eventID), eventID)
+	if evt == nil {
+		if extra := threadFallbackRelays(url.Values{"hint": hints}, relays); len(extra) > 0 {
+			evt = findEmbedNote(fetchEventByID(extra, eventID), eventID)
+			fromHints = true
+		}
+	}
+	if evt == nil {
+		return nil, nil, false
+	}
+	return evt, fetchProfiles(relays, []string{evt.PubKey})[evt.PubKey], fromHints
+}
+
+// findEmbedNote returns the text note with the requested ID whose ID hash and
+// signature check out, so a relay can't answer with someone else's note or altered content
+func findEmbedNote(events []Event, eventID string) *Event {
+	for i := range events {
+		if evt := &events[i]; evt.ID == eventID && evt.Kind == 1 && verifyEvent(evt) {
+			return evt
+		}
+	}
+	return nil
+}
+
+// htmlEmbedHandler renders a note for embedding
+// GET /embed/{nevent|note|hex id}
+func htmlEmbedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	eventID, hints := parseEmbedID(strings.TrimPrefix(r.URL.Path, "/embed/"))
+	if eventID == "" {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	data := HTMLEmbedData{
+		SiteName:  branding().SiteName,
+		ThreadURL: canonicalThreadPath(eventID),
+	}
+	status, cacheControl := http.StatusOK, embedCacheControl
+
+	evt, profile, fromHints := fetchEmbedNote(eventID, hints)
+	if evt == nil {
+		data.NotFound = true
+		status, cacheControl = http.StatusNotFound, "public, max-age=300"
+	} else {
+		if fromHints {
+			cacheControl = embedHintCacheControl
+		}
+		relays := relaysFor(RelayPurposeRead)
+		resolvedRefs := batchResolveNostrRefs(extractNostrRefs([]string{evt.Content}), relays)
+		created := time.Unix(evt.CreatedAt, 0).UTC()
+		data.Author = newDMParticipant(evt.PubKey, profile)
+		data.AuthorURL = canonicalProfilePath(evt.PubKey)
+		data.ContentHTML = processContentToHTMLFull(evt.Content, relays, resolvedRefs, nil)
+		data.Date = created.Format("Jan 2, 2006 · 15:04 UTC")
+		data.Datetime = created.Format(time.RFC3339)
+	}
+
+	var buf strings.Builder
+	if err := cachedEmbedTemplate.Execute(&buf, data); err != nil {
+		log.Printf("Error rendering embed: %v", err)
+		http.Error(w, "Failed to render note", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(status)
+	w.Write([]byte(buf.String()))
+}
+
+// requestOrigin returns the scheme and host the request was made to
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// oEmbedHandler returns embed markup for a thread or embed URL on this instance
+// GET /oembed?url=...&maxwidth=...&maxheight=...&format=json
+func oEmbedHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if format := q.Get("format"); format != "" && format != "json" {
+		http.Error(w, "Only format=json is supported", http.StatusNotImplemented)
+		return
+	}
+
+	target, err := url.Parse(strings.TrimSpace(q.Get("url")))
+	if err != nil || (target.Host != "" && !strings.EqualFold(target.Host, r.Host)) {
+		http.Error(w, "Not a URL on this site", http.StatusNotFound)
+		return
+	}
+	var identifier string
+	hints := target.Query()["hint"]
+	switch {
+	case strings.HasPrefix(target.Path, "/html/thread/"):
+		identifier = strings.TrimPrefix(target.Path, "/html/thread/")
+	case strings.HasPrefix(target.Path, "/embed/"):
+		identifier = strings.TrimPrefix(target.Path, "/embed/")
+	}
+	eventID, idHints := parseEmbedID(identifier)
+	if eventID == "" {
+		http.Error(w, "Not a note URL", http.StatusNotFound)
+		return
+	}
+	hints = append(idHints, hints...)
+
+	evt, profile, fromHints := fetchEmbedNote(eventID, hints)
+	if evt == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	origin := requestOrigin(r)
+	if target.Host != "" && (target.Scheme == "http" || target.Scheme == "https") {
+		origin = target.Scheme + "://" + target.Host
+	}
+	width, height := embedDefaultWidth, embedDefaultHeight
+	if n, err := strconv.Atoi(q.Get("maxwidth")); err == nil && n > 0 {
+		width = min(width, n)
+	}
+	if n, err := strconv.Atoi(q.Get("maxheight")); err == nil && n > 0 {
+		height = min(height, n)
+	}
+
+	// The iframe points at the hex id; hints are only needed to find the note once
+	author := newDMParticipant(evt.PubKey, profile)
+	embedURL := origin + "/embed/" + evt.ID
+	resp := oEmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: branding().SiteName,
+		ProviderURL:  origin + "/",
+		AuthorName:   author.Name,
+		AuthorURL:    origin + canonicalProfilePath(evt.PubKey),
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" style="border:0;max-width:100%%" loading="lazy" title="%s"></iframe>`,
+			html.EscapeString(embedURL), width, height, html.EscapeString("Note by "+author.Name)),
+		Width:    width,
+		Height:   height,
+		CacheAge: 86400,
+	}
+	if fromHints {
+		resp.CacheAge = 300
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if fromHints {
+		w.Header().Set("Cache-Control", embedHintCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", embedCacheControl)
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// htmlEmbedTemplate is standalone: no layout, nav or session, links open in a new tab
+var htmlEmbedTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>{{if .NotFound}}Note not found{{else}}Note by {{.Author.Name}}{{end}} - {{.SiteName}}</title>
+  <base target="_blank">
+  <style>
+    :root {
+      --bg: #ffffff;
+      --text: #24292e;
+      --muted: #666666;
+      --border: #e1e4e8;
+      --accent: #667eea;
+    }
+    @media (prefers-color-scheme: dark) {
+      :root {
+        --bg: #1e1e1e;
+        --text: #e4e4e7;
+        --muted: #a1a1aa;
+        --border: #333333;
+        --accent: #8b9cf7;
+      }
+    }
+    * { box-sizing: border-box; }
+    body {
+      margin: 0;
+      font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif;
+      background: var(--bg);
+      color: var(--text);
+    }
+    .embed {
+      border: 1px solid var(--border);
+      border-radius: 8px;
+      padding: 16px;
+    }
+    .embed a { color: var(--accent); }
+    .embed-author {
+      display: flex;
+      align-items: center;
+      gap: 10px;
+      font-weight: 600;
+      text-decoration: none;
+    }
+    .embed-author img {
+      width: 40px;
+      height: 40px;
+      border-radius: 50%;
+      object-fit: cover;
+    }
+    .embed-content {
+      font-size: 15px;
+      line-height: 1.6;
+      margin: 12px 0;
+      white-space: pre-wrap;
+      word-wrap: break-word;
+    }
+    .embed-content img, .embed-content video { max-width: 100%; border-radius: 8px; display: block; margin-top: 8px; }
+    .embed-content audio { width: 100%; }
+    .embed-content .youtube-embed { width: 100%; aspect-ratio: 16/9; border: 0; border-radius: 8px; }
+    .embed-footer {
+      display: flex;
+      justify-content: space-between;
+      gap: 12px;
+      font-size: 13px;
+      color: var(--muted);
+    }
+  </style>
+</head>
+<body>
+  <article class="embed">
+  {{if .NotFound}}
+    <p>This note couldn't be found. It may have been deleted, or it isn't on the relays {{.SiteName}} uses.</p>
+    <div class="embed-footer"><a href="{{.ThreadURL}}">Look on {{.SiteName}} →</a></div>
+  {{else}}
+    <a href="{{.AuthorURL}}" class="embed-author">
+      {{if .Author.Picture}}<img src="{{.Author.Picture}}" alt="" loading="lazy">{{end}}
+      <span>{{.Author.Name}}</span>
+    </a>
+    <div class="embed-content">{{.ContentHTML.HTML}}</div>
+    <div class="embed-footer">
+      <time datetime="{{.Datetime}}">{{.Date}}</time>
+      <a href="{{.ThreadURL}}">View thread on {{.SiteName}} →</a>
+    </div>
+  {{end}}
+  </article>
+</body>
+</html>
+`
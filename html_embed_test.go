@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestFindEmbedNoteVerifiesEvents(t *testing.T) {
+	alice := testutil.NewKeypair("embed-verify-alice")
+	now := time.Now().Unix()
+	real := testutil.Note(alice, now-60, "the real note")
+	other := testutil.Note(alice, now-30, "a different note")
+
+	asEvent := func(e testutil.Event) Event {
+		return Event{ID: e.ID, PubKey: e.PubKey, CreatedAt: e.CreatedAt, Kind: e.Kind, Tags: e.Tags, Content: e.Content, Sig: e.Sig}
+	}
+	forged := asEvent(real)
+	forged.Content = "forged content"
+	unsigned := asEvent(real)
+	unsigned.Sig = ""
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   bool
+	}{
+		{"real note", []Event{asEvent(real)}, true},
+		{"another note answering the ID", []Event{asEvent(other)}, false},
+		{"real ID and signature over forged content", []Event{forged}, false},
+		{"unsigned", []Event{unsigned}, false},
+		{"forged copy before the real one", []Event{forged, asEvent(real)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findEmbedNote(tt.events, real.ID)
+			if (got != nil) != tt.want {
+				t.Fatalf("findEmbedNote = %v, want found %v", got, tt.want)
+			}
+			if got != nil && got.Content != real.Content {
+				t.Errorf("content = %q, want %q", got.Content, real.Content)
+			}
+		})
+	}
+}
+
+func TestEmbedNoteFromHintIsNotPubliclyCached(t *testing.T) {
+	s := startTestServer(t)
+	hinted := testutil.NewRelay()
+	t.Cleanup(hinted.Close)
+	// Visitor-supplied loopback relays are only followed in dev mode
+	devModeEnabled = true
+	t.Cleanup(func() { devModeEnabled = false })
+
+	alice := testutil.NewKeypair("embed-hint-alice")
+	now := time.Now().Unix()
+	configured := testutil.Note(alice, now-60, "on the configured relay")
+	onlyHinted := testutil.Note(alice, now-30, "only on the hinted relay")
+	s.Relay.Publish(configured)
+	hinted.Publish(onlyHinted)
+
+	tests := []struct {
+		name      string
+		eventID   string
+		wantCache string
+	}{
+		{"configured relay", configured.ID, embedCacheControl},
+		{"hinted relay", onlyHinted.ID, embedHintCacheControl},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nevent := encodeTLV(t, "nevent", tt.eventID, hinted.URL())
+			resp, err := http.Get(s.URL + "/embed/" + nevent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d, want 200", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+			if !strings.Contains(string(body), "Note by") {
+				t.Errorf("embed page doesn't show the note:\n%s", body)
+			}
+
+			resp, err = http.Get(s.URL + "/oembed?url=" + s.URL + "/embed/" + nevent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("oEmbed Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+		})
+	}
+}
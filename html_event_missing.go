@@ -0,0 +1,117 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Missing-event fallback
+// Thread links from other clients often point at events none of our relays have.
+// Before giving up, the thread handler asks the relay hints carried over from an
+// nevent (?hint=) and a relay the visitor supplied (?relay=) once; if those miss too,
+// the visitor gets a page listing what was tried and a form to try another relay.
+
+// maxThreadHintRelays caps the extra relays queried for a missing event
+const maxThreadHintRelays = 4
+
+// HTMLEventNotFoundData is the data for the missing-event page
+type HTMLEventNotFoundData struct {
+	HTMLPageChrome
+	EventID string
+	Tried   []string
+	Hints   []string // carried through the retry form
+	Relay   string   // relay the visitor last asked for
+}
+
+func init() {
+	registerPageTemplate("event-not-found", htmlEventNotFoundContent)
+}
+
+// threadFallbackRelays returns the valid ?hint= and ?relay= relays that aren't already in relays
+func threadFallbackRelays(q url.Values, relays []string) []string {
+	seen := make(map[string]bool, len(relays))
+	for _, r := range relays {
+		seen[r] = true
+	}
+	var extra []string
+	for _, candidate := range append(q["hint"], q.Get("relay")) {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || seen[candidate] || !isVisitorRelayURLSafe(candidate) {
+			continue
+		}
+		seen[candidate] = true
+		extra = append(extra, candidate)
+		if len(extra) == maxThreadHintRelays {
+			break
+		}
+	}
+	return extra
+}
+
+// isVisitorRelayURLSafe checks a relay URL that came from a link or form rather than config
+// Same rules as the pool, except localhost is only allowed in dev mode
+func isVisitorRelayURLSafe(relayURL string) bool {
+	if !isRelayURLSafe(relayURL) {
+		return false
+	}
+	parsed, err := url.Parse(relayURL)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && ip.IsLoopback()) || host == "localhost" {
+		return devModeEnabled
+	}
+	return true
+}
+
+// renderEventNotFound renders the missing-event page with a 404 status
+func renderEventNotFound(w http.ResponseWriter, r *http.Request, eventID string, tried []string) {
+	q := r.URL.Query()
+	data := HTMLEventNotFoundData{
+		HTMLPageChrome: newPageChrome(r, "Event not found"),
+		EventID:        eventID,
+		Tried:          tried,
+		Relay:          q.Get("relay"),
+	}
+	for _, h := range q["hint"] {
+		if len(data.Hints) < maxThreadHintRelays && isVisitorRelayURLSafe(h) {
+			data.Hints = append(data.Hints, h)
+		}
+	}
+	if data.Relay != "" && !isVisitorRelayURLSafe(data.Relay) {
+		data.Error = "That relay URL can't be used - it must be a public ws:// or wss:// address"
+	}
+
+	html, err := renderPageHTML("event-not-found", data)
+	if err != nil {
+		log.Printf("Error rendering event-not-found page: %v", err)
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(html))
+}
+
+var htmlEventNotFoundContent = `{{define "content"}}
+<h1>Event not found</h1>
+<p>None of the relays we asked have this event. It may have been deleted, or it may only be on relays this instance doesn't use.</p>
+<p class="mono text-sm">{{.EventID}}</p>
+<h2>Relays tried</h2>
+<ul class="tried-relays">
+  {{range .Tried}}<li class="mono text-sm">{{.}}</li>{{end}}
+</ul>
+<form method="GET" action="/html/thread/{{.EventID}}" class="card">
+  {{range .Hints}}<input type="hidden" name="hint" value="{{.}}">{{end}}
+  <label for="retry-relay">Try another relay</label>
+  <input type="url" id="retry-relay" name="relay" value="{{.Relay}}" placeholder="wss://relay.example.com" pattern="wss?://.+" required>
+  <button type="submit" class="btn">Look again</button>
+</form>
+{{end}}
+{{define "styles"}}
+    .tried-relays { margin: 0 0 16px 20px; }
+{{end}}`
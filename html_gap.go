@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Timeline gaps
+// For logged-in users, the follows, global and "me" timelines remember the newest post
+// a first page showed (the feed's watermark, kept in the session). When a later first
+// page is full and its oldest post is still newer than the watermark, there are posts in
+// between that the user hasn't seen, so the page ends with a gap element linking to
+// them. The link is the ordinary until= cursor plus gap_from (the watermark) and gap_top
+// (the newest post on that first page); each older page repeats the gap until one
+// reaches posts at or below gap_from. Only then does the watermark move up to gap_top,
+// so leaving without reading through the gap keeps it for the next visit.
+
+// TimelineGap is the "posts you missed" element at the end of a timeline page
+type TimelineGap struct {
+	Missed string // Time span still to load, e.g. "3 hours"
+	URL    string // Next page, carrying the gap parameters
+}
+
+// timelineGapKey returns the watermark key for a feed, "" for feeds without gap tracking
+func timelineGapKey(feedMode string, kinds []int) string {
+	switch feedMode {
+	case "follows", "global", "me":
+		return feedMode + ":" + joinKinds(kinds)
+	}
+	return ""
+}
+
+// feedWatermark returns the newest post time the user has read up to in a feed, 0 if unknown
+func feedWatermark(session *BunkerSession, key string) int64 {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.FeedWatermarks[key]
+}
+
+// advanceFeedWatermark raises a feed's watermark to ts; it never moves backward
+func advanceFeedWatermark(session *BunkerSession, key string, ts int64) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.FeedWatermarks == nil {
+		session.FeedWatermarks = make(map[string]int64)
+	}
+	if ts > session.FeedWatermarks[key] {
+		session.FeedWatermarks[key] = ts
+	}
+}
+
+// timelineGapSpan returns how many seconds of posts lie between a page and the watermark
+// ok is false when there's no gap: no watermark, a page that wasn't full (the feed has
+// nothing older to page to), or a page that already reaches the watermark
+func timelineGapSpan(watermark, oldest int64, fullPage bool) (seconds int64, ok bool) {
+	if watermark <= 0 || !fullPage || oldest <= watermark {
+		return 0, false
+	}
+	return oldest - watermark, true
+}
+
+// timelineGap applies the gap rules to a chronological page (newest first) and returns
+// the gap element, nil if there's none. While a gap is open, nextURL gets the gap cursor
+func timelineGap(session *BunkerSession, key string, q url.Values, items []EventItem, limit int, until *int64, nextURL *string) *TimelineGap {
+	from, top := parseGapParams(q)
+	if len(items) == 0 {
+		// An empty older page is the end of the feed, so an open gap has been read through
+		if until != nil && from > 0 {
+			advanceFeedWatermark(session, key, top)
+		}
+		return nil
+	}
+	if until == nil {
+		// First page: the gap runs from the watermark to the newest post shown
+		from, top = feedWatermark(session, key), items[0].CreatedAt
+	} else if from == 0 {
+		return nil // An ordinary older page
+	}
+
+	span, ok := timelineGapSpan(from, items[len(items)-1].CreatedAt, len(items) >= limit)
+	if !ok {
+		// Reached posts read before, or the end of the feed: the gap has been read through
+		advanceFeedWatermark(session, key, top)
+		return nil
+	}
+	*nextURL += gapParams(from, top)
+	return &TimelineGap{Missed: formatGapSpan(span), URL: *nextURL}
+}
+
+// gapParams formats the gap cursor for a pagination URL
+func gapParams(from, top int64) string {
+	return "&gap_from=" + strconv.FormatInt(from, 10) + "&gap_top=" + strconv.FormatInt(top, 10)
+}
+
+// parseGapParams reads gap_from and gap_top; both are 0 unless they form a valid gap
+func parseGapParams(q url.Values) (from, top int64) {
+	fromParam, topParam := parseInt64(q.Get("gap_from")), parseInt64(q.Get("gap_top"))
+	if fromParam == nil || topParam == nil || *fromParam <= 0 || *topParam <= *fromParam {
+		return 0, 0
+	}
+	return *fromParam, *topParam
+}
+
+// formatGapSpan renders a gap's length for the "load the ... you missed" link
+func formatGapSpan(seconds int64) string {
+	unit, n := "minute", seconds/60
+	switch {
+	case seconds >= 2*86400:
+		unit, n = "day", seconds/86400
+	case seconds >= 3600:
+		unit, n = "hour", seconds/3600
+	}
+	if n <= 1 {
+		return unit // "the hour you missed"
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// timelineGapTemplate is parsed into the timeline template; use {{template "timeline-gap" .Gap}}
+var timelineGapTemplate = `{{define "timeline-gap"}}
+      <div class="timeline-gap" role="separator">
+        <span class="timeline-gap-line" aria-hidden="true"></span>
+        <a href="{{.URL}}" class="timeline-gap-link">Load the {{.Missed}} you missed</a>
+        <span class="timeline-gap-line" aria-hidden="true"></span>
+      </div>
+{{end}}
+{{define "timeline-gap-styles"}}
+    .timeline-gap { display: flex; align-items: center; gap: 12px; margin: 16px 0; }
+    .timeline-gap-line { flex: 1; border-top: 2px dashed var(--border-color); }
+    .timeline-gap-link { font-size: 14px; font-weight: 600; color: var(--accent); text-decoration: none; white-space: nowrap; }
+    .timeline-gap-link:hover { text-decoration: underline; }
+{{end}}`
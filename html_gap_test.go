@@ -0,0 +1,242 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+// gapPage makes a newest-first page with one item per timestamp
+func gapPage(timestamps ...int64) []EventItem {
+	items := make([]EventItem, len(timestamps))
+	for i, ts := range timestamps {
+		items[i] = EventItem{CreatedAt: ts}
+	}
+	return items
+}
+
+func TestTimelineGapSpan(t *testing.T) {
+	tests := []struct {
+		name              string
+		watermark, oldest int64
+		fullPage          bool
+		want              int64
+		wantOK            bool
+	}{
+		{"no watermark", 0, 1000, true, 0, false},
+		{"page not full", 500, 1000, false, 0, false},
+		{"oldest equals watermark", 1000, 1000, true, 0, false},
+		{"oldest below watermark", 1000, 900, true, 0, false},
+		{"one second above watermark", 999, 1000, true, 1, true},
+		{"gap", 400, 1000, true, 600, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := timelineGapSpan(tt.watermark, tt.oldest, tt.fullPage)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("timelineGapSpan(%d, %d, %v) = %d, %v, want %d, %v", tt.watermark, tt.oldest, tt.fullPage, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseGapParams(t *testing.T) {
+	tests := []struct {
+		query     string
+		from, top int64
+	}{
+		{"gap_from=100&gap_top=200", 100, 200},
+		{"gap_from=100&gap_top=100", 0, 0}, // equal bounds are no gap
+		{"gap_from=200&gap_top=100", 0, 0},
+		{"gap_from=0&gap_top=100", 0, 0},
+		{"gap_from=-5&gap_top=100", 0, 0},
+		{"gap_from=100", 0, 0},
+		{"gap_top=100", 0, 0},
+		{"gap_from=abc&gap_top=100", 0, 0},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		if from, top := parseGapParams(q); from != tt.from || top != tt.top {
+			t.Errorf("parseGapParams(%q) = %d, %d, want %d, %d", tt.query, from, top, tt.from, tt.top)
+		}
+	}
+}
+
+func TestTimelineGap(t *testing.T) {
+	const key = "follows:1"
+	until := func(ts int64) *int64 { return &ts }
+
+	tests := []struct {
+		name          string
+		watermark     int64
+		query         string
+		items         []EventItem
+		limit         int
+		until         *int64
+		wantGap       bool
+		wantNext      string // nextURL after the call, starting from "/next"
+		wantWatermark int64
+	}{
+		{
+			name:          "first page without a watermark sets it to the newest post",
+			items:         gapPage(1000, 900, 800),
+			limit:         3,
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "full first page above the watermark opens a gap",
+			watermark:     500,
+			items:         gapPage(1000, 900, 800),
+			limit:         3,
+			wantGap:       true,
+			wantNext:      "/next&gap_from=500&gap_top=1000",
+			wantWatermark: 500,
+		},
+		{
+			name:          "first page whose oldest post is the watermark has no gap",
+			watermark:     800,
+			items:         gapPage(1000, 900, 800),
+			limit:         3,
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "first page one second above the watermark still has a gap",
+			watermark:     799,
+			items:         gapPage(1000, 900, 800),
+			limit:         3,
+			wantGap:       true,
+			wantNext:      "/next&gap_from=799&gap_top=1000",
+			wantWatermark: 799,
+		},
+		{
+			name:          "short first page is the whole feed",
+			watermark:     500,
+			items:         gapPage(1000, 900),
+			limit:         3,
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "all posts at one timestamp equal to the watermark",
+			watermark:     1000,
+			items:         gapPage(1000, 1000, 1000),
+			limit:         3,
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "older page inside the gap carries it on",
+			watermark:     500,
+			query:         "gap_from=500&gap_top=1000",
+			items:         gapPage(799, 700, 600),
+			limit:         3,
+			until:         until(800),
+			wantGap:       true,
+			wantNext:      "/next&gap_from=500&gap_top=1000",
+			wantWatermark: 500,
+		},
+		{
+			name:          "older page reaching gap_from closes the gap",
+			watermark:     500,
+			query:         "gap_from=500&gap_top=1000",
+			items:         gapPage(599, 550, 500),
+			limit:         3,
+			until:         until(600),
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "short older page closes the gap",
+			watermark:     500,
+			query:         "gap_from=500&gap_top=1000",
+			items:         gapPage(599),
+			limit:         3,
+			until:         until(600),
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "empty older page closes the gap",
+			watermark:     500,
+			query:         "gap_from=500&gap_top=1000",
+			limit:         3,
+			until:         until(600),
+			wantNext:      "/next",
+			wantWatermark: 1000,
+		},
+		{
+			name:          "empty first page changes nothing",
+			watermark:     500,
+			limit:         3,
+			wantNext:      "/next",
+			wantWatermark: 500,
+		},
+		{
+			name:          "ordinary older page ignores the watermark",
+			watermark:     500,
+			items:         gapPage(799, 700, 600),
+			limit:         3,
+			until:         until(800),
+			wantNext:      "/next",
+			wantWatermark: 500,
+		},
+		{
+			name:          "closing an old gap never lowers the watermark",
+			watermark:     2000,
+			query:         "gap_from=500&gap_top=1000",
+			items:         gapPage(599),
+			limit:         3,
+			until:         until(600),
+			wantNext:      "/next",
+			wantWatermark: 2000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &BunkerSession{}
+			if tt.watermark > 0 {
+				session.FeedWatermarks = map[string]int64{key: tt.watermark}
+			}
+			q, _ := url.ParseQuery(tt.query)
+			next := "/next"
+
+			gap := timelineGap(session, key, q, tt.items, tt.limit, tt.until, &next)
+			if (gap != nil) != tt.wantGap {
+				t.Errorf("gap = %+v, want gap %v", gap, tt.wantGap)
+			}
+			if gap != nil && gap.URL != next {
+				t.Errorf("gap URL %q differs from next page %q", gap.URL, next)
+			}
+			if next != tt.wantNext {
+				t.Errorf("next = %q, want %q", next, tt.wantNext)
+			}
+			if got := feedWatermark(session, key); got != tt.wantWatermark {
+				t.Errorf("watermark = %d, want %d", got, tt.wantWatermark)
+			}
+		})
+	}
+}
+
+func TestFormatGapSpan(t *testing.T) {
+	tests := []struct {
+		seconds int64
+		want    string
+	}{
+		{1, "minute"},
+		{119, "minute"},
+		{120, "2 minutes"},
+		{3599, "59 minutes"},
+		{3600, "hour"},
+		{7200, "2 hours"},
+		{2*86400 - 1, "47 hours"},
+		{2 * 86400, "2 days"},
+	}
+	for _, tt := range tests {
+		if got := formatGapSpan(tt.seconds); got != tt.want {
+			t.Errorf("formatGapSpan(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
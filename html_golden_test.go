@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Golden-file tests for the timeline's kind templates and the page templates
+// Each testdata/kinds/*.json fixture is one event; it's rendered the way the timeline
+// renders it (EventItem -> renderHTML -> template) as a fragment and compared with
+// testdata/golden/<name>.html. Adding support for a kind means adding a fixture.
+// Page, login and quote templates are rendered with fixed data.
+//
+//	go test -run TestKindTemplatesGolden -update
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files from the current output")
+
+var (
+	relativeTimeRe = regexp.MustCompile(`\b(just now|yesterday|\d+ (mins?|hours?|days?|weeks?|months?|years?) ago)\b`)
+	blankLinesRe   = regexp.MustCompile(`\n{2,}`)
+	styleBlockRe   = regexp.MustCompile(`(?s)<style>.*?</style>`)
+)
+
+// normalizeGolden drops what changes between runs (relative times), the shared
+// stylesheet and whitespace noise
+func normalizeGolden(html string) string {
+	html = styleBlockRe.ReplaceAllString(html, "<style>STYLES</style>")
+	html = relativeTimeRe.ReplaceAllString(html, "TIME")
+	lines := strings.Split(html, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n")) + "\n"
+}
+
+// renderKindFixture renders one fixture event as a timeline fragment
+func renderKindFixture(t *testing.T, evt Event) string {
+	t.Helper()
+	resp := TimelineResponse{Items: []EventItem{{
+		ID:        evt.ID,
+		Kind:      evt.Kind,
+		Pubkey:    evt.PubKey,
+		CreatedAt: evt.CreatedAt,
+		Content:   evt.Content,
+		Tags:      evt.Tags,
+		Sig:       evt.Sig,
+	}}}
+	html, err := renderHTML(resp, nil, nil, []int{evt.Kind}, 20, nil, "", "", false, "", "/html/timeline", "", "", "", false, nil, true)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return normalizeGolden(html)
+}
+
+func TestKindTemplatesGolden(t *testing.T) {
+	// Relays for anything a template looks up (live event participants, quoted notes)
+	startTestServer(t)
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "kinds", "*.json"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no fixtures in testdata/kinds: %v", err)
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var evt Event
+			if err := json.Unmarshal(data, &evt); err != nil {
+				t.Fatalf("parse fixture: %v", err)
+			}
+			checkGolden(t, name, renderKindFixture(t, evt))
+		})
+	}
+}
+
+var fixedGeneratedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestPageTemplatesGolden(t *testing.T) {
+	startTestServer(t)
+	chrome := func(title string) HTMLPageChrome {
+		return HTMLPageChrome{Title: title, LoggedIn: true, CSRFToken: "CSRF", GeneratedAt: fixedGeneratedAt}
+	}
+	hostile := `<script>alert(1)</script>"><img src=x onerror=alert(1)>`
+
+	pages := map[string]struct {
+		name string
+		data interface{}
+	}{
+		"page-pending": {"pending", HTMLPendingData{HTMLPageChrome: chrome("Unsent"), Items: []HTMLPendingEvent{
+			{Token: "tok1", Label: "Reply", Content: hostile, TargetURL: "/html/thread/abc", Relays: []string{"wss://relay.example.com"}, Error: "Operation failed", FailedAgo: "just now"},
+			{Token: "tok2", Label: "Note", Content: "", Error: "No relay accepted it", FailedAgo: "5 mins ago"},
+		}}},
+		"page-pending-empty": {"pending", HTMLPendingData{HTMLPageChrome: chrome("Unsent")}},
+		"page-crawler": {"crawler", HTMLCrawlerPageData{HTMLPageChrome: chrome("Note by Alice"), CanonicalURL: "/html/thread/abc", Found: true, Noun: "note", Text: hostile,
+			Preview: &PreviewMeta{Type: "article", Title: "Note by Alice", Description: hostile, URL: "https://example.com/html/thread/abc", Image: "https://example.com/a.png?x=\"><script>", LargeImage: true}}},
+		"page-crawler-missing": {"crawler", HTMLCrawlerPageData{HTMLPageChrome: HTMLPageChrome{Title: "Note", GeneratedAt: fixedGeneratedAt}, CanonicalURL: "/html/thread/abc", Noun: "note"}},
+		"page-profile-summary": {"profile-summary", HTMLProfileSummaryData{HTMLPageChrome: chrome("Alice"), Summary: HTMLProfileSummary{
+			Author: HTMLDMParticipant{Pubkey: "abc", Npub: "npub1abc", Name: hostile}, Nip05: "alice@example.com", About: hostile, Cached: true,
+			YouFollow: true, MutualNames: []string{"Bob"}, MutualOthers: 2, ProfileURL: "/html/profile/abc", SummaryURL: "/html/profile/abc/summary", LoggedIn: true, CSRFToken: "CSRF",
+		}}},
+		"page-inspect": {"inspect", HTMLInspectData{HTMLPageChrome: chrome("Inspect"), EventID: "abc", JSON: `{"content":"` + hostile + `"}`,
+			Tags: []HTMLInspectTag{{Name: "t", Values: []string{hostile}, Explanation: "Hashtag"}}, ComputedID: "abc", IDValid: true}},
+	}
+	for golden, page := range pages {
+		t.Run(golden, func(t *testing.T) {
+			html, err := renderPageHTML(page.name, page.data)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			checkGolden(t, golden, normalizeGolden(html))
+		})
+	}
+
+	t.Run("login", func(t *testing.T) {
+		var buf strings.Builder
+		err := cachedLoginTemplate.Execute(&buf, struct {
+			Title, Error, Success, NostrConnectURL, Secret, ServerPubKey, ThemeClass string
+			QRCodeDataURL                                                            template.URL
+			Announcement                                                             *Announcement
+		}{Title: "Login with Nostr Connect", Error: hostile, NostrConnectURL: "nostrconnect://abc?relay=wss%3A%2F%2Frelay.example.com", Secret: "secret", ServerPubKey: "abc"})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		checkGolden(t, "login", normalizeGolden(buf.String()))
+	})
+
+	t.Run("quote", func(t *testing.T) {
+		var buf strings.Builder
+		err := cachedQuoteTemplate.Execute(&buf, struct {
+			Title, ThemeClass, ThemeLabel, UserDisplayName, NpubShort, Error, CSRFToken, ComposerDraft string
+			LoggedIn                                                                                   bool
+			QuotedEvent                                                                                Event
+			AuthorProfile                                                                              *ProfileInfo
+			GeneratedAt                                                                                time.Time
+			Announcement                                                                               *Announcement
+		}{Title: "Quote Note", UserDisplayName: "Alice", NpubShort: "npub1abc...xyz", CSRFToken: "CSRF", LoggedIn: true, ComposerDraft: hostile,
+			QuotedEvent: Event{ID: "abc", PubKey: "def", Kind: 1, CreatedAt: 1700000000, Content: hostile}, GeneratedAt: fixedGeneratedAt})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		checkGolden(t, "quote", normalizeGolden(buf.String()))
+	})
+}
+
+// checkGolden compares output with testdata/golden/<name>.html, or rewrites it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", "golden", name+".html")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("missing golden file (run with -update): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run with -update to accept):\n%s", golden, firstDiff(string(want), got))
+	}
+}
+
+// firstDiff returns the first differing line of two outputs, for readable failures
+func firstDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return "line " + strconv.Itoa(i+1) + ":\n  want: " + w + "\n  got:  " + g
+		}
+	}
+	return ""
+}
@@ -37,6 +37,10 @@ func checkUnreadNotifications(r *http.Request, session *BunkerSession, relays []
 	return hasUnreadNotifications(relays, pubkeyHex, lastSeen)
 }
 
+// reactionsGracePeriod is how long the timeline waits for uncached reaction counts
+// once everything else is ready, before rendering placeholders instead
+const reactionsGracePeriod = 250 * time.Millisecond
+
 func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters (same as JSON handler)
 	q := r.URL.Query()
@@ -49,10 +53,11 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 		// Use user's read relays if logged in and have a relay list (NIP-65)
 		if session != nil && session.Connected {
 			// If relay list not fetched yet, try to fetch it now
-			if session.UserRelayList == nil && session.UserPubKey != nil {
+			relayList := session.userRelayList()
+			if relayList == nil && session.UserPubKey != nil {
 				pubkeyHex := hex.EncodeToString(session.UserPubKey)
 				log.Printf("Fetching relay list for user %s...", pubkeyHex[:12])
-				relayList := fetchRelayList(pubkeyHex)
+				relayList = fetchRelayList(pubkeyHex)
 				if relayList != nil {
 					session.mu.Lock()
 					session.UserRelayList = relayList
@@ -60,27 +65,21 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 
-			if session.UserRelayList != nil && len(session.UserRelayList.Read) > 0 {
-				relays = session.UserRelayList.Read
+			if relayList != nil && len(relayList.Read) > 0 {
+				relays = relayList.Read
 				log.Printf("Using user's %d read relays from NIP-65", len(relays))
 			}
 		}
 
 		// Fallback to default relays
 		if len(relays) == 0 {
-			relays = []string{
-				"wss://relay.damus.io",
-				"wss://relay.nostr.band",
-				"wss://relay.primal.net",
-				"wss://nos.lol",
-				"wss://nostr.mom",
-			}
+			relays = relaysFor(RelayPurposeRead)
 		}
 	}
 
 	authors := parseStringList(q.Get("authors"))
 	kinds := parseIntList(q.Get("kinds"))
-	limit := parseLimit(q.Get("limit"), 50)
+	limit, linkLimit := feedLimit(session, q, 50)
 	since := parseInt64(q.Get("since"))
 	until := parseInt64(q.Get("until"))
 	fast := q.Get("fast") == "1" || q.Get("fast") == "true"
@@ -145,8 +144,50 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 		kinds = nil
 	}
 
+	// Hashtag view (?t=tag): everyone's posts with the tag, with a follow/unfollow action
+	hashtag := normalizeHashtag(q.Get("t"))
+	if hashtag != "" {
+		authors = nil
+	}
+
+	// Location filter for geo-tagged events: ?g= geohash prefix (also sent to relays as #g)
+	// and/or ?location= text matched against location tags after fetching
+	geoFilter := normalizeGeohash(q.Get("g"))
+	locationFilter := truncateString(strings.TrimSpace(q.Get("location")), maxLocationLength)
+	isLocationView := geoFilter != "" || locationFilter != ""
+
+	// Followed hashtags (kind 30015 interest sets) are merged into the follows feed
+	var followedTags []string
+	if feedMode == "follows" && len(authors) > 0 && q.Get("authors") == "" && !isBookmarksView && !isLocationView {
+		followedTags = followedTagsOf(session)
+	}
+
+	// Label feed (NIP-32): /labels/{namespace}/{label} shows events labeled by the
+	// people the viewer follows (anyone when logged out), fetched by ID like bookmarks
+	labelFeed, isLabelView := labelFeedFromPath(r)
+	var labelers []string
+	if isLabelView || !fast {
+		labelers = labelAuthorsFor(session, relays)
+	}
+	var labeledEventIDs []string
+	var labelPageUntil int64
+	if isLabelView {
+		followedTags = nil
+		labeledEventIDs, labelPageUntil = fetchLabeledEventIDs(relays, labelers, labelFeed, limit, until)
+		log.Printf("Found %d events labeled %s/%s", len(labeledEventIDs), labelFeed.Namespace, labelFeed.Value)
+		authors, kinds = nil, nil
+	}
+
 	// Check if we should filter out replies (default to true like JSON handler)
-	noReplies := q.Get("no_replies") != "0"
+	// Labeled replies are shown as-is
+	noReplies := q.Get("no_replies") != "0" && !isLabelView
+
+	// Engagement sort (?sort=top|trending&window=24h) for the follows, global and me feeds
+	sortMode, sortWindow := parseFeedSort(q.Get("sort"), q.Get("window"))
+	if isBookmarksView || isLabelView || hashtag != "" || isLocationView {
+		sortMode, sortWindow = "", ""
+	}
+	var sortNextOffset int
 
 	// Build filter - fetch more events if we're filtering replies, since many events are replies
 	fetchLimit := limit
@@ -156,6 +197,7 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 
 	var events []Event
 	var eose bool
+	var fromTags map[string]string // event ID -> followed hashtag it was included for
 
 	// Special case: fetch bookmarked events by ID
 	if isBookmarksView && len(bookmarkedEventIDs) > 0 {
@@ -163,12 +205,60 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 			IDs:   bookmarkedEventIDs,
 			Limit: len(bookmarkedEventIDs),
 		}
-		events, eose = fetchEventsFromRelaysCached(relays, filter)
+		events, eose = fetchEventsFromRelaysCachedCtx(r.Context(), relays, filter)
 		log.Printf("Fetched %d bookmarked events", len(events))
 	} else if isBookmarksView {
 		// No bookmarks found
 		events = []Event{}
 		eose = true
+	} else if isLabelView {
+		events, eose = []Event{}, true
+		if len(labeledEventIDs) > 0 {
+			filter := Filter{
+				IDs:   labeledEventIDs,
+				Limit: len(labeledEventIDs),
+			}
+			events, eose = fetchEventsFromRelaysCachedCtx(r.Context(), relays, filter)
+		}
+	} else if sortMode != "" {
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		endRank := traceSpan(r.Context(), "ranking")
+		ranking := engagementStats.Ranking(sortMode, sortWindow)
+		events, sortNextOffset = fetchRankedEvents(r.Context(), relays, ranking, max(offset, 0), limit, authors, kinds, noReplies)
+		endRank()
+		eose = true
+	} else if len(followedTags) > 0 {
+		// Separate parallel queries so the author filter stays efficient
+		authorFilter := Filter{
+			Authors: authors,
+			Kinds:   kinds,
+			Limit:   fetchLimit,
+			Since:   since,
+			Until:   until,
+		}
+		tagFilter := Filter{
+			Kinds: kinds,
+			Limit: fetchLimit,
+			Since: since,
+			Until: until,
+			Tags:  map[string][]string{"t": followedTags},
+		}
+		var tagEvents []Event
+		var tagEOSE bool
+		var fetchWG sync.WaitGroup
+		fetchWG.Add(2)
+		go func() {
+			defer fetchWG.Done()
+			events, eose = fetchEventsFromRelaysCachedCtx(r.Context(), relays, authorFilter)
+		}()
+		go func() {
+			defer fetchWG.Done()
+			defer traceSpan(r.Context(), "followed hashtags")()
+			tagEvents, tagEOSE = fetchEventsFromRelaysCachedCtx(r.Context(), relays, tagFilter)
+		}()
+		fetchWG.Wait()
+		events, fromTags = mergeFollowedTagEvents(events, tagEvents, authors, followedTags, fetchLimit)
+		eose = eose && tagEOSE
 	} else {
 		filter := Filter{
 			Authors: authors,
@@ -177,7 +267,32 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 			Since:   since,
 			Until:   until,
 		}
-		events, eose = fetchEventsFromRelaysCached(relays, filter)
+		if hashtag != "" {
+			filter.Tags = map[string][]string{"t": {hashtag}}
+		}
+		if geoFilter != "" {
+			if filter.Tags == nil {
+				filter.Tags = make(map[string][]string)
+			}
+			filter.Tags["g"] = []string{geoFilter}
+		}
+		events, eose = fetchEventsFromRelaysCachedCtx(r.Context(), relays, filter)
+	}
+
+	// Relays match #g exactly, so also check the prefix ourselves; names are only checked here
+	if isLocationView {
+		filtered := make([]Event, 0, len(events))
+		for _, evt := range events {
+			if matchesLocation(evt, geoFilter, locationFilter) {
+				filtered = append(filtered, evt)
+			}
+		}
+		events = filtered
+	}
+
+	// Public feeds hide events from brand-new pubkeys when NEW_KEY_EMBARGO is set
+	if feedMode == "global" && len(authors) == 0 && !isBookmarksView && !isLabelView {
+		events = newKeyEmbargo.Filter(events)
 	}
 
 	// Filter out replies (events with e tags) from main timeline
@@ -190,10 +305,12 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		events = filtered
-		// Apply original limit after filtering
-		if len(events) > limit {
-			events = events[:limit]
-		}
+	}
+
+	// Apply original limit after filtering; merged follows+hashtag feeds can return
+	// up to two fetches' worth even with replies shown. Bookmarks are shown in full.
+	if !isBookmarksView && len(events) > limit {
+		events = events[:limit]
 	}
 
 	// Filter out kind 30311 (live events) that don't have a streaming or recording URL
@@ -238,6 +355,7 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 	profiles := make(map[string]*ProfileInfo)
 	reactions := make(map[string]*ReactionsSummary)
 	replyCounts := make(map[string]int)
+	labels := make(map[string][]EventLabel)
 
 	var wg sync.WaitGroup
 
@@ -249,6 +367,7 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 			for pk := range pubkeySet {
 				pubkeys = append(pubkeys, pk)
 			}
+			defer traceSpan(r.Context(), "profiles")()
 			profiles = fetchProfiles(relays, pubkeys)
 		}()
 	}
@@ -258,36 +377,71 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer traceSpan(r.Context(), "reply counts")()
 			replyCounts = fetchReplyCounts(relays, eventIDs)
 		}()
 	}
 
-	// Only fetch reactions in full mode (slower)
+	// Only fetch reactions in full mode (slower). Cached counts are used as-is; missing
+	// ones are fetched without holding up the page: if they aren't in shortly after the
+	// rest is ready, the notes get placeholders and the fetch finishes in the background
+	// to fill the cache for the next load (and /html/reactions fragment requests)
+	var pendingReactions []string
+	var reactionsDone chan map[string]*ReactionsSummary
 	if !fast && len(eventIDs) > 0 {
+		reactions, pendingReactions = reactionCache.GetMultiple(eventIDs)
+		if len(pendingReactions) > 0 {
+			reactionsDone = make(chan map[string]*ReactionsSummary, 1)
+			go func(ids []string) {
+				defer traceSpan(r.Context(), "reactions")()
+				reactionsDone <- fetchReactionsCached(relays, ids)
+			}(pendingReactions)
+		}
+
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			reactions = fetchReactions(relays, eventIDs)
+			defer traceSpan(r.Context(), "labels")()
+			labels = fetchLabels(relays, eventIDs, labelers)
 		}()
 	}
 
 	wg.Wait()
 
+	if reactionsDone != nil {
+		select {
+		case fetched := <-reactionsDone:
+			for id, summary := range fetched {
+				reactions[id] = summary
+			}
+			pendingReactions = nil
+		case <-time.After(reactionsGracePeriod):
+			log.Printf("Reactions for %d events still loading, rendering placeholders", len(pendingReactions))
+		}
+	}
+	reactionsPending := make(map[string]bool, len(pendingReactions))
+	for _, id := range pendingReactions {
+		reactionsPending[id] = true
+	}
+
 	// Build response
 	items := make([]EventItem, len(events))
 	for i, evt := range events {
 		items[i] = EventItem{
-			ID:            evt.ID,
-			Kind:          evt.Kind,
-			Pubkey:        evt.PubKey,
-			CreatedAt:     evt.CreatedAt,
-			Content:       evt.Content,
-			Tags:          evt.Tags,
-			Sig:           evt.Sig,
-			RelaysSeen:    evt.RelaysSeen,
-			AuthorProfile: profiles[evt.PubKey],
-			Reactions:     reactions[evt.ID],
-			ReplyCount:    replyCounts[evt.ID],
+			ID:               evt.ID,
+			Kind:             evt.Kind,
+			Pubkey:           evt.PubKey,
+			CreatedAt:        evt.CreatedAt,
+			Content:          evt.Content,
+			Tags:             evt.Tags,
+			Sig:              evt.Sig,
+			RelaysSeen:       evt.RelaysSeen,
+			AuthorProfile:    profiles[evt.PubKey],
+			Reactions:        reactions[evt.ID],
+			ReactionsPending: reactionsPending[evt.ID],
+			ReplyCount:       replyCounts[evt.ID],
+			Labels:           labels[evt.ID],
+			FromTag:          fromTags[evt.ID],
 		}
 	}
 
@@ -301,16 +455,55 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
+	if isLabelView {
+		resp.Label = &labelFeed
+	}
+	resp.Hashtag = hashtag
+	resp.Geohash, resp.Location = geoFilter, locationFilter
+	resp.Sort, resp.Window = sortMode, sortWindow
+
 	// Add pagination if we have results
-	if len(items) > 0 {
+	// Label feeds page through the label events rather than the labeled events
+	if isLabelView && len(labeledEventIDs) > 0 && labelPageUntil > 0 {
+		nextUntil := labelPageUntil - 1
+		resp.Page.Until = &nextUntil
+		nextURL := fmt.Sprintf("%s?until=%d%s", labelFeed.URL(), nextUntil, limitParam(linkLimit))
+		if fast {
+			nextURL += "&fast=1"
+		}
+		resp.Page.Next = &nextURL
+	} else if sortMode != "" {
+		// Ranked feeds page by position in the ranking
+		if sortNextOffset > 0 {
+			nextURL := rankedPageURL(r.URL.Path, kinds, linkLimit, feedMode, sortMode, sortWindow, sortNextOffset)
+			if fast {
+				nextURL += "&fast=1"
+			}
+			resp.Page.Next = &nextURL
+		}
+	} else if len(items) > 0 && !isLabelView {
 		lastCreatedAt := items[len(items)-1].CreatedAt
 		resp.Page.Until = &lastCreatedAt
-		nextURL := buildPaginationURL(r.URL.Path, relays, authors, kinds, limit, lastCreatedAt)
+		nextURL := buildPaginationURL(r.URL.Path, relays, authors, kinds, linkLimit, lastCreatedAt)
 		// Preserve fast mode and feed mode in pagination
 		if fast {
 			nextURL += "&fast=1"
 		}
 		nextURL += "&feed=" + feedMode
+		if hashtag != "" {
+			nextURL += "&t=" + url.QueryEscape(hashtag)
+		}
+		if geoFilter != "" {
+			nextURL += "&g=" + geoFilter
+		}
+		if locationFilter != "" {
+			nextURL += "&location=" + url.QueryEscape(locationFilter)
+		}
+		// Gap tracking only for the plain feeds, not filtered views or deck columns
+		plainFeed := hashtag == "" && geoFilter == "" && locationFilter == "" && !isBookmarksView && q.Get("authors") == ""
+		if key := timelineGapKey(feedMode, kinds); key != "" && plainFeed && session != nil && session.Connected && q.Get("fragment") != "1" {
+			resp.Gap = timelineGap(session, key, q, items, limit, until, &nextURL)
+		}
 		resp.Page.Next = &nextURL
 
 		// Prefetch next page in background to warm the cache
@@ -323,7 +516,12 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 	successMsg := q.Get("success")
 
 	// Build current URL for reaction redirects
+	// Fragment requests come from the deck, which passes its own URL as return_url
 	currentURL := r.URL.Path + "?" + r.URL.RawQuery
+	fragment := q.Get("fragment") == "1"
+	if fragment && q.Get("return_url") != "" {
+		currentURL = sanitizeReturnURL(q.Get("return_url"))
+	}
 
 	// Get theme from cookie
 	themeClass, themeLabel := getThemeFromRequest(r)
@@ -335,10 +533,14 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check for unread notifications
+	endUnread := traceSpan(r.Context(), "unread notifications")
 	hasUnreadNotifs := checkUnreadNotifications(r, session, relays)
+	endUnread()
 
 	// Render HTML - showReactions is opposite of fast mode
-	html, err := renderHTML(resp, relays, authors, kinds, limit, session, errorMsg, successMsg, !fast, feedMode, currentURL, themeClass, themeLabel, csrfToken, hasUnreadNotifs)
+	endRender := traceSpan(r.Context(), "render")
+	html, err := renderHTML(resp, relays, authors, kinds, limit, session, errorMsg, successMsg, !fast, feedMode, currentURL, themeClass, themeLabel, csrfToken, hasUnreadNotifs, announcementFor(r), fragment)
+	endRender()
 	if err != nil {
 		log.Printf("Error rendering HTML: %v", err)
 		http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -347,12 +549,21 @@ func htmlTimelineHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "max-age=5")
+	if fragment && resp.Page.Next != nil {
+		w.Header().Set("Link", "<"+*resp.Page.Next+`>; rel="next"`)
+	}
 	w.Write([]byte(html))
 }
 
 func htmlThreadHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract event ID from path: /html/thread/{eventId}
 	eventID := strings.TrimPrefix(r.URL.Path, "/html/thread/")
+	if strings.HasPrefix(eventID, "note1") || strings.HasPrefix(eventID, "nevent1") {
+		if path := canonicalPathForIdentifier(eventID); path != "" {
+			redirectToCanonical(w, r, path)
+			return
+		}
+	}
 	if eventID == "" || !isValidEventID(eventID) {
 		http.Error(w, "Invalid event ID", http.StatusBadRequest)
 		return
@@ -361,13 +572,7 @@ func htmlThreadHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	relays := parseStringList(q.Get("relays"))
 	if len(relays) == 0 {
-		relays = []string{
-			"wss://relay.damus.io",
-			"wss://relay.nostr.band",
-			"wss://relay.primal.net",
-			"wss://nos.lol",
-			"wss://nostr.mom",
-		}
+		relays = relaysFor(RelayPurposeRead)
 	}
 
 	log.Printf("HTML: Fetching thread for event: %s", eventID)
@@ -381,6 +586,7 @@ func htmlThreadHandler(w http.ResponseWriter, r *http.Request) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer traceSpan(r.Context(), "root event")()
 		events := fetchEventByID(relays, eventID)
 		if len(events) > 0 {
 			rootEvent = &events[0]
@@ -391,13 +597,36 @@ func htmlThreadHandler(w http.ResponseWriter, r *http.Request) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer traceSpan(r.Context(), "replies")()
 		replies = fetchReplies(relays, []string{eventID})
 	}()
 
 	wg.Wait()
 
+	// Not on our relays: try nevent relay hints and any relay the visitor supplied
+	tried := relays
 	if rootEvent == nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		if extra := threadFallbackRelays(q, relays); len(extra) > 0 {
+			log.Printf("HTML: Event %s not found, trying %d hinted relays", eventID, len(extra))
+			tried = append(append([]string(nil), relays...), extra...)
+			if events := fetchEventByID(extra, eventID); len(events) > 0 {
+				rootEvent = &events[0]
+				seen := make(map[string]bool, len(replies))
+				for _, reply := range replies {
+					seen[reply.ID] = true
+				}
+				for _, reply := range fetchReplies(extra, []string{eventID}) {
+					if !seen[reply.ID] {
+						replies = append(replies, reply)
+					}
+				}
+				relays = tried
+			}
+		}
+	}
+
+	if rootEvent == nil {
+		renderEventNotFound(w, r, eventID, tried)
 		return
 	}
 
@@ -511,10 +740,14 @@ func htmlThreadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check for unread notifications
+	endUnread := traceSpan(r.Context(), "unread notifications")
 	hasUnreadNotifs := checkUnreadNotifications(r, session, relays)
+	endUnread()
 
 	// Render HTML
-	htmlContent, err := renderThreadHTML(resp, relays, session, currentURL, themeClass, themeLabel, successMsg, csrfToken, hasUnreadNotifs)
+	endRender := traceSpan(r.Context(), "render")
+	htmlContent, err := renderThreadHTML(resp, relays, session, currentURL, themeClass, themeLabel, q.Get("error"), successMsg, csrfToken, hasUnreadNotifs, announcementFor(r), notePreview(requestOrigin(r), rootEvent, rootItem.AuthorProfile))
+	endRender()
 	if err != nil {
 		log.Printf("Error rendering thread HTML: %v", err)
 		http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -534,29 +767,33 @@ func htmlProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Handle npub format - decode to hex if needed
-	if strings.HasPrefix(pubkey, "npub1") {
-		hexPubkey, err := decodeBech32Pubkey(pubkey)
-		if err != nil {
+	if identifier, ok := strings.CutSuffix(pubkey, "/summary"); ok {
+		htmlProfileSummaryHandler(w, r, identifier)
+		return
+	}
+
+	// npub and nprofile forms redirect to the hex pubkey
+	if strings.HasPrefix(pubkey, "npub1") || strings.HasPrefix(pubkey, "nprofile1") {
+		path := canonicalPathForIdentifier(pubkey)
+		if path == "" {
 			http.Error(w, "Invalid npub format", http.StatusBadRequest)
 			return
 		}
-		pubkey = hexPubkey
+		redirectToCanonical(w, r, path)
+		return
 	}
 
 	q := r.URL.Query()
 	relays := parseStringList(q.Get("relays"))
 	if len(relays) == 0 {
-		relays = []string{
-			"wss://relay.damus.io",
-			"wss://relay.nostr.band",
-			"wss://relay.primal.net",
-			"wss://nos.lol",
-			"wss://nostr.mom",
-		}
+		relays = relaysFor(RelayPurposeRead)
 	}
 
-	limit := parseLimit(q.Get("limit"), 20)
+	// Session for login status and the page size preference
+	session := getSessionFromRequest(r)
+	loggedIn := session != nil && session.Connected
+
+	limit, linkLimit := feedLimit(session, q, 20)
 	until := parseInt64(q.Get("until"))
 
 	log.Printf("HTML: Fetching profile for pubkey: %s", pubkey[:16])
@@ -635,7 +872,7 @@ func htmlProfileHandler(w http.ResponseWriter, r *http.Request) {
 	if len(items) > 0 {
 		lastCreatedAt := items[len(items)-1].CreatedAt
 		pageUntil = &lastCreatedAt
-		next := fmt.Sprintf("/html/profile/%s?limit=%d&until=%d", pubkey, limit, lastCreatedAt)
+		next := fmt.Sprintf("/html/profile/%s?until=%d%s", pubkey, lastCreatedAt, limitParam(linkLimit))
 		nextURL = &next
 	}
 
@@ -659,10 +896,6 @@ func htmlProfileHandler(w http.ResponseWriter, r *http.Request) {
 	// Get theme from cookie
 	themeClass, themeLabel := getThemeFromRequest(r)
 
-	// Get session for login status
-	session := getSessionFromRequest(r)
-	loggedIn := session != nil && session.Connected
-
 	// Check if logged-in user follows this profile and if this is their own profile
 	var isFollowing, isSelf bool
 	if session != nil && session.Connected {
@@ -693,10 +926,14 @@ func htmlProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check for unread notifications
+	endUnread := traceSpan(r.Context(), "unread notifications")
 	hasUnreadNotifs := checkUnreadNotifications(r, session, relays)
+	endUnread()
 
 	// Render HTML
-	htmlContent, err := renderProfileHTML(resp, relays, limit, themeClass, themeLabel, loggedIn, currentURL, csrfToken, isFollowing, isSelf, hasUnreadNotifs)
+	endRender := traceSpan(r.Context(), "render")
+	htmlContent, err := renderProfileHTML(resp, relays, limit, themeClass, themeLabel, densityClass(session), loggedIn, currentURL, csrfToken, isFollowing, isSelf, hasUnreadNotifs, announcementFor(r), profilePreview(requestOrigin(r), resp.Pubkey, resp.Profile))
+	endRender()
 	if err != nil {
 		log.Printf("Error rendering profile HTML: %v", err)
 		http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -743,7 +980,12 @@ func htmlThemeHandler(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteLaxMode,
 	})
 
-	// Redirect back to referer or timeline (sanitize to prevent open redirect)
+	// Redirect back to referer or timeline
+	http.Redirect(w, r, refererReturnURL(r), http.StatusSeeOther)
+}
+
+// refererReturnURL returns the Referer's path and query, sanitized to prevent open redirect
+func refererReturnURL(r *http.Request) string {
 	returnURL := r.Header.Get("Referer")
 	// Extract just the path from Referer header (ignore host) to prevent open redirect
 	if returnURL != "" {
@@ -756,8 +998,7 @@ func htmlThemeHandler(w http.ResponseWriter, r *http.Request) {
 			returnURL = ""
 		}
 	}
-	returnURL = sanitizeReturnURL(returnURL)
-	http.Redirect(w, r, returnURL, http.StatusSeeOther)
+	return sanitizeReturnURL(returnURL)
 }
 
 func htmlNotificationsHandler(w http.ResponseWriter, r *http.Request) {
@@ -769,6 +1010,7 @@ func htmlNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	pubkeyHex := hex.EncodeToString(session.UserPubKey)
+	fragment := r.URL.Query().Get("fragment") == "1" // Deck column: items only
 
 	// Parse until parameter for pagination
 	var until *int64
@@ -779,21 +1021,13 @@ func htmlNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user's relays
-	relays := []string{
-		"wss://relay.damus.io",
-		"wss://relay.nostr.band",
-		"wss://relay.primal.net",
-		"wss://nos.lol",
-		"wss://nostr.mom",
-	}
-
-	// Use user's read relays if available
-	if session.UserRelayList != nil && len(session.UserRelayList.Read) > 0 {
-		relays = session.UserRelayList.Read
-	}
+	relays := session.readRelays()
 
 	// Fetch notifications (request one extra to know if there are more)
-	const limit = 50
+	limit := 50
+	if size := feedPageSizeOf(session); size > 0 {
+		limit = size
+	}
 	notifications := fetchNotifications(relays, pubkeyHex, limit+1, until)
 
 	// Collect pubkeys for profile enrichment and target event IDs
@@ -867,7 +1101,7 @@ func htmlNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Render template
-	htmlContent, err := renderNotificationsHTML(notifications, profiles, targetEvents, themeClass, themeLabel, userDisplayName, pubkeyHex, pagination)
+	htmlContent, err := renderNotificationsHTML(notifications, profiles, targetEvents, themeClass, themeLabel, densityClass(session), userDisplayName, pubkeyHex, pagination, announcementFor(r), fragment)
 	if err != nil {
 		log.Printf("Error rendering notifications HTML: %v", err)
 		http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -876,6 +1110,9 @@ func htmlNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
+	if fragment && pagination != nil {
+		w.Header().Set("Link", "<"+pagination.Next+`>; rel="next"`)
+	}
 	w.Write([]byte(htmlContent))
 }
 
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// devModeEnabled shows developer affordances such as the event "source" link (DEV_MODE=1)
+var devModeEnabled = os.Getenv("DEV_MODE") == "1"
+
+// tagExplanations describes well-known tag names for the event inspector
+var tagExplanations = map[string]string{
+	"e":               "Event reference (NIP-10: relay hint, marker root/reply/mention, author)",
+	"p":               "Pubkey reference - mentions or notifies this user",
+	"a":               "Addressable event reference (kind:pubkey:d-tag)",
+	"q":               "Quoted event (NIP-18)",
+	"t":               "Hashtag",
+	"d":               "Identifier for replaceable/addressable events",
+	"r":               "URL reference, or relay entry in a NIP-65 relay list",
+	"k":               "Kind of the referenced event",
+	"imeta":           "Inline media metadata (NIP-92: url, mime type, dimensions, blurhash)",
+	"emoji":           "Custom emoji shortcode and image URL (NIP-30)",
+	"subject":         "Subject line",
+	"title":           "Title of an article, listing or event",
+	"summary":         "Short summary",
+	"image":           "Header or preview image URL",
+	"published_at":    "Original publication time (unix seconds)",
+	"client":          "Client that published the event",
+	"content-warning": "Content warning (NIP-36)",
+	"expiration":      "Time after which relays may delete the event (NIP-40)",
+	"nonce":           "Proof of work nonce and target difficulty (NIP-13)",
+	"alt":             "Human-readable fallback description (NIP-31)",
+	"-":               "Protected event - only the author may publish it (NIP-70)",
+	"L":               "Label namespace (NIP-32)",
+	"l":               "Label (NIP-32)",
+	"relay":           "Relay URL",
+	"relays":          "Relays the event should be sent to",
+	"amount":          "Amount in millisats",
+	"bolt11":          "Lightning invoice",
+	"description":     "Zap request or description",
+	"preimage":        "Lightning payment preimage",
+	"zap":             "Zap split recipient (NIP-57)",
+	"g":               "Geohash",
+	"location":        "Location",
+	"price":           "Price, currency and frequency (NIP-99)",
+	"start":           "Start time",
+	"end":             "End time",
+	"status":          "Status",
+	"streaming":       "Live stream URL (NIP-53)",
+	"option":          "Poll option (NIP-88)",
+	"polltype":        "Poll type: singlechoice or multiplechoice",
+	"endsAt":          "Poll closing time",
+}
+
+// HTMLInspectTag is one tag row in the inspector
+type HTMLInspectTag struct {
+	Name        string
+	Values      []string
+	Explanation string
+}
+
+// HTMLInspectSighting is a relay that delivered the event
+type HTMLInspectSighting struct {
+	Relay  string
+	SeenAt string
+	After  string // delay after the first sighting
+}
+
+// HTMLInspectData is the data for the event inspector page
+type HTMLInspectData struct {
+	HTMLPageChrome
+	EventID    string
+	JSON       string
+	Tags       []HTMLInspectTag
+	ComputedID string
+	IDValid    bool
+	SigValid   bool
+	FirstSeen  string
+	Sightings  []HTMLInspectSighting
+}
+
+func init() {
+	registerPageTemplate("inspect", htmlInspectContent)
+}
+
+// inspectPath returns the event inspector's path for an event
+func inspectPath(eventID string) string {
+	return "/event/" + eventID + "/inspect"
+}
+
+// htmlEventHandler serves /event/{id}/inspect
+// Under /html/event/ the inspector redirects to its own path, and a bare event ID
+// (the bookmark list's links) redirects to the note's thread
+func htmlEventHandler(w http.ResponseWriter, r *http.Request) {
+	legacy := strings.HasPrefix(r.URL.Path, "/html/event/")
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/html"), "/event/")
+	eventID, action, _ := strings.Cut(rest, "/")
+	if !isValidEventID(eventID) {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case legacy && action == "inspect":
+		http.Redirect(w, r, inspectPath(eventID), http.StatusMovedPermanently)
+	case legacy && action == "":
+		http.Redirect(w, r, canonicalThreadPath(eventID), http.StatusMovedPermanently)
+	case action == "inspect":
+		htmlInspectHandler(w, r, eventID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// htmlInspectHandler shows the raw event, its tags, ID/signature checks and the
+// relays it arrived from. Only uses ingestion metadata - no relay queries
+func htmlInspectHandler(w http.ResponseWriter, r *http.Request, eventID string) {
+	seen, ok := seenEventCache.Get(eventID)
+	if !ok {
+		http.Error(w, "Event not in cache - open it in the timeline or thread view first", http.StatusNotFound)
+		return
+	}
+	evt := seen.Event
+
+	// Canonical NIP-01 field order, without server-side fields
+	canonical := struct {
+		ID        string     `json:"id"`
+		PubKey    string     `json:"pubkey"`
+		CreatedAt int64      `json:"created_at"`
+		Kind      int        `json:"kind"`
+		Tags      [][]string `json:"tags"`
+		Content   string     `json:"content"`
+		Sig       string     `json:"sig"`
+	}{evt.ID, evt.PubKey, evt.CreatedAt, evt.Kind, evt.Tags, evt.Content, evt.Sig}
+	if canonical.Tags == nil {
+		canonical.Tags = [][]string{}
+	}
+	pretty, err := json.MarshalIndent(canonical, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to encode event", http.StatusInternalServerError)
+		return
+	}
+
+	computedID := calculateEventID(&evt)
+	data := HTMLInspectData{
+		HTMLPageChrome: newPageChrome(r, "Event source"),
+		EventID:        evt.ID,
+		JSON:           string(pretty),
+		ComputedID:     computedID,
+		IDValid:        computedID == evt.ID,
+		SigValid:       validateEventSignature(&evt),
+		FirstSeen:      seen.FirstSeen.UTC().Format(time.RFC3339),
+	}
+
+	for _, tag := range evt.Tags {
+		if len(tag) == 0 {
+			continue
+		}
+		explanation := tagExplanations[tag[0]]
+		if tag[0] == "g" && len(tag) >= 2 {
+			if p, ok := decodeGeohash(tag[1]); ok {
+				explanation += " (" + p.String() + ")"
+			}
+		}
+		data.Tags = append(data.Tags, HTMLInspectTag{
+			Name:        tag[0],
+			Values:      tag[1:],
+			Explanation: explanation,
+		})
+	}
+
+	data.Sightings = eventSightings(seen)
+
+	renderPage(w, "inspect", data)
+}
+
+// eventSightings lists the relays an ingested event arrived from, in arrival order
+func eventSightings(seen *SeenEvent) []HTMLInspectSighting {
+	sightings := make([]HTMLInspectSighting, 0, len(seen.Sightings))
+	for _, s := range seen.Sightings {
+		sightings = append(sightings, HTMLInspectSighting{
+			Relay:  s.Relay,
+			SeenAt: s.SeenAt.UTC().Format("2006-01-02 15:04:05.000"),
+			After:  s.SeenAt.Sub(seen.FirstSeen).Round(time.Millisecond).String(),
+		})
+	}
+	return sightings
+}
+
+var htmlInspectContent = `{{define "content"}}
+<h1>Event source</h1>
+<p class="mono text-sm">{{.EventID}}</p>
+<p><a href="/html/thread/{{.EventID}}">View thread</a></p>
+
+<h2>Verification</h2>
+<table class="data-table">
+  <tbody>
+    <tr><th scope="row">Computed ID</th><td class="mono">{{.ComputedID}}</td></tr>
+    <tr><th scope="row">ID matches</th><td>{{if .IDValid}}<span class="badge">yes</span>{{else}}<span class="badge danger">no</span>{{end}}</td></tr>
+    <tr><th scope="row">Signature valid</th><td>{{if .SigValid}}<span class="badge">yes</span>{{else}}<span class="badge danger">no</span>{{end}}</td></tr>
+  </tbody>
+</table>
+
+<h2>Seen on</h2>
+<p class="text-muted text-sm">First received {{.FirstSeen}}</p>
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Relay</th><th scope="col">Received (UTC)</th><th scope="col">After first</th></tr>
+  </thead>
+  <tbody>
+    {{range .Sightings}}
+    <tr><td class="mono"><a href="{{relayInfoURL .Relay}}">{{.Relay}}</a></td><td class="text-sm">{{.SeenAt}}</td><td class="text-sm">+{{.After}}</td></tr>
+    {{end}}
+  </tbody>
+</table>
+
+<h2>Tags</h2>
+{{if .Tags}}
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Tag</th><th scope="col">Values</th><th scope="col">Meaning</th></tr>
+  </thead>
+  <tbody>
+    {{range .Tags}}
+    <tr>
+      <td class="mono">{{.Name}}</td>
+      <td class="mono text-sm">{{range $i, $v := .Values}}{{if $i}}<br>{{end}}{{$v}}{{end}}</td>
+      <td class="text-sm">{{if .Explanation}}{{.Explanation}}{{else}}<span class="text-muted">Unknown tag</span>{{end}}</td>
+    </tr>
+    {{end}}
+  </tbody>
+</table>
+{{else}}
+<p class="text-muted text-sm">No tags.</p>
+{{end}}
+
+<h2>JSON</h2>
+<pre>{{.JSON}}</pre>
+{{end}}`
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestInspectRoutes(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("inspect-alice")
+	note := testutil.Note(alice, time.Now().Unix()-60, "inspect me")
+	s.Relay.Publish(note)
+	// The inspector only shows events the server has received
+	s.get(t, nil, "/html/thread/"+note.ID)
+
+	assertContains(t, s.get(t, nil, "/event/"+note.ID+"/inspect"), "inspect me", note.ID)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for path, want := range map[string]string{
+		"/html/event/" + note.ID + "/inspect": "/event/" + note.ID + "/inspect",
+		"/html/event/" + note.ID:              "/html/thread/" + note.ID,
+	} {
+		resp, err := client.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+			t.Errorf("%s: %d to %q, want a 301 to %s", path, resp.StatusCode, resp.Header.Get("Location"), want)
+		}
+	}
+
+	for _, path := range []string{"/event/" + note.ID, "/event/" + note.ID + "/other", "/event/not-an-id/inspect"} {
+		resp, err := client.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
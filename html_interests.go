@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// htmlFollowTagHandler follows or unfollows a hashtag by updating the user's
+// kind 30015 interest sets. Follows go to the "hashtags" set; unfollows remove
+// the tag from every set that has it
+func htmlFollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20", http.StatusSeeOther)
+		return
+	}
+
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	if !validateCSRFToken(session.ID, r.FormValue("csrf_token")) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+
+	tag := normalizeHashtag(r.FormValue("tag"))
+	action := strings.TrimSpace(r.FormValue("action")) // "follow" or "unfollow"
+	returnURL := sanitizeReturnURL(strings.TrimSpace(r.FormValue("return_url")))
+
+	separator := "?"
+	if strings.Contains(returnURL, "?") {
+		separator = "&"
+	}
+
+	if tag == "" {
+		http.Redirect(w, r, returnURL+separator+"error=Invalid+hashtag", http.StatusSeeOther)
+		return
+	}
+	if action != "follow" && action != "unfollow" {
+		action = "follow"
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	relays := session.writeRelays()
+	userPubkey := hex.EncodeToString(session.UserPubKey)
+
+	// Fetch current sets from where we publish them and where lists are usually kept
+	sets := fetchInterestSets(append(append([]string(nil), relays...), relaysFor(RelayPurposeMetadata)...), userPubkey)
+
+	// Build the updated sets; only changed ones are republished
+	var updates []UnsignedEvent
+	if action == "follow" {
+		var target *Event
+		for i := range sets {
+			if extractDTag(sets[i].Tags) == followedTagsSetID {
+				target = &sets[i]
+				break
+			}
+		}
+		tags := [][]string{{"d", followedTagsSetID}}
+		content := ""
+		if target != nil {
+			tags, content = target.Tags, target.Content
+		}
+		if !setHasTag(tags, tag) {
+			updates = append(updates, UnsignedEvent{
+				Kind:      kindInterestSet,
+				Content:   content,
+				Tags:      append(append([][]string(nil), tags...), []string{"t", tag}),
+				CreatedAt: time.Now().Unix(),
+			})
+		}
+	} else {
+		for _, set := range sets {
+			if !setHasTag(set.Tags, tag) {
+				continue
+			}
+			tags := make([][]string, 0, len(set.Tags))
+			for _, t := range set.Tags {
+				if len(t) >= 2 && t[0] == "t" && normalizeHashtag(t[1]) == tag {
+					continue
+				}
+				tags = append(tags, t)
+			}
+			updates = append(updates, UnsignedEvent{
+				Kind:      kindInterestSet,
+				Content:   set.Content,
+				Tags:      tags,
+				CreatedAt: time.Now().Unix(),
+			})
+		}
+	}
+
+	for _, event := range updates {
+		signedEvent, err := session.SignEvent(ctx, event)
+		if err != nil {
+			http.Redirect(w, r, returnURL+separator+"error="+escapeURLParam(sanitizeErrorForUser("Sign event", err)), http.StatusSeeOther)
+			return
+		}
+		publishEvent(ctx, relays, signedEvent)
+		log.Printf("Published interest set update: %s (action=%s, tag=%s)", signedEvent.ID, action, tag)
+	}
+
+	// Update the session's cached followed tags
+	session.mu.Lock()
+	newTags := make([]string, 0, len(session.FollowedTags)+1)
+	for _, t := range session.FollowedTags {
+		if t != tag {
+			newTags = append(newTags, t)
+		}
+	}
+	if action == "follow" {
+		newTags = append(newTags, tag)
+	}
+	session.FollowedTags = newTags
+	session.mu.Unlock()
+
+	http.Redirect(w, r, returnURL, http.StatusSeeOther)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// labelFeedFromPath parses /labels/{namespace}/{label} into a label
+func labelFeedFromPath(r *http.Request) (EventLabel, bool) {
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/labels/")
+	if !ok {
+		return EventLabel{}, false
+	}
+	nsPart, valuePart, ok := strings.Cut(rest, "/")
+	if !ok {
+		return EventLabel{}, false
+	}
+	ns, err := url.PathUnescape(nsPart)
+	if err != nil {
+		return EventLabel{}, false
+	}
+	value, err := url.PathUnescape(valuePart)
+	if err != nil {
+		return EventLabel{}, false
+	}
+	if ns == "" || value == "" || len(ns) > maxLabelLength || len(value) > maxLabelLength {
+		return EventLabel{}, false
+	}
+	return EventLabel{Namespace: ns, Value: value}, true
+}
+
+// htmlLabelFeedHandler serves /labels/{namespace}/{label} through the timeline
+func htmlLabelFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := labelFeedFromPath(r); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	htmlTimelineHandler(w, r)
+}
+
+// htmlLabelHandler publishes a kind 1985 label for an event (NIP-32)
+func htmlLabelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20", http.StatusSeeOther)
+		return
+	}
+
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	if !validateCSRFToken(session.ID, r.FormValue("csrf_token")) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+
+	eventID := strings.TrimSpace(r.FormValue("event_id"))
+	eventPubkey := strings.TrimSpace(r.FormValue("event_pubkey"))
+	returnURL := sanitizeReturnURL(strings.TrimSpace(r.FormValue("return_url")))
+	namespace := strings.TrimSpace(r.FormValue("namespace"))
+	value := strings.TrimSpace(r.FormValue("label"))
+
+	separator := "?"
+	if strings.Contains(returnURL, "?") {
+		separator = "&"
+	}
+
+	if !isValidEventID(eventID) {
+		http.Redirect(w, r, returnURL+separator+"error=Invalid+event+ID", http.StatusSeeOther)
+		return
+	}
+	if namespace == "" {
+		namespace = labelNamespace
+	}
+	if value == "" || len(value) > maxLabelLength || len(namespace) > maxLabelLength {
+		http.Redirect(w, r, returnURL+separator+"error=Label+must+be+1-64+characters", http.StatusSeeOther)
+		return
+	}
+
+	tags := [][]string{
+		{"L", namespace},
+		{"l", value, namespace},
+		{"e", eventID},
+	}
+	if isValidEventID(eventPubkey) {
+		tags = append(tags, []string{"p", eventPubkey})
+	}
+
+	event := UnsignedEvent{
+		Kind:      kindLabel,
+		Content:   "",
+		Tags:      tags,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	signedEvent, err := session.SignEvent(ctx, event)
+	if err != nil {
+		http.Redirect(w, r, returnURL+separator+"error="+escapeURLParam(sanitizeErrorForUser("Sign event", err)), http.StatusSeeOther)
+		return
+	}
+
+	relays := session.writeRelays()
+
+	publishEvent(ctx, relays, signedEvent)
+
+	log.Printf("Published label %s/%s for event %s", namespace, value, eventID)
+	http.Redirect(w, r, returnURL+separator+"success=Label+added", http.StatusSeeOther)
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dmInboxLimit is how many gift wraps are unwrapped per page
+// Each one costs two round trips to the user's signer
+const dmInboxLimit = 30
+
+// HTMLDMParticipant is a conversation member with profile info for display
+type HTMLDMParticipant struct {
+	Pubkey  string
+	Npub    string
+	Name    string
+	Picture string
+}
+
+// HTMLDMMessage is a decrypted message prepared for display
+type HTMLDMMessage struct {
+	Sender    HTMLDMParticipant
+	FromMe    bool
+	Subject   string
+	Content   string
+	CreatedAt int64
+}
+
+// HTMLDMConversation is a row in the conversation list
+type HTMLDMConversation struct {
+	ID           string
+	Participants []HTMLDMParticipant
+	Preview      string
+	LastAt       int64
+	Count        int
+}
+
+// HTMLDMRelayResult is one relay's answer to a published gift wrap
+type HTMLDMRelayResult struct {
+	Relay string
+	OK    bool
+	Error string
+}
+
+// HTMLDMSendResult is the publish outcome of one gift wrap
+type HTMLDMSendResult struct {
+	Recipient HTMLDMParticipant
+	Self      bool // the sender's own copy
+	Accepted  int
+	Relays    []HTMLDMRelayResult
+}
+
+// HTMLMessagesData is the data for the DM inbox and conversation pages
+type HTMLMessagesData struct {
+	HTMLPageChrome
+	Conversations []HTMLDMConversation
+	Conversation  *HTMLDMConversation // set on the conversation view
+	Messages      []HTMLDMMessage
+	Failed        int    // wraps that could not be unwrapped
+	SignerError   string // first signer error, e.g. decrypt permission denied
+	SignerAuthURL string // where to approve a pending signer permission
+	NextUntil     string // pagination cursor for older wraps
+	SendResults   []HTMLDMSendResult
+}
+
+func init() {
+	registerPageTemplate("messages", htmlMessagesContent)
+}
+
+// htmlMessagesHandler serves the read-only NIP-17 inbox at /html/messages
+// and a single conversation at /html/messages/{pubkey[,pubkey...]}
+func htmlMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login", http.StatusSeeOther)
+		return
+	}
+	pubkeyHex := hex.EncodeToString(session.UserPubKey)
+
+	conversationID := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/html/messages"), "/")
+	if conversationID != "" {
+		for _, pk := range strings.Split(conversationID, ",") {
+			if !isValidEventID(pk) {
+				http.Error(w, "Invalid conversation", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	var until *int64
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		if u, err := strconv.ParseInt(untilStr, 10, 64); err == nil {
+			until = &u
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 60*time.Second)
+	defer cancel()
+
+	relays := fetchDMRelays(pubkeyHex)
+	wraps := fetchGiftWraps(ctx, relays, pubkeyHex, dmInboxLimit, until)
+
+	data := HTMLMessagesData{HTMLPageChrome: newPageChrome(r, "Messages")}
+
+	// Decrypted messages stay in this slice for the rest of the request only
+	var messages []DMMessage
+	for _, wrap := range wraps {
+		msg, err := unwrapGiftWrap(ctx, session, wrap)
+		if err != nil {
+			data.Failed++
+			var authErr *SignerAuthError
+			if errors.As(err, &authErr) {
+				// Every other wrap would hit the same approval prompt
+				data.SignerError = "approval required"
+				data.SignerAuthURL = authErr.SafeURL()
+				break
+			}
+			if data.SignerError == "" && strings.HasPrefix(err.Error(), "nip44_decrypt failed") {
+				data.SignerError = err.Error()
+			}
+			log.Printf("DM: could not unwrap %s: %v", shortID(wrap.ID), err)
+			continue
+		}
+		messages = append(messages, *msg)
+	}
+	if len(wraps) == dmInboxLimit {
+		oldest := wraps[0].CreatedAt
+		for _, wrap := range wraps {
+			if wrap.CreatedAt < oldest {
+				oldest = wrap.CreatedAt
+			}
+		}
+		data.NextUntil = strconv.FormatInt(oldest-1, 10)
+	}
+
+	conversations := groupDMConversations(messages, pubkeyHex)
+
+	pubkeySet := make(map[string]bool)
+	for _, conv := range conversations {
+		for _, pk := range conv.Counterparty {
+			pubkeySet[pk] = true
+		}
+	}
+	pubkeys := make([]string, 0, len(pubkeySet))
+	for pk := range pubkeySet {
+		pubkeys = append(pubkeys, pk)
+	}
+	profiles := fetchProfiles(relaysFor(RelayPurposeMetadata), pubkeys)
+	participant := func(pk string) HTMLDMParticipant {
+		return newDMParticipant(pk, profiles[pk])
+	}
+
+	for _, conv := range conversations {
+		row := HTMLDMConversation{
+			ID:      conv.ID,
+			Preview: truncateDMPreview(conv.LastMessage().Content),
+			LastAt:  conv.LastMessage().CreatedAt,
+			Count:   len(conv.Messages),
+		}
+		for _, pk := range conv.Counterparty {
+			row.Participants = append(row.Participants, participant(pk))
+		}
+		data.Conversations = append(data.Conversations, row)
+
+		if conv.ID == conversationID {
+			data.Conversation = &row
+			for _, msg := range conv.Messages {
+				data.Messages = append(data.Messages, HTMLDMMessage{
+					Sender:    participant(msg.Sender),
+					FromMe:    msg.Sender == pubkeyHex,
+					Subject:   msg.Subject,
+					Content:   msg.Content,
+					CreatedAt: msg.CreatedAt,
+				})
+			}
+		}
+	}
+
+	if conversationID != "" && data.Conversation == nil {
+		// No messages in the fetched window - still show an empty conversation
+		row := HTMLDMConversation{ID: conversationID}
+		for _, pk := range strings.Split(conversationID, ",") {
+			row.Participants = append(row.Participants, participant(pk))
+		}
+		data.Conversation = &row
+	}
+	if data.Conversation != nil {
+		data.Title = "Messages with " + data.Conversation.Participants[0].Name
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, "messages", data)
+}
+
+// htmlSendMessageHandler sends a NIP-17 direct message via POST form
+// Renders the per-wrap publish results rather than redirecting, so the user can see
+// which relays accepted each copy
+func htmlSendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/html/messages", http.StatusSeeOther)
+		return
+	}
+
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	csrfToken := r.FormValue("csrf_token")
+	if !validateCSRFToken(session.ID, csrfToken) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+
+	conversationID := r.FormValue("conversation")
+	recipients := strings.Split(conversationID, ",")
+	for _, pk := range recipients {
+		if !isValidEventID(pk) {
+			http.Redirect(w, r, "/html/messages?error=Invalid+recipient", http.StatusSeeOther)
+			return
+		}
+	}
+	returnURL := "/html/messages/" + conversationID
+
+	content := strings.TrimSpace(r.FormValue("content"))
+	if content == "" {
+		http.Redirect(w, r, returnURL+"?error=Message+is+required", http.StatusSeeOther)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 60*time.Second)
+	defer cancel()
+
+	results, err := sendDirectMessage(ctx, session, recipients, content)
+	if err != nil {
+		var authErr *SignerAuthError
+		if errors.As(err, &authErr) {
+			// Incremental permission: the signer wants the user to approve encryption first
+			data := HTMLMessagesData{
+				HTMLPageChrome: newPageChrome(r, "Approve in your signer"),
+				SignerError:    "approval required",
+				SignerAuthURL:  authErr.SafeURL(),
+			}
+			data.Conversation = &HTMLDMConversation{ID: conversationID}
+			for _, pk := range recipients {
+				data.Conversation.Participants = append(data.Conversation.Participants, newDMParticipant(pk, nil))
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			renderPage(w, "messages", data)
+			return
+		}
+		// Errors never include the message itself, so they are safe to log
+		userErr := sanitizeErrorForUser("Send message", err)
+		if strings.HasPrefix(err.Error(), "nip44_encrypt failed") {
+			userErr = "Your signer refused to encrypt the message. Allow NIP-44 encryption for this app, or log out and reconnect to grant it."
+		}
+		http.Redirect(w, r, returnURL+"?error="+escapeURLParam(userErr), http.StatusSeeOther)
+		return
+	}
+
+	sender := hex.EncodeToString(session.UserPubKey)
+	profiles := fetchProfiles(relaysFor(RelayPurposeMetadata), recipients)
+	data := HTMLMessagesData{HTMLPageChrome: newPageChrome(r, "Message sent")}
+	data.Conversation = &HTMLDMConversation{ID: conversationID}
+	for _, pk := range recipients {
+		data.Conversation.Participants = append(data.Conversation.Participants, newDMParticipant(pk, profiles[pk]))
+	}
+	for _, res := range results {
+		row := HTMLDMSendResult{
+			Recipient: newDMParticipant(res.Recipient, profiles[res.Recipient]),
+			Self:      res.Recipient == sender,
+		}
+		for _, rr := range res.Relays {
+			relayRow := HTMLDMRelayResult{Relay: rr.Relay, OK: rr.Err == nil}
+			if rr.Err != nil {
+				relayRow.Error = rr.Err.Error()
+			} else {
+				row.Accepted++
+			}
+			row.Relays = append(row.Relays, relayRow)
+		}
+		data.SendResults = append(data.SendResults, row)
+		log.Printf("DM wrap %s: accepted by %d/%d relays", shortID(res.WrapID), row.Accepted, len(res.Relays))
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, "messages", data)
+}
+
+// newDMParticipant builds display info for a pubkey
+func newDMParticipant(pubkey string, profile *ProfileInfo) HTMLDMParticipant {
+	p := HTMLDMParticipant{Pubkey: pubkey}
+	if npub, err := encodeBech32Pubkey(pubkey); err == nil {
+		p.Npub = npub
+		p.Name = formatNpubShort(npub)
+	}
+	if profile != nil {
+		if profile.DisplayName != "" {
+			p.Name = profile.DisplayName
+		} else if profile.Name != "" {
+			p.Name = profile.Name
+		}
+		p.Picture = profile.Picture
+	}
+	return p
+}
+
+// truncateDMPreview shortens a message for the conversation list
+func truncateDMPreview(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) > 80 {
+		return string(runes[:80]) + "…"
+	}
+	return content
+}
+
+var htmlMessagesContent = `{{define "content"}}
+<h1>{{if .Conversation}}{{range $i, $p := .Conversation.Participants}}{{if $i}}, {{end}}{{$p.Name}}{{end}}{{else}}Messages{{end}}</h1>
+<div class="card dm-notice" role="note">
+  <strong>About privacy:</strong> messages are end-to-end encrypted (NIP-17). Your signer decrypts them, so your key never reaches this server.
+  The decrypted text passes through this server only to render this page: it is not stored, cached or logged.
+  Relays see the encrypted wraps and who they are addressed to, but not the sender, time or content.
+</div>
+{{if .SignerAuthURL}}
+<div class="error-box">Your signer needs you to approve encryption for this app. <a href="{{.SignerAuthURL}}" target="_blank" rel="noopener noreferrer">Approve in your signer</a>, then reload this page or send again.</div>
+{{else if .SignerError}}
+<div class="error-box">Your signer did not complete the request ({{.SignerError}}). Check that it allows NIP-44 encryption for this app, or log out and reconnect to grant it.</div>
+{{else if .Failed}}
+<p class="text-muted text-sm">{{.Failed}} message{{if gt .Failed 1}}s{{end}} could not be decrypted.</p>
+{{end}}
+
+{{if .SendResults}}
+<h2>Message sent</h2>
+<p class="text-muted text-sm">Each copy is wrapped separately and published to that person's DM relays.</p>
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Copy for</th><th scope="col">Relay</th><th scope="col">Result</th></tr>
+  </thead>
+  <tbody>
+    {{range .SendResults}}
+    {{$recipient := .}}
+    {{range .Relays}}
+    <tr>
+      <td>{{if $recipient.Self}}You{{else}}{{$recipient.Recipient.Name}}{{end}}</td>
+      <td class="mono">{{.Relay}}</td>
+      <td>{{if .OK}}<span class="badge accent">accepted</span>{{else}}<span class="badge danger" title="{{.Error}}">failed</span> <span class="text-sm text-muted">{{.Error}}</span>{{end}}</td>
+    </tr>
+    {{else}}
+    <tr><td>{{if $recipient.Self}}You{{else}}{{$recipient.Recipient.Name}}{{end}}</td><td colspan="2" class="text-muted text-sm">No DM relays found</td></tr>
+    {{end}}
+    {{end}}
+  </tbody>
+</table>
+<p><a href="/html/messages/{{.Conversation.ID}}" class="text-link text-sm">← Back to conversation</a></p>
+{{else if .Conversation}}
+<p><a href="/html/messages" class="text-link text-sm">← All conversations</a></p>
+{{if .Messages}}
+<ol class="dm-thread">
+  {{range .Messages}}
+  <li class="dm-message{{if .FromMe}} from-me{{end}}">
+    <div class="text-muted text-sm">{{if .FromMe}}You{{else}}<a href="/html/profile/{{.Sender.Npub}}">{{.Sender.Name}}</a>{{end}} · {{formatTime .CreatedAt}}</div>
+    {{if .Subject}}<div class="dm-subject">{{.Subject}}</div>{{end}}
+    <div class="dm-content">{{.Content}}</div>
+  </li>
+  {{end}}
+</ol>
+{{else}}
+<p class="text-muted text-sm">No messages in this conversation yet.</p>
+{{end}}
+<form method="POST" action="/html/messages/send" class="dm-compose">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+  <input type="hidden" name="conversation" value="{{.Conversation.ID}}">
+  <label for="dm-content">Message</label>
+  <textarea id="dm-content" name="content" rows="3" required></textarea>
+  <button type="submit" class="btn">Send</button>
+</form>
+{{else}}
+{{if .Conversations}}
+<ul class="dm-list">
+  {{range .Conversations}}
+  <li>
+    <a href="/html/messages/{{.ID}}" class="dm-row">
+      {{with index .Participants 0}}{{if .Picture}}<img src="{{.Picture}}" alt="" class="dm-avatar" loading="lazy">{{end}}{{end}}
+      <span class="dm-summary">
+        <span class="dm-names">{{range $i, $p := .Participants}}{{if $i}}, {{end}}{{$p.Name}}{{end}}</span>
+        <span class="text-muted text-sm">{{.Preview}}</span>
+      </span>
+      <span class="text-muted text-sm">{{formatTime .LastAt}}</span>
+    </a>
+  </li>
+  {{end}}
+</ul>
+{{else}}
+<p class="text-muted text-sm">No messages found on your DM relays.</p>
+{{end}}
+{{end}}
+{{if .NextUntil}}
+<p><a href="?until={{.NextUntil}}" class="text-link text-sm">Older messages →</a></p>
+{{end}}
+{{end}}
+
+{{define "styles"}}
+    .dm-notice { font-size: 13px; color: var(--text-secondary); }
+    .dm-list, .dm-thread { list-style: none; padding: 0; margin: 0; }
+    .dm-row {
+      display: flex;
+      align-items: center;
+      gap: 12px;
+      padding: 10px 0;
+      border-bottom: 1px solid var(--border-color);
+      color: inherit;
+      text-decoration: none;
+    }
+    .dm-avatar { width: 36px; height: 36px; border-radius: 50%; object-fit: cover; }
+    .dm-summary { display: flex; flex-direction: column; flex: 1; min-width: 0; }
+    .dm-names { font-weight: 600; }
+    .dm-message {
+      max-width: 80%;
+      margin: 8px 0;
+      padding: 8px 12px;
+      background: var(--bg-secondary);
+      border-radius: 8px;
+    }
+    .dm-message.from-me { margin-left: auto; background: var(--bg-badge); }
+    .dm-subject { font-weight: 600; }
+    .dm-content { white-space: pre-wrap; overflow-wrap: anywhere; }
+    .dm-compose { margin-top: 16px; }
+    .dm-compose textarea { width: 100%; box-sizing: border-box; margin-bottom: 8px; }
+{{end}}`
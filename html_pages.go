@@ -0,0 +1,433 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Secondary pages (relay info, stats, inspector, ...) share one layout.
+// Each page registers a "content" template with registerPageTemplate in an
+// init() function; initPageTemplates compiles them against htmlPageLayout.
+
+var (
+	pageTemplateSources = make(map[string]string)
+	cachedPageTemplates = make(map[string]*template.Template)
+)
+
+// registerPageTemplate adds a page body to be compiled with the shared layout
+func registerPageTemplate(name, content string) {
+	pageTemplateSources[name] = content
+}
+
+// initPageTemplates compiles every registered page template
+func initPageTemplates() {
+	for name, content := range pageTemplateSources {
+		tmpl, err := template.New(name).Funcs(templateFuncMap).Parse(htmlPageLayout + announcementTemplate + brandingTemplate + previewMetaTemplate)
+		if err == nil {
+			tmpl, err = tmpl.Parse(content)
+		}
+		if err != nil {
+			log.Fatalf("Failed to compile %s page template: %v", name, err)
+		}
+		cachedPageTemplates[name] = tmpl
+	}
+}
+
+// HTMLPageChrome holds the fields the shared layout needs
+// Page data structs embed it so templates can use {{.Title}}, {{.LoggedIn}} etc.
+type HTMLPageChrome struct {
+	Title        string
+	ThemeClass   string
+	ThemeLabel   string
+	LoggedIn     bool
+	CSRFToken    string
+	Error        string
+	Success      string
+	GeneratedAt  time.Time
+	Announcement *Announcement
+}
+
+// newPageChrome builds the layout fields from the request
+func newPageChrome(r *http.Request, title string) HTMLPageChrome {
+	themeClass, themeLabel := getThemeFromRequest(r)
+	chrome := HTMLPageChrome{
+		Title:        title,
+		ThemeClass:   themeClass,
+		ThemeLabel:   themeLabel,
+		Error:        r.URL.Query().Get("error"),
+		Success:      r.URL.Query().Get("success"),
+		GeneratedAt:  time.Now(),
+		Announcement: announcementFor(r),
+	}
+	if session := getSessionFromRequest(r); session != nil && session.Connected {
+		chrome.LoggedIn = true
+		chrome.CSRFToken = generateCSRFToken(session.ID)
+	}
+	return chrome
+}
+
+// renderPageHTML executes a registered page template into a string
+func renderPageHTML(name string, data interface{}) (string, error) {
+	tmpl, ok := cachedPageTemplates[name]
+	if !ok {
+		log.Printf("Page template %q not registered", name)
+		return "", errPageTemplateMissing
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderPage renders a registered page template as the HTTP response
+func renderPage(w http.ResponseWriter, name string, data interface{}) {
+	html, err := renderPageHTML(name, data)
+	if err != nil {
+		log.Printf("Error rendering %s page: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(html))
+}
+
+var errPageTemplateMissing = errors.New("page template not registered")
+
+var htmlPageLayout = `<!DOCTYPE html>
+<html lang="en"{{if .ThemeClass}} class="{{.ThemeClass}}"{{end}}>
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>{{.Title}} - {{(branding).SiteName}}</title>
+  {{template "branding-head"}}
+  {{block "head" .}}{{end}}
+  <link rel="icon" href="/static/favicon.ico" />
+  <style>
+{{template "announcement-styles"}}
+    :root {
+      --bg-page: #f5f5f5;
+      --bg-container: #ffffff;
+      --bg-card: #ffffff;
+      --bg-secondary: #f8f9fa;
+      --bg-input: #ffffff;
+      --bg-badge: #f0f0f0;
+      --bg-badge-hover: #e0e0e0;
+      --text-primary: #333333;
+      --text-secondary: #666666;
+      --text-muted: #999999;
+      --text-content: #24292e;
+      --border-color: #e1e4e8;
+      --border-light: #dee2e6;
+      --accent: #667eea;
+      --accent-hover: #5568d3;
+      --accent-secondary: #764ba2;
+      --success: #2e7d32;
+      --success-bg: #28a745;
+      --error-bg: #fff5f5;
+      --error-border: #fecaca;
+      --error-accent: #dc2626;
+      --shadow: rgba(0,0,0,0.1);
+    }
+    @media (prefers-color-scheme: dark) {
+      :root:not(.light) {
+        --bg-page: #121212;
+        --bg-container: #1e1e1e;
+        --bg-card: #1e1e1e;
+        --bg-secondary: #252525;
+        --bg-input: #2a2a2a;
+        --bg-badge: #2a2a2a;
+        --bg-badge-hover: #3a3a3a;
+        --text-primary: #e4e4e7;
+        --text-secondary: #a1a1aa;
+        --text-muted: #71717a;
+        --text-content: #e4e4e7;
+        --border-color: #333333;
+        --border-light: #333333;
+        --accent: #818cf8;
+        --accent-hover: #6366f1;
+        --accent-secondary: #a78bfa;
+        --success: #4ade80;
+        --success-bg: #22c55e;
+        --error-bg: #2d1f1f;
+        --error-border: #7f1d1d;
+        --error-accent: #f87171;
+        --shadow: rgba(0,0,0,0.3);
+      }
+    }
+    html.dark {
+      --bg-page: #121212;
+      --bg-container: #1e1e1e;
+      --bg-card: #1e1e1e;
+      --bg-secondary: #252525;
+      --bg-input: #2a2a2a;
+      --bg-badge: #2a2a2a;
+      --bg-badge-hover: #3a3a3a;
+      --text-primary: #e4e4e7;
+      --text-secondary: #a1a1aa;
+      --text-muted: #71717a;
+      --text-content: #e4e4e7;
+      --border-color: #333333;
+      --border-light: #333333;
+      --accent: #818cf8;
+      --accent-hover: #6366f1;
+      --accent-secondary: #a78bfa;
+      --success: #4ade80;
+      --success-bg: #22c55e;
+      --error-bg: #2d1f1f;
+      --error-border: #7f1d1d;
+      --error-accent: #f87171;
+      --shadow: rgba(0,0,0,0.3);
+    }
+    html.light {
+      --bg-page: #f5f5f5;
+      --bg-container: #ffffff;
+      --bg-card: #ffffff;
+      --bg-secondary: #f8f9fa;
+      --bg-input: #ffffff;
+      --bg-badge: #f0f0f0;
+      --bg-badge-hover: #e0e0e0;
+      --text-primary: #333333;
+      --text-secondary: #666666;
+      --text-muted: #999999;
+      --text-content: #24292e;
+      --border-color: #e1e4e8;
+      --border-light: #dee2e6;
+      --accent: #667eea;
+      --accent-hover: #5568d3;
+      --accent-secondary: #764ba2;
+      --success: #2e7d32;
+      --success-bg: #28a745;
+      --error-bg: #fff5f5;
+      --error-border: #fecaca;
+      --error-accent: #dc2626;
+      --shadow: rgba(0,0,0,0.1);
+    }
+    * { box-sizing: border-box; margin: 0; padding: 0; }
+    body {
+      font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
+      line-height: 1.6;
+      color: var(--text-primary);
+      background: var(--bg-page);
+      padding: 20px;
+    }
+    .container {
+      max-width: 800px;
+      margin: 0 auto;
+      background: var(--bg-container);
+      border-radius: 8px;
+      box-shadow: 0 2px 8px var(--shadow);
+    }
+    nav {
+      padding: 12px 15px;
+      background: var(--bg-secondary);
+      border-bottom: 1px solid var(--border-light);
+      display: flex;
+      align-items: center;
+      gap: 8px;
+      flex-wrap: wrap;
+    }
+    .nav-tab {
+      padding: 8px 16px;
+      background: var(--bg-badge);
+      color: var(--text-secondary);
+      text-decoration: none;
+      border-radius: 4px;
+      font-size: 14px;
+    }
+    .nav-tab:hover { background: var(--bg-badge-hover); }
+    .nav-tab.active { background: var(--accent); color: white; }
+    main { padding: 12px 20px 20px 20px; min-height: 400px; }
+    h1 { font-size: 1.4rem; margin: 8px 0 16px 0; }
+    h2 { font-size: 1.1rem; margin: 20px 0 10px 0; }
+    p { margin-bottom: 10px; }
+    footer {
+      text-align: center;
+      padding: 20px;
+      background: var(--bg-secondary);
+      color: var(--text-secondary);
+      font-size: 13px;
+      border-top: 1px solid var(--border-color);
+      border-radius: 0 0 8px 8px;
+    }
+    .skip-link {
+      position: absolute;
+      top: 0;
+      left: 0;
+      transform: translateY(-100%);
+      background: var(--accent);
+      color: white;
+      padding: 8px 16px;
+      z-index: 1000;
+      text-decoration: none;
+      border-radius: 0 0 4px 0;
+    }
+    .skip-link:focus { transform: translateY(0); }
+    .sr-only {
+      position: absolute;
+      width: 1px;
+      height: 1px;
+      padding: 0;
+      margin: -1px;
+      overflow: hidden;
+      clip: rect(0, 0, 0, 0);
+      white-space: nowrap;
+      border: 0;
+    }
+    @keyframes flashFadeOut {
+      0%, 60% { opacity: 1; max-height: 100px; padding: 12px; margin-bottom: 16px; }
+      100% { opacity: 0; max-height: 0; padding: 0; margin-bottom: 0; overflow: hidden; }
+    }
+    .flash-message {
+      background: var(--success-bg);
+      color: white;
+      border: 1px solid var(--success);
+      border-radius: 4px;
+      animation: flashFadeOut 3s ease-out forwards;
+    }
+    .error-box {
+      background: var(--error-bg);
+      color: var(--error-accent);
+      border: 1px solid var(--error-border);
+      padding: 12px;
+      border-radius: 4px;
+      margin-bottom: 16px;
+    }
+    /* Utility classes */
+    .ml-auto { margin-left: auto; }
+    .flex-center { display: flex; align-items: center; }
+    .gap-md { gap: 12px; }
+    .text-link { color: var(--accent); text-decoration: none; }
+    .text-link:hover { text-decoration: underline; }
+    .text-muted { color: var(--text-secondary); text-decoration: none; }
+    .text-sm { font-size: 13px; }
+    .text-xs { font-size: 12px; }
+    .font-medium { font-weight: 500; }
+    .mono { font-family: monospace; word-break: break-all; }
+    /* Cards and tables */
+    .card {
+      background: var(--bg-card);
+      border: 1px solid var(--border-color);
+      border-radius: 6px;
+      padding: 16px;
+      margin-bottom: 12px;
+    }
+    .data-table {
+      width: 100%;
+      border-collapse: collapse;
+      font-size: 14px;
+      margin-bottom: 16px;
+    }
+    .data-table th, .data-table td {
+      text-align: left;
+      padding: 6px 8px;
+      border-bottom: 1px solid var(--border-color);
+      vertical-align: top;
+    }
+    .data-table th {
+      color: var(--text-secondary);
+      font-weight: 600;
+      font-size: 12px;
+      text-transform: uppercase;
+    }
+    .badge {
+      display: inline-block;
+      padding: 1px 8px;
+      margin: 1px 2px 1px 0;
+      background: var(--bg-badge);
+      color: var(--text-secondary);
+      border-radius: 10px;
+      font-size: 12px;
+    }
+    .badge.accent { background: var(--accent); color: white; }
+    .badge.danger { background: var(--error-accent); color: white; }
+    .bar {
+      display: inline-block;
+      height: 10px;
+      background: var(--accent);
+      border-radius: 2px;
+      vertical-align: middle;
+    }
+    pre {
+      background: var(--bg-secondary);
+      border: 1px solid var(--border-color);
+      border-radius: 6px;
+      padding: 12px;
+      overflow-x: auto;
+      font-size: 13px;
+      margin-bottom: 16px;
+    }
+    .btn {
+      display: inline-block;
+      padding: 8px 16px;
+      background: var(--accent);
+      color: white;
+      border: none;
+      border-radius: 4px;
+      font-size: 14px;
+      font-family: inherit;
+      cursor: pointer;
+      text-decoration: none;
+    }
+    .btn:hover { background: var(--accent-hover); }
+    .btn.danger { background: var(--error-accent); }
+    textarea, input[type="text"], input[type="url"], select {
+      width: 100%;
+      padding: 8px 12px;
+      border: 1px solid var(--border-color);
+      border-radius: 4px;
+      font-size: 14px;
+      font-family: inherit;
+      background: var(--bg-input);
+      color: var(--text-primary);
+      margin-bottom: 10px;
+    }
+    label { display: block; font-size: 13px; color: var(--text-secondary); margin-bottom: 4px; }
+    {{block "styles" .}}{{end}}
+{{template "branding-styles"}}
+  </style>
+</head>
+<body>
+  <a href="#main-content" class="skip-link">Skip to main content</a>
+  {{template "announcement" .Announcement}}
+  <div id="top" class="container">
+    <nav>
+      {{template "branding-logo"}}
+      {{if .LoggedIn}}
+      <a href="/html/timeline?kinds=1&limit=20&feed=follows" class="nav-tab">Follows</a>
+      {{end}}
+      <a href="/html/timeline?kinds=1&limit=20&feed=global" class="nav-tab">Global</a>
+      {{if .LoggedIn}}
+      <a href="/html/timeline?kinds=1&limit=20&feed=me" class="nav-tab">Me</a>
+      {{end}}
+      <div class="ml-auto flex-center gap-md">
+        {{if .LoggedIn}}
+        <a href="/html/messages" class="text-muted" title="Messages">{{icon "mail" "✉️"}}</a>
+        <a href="/html/notifications" class="text-muted" title="Notifications">{{icon "bell" "🔔"}}</a>
+        <a href="/html/logout" class="text-muted text-sm">Logout</a>
+        {{else}}
+        <a href="/html/login" class="text-link text-sm font-medium">Login</a>
+        {{end}}
+      </div>
+    </nav>
+
+    <main id="main-content">
+      {{if .Error}}
+      <div class="error-box" role="alert">{{.Error}}</div>
+      {{end}}
+      {{if .Success}}
+      <div class="flash-message" role="status">{{.Success}}</div>
+      {{end}}
+      {{template "content" .}}
+    </main>
+
+    <footer>
+      <p>Generated: {{.GeneratedAt.Format "15:04:05"}} · {{template "branding-footer"}}</p>
+    </footer>
+  </div>
+</body>
+</html>`
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Profile summaries
+// GET /html/profile/{npub|hex}/summary is a small card: avatar, name, NIP-05, the first
+// line of the bio, how the viewer is connected to the account, and a follow button.
+// Author names in threads and the timeline link here with return_url pointing back at
+// the note's anchor, so "Back" lands where the reader was. With ?fragment=1 only the
+// card is returned, for hypermedia clients that load it inline under the note (author
+// links carry it as data-fragment). The card only uses cached profiles and contact
+// lists: no relay queries on the request path, so it may be missing details the full
+// profile page would fetch.
+
+// maxSummaryMutuals is how many followed accounts the card names
+const maxSummaryMutuals = 3
+
+// HTMLProfileSummary is the profile card
+type HTMLProfileSummary struct {
+	Author       HTMLDMParticipant
+	Nip05        string
+	About        string // First line of the bio
+	Cached       bool   // Whether the profile was in the cache
+	IsSelf       bool
+	YouFollow    bool
+	FollowsYou   bool
+	MutualNames  []string // Up to maxSummaryMutuals people you follow who follow them
+	MutualOthers int      // How many more beyond MutualNames
+	ProfileURL   string
+	SummaryURL   string // For the follow form to come back to
+	ReturnURL    string // Where "Back" goes, "" if none
+	LoggedIn     bool
+	CSRFToken    string
+}
+
+// HTMLProfileSummaryData is the data for the interstitial page
+type HTMLProfileSummaryData struct {
+	HTMLPageChrome
+	Summary HTMLProfileSummary
+}
+
+func init() {
+	registerPageTemplate("profile-summary", htmlProfileSummaryContent)
+}
+
+// profileSummaryURL returns the summary link for an author, returning to returnURL
+func profileSummaryURL(pubkey, returnURL string) string {
+	u := "/html/profile/" + pubkey + "/summary"
+	if returnURL != "" {
+		u += "?return_url=" + url.QueryEscape(returnURL)
+	}
+	return u
+}
+
+// summaryPubkey decodes the {npub|hex} part of a summary path
+func summaryPubkey(identifier string) string {
+	if strings.HasPrefix(identifier, "npub1") {
+		if pk, err := decodeBech32Pubkey(identifier); err == nil {
+			return pk
+		}
+		return ""
+	}
+	if b, err := hex.DecodeString(identifier); err == nil && len(b) == 32 {
+		return strings.ToLower(identifier)
+	}
+	return ""
+}
+
+// firstLine returns the first non-empty line of s, shortened to maxRunes
+func firstLine(s string, maxRunes int) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if runes := []rune(line); len(runes) > maxRunes {
+				return string(runes[:maxRunes]) + "…"
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// buildProfileSummary fills the card from the profile and contact caches
+func buildProfileSummary(pubkey string, session *BunkerSession) HTMLProfileSummary {
+	profile, cached := profileCache.Get(pubkey)
+	summary := HTMLProfileSummary{
+		Author:     newDMParticipant(pubkey, profile),
+		Cached:     cached && profile != nil,
+		ProfileURL: canonicalProfilePath(pubkey),
+		SummaryURL: profileSummaryURL(pubkey, ""),
+	}
+	if profile != nil {
+		summary.Nip05 = profile.Nip05
+		summary.About = firstLine(profile.About, 160)
+	}
+
+	if session == nil || !session.Connected {
+		return summary
+	}
+	viewer := hex.EncodeToString(session.UserPubKey)
+	summary.LoggedIn = true
+	summary.CSRFToken = generateCSRFToken(session.ID)
+	summary.IsSelf = viewer == pubkey
+
+	if theirs, ok := contactCache.Get(pubkey); ok {
+		summary.FollowsYou = slices.Contains(theirs, viewer)
+	}
+	follows, _ := contactCache.Get(viewer)
+	summary.YouFollow = slices.Contains(follows, pubkey)
+	for _, followed := range follows {
+		if followed == pubkey {
+			continue
+		}
+		if theirs, ok := contactCache.Get(followed); ok && slices.Contains(theirs, pubkey) {
+			if len(summary.MutualNames) < maxSummaryMutuals {
+				summary.MutualNames = append(summary.MutualNames, strings.TrimPrefix(getUserDisplayName(followed), "@"))
+			} else {
+				summary.MutualOthers++
+			}
+		}
+	}
+	return summary
+}
+
+// htmlProfileSummaryHandler serves the profile card as a page or, with ?fragment=1, alone
+func htmlProfileSummaryHandler(w http.ResponseWriter, r *http.Request, identifier string) {
+	pubkey := summaryPubkey(identifier)
+	if pubkey == "" {
+		http.Error(w, "Invalid pubkey", http.StatusBadRequest)
+		return
+	}
+
+	session := getSessionFromRequest(r)
+	summary := buildProfileSummary(pubkey, session)
+	if returnURL := r.URL.Query().Get("return_url"); returnURL != "" {
+		summary.ReturnURL = sanitizeReturnURL(returnURL)
+		summary.SummaryURL = profileSummaryURL(pubkey, summary.ReturnURL)
+	}
+
+	// Private: the card shows the viewer's follow relationships
+	w.Header().Set("Cache-Control", "private, max-age=60")
+
+	if r.URL.Query().Get("fragment") == "1" {
+		var buf strings.Builder
+		if err := cachedPageTemplates["profile-summary"].ExecuteTemplate(&buf, "profile-summary-card", summary); err != nil {
+			log.Printf("Error rendering profile summary: %v", err)
+			http.Error(w, "Error rendering profile summary", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(buf.String()))
+		return
+	}
+
+	renderPage(w, "profile-summary", HTMLProfileSummaryData{
+		HTMLPageChrome: newPageChrome(r, summary.Author.Name),
+		Summary:        summary,
+	})
+}
+
+var htmlProfileSummaryContent = `{{define "content"}}
+{{template "profile-summary-card" .Summary}}
+<p class="summary-nav">
+  {{with .Summary.ReturnURL}}<a href="{{.}}">← Back</a> &middot; {{end}}<a href="{{.Summary.ProfileURL}}">Full profile →</a>
+</p>
+{{end}}
+{{define "profile-summary-card"}}
+<section class="card profile-summary" aria-label="Profile summary">
+  <div class="profile-summary-head">
+    <img class="profile-summary-avatar" src="{{if .Author.Picture}}{{.Author.Picture}}{{else}}/static/avatar.jpg{{end}}" alt="" loading="lazy">
+    <div>
+      <a href="{{.ProfileURL}}" class="profile-summary-name">{{.Author.Name}}</a>
+      {{if .Nip05}}<div class="text-muted text-sm" title="NIP-05 identifier claimed in the profile; not checked here">{{.Nip05}} <span class="badge">unverified</span></div>{{end}}
+    </div>
+  </div>
+  {{if .About}}<p class="profile-summary-about">{{.About}}</p>{{end}}
+  {{if not .Cached}}<p class="text-muted text-sm">Profile details aren't loaded yet; the full profile has them.</p>{{end}}
+  {{if and .LoggedIn (not .IsSelf)}}
+  <p class="text-muted text-sm">
+    {{if and .YouFollow .FollowsYou}}You follow each other{{else if .YouFollow}}You follow them{{else if .FollowsYou}}Follows you{{else}}You don't follow them{{end}}{{if .MutualNames}} &middot; followed by {{range $i, $name := .MutualNames}}{{if $i}}, {{end}}{{$name}}{{end}}{{if .MutualOthers}} and {{.MutualOthers}} more you follow{{end}}{{end}}
+  </p>
+  <form method="POST" action="/html/follow" class="inline-form">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="pubkey" value="{{.Author.Pubkey}}">
+    <input type="hidden" name="return_url" value="{{.SummaryURL}}">
+    {{if .YouFollow}}
+    <input type="hidden" name="action" value="unfollow">
+    <button type="submit" class="btn">Unfollow</button>
+    {{else}}
+    <input type="hidden" name="action" value="follow">
+    <button type="submit" class="btn">Follow</button>
+    {{end}}
+  </form>
+  {{end}}
+</section>
+{{end}}
+{{define "styles"}}
+    .profile-summary-head { display: flex; align-items: center; gap: 12px; }
+    .profile-summary-avatar { width: 56px; height: 56px; border-radius: 50%; object-fit: cover; }
+    .profile-summary-name { font-size: 18px; font-weight: 600; text-decoration: none; }
+    .profile-summary-about { margin: 12px 0; }
+    .summary-nav { margin-top: 16px; }
+{{end}}`
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// maxReactionFragmentIDs caps the event IDs accepted by one fragment request
+const maxReactionFragmentIDs = 100
+
+// HTMLReactionsFragmentItem is one note's reaction counts in the fragment response
+type HTMLReactionsFragmentItem struct {
+	ID        string
+	Reactions *ReactionsSummary
+}
+
+// htmlReactionsFragmentTemplate renders one element per requested note, with the same
+// id as the timeline placeholder so a hypermedia client can swap it in place.
+// Empty elements are still sent so placeholders are cleared
+var htmlReactionsFragmentTemplate = `{{range .}}<div class="note-footer-reactions" id="reactions-{{.ID}}">
+{{if .Reactions}}{{range $type, $count := .Reactions.ByType}}  <span class="reaction-badge">{{$type}} {{$count}}</span>
+{{end}}{{end}}</div>
+{{end}}`
+
+var cachedReactionsFragmentTemplate *template.Template
+
+func init() {
+	var err error
+	cachedReactionsFragmentTemplate, err = template.New("reactions").Parse(htmlReactionsFragmentTemplate)
+	if err != nil {
+		log.Fatalf("Failed to compile reactions fragment template: %v", err)
+	}
+}
+
+// htmlReactionsHandler serves reaction counts for timeline placeholders as an HTML fragment
+// GET /html/reactions?ids=id1,id2
+func htmlReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	for _, id := range parseStringList(r.URL.Query().Get("ids")) {
+		if isValidEventID(id) && len(ids) < maxReactionFragmentIDs {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "Invalid event IDs", http.StatusBadRequest)
+		return
+	}
+
+	// Same relays as the timeline: the user's NIP-65 read relays, else the defaults
+	relays := relaysFor(RelayPurposeRead)
+	if session := getSessionFromRequest(r); session != nil && session.Connected {
+		session.mu.Lock()
+		if session.UserRelayList != nil && len(session.UserRelayList.Read) > 0 {
+			relays = session.UserRelayList.Read
+		}
+		session.mu.Unlock()
+	}
+
+	reactions := fetchReactionsCached(relays, ids)
+	items := make([]HTMLReactionsFragmentItem, len(ids))
+	for i, id := range ids {
+		items[i] = HTMLReactionsFragmentItem{ID: id, Reactions: reactions[id]}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "max-age=5")
+	if err := cachedReactionsFragmentTemplate.Execute(w, items); err != nil {
+		log.Printf("Error rendering reactions fragment: %v", err)
+	}
+}
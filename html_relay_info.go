@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Relay information documents (NIP-11)
+// GET /html/relays/info?url=wss://... fetches the relay's NIP-11 document (an HTTP GET
+// of the relay URL with Accept: application/nostr+json) and shows its name, operator,
+// supported NIPs and limits. Relay names in "seen on" lists link here. Documents are
+// fetched through the SSRF-safe dialer and cached for an hour, failures for 5 minutes.
+
+const (
+	relayInfoTTL     = time.Hour
+	relayInfoFailTTL = 5 * time.Minute
+	relayInfoMaxSize = 500
+	relayInfoMaxBody = 64 * 1024
+)
+
+// RelayInfoDocument is the subset of a NIP-11 document we display
+type RelayInfoDocument struct {
+	Name          string           `json:"name"`
+	Description   string           `json:"description"`
+	Pubkey        string           `json:"pubkey"`
+	Contact       string           `json:"contact"`
+	SupportedNIPs []int            `json:"supported_nips"`
+	Software      string           `json:"software"`
+	Version       string           `json:"version"`
+	Limitation    *RelayLimitation `json:"limitation,omitempty"`
+}
+
+// RelayLimitation is the NIP-11 "limitation" object
+type RelayLimitation struct {
+	MaxMessageLength int  `json:"max_message_length"`
+	MaxSubscriptions int  `json:"max_subscriptions"`
+	MaxLimit         int  `json:"max_limit"`
+	AuthRequired     bool `json:"auth_required"`
+	PaymentRequired  bool `json:"payment_required"`
+	RestrictedWrites bool `json:"restricted_writes"`
+}
+
+type cachedRelayInfo struct {
+	doc       *RelayInfoDocument // nil if the fetch failed
+	fetchedAt time.Time
+}
+
+// RelayInfoCache holds fetched NIP-11 documents by relay URL
+type RelayInfoCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedRelayInfo
+}
+
+var relayInfoCache = &RelayInfoCache{entries: make(map[string]cachedRelayInfo)}
+
+// HTTP client for NIP-11 fetches; redirects aren't followed
+var relayInfoHTTPClient = &http.Client{
+	Timeout: 5 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         ssrfSafeDialContext,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// relayInfoURL returns the link to the relay info page for a relay URL
+func relayInfoURL(relayURL string) string {
+	return "/html/relays/info?url=" + url.QueryEscape(relayURL)
+}
+
+// Get returns the relay's document, fetching it if it isn't cached or has expired
+func (c *RelayInfoCache) Get(relayURL string) (*RelayInfoDocument, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.entries[relayURL]
+	c.mu.Unlock()
+	if ok {
+		ttl := relayInfoTTL
+		if cached.doc == nil {
+			ttl = relayInfoFailTTL
+		}
+		if now.Sub(cached.fetchedAt) < ttl {
+			return cached.doc, cached.doc != nil
+		}
+	}
+
+	doc, err := fetchRelayInfo(relayURL)
+	if err != nil {
+		log.Printf("NIP-11 fetch for %s failed: %v", relayURL, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[relayURL]; !exists && len(c.entries) >= relayInfoMaxSize {
+		for key := range c.entries {
+			delete(c.entries, key) // Arbitrary eviction is fine for a cache this small
+			break
+		}
+	}
+	c.entries[relayURL] = cachedRelayInfo{doc: doc, fetchedAt: now}
+	return doc, doc != nil
+}
+
+// fetchRelayInfo GETs a relay's NIP-11 document over http(s)
+func fetchRelayInfo(relayURL string) (*RelayInfoDocument, error) {
+	httpURL := "https://" + strings.TrimPrefix(relayURL, "wss://")
+	if strings.HasPrefix(relayURL, "ws://") {
+		httpURL = "http://" + strings.TrimPrefix(relayURL, "ws://")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, httpURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/nostr+json")
+	req.Header.Set("User-Agent", "nostr-hypermedia/1.0")
+
+	resp, err := relayInfoHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var doc RelayInfoDocument
+	if err := json.NewDecoder(io.LimitReader(resp.Body, relayInfoMaxBody)).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// HTMLRelayInfoData is the data for a single relay's info page
+type HTMLRelayInfoData struct {
+	HTMLPageChrome
+	RelayURL string
+	Doc      *RelayInfoDocument // nil if unavailable
+	Operator string             // Profile path for Doc.Pubkey, if it's a valid pubkey
+}
+
+func init() {
+	registerPageTemplate("relay-info", htmlRelayInfoContent)
+}
+
+// htmlRelayInfoHandler shows one relay's NIP-11 document
+func htmlRelayInfoHandler(w http.ResponseWriter, r *http.Request) {
+	relayURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if !isVisitorRelayURLSafe(relayURL) {
+		http.Error(w, "Invalid relay URL", http.StatusBadRequest)
+		return
+	}
+
+	data := HTMLRelayInfoData{
+		HTMLPageChrome: newPageChrome(r, "Relay info"),
+		RelayURL:       relayURL,
+	}
+	if doc, ok := relayInfoCache.Get(relayURL); ok {
+		data.Doc = doc
+		if pk, err := hex.DecodeString(doc.Pubkey); err == nil && len(pk) == 32 {
+			data.Operator = canonicalProfilePath(doc.Pubkey)
+		}
+	}
+
+	renderPage(w, "relay-info", data)
+}
+
+var htmlRelayInfoContent = `{{define "content"}}
+<h1>{{if and .Doc .Doc.Name}}{{.Doc.Name}}{{else}}Relay info{{end}}</h1>
+<p class="mono text-sm">{{.RelayURL}}</p>
+{{with .Doc}}
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<table class="data-table">
+  <tbody>
+    {{if $.Operator}}<tr><th scope="row">Operator</th><td><a href="{{$.Operator}}" class="mono text-sm">{{.Pubkey}}</a></td></tr>{{end}}
+    {{if .Contact}}<tr><th scope="row">Contact</th><td>{{.Contact}}</td></tr>{{end}}
+    {{if .Software}}<tr><th scope="row">Software</th><td class="mono text-sm">{{.Software}}{{if .Version}} {{.Version}}{{end}}</td></tr>{{end}}
+    <tr><th scope="row">Supported NIPs</th><td>{{range .SupportedNIPs}}<span class="badge">{{.}}</span>{{else}}<span class="text-muted">Not listed</span>{{end}}</td></tr>
+    {{with .Limitation}}
+    <tr><th scope="row">Access</th><td>{{if .AuthRequired}}<span class="badge">auth required</span>{{end}}{{if .PaymentRequired}}<span class="badge">paid</span>{{end}}{{if .RestrictedWrites}}<span class="badge">restricted writes</span>{{end}}{{if not (or .AuthRequired .PaymentRequired .RestrictedWrites)}}Open{{end}}</td></tr>
+    {{if .MaxMessageLength}}<tr><th scope="row">Max message length</th><td>{{.MaxMessageLength}} bytes</td></tr>{{end}}
+    {{if .MaxSubscriptions}}<tr><th scope="row">Max subscriptions</th><td>{{.MaxSubscriptions}}</td></tr>{{end}}
+    {{if .MaxLimit}}<tr><th scope="row">Max limit per filter</th><td>{{.MaxLimit}}</td></tr>{{end}}
+    {{end}}
+  </tbody>
+</table>
+{{else}}
+<p class="text-muted text-sm">This relay didn't return a NIP-11 information document. It may not publish one, or it couldn't be reached.</p>
+{{end}}
+<p><a href="/html/relays">All relays →</a></p>
+{{end}}`
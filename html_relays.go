@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+)
+
+// HTMLRelaysData is the data for the relay info page
+type HTMLRelaysData struct {
+	HTMLPageChrome
+	Relays       []RelayConfigEntry
+	UserRead     []string
+	UserWrite    []string
+	HasUserNIP65 bool
+}
+
+func init() {
+	registerPageTemplate("relays", htmlRelaysContent)
+}
+
+// htmlRelaysHandler shows the instance's configured relays and their purposes,
+// plus the logged-in user's NIP-65 relays
+func htmlRelaysHandler(w http.ResponseWriter, r *http.Request) {
+	data := HTMLRelaysData{
+		HTMLPageChrome: newPageChrome(r, "Relays"),
+		Relays:         getRelayConfig().Relays,
+	}
+
+	if session := getSessionFromRequest(r); session != nil && session.Connected {
+		session.mu.Lock()
+		if session.UserRelayList != nil {
+			data.UserRead = session.UserRelayList.Read
+			data.UserWrite = session.UserRelayList.Write
+			data.HasUserNIP65 = true
+		}
+		session.mu.Unlock()
+	}
+
+	renderPage(w, "relays", data)
+}
+
+var htmlRelaysContent = `{{define "content"}}
+<h1>Relays</h1>
+<p class="text-muted text-sm">Default relays this instance uses, and what each one is used for. Logged-in users with a NIP-65 relay list read and write through their own relays instead.</p>
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Relay</th><th scope="col">Purposes</th><th scope="col">Description</th></tr>
+  </thead>
+  <tbody>
+    {{range .Relays}}
+    <tr>
+      <td class="mono"><a href="{{relayInfoURL .URL}}">{{.URL}}</a></td>
+      <td>{{range .Purposes}}<span class="badge">{{.}}</span>{{end}}</td>
+      <td class="text-sm">{{.Description}}</td>
+    </tr>
+    {{end}}
+  </tbody>
+</table>
+{{if .LoggedIn}}
+<h2>Your relays (NIP-65)</h2>
+{{if .HasUserNIP65}}
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Relay</th><th scope="col">Purposes</th></tr>
+  </thead>
+  <tbody>
+    {{range .UserRead}}<tr><td class="mono"><a href="{{relayInfoURL .}}">{{.}}</a></td><td><span class="badge">read</span></td></tr>{{end}}
+    {{range .UserWrite}}<tr><td class="mono"><a href="{{relayInfoURL .}}">{{.}}</a></td><td><span class="badge">write</span></td></tr>{{end}}
+  </tbody>
+</table>
+{{else}}
+<p class="text-muted text-sm">No relay list found for your account; the defaults above are used.</p>
+{{end}}
+{{end}}
+{{end}}`
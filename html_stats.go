@@ -0,0 +1,402 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stats page panels, individually enabled with STATS_PANELS (default: all)
+const (
+	StatsPanelKinds   = "kinds"   // events ingested per kind
+	StatsPanelAuthors = "authors" // distinct authors seen
+	StatsPanelRelays  = "relays"  // relay connection health
+	StatsPanelCaches  = "caches"  // in-memory cache sizes
+	StatsPanelUptime  = "uptime"  // process uptime
+	StatsPanelTraffic = "traffic" // page views by people and crawlers
+)
+
+// statsPanels holds the enabled panels, parsed once at startup
+var statsPanels = parseStatsPanels(os.Getenv("STATS_PANELS"))
+
+// parseStatsPanels parses a comma-separated panel list; empty enables everything
+func parseStatsPanels(value string) map[string]bool {
+	panels := make(map[string]bool)
+	if strings.TrimSpace(value) == "" {
+		for _, p := range []string{StatsPanelKinds, StatsPanelAuthors, StatsPanelRelays, StatsPanelCaches, StatsPanelUptime, StatsPanelTraffic} {
+			panels[p] = true
+		}
+		return panels
+	}
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			panels[p] = true
+		}
+	}
+	return panels
+}
+
+// statsKindNames labels common kinds on the stats page
+var statsKindNames = map[int]string{
+	0:     "Profile",
+	1:     "Note",
+	3:     "Contact list",
+	5:     "Deletion",
+	6:     "Repost",
+	7:     "Reaction",
+	16:    "Generic repost",
+	1068:  "Poll",
+	1111:  "Comment",
+	9735:  "Zap receipt",
+	10000: "Mute list",
+	10002: "Relay list",
+	30023: "Article",
+	30311: "Live event",
+	30402: "Classified",
+	31922: "Calendar event (date)",
+	31923: "Calendar event (time)",
+}
+
+// statsCacheTTL is how long a rendered stats page is reused
+const statsCacheTTL = time.Minute
+
+type cachedStatsPage struct {
+	html       string
+	renderedAt time.Time
+}
+
+var (
+	statsPageMu    sync.Mutex
+	statsPageCache = make(map[string]cachedStatsPage) // theme|login state -> page
+)
+
+// HTMLStatsKind is one row of the per-kind ingestion table
+type HTMLStatsKind struct {
+	Kind      int
+	Name      string
+	Day       int
+	Week      int
+	WeekWidth int // bar width in percent of the busiest kind
+}
+
+// HTMLStatsRelay is one row of the relay health table
+type HTMLStatsRelay struct {
+	URL          string
+	Connected    bool
+	Connects     int
+	DialFailures int
+	Events       int
+	LastEvent    string
+	LastError    string
+}
+
+// HTMLStatsCache is one row of the cache sizes table
+type HTMLStatsCache struct {
+	Name    string
+	Entries int
+	Limit   string
+}
+
+// HTMLStatsTraffic counts thread and profile page views by visitor type
+type HTMLStatsTraffic struct {
+	Human           int64
+	Crawler         int64
+	CrawlerCached   int64
+	CrawlerFallback int64
+}
+
+// HTMLStatsEmbargo describes the new-key embargo
+type HTMLStatsEmbargo struct {
+	MinAge     string
+	Anchors    int
+	Trusted    int
+	Tracked    int
+	Suppressed int64
+	Refreshed  string
+}
+
+// HTMLStatsData is the data for the instance statistics page
+type HTMLStatsData struct {
+	HTMLPageChrome
+	Panels      map[string]bool
+	Kinds       []HTMLStatsKind
+	TotalDay    int
+	TotalWeek   int
+	AuthorsDay  int
+	AuthorsWeek int
+	Relays      []HTMLStatsRelay
+	Caches      []HTMLStatsCache
+	Embargo     *HTMLStatsEmbargo // New-key embargo policy, always disclosed when enabled
+	Traffic     HTMLStatsTraffic
+	Uptime      string
+	StartedAt   string
+}
+
+func init() {
+	registerPageTemplate("stats", htmlStatsContent)
+}
+
+// htmlStatsHandler serves the public instance statistics page
+// The page is built from in-memory counters and reused for a minute per theme/login/announcement state
+// The logged-in variant has the member nav, so only browsers may cache it
+func htmlStatsHandler(w http.ResponseWriter, r *http.Request) {
+	chrome := newPageChrome(r, "Instance stats")
+	// Flash messages and the session's CSRF token aren't part of the cached page
+	chrome.Error, chrome.Success, chrome.CSRFToken = "", "", ""
+	key := chrome.ThemeClass + "|" + strconv.FormatBool(chrome.LoggedIn)
+	if chrome.Announcement != nil {
+		key += "|" + chrome.Announcement.ID
+	}
+	cacheControl := "public, max-age=60"
+	if chrome.LoggedIn {
+		cacheControl = "private, max-age=60"
+	}
+	// Theme, login and dismissed announcements all come from cookies
+	w.Header().Set("Vary", "Cookie")
+
+	statsPageMu.Lock()
+	defer statsPageMu.Unlock()
+
+	if cached, ok := statsPageCache[key]; ok && time.Since(cached.renderedAt) < statsCacheTTL {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Write([]byte(cached.html))
+		return
+	}
+
+	html, err := renderPageHTML("stats", buildStatsData(chrome))
+	if err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+		return
+	}
+	statsPageCache[key] = cachedStatsPage{html: html, renderedAt: time.Now()}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write([]byte(html))
+}
+
+// buildStatsData collects the enabled panels from the ingestion, pool and cache counters
+func buildStatsData(chrome HTMLPageChrome) HTMLStatsData {
+	data := HTMLStatsData{
+		HTMLPageChrome: chrome,
+		Panels:         statsPanels,
+	}
+
+	if statsPanels[StatsPanelKinds] || statsPanels[StatsPanelAuthors] {
+		snap := ingestStats.Snapshot()
+		data.TotalDay, data.TotalWeek = snap.TotalDay, snap.TotalWeek
+		data.AuthorsDay, data.AuthorsWeek = snap.AuthorsDay, snap.AuthorsWeek
+
+		maxWeek := 1
+		if len(snap.Kinds) > 0 && snap.Kinds[0].Week > maxWeek {
+			maxWeek = snap.Kinds[0].Week
+		}
+		for _, kc := range snap.Kinds {
+			name := statsKindNames[kc.Kind]
+			if name == "" {
+				name = "Kind " + strconv.Itoa(kc.Kind)
+			}
+			data.Kinds = append(data.Kinds, HTMLStatsKind{
+				Kind:      kc.Kind,
+				Name:      name,
+				Day:       kc.Day,
+				Week:      kc.Week,
+				WeekWidth: kc.Week * 100 / maxWeek,
+			})
+		}
+	}
+
+	if statsPanels[StatsPanelRelays] {
+		for _, h := range relayPool.Health() {
+			row := HTMLStatsRelay{
+				URL:          h.URL,
+				Connected:    h.Connected,
+				Connects:     h.Connects,
+				DialFailures: h.DialFailures,
+				Events:       h.Events,
+				LastError:    h.LastError,
+			}
+			if !h.LastEventAt.IsZero() {
+				row.LastEvent = formatRelativeTime(h.LastEventAt.Unix())
+			}
+			data.Relays = append(data.Relays, row)
+		}
+	}
+
+	if statsPanels[StatsPanelCaches] {
+		data.Caches = []HTMLStatsCache{
+			{Name: "Profiles", Entries: profileCache.Len(), Limit: "10 min TTL"},
+			{Name: "Relay queries", Entries: eventCache.Len(), Limit: fmt.Sprintf("max %d", eventCache.maxSize)},
+			{Name: "Contact lists", Entries: contactCache.Len(), Limit: "2 min TTL"},
+			{Name: "Relay lists", Entries: relayListCache.Len(), Limit: "30 min TTL"},
+			{Name: "Link previews", Entries: linkPreviewCache.Len(), Limit: "24 h TTL"},
+			{Name: "Reactions", Entries: reactionCache.Len(), Limit: "1 min TTL"},
+			{Name: "Engagement", Entries: engagementStats.Len(), Limit: "7 d"},
+			{Name: "Publish receipts", Entries: publishReceipts.Len(), Limit: fmt.Sprintf("7 d, max %d", publishReceipts.maxSize)},
+			{Name: "Ingested events", Entries: seenEventCache.Len(), Limit: fmt.Sprintf("max %d", seenEventCache.maxSize)},
+			{Name: "Crawler pages", Entries: crawlerPageCache.Len(), Limit: fmt.Sprintf("10 min TTL, max %d", crawlerPageMaxSize)},
+		}
+	}
+
+	if newKeyEmbargo.Enabled() {
+		snap := newKeyEmbargo.Snapshot()
+		data.Embargo = &HTMLStatsEmbargo{
+			MinAge:     formatEmbargoAge(snap.MinAge),
+			Anchors:    snap.Anchors,
+			Trusted:    snap.Trusted,
+			Tracked:    snap.Tracked,
+			Suppressed: snap.Suppressed,
+		}
+		if !snap.Refreshed.IsZero() {
+			data.Embargo.Refreshed = formatRelativeTime(snap.Refreshed.Unix())
+		}
+	}
+
+	if statsPanels[StatsPanelTraffic] {
+		data.Traffic = HTMLStatsTraffic{
+			Human:           trafficStats.Human.Load(),
+			Crawler:         trafficStats.Crawler.Load(),
+			CrawlerCached:   trafficStats.CrawlerCached.Load(),
+			CrawlerFallback: trafficStats.CrawlerFallback.Load(),
+		}
+	}
+
+	if statsPanels[StatsPanelUptime] {
+		data.Uptime = formatUptime(time.Since(serverStartTime))
+		data.StartedAt = serverStartTime.UTC().Format("2006-01-02 15:04 MST")
+	}
+
+	return data
+}
+
+// formatUptime formats a duration as "3d 4h 12m"
+func formatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+var htmlStatsContent = `{{define "content"}}
+<h1>Instance stats</h1>
+<p class="text-muted text-sm">Counters kept in memory since the server started. Updated at most once a minute.</p>
+
+{{if .Panels.uptime}}
+<h2>Uptime</h2>
+<p>{{.Uptime}} <span class="text-muted text-sm">(since {{.StartedAt}})</span></p>
+{{end}}
+
+{{if .Panels.kinds}}
+<h2>Events ingested</h2>
+<p class="text-sm">{{.TotalDay}} in the last 24 hours · {{.TotalWeek}} in the last 7 days</p>
+{{if .Kinds}}
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Kind</th><th scope="col">24 h</th><th scope="col">7 days</th><th scope="col"><span class="sr-only">Share</span></th></tr>
+  </thead>
+  <tbody>
+    {{range .Kinds}}
+    <tr>
+      <td>{{.Name}} <span class="text-muted text-sm">({{.Kind}})</span></td>
+      <td>{{.Day}}</td>
+      <td>{{.Week}}</td>
+      <td class="bar-cell"><span class="bar" style="width: {{.WeekWidth}}%"></span></td>
+    </tr>
+    {{end}}
+  </tbody>
+</table>
+{{else}}
+<p class="text-muted text-sm">No events ingested yet.</p>
+{{end}}
+{{end}}
+
+{{if .Panels.authors}}
+<h2>Authors</h2>
+<table class="data-table">
+  <tbody>
+    <tr><th scope="row">Distinct authors, last 24 hours</th><td>{{.AuthorsDay}}</td></tr>
+    <tr><th scope="row">Distinct authors, last 7 days</th><td>{{.AuthorsWeek}}</td></tr>
+  </tbody>
+</table>
+{{end}}
+
+{{if .Panels.relays}}
+<h2>Relay health</h2>
+{{if .Relays}}
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Relay</th><th scope="col">Status</th><th scope="col">Connects</th><th scope="col">Failures</th><th scope="col">Events</th><th scope="col">Last event</th></tr>
+  </thead>
+  <tbody>
+    {{range .Relays}}
+    <tr>
+      <td class="mono">{{.URL}}</td>
+      <td>{{if .Connected}}<span class="badge accent">connected</span>{{else}}<span class="badge">idle</span>{{end}}</td>
+      <td>{{.Connects}}</td>
+      <td>{{if .DialFailures}}<span title="{{.LastError}}">{{.DialFailures}}</span>{{else}}0{{end}}</td>
+      <td>{{.Events}}</td>
+      <td class="text-sm">{{if .LastEvent}}{{.LastEvent}}{{else}}-{{end}}</td>
+    </tr>
+    {{end}}
+  </tbody>
+</table>
+{{else}}
+<p class="text-muted text-sm">No relay connections yet.</p>
+{{end}}
+{{end}}
+
+{{if .Panels.traffic}}
+<h2>Page views</h2>
+<p class="text-muted text-sm">Thread and profile pages since start. Crawlers are served cached pages and never trigger relay queries.</p>
+<table class="data-table">
+  <tbody>
+    <tr><th scope="row">People</th><td>{{.Traffic.Human}}</td></tr>
+    <tr><th scope="row">Crawlers</th><td>{{.Traffic.Crawler}}</td></tr>
+    <tr><th scope="row">Crawlers, served a cached page</th><td>{{.Traffic.CrawlerCached}}</td></tr>
+    <tr><th scope="row">Crawlers, not cached</th><td>{{.Traffic.CrawlerFallback}}</td></tr>
+  </tbody>
+</table>
+{{end}}
+
+{{if .Panels.caches}}
+<h2>Caches</h2>
+<table class="data-table">
+  <thead>
+    <tr><th scope="col">Cache</th><th scope="col">Entries</th><th scope="col">Limit</th></tr>
+  </thead>
+  <tbody>
+    {{range .Caches}}
+    <tr><td>{{.Name}}</td><td>{{.Entries}}</td><td class="text-sm text-muted">{{.Limit}}</td></tr>
+    {{end}}
+  </tbody>
+</table>
+{{end}}
+
+{{with .Embargo}}
+<h2>New account policy</h2>
+<p class="text-sm">The global feed hides posts from accounts whose oldest post this instance has seen is less than {{.MinAge}} old{{if .Anchors}}, unless they are followed by someone the instance's {{.Anchors}} anchor account{{if gt .Anchors 1}}s{{end}} follow{{if eq .Anchors 1}}s{{end}}{{end}}. Follow feeds, profiles and threads show everything.</p>
+<table class="data-table">
+  <tbody>
+    <tr><th scope="row">Posts hidden since start</th><td>{{.Suppressed}}</td></tr>
+    <tr><th scope="row">Accounts tracked</th><td>{{.Tracked}}</td></tr>
+    {{if .Anchors}}<tr><th scope="row">Accounts vouched for</th><td>{{.Trusted}} <span class="text-muted text-sm">({{if .Refreshed}}updated {{.Refreshed}}{{else}}loading{{end}})</span></td></tr>{{end}}
+  </tbody>
+</table>
+{{end}}
+
+{{end}}
+
+{{define "styles"}}
+    .bar-cell { width: 35%; }
+{{end}}`
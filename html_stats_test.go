@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestStatsCacheControl(t *testing.T) {
+	s := startTestServer(t)
+	client, _ := s.login(t, testutil.NewKeypair("stats-alice"))
+
+	for _, tt := range []struct {
+		name   string
+		client *http.Client
+		want   string
+	}{
+		{"anonymous", http.DefaultClient, "public, max-age=60"},
+		{"logged in", client, "private, max-age=60"},
+	} {
+		// The second request is served from the page cache
+		for i := 0; i < 2; i++ {
+			resp, err := tt.client.Get(s.URL + "/about/stats")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("Cache-Control"); got != tt.want {
+				t.Errorf("%s request %d: Cache-Control %q, want %q", tt.name, i+1, got, tt.want)
+			}
+			if got := resp.Header.Get("Vary"); got != "Cookie" {
+				t.Errorf("%s request %d: Vary %q, want Cookie", tt.name, i+1, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+// testServer is the full HTTP server running against an in-memory relay
+type testServer struct {
+	URL   string
+	Relay *testutil.Relay
+}
+
+var initTestTemplates sync.Once
+
+// startTestServer starts an in-memory relay and the HTTP server, with RELAY_CONFIG
+// pointing every relay purpose at the relay
+// Caches are process-wide, so each test should seed its own keypairs.
+func startTestServer(t *testing.T) *testServer {
+	t.Helper()
+	initTestTemplates.Do(func() {
+		initTemplates()
+		initAuthTemplates()
+	})
+
+	relay := testutil.NewRelay()
+	t.Cleanup(relay.Close)
+
+	path := filepath.Join(t.TempDir(), "relays.json")
+	config := fmt.Sprintf(`{"relays":[{"url":%q,"purposes":["read","write","metadata","search","dm"]}]}`, relay.URL())
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RELAY_CONFIG", path)
+	cfg, err := loadRelayConfig(os.Getenv("RELAY_CONFIG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setRelayConfig(cfg)
+	t.Cleanup(func() { setRelayConfig(defaultRelayConfig()) })
+
+	srv := httptest.NewServer(traceRequests(newRouter()))
+	t.Cleanup(srv.Close)
+	return &testServer{URL: srv.URL, Relay: relay}
+}
+
+// login connects a mock signer for kp and returns a client holding the session cookie
+// The client doesn't follow redirects, so tests can check where a form sends the user.
+func (s *testServer) login(t *testing.T, kp *testutil.Keypair) (*http.Client, *testutil.Signer) {
+	t.Helper()
+	signer, err := testutil.NewSigner(s.Relay.URL(), kp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(signer.Close)
+
+	jar, err := testutil.Login(s.URL, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{
+		Jar:     jar,
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, signer
+}
+
+// get fetches a page and returns its body, failing the test on anything but a 200
+func (s *testServer) get(t *testing.T, client *http.Client, path string) string {
+	t.Helper()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(s.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, resp.StatusCode)
+	}
+	return string(body)
+}
+
+// post submits a form and returns the redirect location, failing the test on anything but a 303
+func (s *testServer) post(t *testing.T, client *http.Client, path string, form url.Values) string {
+	t.Helper()
+	resp, err := client.PostForm(s.URL+path, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("POST %s: status %d", path, resp.StatusCode)
+	}
+	return resp.Header.Get("Location")
+}
+
+var csrfFieldRe = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// csrfToken scrapes the session's CSRF token from a page with a form
+func (s *testServer) csrfToken(t *testing.T, client *http.Client) string {
+	t.Helper()
+	m := csrfFieldRe.FindStringSubmatch(s.get(t, client, "/html/timeline?kinds=1&limit=5"))
+	if m == nil {
+		t.Fatal("no csrf_token on the timeline")
+	}
+	return m[1]
+}
+
+// waitForEvent polls the relay for an event matching filter
+func waitForEvent(t *testing.T, relay *testutil.Relay, filter testutil.Filter) testutil.Event {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if events := relay.Query(filter); len(events) > 0 {
+			return events[0]
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("no event on the relay matching %+v", filter)
+	return testutil.Event{}
+}
+
+func assertContains(t *testing.T, page string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(page, w) {
+			t.Errorf("page is missing %q", w)
+		}
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("timeline-alice")
+	now := time.Now().Unix()
+	s.Relay.Publish(
+		testutil.Profile(alice, now-100, `{"name":"Alice Timeline"}`),
+		testutil.Note(alice, now-60, "first timeline note"),
+		testutil.Note(alice, now-30, "second timeline note"),
+	)
+
+	page := s.get(t, nil, "/html/timeline?kinds=1&limit=10&authors="+alice.PubKey)
+	assertContains(t, page, "first timeline note", "second timeline note", "Alice Timeline")
+	if strings.Index(page, "second timeline note") > strings.Index(page, "first timeline note") {
+		t.Error("timeline is not newest first")
+	}
+}
+
+func TestThread(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("thread-alice")
+	bob := testutil.NewKeypair("thread-bob")
+	now := time.Now().Unix()
+	root := testutil.Note(alice, now-60, "thread root note")
+	reply := testutil.Reply(bob, now-30, "thread reply note", root, root)
+	s.Relay.Publish(root, reply)
+
+	assertContains(t, s.get(t, nil, "/html/thread/"+root.ID), "thread root note", "thread reply note")
+	// A reply's own thread links back up to its parent
+	assertContains(t, s.get(t, nil, "/html/thread/"+reply.ID), "thread reply note", `href="/html/thread/`+root.ID+`"`)
+}
+
+func TestProfile(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("profile-alice")
+	now := time.Now().Unix()
+	s.Relay.Publish(
+		testutil.Profile(alice, now-100, `{"name":"Alice Profile","about":"Writes <b>about</b> birds"}`),
+		testutil.Note(alice, now-30, "profile page note"),
+	)
+
+	page := s.get(t, nil, "/html/profile/"+alice.PubKey)
+	assertContains(t, page, "Alice Profile", "Writes &lt;b&gt;about&lt;/b&gt; birds", "profile page note")
+	if strings.Contains(page, "<b>about</b>") {
+		t.Error("profile about is not escaped")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("publish-alice")
+	client, _ := s.login(t, alice)
+
+	location := s.post(t, client, "/html/post", url.Values{
+		"csrf_token": {s.csrfToken(t, client)},
+		"content":    {"published through the server"},
+	})
+	if !strings.Contains(location, "success=") {
+		t.Fatalf("post redirected to %s", location)
+	}
+
+	note := waitForEvent(t, s.Relay, testutil.Filter{Authors: []string{alice.PubKey}, Kinds: []int{1}})
+	if note.Content != "published through the server" {
+		t.Errorf("relay has %q", note.Content)
+	}
+	assertContains(t, s.get(t, client, "/html/thread/"+note.ID), "published through the server")
+}
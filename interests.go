@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/hex"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NIP-51 interest sets (kind 30015)
+// Followed hashtags are the union of the "t" tags across all of a user's interest sets.
+// Follows made here are added to the set with d-tag "hashtags".
+
+const kindInterestSet = 30015
+
+// followedTagsSetID is the d-tag of the interest set that follow actions write to
+const followedTagsSetID = "hashtags"
+
+// maxHashtagLength caps hashtags accepted from forms and query strings
+const maxHashtagLength = 64
+
+// normalizeHashtag lowercases a hashtag and strips a leading '#'
+// Returns "" if it contains anything other than letters, digits, '_' or '-'
+func normalizeHashtag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" || len(tag) > maxHashtagLength {
+		return ""
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return ""
+		}
+	}
+	return tag
+}
+
+// fetchInterestSets fetches the user's kind 30015 interest sets, newest version of each d-tag
+func fetchInterestSets(relays []string, pubkey string) []Event {
+	filter := Filter{
+		Kinds:   []int{kindInterestSet},
+		Authors: []string{pubkey},
+		Limit:   50,
+	}
+	events, _ := fetchEventsFromRelays(relays, filter)
+
+	// Events are newest first, so the first one per d-tag wins
+	seen := make(map[string]bool)
+	sets := make([]Event, 0, len(events))
+	for _, evt := range events {
+		d := extractDTag(evt.Tags)
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		sets = append(sets, evt)
+	}
+	return sets
+}
+
+// followedTagsFromSets returns the distinct normalized hashtags across interest sets, sorted
+func followedTagsFromSets(sets []Event) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, set := range sets {
+		for _, tag := range set.Tags {
+			if len(tag) < 2 || tag[0] != "t" {
+				continue
+			}
+			if t := normalizeHashtag(tag[1]); t != "" && !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// prefetchUserInterests fetches the user's interest sets and populates the session's followed tags
+// This should be called after login so the follows feed can include followed hashtags
+func prefetchUserInterests(session *BunkerSession, relays []string) {
+	go func() {
+		pubkeyHex := hex.EncodeToString(session.UserPubKey)
+		tags := followedTagsFromSets(fetchInterestSets(relays, pubkeyHex))
+		session.mu.Lock()
+		session.FollowedTags = tags
+		session.mu.Unlock()
+		log.Printf("Cached %d followed hashtags for user %s", len(tags), pubkeyHex[:16])
+	}()
+}
+
+// followedTagsOf returns a copy of the session's followed hashtags (nil when logged out)
+func followedTagsOf(session *BunkerSession) []string {
+	if session == nil || !session.Connected {
+		return nil
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return append([]string(nil), session.FollowedTags...)
+}
+
+// setHasTag reports whether an interest set's tags include the normalized hashtag
+func setHasTag(tags [][]string, hashtag string) bool {
+	for _, t := range tags {
+		if len(t) >= 2 && t[0] == "t" && normalizeHashtag(t[1]) == hashtag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFollowedTag returns the first of the event's hashtags that the user follows
+func matchFollowedTag(evt Event, followed map[string]bool) string {
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "t" {
+			if t := normalizeHashtag(tag[1]); followed[t] {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// mergeFollowedTagEvents merges the follows feed with events from followed hashtags,
+// newest first and deduped by ID. Returns the merged events and, for tag-sourced events
+// from people the user doesn't follow, the followed hashtag each was included for
+// Each list was fetched with its own limit, so a full list says nothing about what's
+// older than its oldest event; the merge is cut at the newer of the full lists' oldest
+// timestamps so the next page (until = oldest shown) can't skip events from either list.
+func mergeFollowedTagEvents(authorEvents, tagEvents []Event, authors, followedTags []string, fetchLimit int) ([]Event, map[string]string) {
+	followedAuthors := make(map[string]bool, len(authors))
+	for _, pk := range authors {
+		followedAuthors[pk] = true
+	}
+	followed := make(map[string]bool, len(followedTags))
+	for _, t := range followedTags {
+		followed[t] = true
+	}
+
+	seen := make(map[string]bool, len(authorEvents)+len(tagEvents))
+	merged := make([]Event, 0, len(authorEvents)+len(tagEvents))
+	fromTags := make(map[string]string)
+	for _, evt := range authorEvents {
+		if !seen[evt.ID] {
+			seen[evt.ID] = true
+			merged = append(merged, evt)
+		}
+	}
+	for _, evt := range tagEvents {
+		if seen[evt.ID] {
+			continue
+		}
+		seen[evt.ID] = true
+		merged = append(merged, evt)
+		if !followedAuthors[evt.PubKey] {
+			if t := matchFollowedTag(evt, followed); t != "" {
+				fromTags[evt.ID] = t
+			}
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].CreatedAt != merged[j].CreatedAt {
+			return merged[i].CreatedAt > merged[j].CreatedAt
+		}
+		return merged[i].ID > merged[j].ID
+	})
+
+	// A list that came back short is exhausted for this window and doesn't bound the page
+	cutoff := max(oldestIfFull(authorEvents, fetchLimit), oldestIfFull(tagEvents, fetchLimit))
+	if cutoff > 0 {
+		kept := merged[:0]
+		for _, evt := range merged {
+			if evt.CreatedAt >= cutoff {
+				kept = append(kept, evt)
+			} else {
+				delete(fromTags, evt.ID)
+			}
+		}
+		merged = kept
+	}
+	return merged, fromTags
+}
+
+// oldestIfFull returns the oldest created_at of a fetch that hit its limit, or 0
+func oldestIfFull(events []Event, fetchLimit int) int64 {
+	if fetchLimit <= 0 || len(events) < fetchLimit {
+		return 0
+	}
+	oldest := events[0].CreatedAt
+	for _, evt := range events[1:] {
+		oldest = min(oldest, evt.CreatedAt)
+	}
+	return oldest
+}
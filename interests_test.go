@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// feedEvents makes one event per timestamp, tagged #t when tag is set
+func feedEvents(prefix, tag string, timestamps ...int64) []Event {
+	events := make([]Event, len(timestamps))
+	for i, ts := range timestamps {
+		events[i] = Event{ID: fmt.Sprintf("%s%d", prefix, ts), PubKey: prefix, CreatedAt: ts}
+		if tag != "" {
+			events[i].Tags = [][]string{{"t", tag}}
+		}
+	}
+	return events
+}
+
+func TestMergeFollowedTagEventsCutoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		authors    []Event
+		tags       []Event
+		fetchLimit int
+		want       []int64
+	}{
+		{
+			name:       "both full, cut at the newer oldest",
+			authors:    feedEvents("a", "", 100, 90, 80),
+			tags:       feedEvents("t", "go", 95, 50, 10),
+			fetchLimit: 3,
+			want:       []int64{100, 95, 90, 80},
+		},
+		{
+			name:       "tag list short, only the author list bounds the page",
+			authors:    feedEvents("a", "", 100, 90, 80),
+			tags:       feedEvents("t", "go", 85, 20),
+			fetchLimit: 3,
+			want:       []int64{100, 90, 85, 80},
+		},
+		{
+			name:       "both short, nothing is cut",
+			authors:    feedEvents("a", "", 100),
+			tags:       feedEvents("t", "go", 50),
+			fetchLimit: 3,
+			want:       []int64{100, 50},
+		},
+		{
+			name:       "equal timestamps at the cutoff are kept",
+			authors:    feedEvents("a", "", 100, 80),
+			tags:       feedEvents("t", "go", 90, 80),
+			fetchLimit: 2,
+			want:       []int64{100, 90, 80, 80},
+		},
+		{
+			name:       "empty tag list",
+			authors:    feedEvents("a", "", 100, 90),
+			fetchLimit: 2,
+			want:       []int64{100, 90},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, fromTags := mergeFollowedTagEvents(tt.authors, tt.tags, []string{"a"}, []string{"go"}, tt.fetchLimit)
+			var got []int64
+			for _, evt := range merged {
+				got = append(got, evt.CreatedAt)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("merged created_at = %v, want %v", got, tt.want)
+			}
+			for id := range fromTags {
+				if !slices.ContainsFunc(merged, func(evt Event) bool { return evt.ID == id }) {
+					t.Errorf("fromTags has %s, which was cut", id)
+				}
+			}
+		})
+	}
+}
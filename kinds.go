@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Per-kind event limits
+// Applied before signing anything we publish (SignEvent) and when ingesting events
+// from relays, so a buggy form can't publish a 2MB note and other clients' oversized
+// events aren't rendered. Kinds without an entry get defaultEventLimits; add an entry
+// when adding support for a kind that needs more (or less) room.
+
+// EventLimits caps an event's size
+type EventLimits struct {
+	MaxContent  int // bytes of content
+	MaxTags     int // number of tags
+	MaxTagValue int // bytes per tag element
+}
+
+// maxTagElements caps the elements in a single tag (imeta tags are the longest in practice)
+const maxTagElements = 64
+
+var defaultEventLimits = EventLimits{MaxContent: 64 * 1024, MaxTags: 500, MaxTagValue: 4 * 1024}
+
+// maxListTags caps contact lists and NIP-51 lists, bounding the memory one takes and the
+// time to sign it. Real lists stay well under this; these are replaceable, so dropping a
+// real one on ingest would have us publish a short list over it.
+const maxListTags = 20000
+
+var kindLimits = map[int]EventLimits{
+	0:     {MaxContent: 32 * 1024, MaxTags: 100, MaxTagValue: 4 * 1024},      // profile metadata
+	1:     {MaxContent: 32 * 1024, MaxTags: 500, MaxTagValue: 4 * 1024},      // note
+	3:     {MaxContent: 64 * 1024, MaxTags: maxListTags, MaxTagValue: 1024},  // contact list
+	5:     {MaxContent: 4 * 1024, MaxTags: 500, MaxTagValue: 1024},           // NIP-09 deletion request
+	6:     {MaxContent: 64 * 1024, MaxTags: 50, MaxTagValue: 4 * 1024},       // repost (embeds the original)
+	7:     {MaxContent: 1024, MaxTags: 50, MaxTagValue: 4 * 1024},            // reaction
+	13:    {MaxContent: 256 * 1024, MaxTags: 0, MaxTagValue: 0},              // NIP-59 seal, tags must be empty
+	14:    {MaxContent: maxPlaintextSize, MaxTags: 100, MaxTagValue: 1024},   // NIP-17 message (rumor), NIP-44 plaintext limit
+	1059:  {MaxContent: 512 * 1024, MaxTags: 10, MaxTagValue: 1024},          // NIP-59 gift wrap
+	1984:  {MaxContent: 4 * 1024, MaxTags: 20, MaxTagValue: 1024},            // report
+	1985:  {MaxContent: 4 * 1024, MaxTags: 200, MaxTagValue: 1024},           // label
+	9735:  {MaxContent: 4 * 1024, MaxTags: 50, MaxTagValue: 64 * 1024},       // zap receipt (description holds the zap request)
+	10000: {MaxContent: 256 * 1024, MaxTags: maxListTags, MaxTagValue: 1024}, // mute list
+	10002: {MaxContent: 4 * 1024, MaxTags: 100, MaxTagValue: 1024},           // relay list
+	10003: {MaxContent: 256 * 1024, MaxTags: maxListTags, MaxTagValue: 1024}, // bookmarks
+	30000: {MaxContent: 256 * 1024, MaxTags: maxListTags, MaxTagValue: 1024}, // follow sets
+	30003: {MaxContent: 256 * 1024, MaxTags: maxListTags, MaxTagValue: 1024}, // bookmark sets
+	30015: {MaxContent: 64 * 1024, MaxTags: maxListTags, MaxTagValue: 1024},  // interest sets
+	30023: {MaxContent: 512 * 1024, MaxTags: 500, MaxTagValue: 4 * 1024},     // long-form article
+}
+
+// limitsForKind returns the limits for a kind
+func limitsForKind(kind int) EventLimits {
+	if l, ok := kindLimits[kind]; ok {
+		return l
+	}
+	return defaultEventLimits
+}
+
+// EventLimitError describes which limit an event broke, in terms fit to show the user
+type EventLimitError struct {
+	Kind   int
+	Reason string
+}
+
+func (e *EventLimitError) Error() string {
+	return e.Reason
+}
+
+// checkEventLimits reports the first limit the content and tags break, or nil
+func checkEventLimits(kind int, content string, tags [][]string) error {
+	limits := limitsForKind(kind)
+	if !utf8.ValidString(content) {
+		return &EventLimitError{Kind: kind, Reason: "Content is not valid UTF-8"}
+	}
+	if len(content) > limits.MaxContent {
+		return &EventLimitError{Kind: kind, Reason: fmt.Sprintf("Content is too long (%d bytes, limit %d)", len(content), limits.MaxContent)}
+	}
+	if len(tags) > limits.MaxTags {
+		return &EventLimitError{Kind: kind, Reason: fmt.Sprintf("Too many tags (%d, limit %d)", len(tags), limits.MaxTags)}
+	}
+	for _, tag := range tags {
+		if len(tag) > maxTagElements {
+			return &EventLimitError{Kind: kind, Reason: fmt.Sprintf("Tag has too many values (%d, limit %d)", len(tag), maxTagElements)}
+		}
+		for _, v := range tag {
+			if !utf8.ValidString(v) {
+				return &EventLimitError{Kind: kind, Reason: "Tag is not valid UTF-8"}
+			}
+			if len(v) > limits.MaxTagValue {
+				return &EventLimitError{Kind: kind, Reason: fmt.Sprintf("Tag value is too long (%d bytes, limit %d)", len(v), limits.MaxTagValue)}
+			}
+		}
+	}
+	return nil
+}
+
+// prepareEventForSigning strips control characters from the content and tags and checks
+// the result against the kind's limits
+func prepareEventForSigning(event *UnsignedEvent) error {
+	event.Content = stripControlChars(event.Content)
+	for i, tag := range event.Tags {
+		for j, v := range tag {
+			event.Tags[i][j] = stripControlChars(v)
+		}
+	}
+	return checkEventLimits(event.Kind, event.Content, event.Tags)
+}
+
+// stripControlChars removes control characters other than newline and tab,
+// and drops invalid UTF-8 so it can't reach the signer
+func stripControlChars(s string) string {
+	clean := true
+	for _, r := range s {
+		if r == utf8.RuneError || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+			return -1
+		}
+		return r
+	}, s)
+}
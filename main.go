@@ -4,11 +4,15 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 )
 
 // Request body size limits
 const (
 	maxBodySize = 32 * 1024 // 32KB for POST requests
+	// Note composers: a percent-encoded note can be three times its content limit
+	// (kinds.go), and a note over the limit must still arrive to get the limit's error
+	maxComposerBodySize = 128 * 1024
 )
 
 // limitBody wraps an HTTP handler to limit request body size
@@ -30,12 +34,14 @@ func securityHeaders(next http.HandlerFunc) http.HandlerFunc {
 		// - media-src *: allow audio/video from anywhere
 		// - frame-src youtube.com youtube-nocookie.com: allow YouTube embeds
 		// - style-src 'self' 'unsafe-inline': allow inline styles for theming
+		// - font-src 'self': fonts only from static/ (see assets.go)
 		// - script-src 'self': only allow scripts from same origin
 		csp := "default-src 'self'; " +
 			"img-src * data:; " +
 			"media-src *; " +
 			"frame-src https://www.youtube.com https://www.youtube-nocookie.com; " +
 			"style-src 'self' 'unsafe-inline'; " +
+			"font-src 'self'; " +
 			"script-src 'self'"
 		w.Header().Set("Content-Security-Policy", csp)
 
@@ -60,55 +66,105 @@ func main() {
 	initTemplates()
 	initAuthTemplates()
 
+	// Load default relay configuration (RELAY_CONFIG), reloaded on SIGHUP
+	initRelayConfig()
+
+	// Instance name, logo, accent colour and footer links (BRANDING_CONFIG), reloaded on SIGHUP
+	initBranding()
+
+	// Outgoing operator webhooks (WEBHOOK_CONFIG), if configured
+	initWebhooks()
+
+	// Operators allowed on the admin status page (ADMIN_PUBKEYS)
+	initAdmins()
+
+	// Poll the instance announcement (ANNOUNCEMENT_PUBKEY/ANNOUNCEMENT_D), if configured
+	initAnnouncements()
+
+	// New-key embargo for the global feed (optional)
+	initNewKeyEmbargo()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	// Start NIP-46 connection listener for nostrconnect:// flow
+	StartConnectionListener(defaultNostrConnectRelays)
+
+	log.Printf("Starting server on :%s", port)
+	log.Printf("Open http://localhost:%s in your browser", port)
+	if debugTiming {
+		log.Printf("Request timing enabled (DEBUG_TIMING=true)")
+	}
+	if err := http.ListenAndServe(":"+port, traceRequests(newRouter())); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newRouter registers every route on a fresh mux
+func newRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
 	// Serve static files
-	fs := http.FileServer(http.Dir("./static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	mux.Handle("/static/", staticHandler())
 
 	// API endpoints (these handle content negotiation internally)
-	http.HandleFunc("/timeline", timelineHandler)
-	http.HandleFunc("/thread/", threadHandler)
+	mux.HandleFunc("/timeline", timelineHandler)
+	mux.HandleFunc("/thread/", threadHandler)
 
-	// Root path redirects to HTML timeline, everything else 404
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	// Root path redirects to HTML timeline, /{note1|nevent1|npub1|nprofile1} to the
+	// canonical page, everything else 404
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
 			http.Redirect(w, r, "/html/timeline?kinds=1&limit=20", http.StatusFound)
+		} else if path := canonicalPathForIdentifier(strings.TrimPrefix(r.URL.Path, "/")); path != "" {
+			redirectToCanonical(w, r, path)
 		} else {
 			http.NotFound(w, r)
 		}
 	})
 	// HTML handlers wrapped with security headers
-	http.HandleFunc("/html/timeline", securityHeaders(htmlTimelineHandler))
-	http.HandleFunc("/html/thread/", securityHeaders(htmlThreadHandler))
-	http.HandleFunc("/html/profile/edit", securityHeaders(limitBody(htmlProfileEditHandler, maxBodySize)))
-	http.HandleFunc("/html/profile/", securityHeaders(htmlProfileHandler))
-	http.HandleFunc("/html/login", securityHeaders(limitBody(htmlLoginHandler, maxBodySize)))
-	http.HandleFunc("/html/logout", securityHeaders(htmlLogoutHandler))
-	http.HandleFunc("/html/post", securityHeaders(limitBody(htmlPostNoteHandler, maxBodySize)))
-	http.HandleFunc("/html/reply", securityHeaders(limitBody(htmlReplyHandler, maxBodySize)))
-	http.HandleFunc("/html/react", securityHeaders(limitBody(htmlReactHandler, maxBodySize)))
-	http.HandleFunc("/html/bookmark", securityHeaders(limitBody(htmlBookmarkHandler, maxBodySize)))
-	http.HandleFunc("/html/repost", securityHeaders(limitBody(htmlRepostHandler, maxBodySize)))
-	http.HandleFunc("/html/follow", securityHeaders(limitBody(htmlFollowHandler, maxBodySize)))
-	http.HandleFunc("/html/quote/", securityHeaders(htmlQuoteHandler))
-	http.HandleFunc("/html/check-connection", securityHeaders(htmlCheckConnectionHandler))
-	http.HandleFunc("/html/reconnect", securityHeaders(htmlReconnectHandler))
-	http.HandleFunc("/html/theme", securityHeaders(htmlThemeHandler))
-	http.HandleFunc("/html/notifications", securityHeaders(htmlNotificationsHandler))
-	http.HandleFunc("/health", healthHandler)
-
-	// Start NIP-46 connection listener for nostrconnect:// flow
-	StartConnectionListener(defaultNostrConnectRelays)
-
-	log.Printf("Starting server on :%s", port)
-	log.Printf("Open http://localhost:%s in your browser", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal(err)
-	}
+	mux.HandleFunc("/html/timeline", securityHeaders(htmlTimelineHandler))
+	mux.HandleFunc("/html/thread/", securityHeaders(crawlerPages(htmlThreadHandler)))
+	mux.HandleFunc("/event/", securityHeaders(htmlEventHandler))
+	mux.HandleFunc("/html/event/", securityHeaders(htmlEventHandler))
+	mux.HandleFunc("/html/profile/edit", securityHeaders(limitBody(htmlProfileEditHandler, maxBodySize)))
+	mux.HandleFunc("/html/profile/", securityHeaders(crawlerPages(htmlProfileHandler)))
+	mux.HandleFunc("/html/login", securityHeaders(limitBody(htmlLoginHandler, maxBodySize)))
+	mux.HandleFunc("/html/logout", securityHeaders(htmlLogoutHandler))
+	mux.HandleFunc("/html/post", securityHeaders(limitBody(htmlPostNoteHandler, maxComposerBodySize)))
+	mux.HandleFunc("/html/reply", securityHeaders(limitBody(htmlReplyHandler, maxComposerBodySize)))
+	mux.HandleFunc("/html/react", securityHeaders(limitBody(htmlReactHandler, maxBodySize)))
+	mux.HandleFunc("/html/bookmark", securityHeaders(limitBody(htmlBookmarkHandler, maxBodySize)))
+	mux.HandleFunc("/html/repost", securityHeaders(limitBody(htmlRepostHandler, maxBodySize)))
+	mux.HandleFunc("/html/pending", securityHeaders(limitBody(htmlPendingHandler, maxBodySize)))
+	mux.HandleFunc("/html/republish", securityHeaders(limitBody(htmlRepublishHandler, maxBodySize)))
+	mux.HandleFunc("/html/follow", securityHeaders(limitBody(htmlFollowHandler, maxBodySize)))
+	mux.HandleFunc("/html/follow-tag", securityHeaders(limitBody(htmlFollowTagHandler, maxBodySize)))
+	mux.HandleFunc("/html/label", securityHeaders(limitBody(htmlLabelHandler, maxBodySize)))
+	mux.HandleFunc("/html/quote/", securityHeaders(limitBody(htmlQuoteHandler, maxComposerBodySize)))
+	mux.HandleFunc("/html/check-connection", securityHeaders(htmlCheckConnectionHandler))
+	mux.HandleFunc("/html/reconnect", securityHeaders(htmlReconnectHandler))
+	mux.HandleFunc("/html/theme", securityHeaders(htmlThemeHandler))
+	mux.HandleFunc("/html/reactions", securityHeaders(htmlReactionsHandler))
+	mux.HandleFunc("/html/announcement/dismiss", securityHeaders(limitBody(htmlDismissAnnouncementHandler, maxBodySize)))
+	mux.HandleFunc("/html/notifications", securityHeaders(htmlNotificationsHandler))
+	mux.HandleFunc("/html/deck", securityHeaders(limitBody(htmlDeckHandler, maxBodySize)))
+	mux.HandleFunc("/html/messages", securityHeaders(htmlMessagesHandler))
+	mux.HandleFunc("/html/messages/", securityHeaders(htmlMessagesHandler))
+	mux.HandleFunc("/html/messages/send", securityHeaders(limitBody(htmlSendMessageHandler, maxBodySize)))
+	mux.HandleFunc("/html/relays", securityHeaders(htmlRelaysHandler))
+	mux.HandleFunc("/html/relays/info", securityHeaders(htmlRelayInfoHandler))
+	mux.HandleFunc("/html/settings/appearance", securityHeaders(limitBody(htmlAppearanceHandler, maxBodySize)))
+	mux.HandleFunc("/html/settings/danger", securityHeaders(limitBody(htmlDangerHandler, maxBodySize)))
+	mux.HandleFunc("/about/stats", securityHeaders(htmlStatsHandler))
+	mux.HandleFunc("/html/admin/status", securityHeaders(htmlAdminStatusHandler))
+	mux.HandleFunc("/embed/", embedHeaders(htmlEmbedHandler))
+	mux.HandleFunc("/oembed", oEmbedHandler)
+	mux.HandleFunc("/labels/", securityHeaders(htmlLabelFeedHandler))
+	mux.HandleFunc("/health", healthHandler)
+	return mux
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NIP-17 private direct messages
+// kind 14 rumor (unsigned) -> sealed in kind 13 by the sender -> gift-wrapped in kind 1059
+// with an ephemeral key. Both layers are NIP-44 encrypted to the recipient, so unwrapping
+// takes two nip44_decrypt calls to the user's signer.
+//
+// Decrypted messages only live in DMMessage values for the duration of a request:
+// they are never stored in a cache, written to the session, or logged.
+
+const (
+	kindDMRumor     = 14
+	kindSeal        = 13
+	kindGiftWrap    = 1059
+	kindDMRelayList = 10050
+)
+
+// dmRelayCache caches kind 10050 DM relay lists (pubkey -> relay URLs)
+var dmRelayCache = &ContactCache{ttl: 30 * time.Minute}
+
+// DMMessage is a decrypted kind 14 message. Never cache or log these
+type DMMessage struct {
+	ID         string // rumor ID, recomputed (rumors are unsigned)
+	WrapID     string // gift wrap event ID (safe to log)
+	Sender     string
+	Recipients []string
+	Subject    string
+	Content    string
+	CreatedAt  int64
+}
+
+// DMConversation groups messages by the set of other participants
+type DMConversation struct {
+	ID           string   // counterparty pubkeys, sorted and comma-joined
+	Counterparty []string // everyone in the conversation except the user
+	Messages     []DMMessage
+}
+
+// LastMessage returns the newest message in the conversation
+func (c *DMConversation) LastMessage() DMMessage {
+	return c.Messages[len(c.Messages)-1]
+}
+
+// fetchDMRelays returns the user's kind 10050 DM inbox relays,
+// falling back to the configured dm relays when none are published
+func fetchDMRelays(pubkey string) []string {
+	if relays, ok := dmRelayCache.Get(pubkey); ok {
+		return relays
+	}
+
+	filter := Filter{
+		Authors: []string{pubkey},
+		Kinds:   []int{kindDMRelayList},
+		Limit:   1,
+	}
+	// Looked up like kind 10002 relay lists (fetchRelayList)
+	indexerRelays := append(relaysFor(RelayPurposeMetadata), relaysFor(RelayPurposeRead)...)
+	events, _ := fetchEventsFromRelaysWithTimeout(indexerRelays, filter, 2*time.Second)
+
+	var relays []string
+	if len(events) > 0 {
+		for _, tag := range events[0].Tags {
+			if len(tag) >= 2 && tag[0] == "relay" && isRelayURLSafe(tag[1]) {
+				relays = append(relays, tag[1])
+			}
+		}
+	}
+	if len(relays) == 0 {
+		relays = relaysFor(RelayPurposeDM)
+	}
+
+	dmRelayCache.Set(pubkey, relays)
+	return relays
+}
+
+// fetchGiftWraps fetches kind 1059 gift wraps addressed to the user
+func fetchGiftWraps(ctx context.Context, relays []string, pubkey string, limit int, until *int64) []Event {
+	filter := Filter{
+		Kinds: []int{kindGiftWrap},
+		PTags: []string{pubkey},
+		Limit: limit,
+		Until: until,
+	}
+	// Uncached: wraps are ciphertext, but there's no reason to keep them around
+	events, _ := fetchEventsFromRelaysCtx(ctx, relays, filter, 3*time.Second)
+	return events
+}
+
+// unwrapGiftWrap decrypts a gift wrap and its seal through the user's signer
+// and returns the inner kind 14 message
+func unwrapGiftWrap(ctx context.Context, session *BunkerSession, wrap Event) (*DMMessage, error) {
+	if wrap.Kind != kindGiftWrap {
+		return nil, fmt.Errorf("not a gift wrap (kind %d)", wrap.Kind)
+	}
+
+	sealJSON, err := session.Nip44Decrypt(ctx, wrap.PubKey, wrap.Content)
+	if err != nil {
+		return nil, err
+	}
+	var seal Event
+	if err := json.Unmarshal([]byte(sealJSON), &seal); err != nil {
+		return nil, errors.New("malformed seal")
+	}
+	if seal.Kind != kindSeal {
+		return nil, fmt.Errorf("unexpected seal kind %d", seal.Kind)
+	}
+	// The seal is signed by the real sender - verify it so the sender can't be forged
+	if calculateEventID(&seal) != seal.ID || !validateEventSignature(&seal) {
+		return nil, errors.New("invalid seal signature")
+	}
+
+	rumorJSON, err := session.Nip44Decrypt(ctx, seal.PubKey, seal.Content)
+	if err != nil {
+		return nil, err
+	}
+	var rumor Event
+	if err := json.Unmarshal([]byte(rumorJSON), &rumor); err != nil {
+		return nil, errors.New("malformed rumor")
+	}
+	if rumor.Kind != kindDMRumor {
+		return nil, fmt.Errorf("unsupported rumor kind %d", rumor.Kind)
+	}
+	if rumor.PubKey != seal.PubKey {
+		return nil, errors.New("rumor author does not match seal signer")
+	}
+
+	msg := &DMMessage{
+		ID:        calculateEventID(&rumor),
+		WrapID:    wrap.ID,
+		Sender:    rumor.PubKey,
+		Content:   rumor.Content,
+		CreatedAt: rumor.CreatedAt,
+	}
+	for _, tag := range rumor.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "p":
+			if isValidEventID(tag[1]) {
+				msg.Recipients = append(msg.Recipients, tag[1])
+			}
+		case "subject":
+			msg.Subject = tag[1]
+		}
+	}
+	return msg, nil
+}
+
+// dmCounterparty returns the other participants of a message as seen by userPubkey, sorted
+func dmCounterparty(msg DMMessage, userPubkey string) []string {
+	seen := make(map[string]bool)
+	var others []string
+	for _, pk := range append([]string{msg.Sender}, msg.Recipients...) {
+		if pk == userPubkey || seen[pk] {
+			continue
+		}
+		seen[pk] = true
+		others = append(others, pk)
+	}
+	if len(others) == 0 {
+		// Note to self
+		others = []string{userPubkey}
+	}
+	sort.Strings(others)
+	return others
+}
+
+// groupDMConversations groups messages by counterparty set, newest conversation first
+// Messages within a conversation are oldest first; duplicate rumors are dropped
+func groupDMConversations(messages []DMMessage, userPubkey string) []*DMConversation {
+	byID := make(map[string]*DMConversation)
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		if seen[msg.ID] {
+			continue
+		}
+		seen[msg.ID] = true
+
+		others := dmCounterparty(msg, userPubkey)
+		id := strings.Join(others, ",")
+		conv := byID[id]
+		if conv == nil {
+			conv = &DMConversation{ID: id, Counterparty: others}
+			byID[id] = conv
+		}
+		conv.Messages = append(conv.Messages, msg)
+	}
+
+	conversations := make([]*DMConversation, 0, len(byID))
+	for _, conv := range byID {
+		sort.Slice(conv.Messages, func(i, j int) bool {
+			return conv.Messages[i].CreatedAt < conv.Messages[j].CreatedAt
+		})
+		conversations = append(conversations, conv)
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].LastMessage().CreatedAt > conversations[j].LastMessage().CreatedAt
+	})
+	return conversations
+}
+
+// dmTimestampJitter is how far seals and wraps are backdated (NIP-59 recommends up to two days)
+const dmTimestampJitter = 2 * 24 * 60 * 60
+
+// randomPastTimestamp returns a created_at randomly up to two days in the past,
+// so relays can't correlate wraps with the real send time
+func randomPastTimestamp() int64 {
+	n, err := rand.Int(rand.Reader, big.NewInt(dmTimestampJitter))
+	if err != nil {
+		return time.Now().Unix()
+	}
+	return time.Now().Unix() - n.Int64()
+}
+
+// rumorJSON serializes an unsigned rumor (no sig field)
+func rumorJSON(rumor *Event) string {
+	return mustJSON(struct {
+		ID        string     `json:"id"`
+		PubKey    string     `json:"pubkey"`
+		CreatedAt int64      `json:"created_at"`
+		Kind      int        `json:"kind"`
+		Tags      [][]string `json:"tags"`
+		Content   string     `json:"content"`
+	}{rumor.ID, rumor.PubKey, rumor.CreatedAt, rumor.Kind, rumor.Tags, rumor.Content})
+}
+
+// giftWrapSeal wraps a signed seal for recipient with a fresh throwaway key
+// The server only ever sees the seal's ciphertext, never the user's key
+func giftWrapSeal(seal *Event, recipient string) (*Event, error) {
+	recipientBytes, err := hex.DecodeString(recipient)
+	if err != nil {
+		return nil, errors.New("invalid recipient pubkey")
+	}
+	ephemeralPriv, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPub, err := GetPublicKey(ephemeralPriv)
+	if err != nil {
+		return nil, err
+	}
+	convKey, err := GetConversationKey(ephemeralPriv, recipientBytes)
+	if err != nil {
+		return nil, err
+	}
+	content, err := Nip44Encrypt(mustJSON(seal), convKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wrap := &Event{
+		PubKey:    hex.EncodeToString(ephemeralPub),
+		CreatedAt: randomPastTimestamp(),
+		Kind:      kindGiftWrap,
+		Tags:      [][]string{{"p", recipient}},
+		Content:   content,
+	}
+	wrap.ID = calculateEventID(wrap)
+	wrap.Sig = signEvent(ephemeralPriv, wrap.ID)
+	return wrap, nil
+}
+
+// DMWrapResult is the publish outcome of one gift wrap
+type DMWrapResult struct {
+	Recipient string // pubkey the wrap is addressed to
+	WrapID    string
+	Relays    []RelayPublishResult
+}
+
+// sendDirectMessage sends a kind 14 message to recipients as NIP-17 gift wraps:
+// one per recipient plus the sender's own copy, each published to that person's DM relays.
+// The signer encrypts the rumor and signs each seal; all wraps are built before anything
+// is published, so a signer failure never leaves a partial send
+func sendDirectMessage(ctx context.Context, session *BunkerSession, recipients []string, content string) ([]DMWrapResult, error) {
+	sender := hex.EncodeToString(session.UserPubKey)
+
+	tags := make([][]string, 0, len(recipients))
+	for _, pk := range recipients {
+		tags = append(tags, []string{"p", pk})
+	}
+	// The rumor is never signed, so apply the publish limits here
+	content = stripControlChars(content)
+	if err := checkEventLimits(kindDMRumor, content, tags); err != nil {
+		return nil, err
+	}
+	rumor := &Event{
+		PubKey:    sender,
+		CreatedAt: time.Now().Unix(),
+		Kind:      kindDMRumor,
+		Tags:      tags,
+		Content:   content,
+	}
+	rumor.ID = calculateEventID(rumor)
+	plaintext := rumorJSON(rumor)
+
+	// Recipients first, then the sender's copy (skipped for notes to self)
+	targets := append([]string(nil), recipients...)
+	selfIncluded := false
+	for _, pk := range recipients {
+		selfIncluded = selfIncluded || pk == sender
+	}
+	if !selfIncluded {
+		targets = append(targets, sender)
+	}
+
+	wraps := make([]*Event, 0, len(targets))
+	for _, target := range targets {
+		encrypted, err := session.Nip44Encrypt(ctx, target, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		seal, err := session.SignEvent(ctx, UnsignedEvent{
+			Kind:      kindSeal,
+			Content:   encrypted,
+			Tags:      [][]string{},
+			CreatedAt: randomPastTimestamp(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if seal.Kind != kindSeal || seal.PubKey != sender || !validateEventSignature(seal) {
+			return nil, errors.New("signer returned an invalid seal")
+		}
+		wrap, err := giftWrapSeal(seal, target)
+		if err != nil {
+			return nil, fmt.Errorf("gift wrap failed: %v", err)
+		}
+		wraps = append(wraps, wrap)
+	}
+
+	results := make([]DMWrapResult, len(wraps))
+	for i, wrap := range wraps {
+		results[i] = DMWrapResult{
+			Recipient: targets[i],
+			WrapID:    wrap.ID,
+			Relays:    publishEventWithResults(ctx, fetchDMRelays(targets[i]), wrap),
+		}
+	}
+	return results, nil
+}
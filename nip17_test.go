@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+// lockedBuffer collects log output written from any goroutine
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs sends the standard logger to a buffer for the rest of the test
+func captureLogs(t *testing.T) *lockedBuffer {
+	buf := &lockedBuffer{}
+	prev := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() { log.SetOutput(prev) })
+	return buf
+}
+
+// dmCaches is every process-wide store a request can write to. The relay pool isn't
+// here: it only holds connections, and everything on the wire is NIP-44 ciphertext.
+func dmCaches() map[string]interface{} {
+	return map[string]interface{}{
+		"profileCache":       profileCache,
+		"eventCache":         eventCache,
+		"contactCache":       contactCache,
+		"relayListCache":     relayListCache,
+		"reactionCache":      reactionCache,
+		"linkPreviewCache":   linkPreviewCache,
+		"seenEventCache":     seenEventCache,
+		"crawlerPageCache":   crawlerPageCache,
+		"relayInfoCache":     relayInfoCache,
+		"dmRelayCache":       dmRelayCache,
+		"statsPageCache":     statsPageCache,
+		"publishReceipts":    publishReceipts,
+		"ingestStats":        ingestStats,
+		"engagementStats":    engagementStats,
+		"newKeyEmbargo":      newKeyEmbargo,
+		"accountWipeJobs":    accountWipeJobs,
+		"pendingConnections": pendingConnections,
+		"bunkerSessions":     bunkerSessions, // Sessions, their pending events and deck state
+	}
+}
+
+var (
+	syncMapType  = reflect.TypeOf(sync.Map{})
+	mutexType    = reflect.TypeOf(sync.Mutex{})
+	rwMutexType  = reflect.TypeOf(sync.RWMutex{})
+	atomicPtrPkg = reflect.TypeOf(sync.Mutex{}).PkgPath() + "/atomic"
+)
+
+// reachFinder looks for a string anywhere in the memory reachable from a value,
+// including unexported fields, sync.Maps and atomic pointers
+type reachFinder struct {
+	needle []byte
+	seen   map[uintptr]bool
+}
+
+// find returns the path to where the needle was found, or ""
+func (f *reachFinder) find(v reflect.Value, path string) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if strings.Contains(v.String(), string(f.needle)) {
+			return path
+		}
+	case reflect.Pointer:
+		if v.IsNil() || f.seen[v.Pointer()] {
+			return ""
+		}
+		f.seen[v.Pointer()] = true
+		return f.find(v.Elem(), path)
+	case reflect.Interface:
+		return f.find(v.Elem(), path)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if bytes.Contains(v.Bytes(), f.needle) {
+				return path
+			}
+			return ""
+		}
+		for i := 0; i < v.Len(); i++ {
+			if p := f.find(v.Index(i), path+"[]"); p != "" {
+				return p
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if p := f.find(v.Index(i), path+"[]"); p != "" {
+				return p
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() || f.seen[v.Pointer()] {
+			return ""
+		}
+		f.seen[v.Pointer()] = true
+		iter := v.MapRange()
+		for iter.Next() {
+			if p := f.find(iter.Key(), path+"{key}"); p != "" {
+				return p
+			}
+			if p := f.find(iter.Value(), path+"{}"); p != "" {
+				return p
+			}
+		}
+	case reflect.Struct:
+		return f.findInStruct(v, path)
+	}
+	return ""
+}
+
+func (f *reachFinder) findInStruct(v reflect.Value, path string) string {
+	t := v.Type()
+	if t == syncMapType && v.CanAddr() {
+		var found string
+		(*sync.Map)(v.Addr().UnsafePointer()).Range(func(key, value any) bool {
+			found = f.find(reflect.ValueOf(key), path+"{key}")
+			if found == "" {
+				found = f.find(reflect.ValueOf(value), path+"{}")
+			}
+			return found == ""
+		})
+		return found
+	}
+	if t.PkgPath() == atomicPtrPkg && strings.HasPrefix(t.Name(), "Pointer[") {
+		// atomic.Pointer[T] is {_ [0]*T; _ noCopy; v unsafe.Pointer}
+		elem := t.Field(0).Type.Elem().Elem()
+		if p := v.Field(t.NumField() - 1).UnsafePointer(); p != nil {
+			return f.find(reflect.NewAt(elem, p), path)
+		}
+		return ""
+	}
+
+	// Hold the struct's own lock while reading its fields
+	if mu := v.FieldByName("mu"); mu.IsValid() && mu.CanAddr() {
+		switch mu.Type() {
+		case mutexType:
+			m := (*sync.Mutex)(mu.Addr().UnsafePointer())
+			m.Lock()
+			defer m.Unlock()
+		case rwMutexType:
+			m := (*sync.RWMutex)(mu.Addr().UnsafePointer())
+			m.RLock()
+			defer m.RUnlock()
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if p := f.find(v.Field(i), path+"."+t.Field(i).Name); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// findInCaches returns where in the process-wide caches and stores needle is held, or ""
+func findInCaches(needle string) string {
+	f := &reachFinder{needle: []byte(needle), seen: make(map[uintptr]bool)}
+	for name, cache := range dmCaches() {
+		if p := f.find(reflect.ValueOf(cache), name); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// secretText returns a unique message body to search for
+func secretText(t *testing.T) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return "dm-secret-" + hex.EncodeToString(b)
+}
+
+func TestDMPlaintextNeverCachedOrLogged(t *testing.T) {
+	logs := captureLogs(t)
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("dm-leak-alice")
+	bob := testutil.NewKeypair("dm-leak-bob")
+	received, sent := secretText(t), secretText(t)
+
+	// Bob -> Alice, with Bob's own copy
+	rumor := testutil.Rumor(bob, time.Now().Unix()-60, received, alice.PubKey)
+	for _, to := range []string{alice.PubKey, bob.PubKey} {
+		wrap, err := testutil.GiftWrap(bob, rumor, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Relay.Publish(wrap)
+	}
+
+	client, _ := s.login(t, alice)
+	assertContains(t, s.get(t, client, "/html/messages"), received)
+	assertContains(t, s.get(t, client, "/html/messages/"+bob.PubKey), received)
+
+	// Alice -> Bob (the wraps are published before the response), then read it back
+	// from her own copy
+	resp, err := client.PostForm(s.URL+"/html/messages/send", url.Values{
+		"csrf_token":   {s.csrfToken(t, client)},
+		"conversation": {bob.PubKey},
+		"content":      {sent},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	thread := s.get(t, client, "/html/messages/"+bob.PubKey)
+	assertContains(t, thread, received, sent)
+
+	// Let background work (relay ingest, publish results) settle
+	time.Sleep(200 * time.Millisecond)
+
+	for _, secret := range []string{received, sent} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("log output contains DM plaintext %q", secret)
+		}
+		if where := findInCaches(secret); where != "" {
+			t.Errorf("DM plaintext %q is held in %s", secret, where)
+		}
+	}
+}
+
+func TestReachFinderFindsHiddenStrings(t *testing.T) {
+	// The leak test is only as good as its search: check it looks everywhere it claims to
+	secret := secretText(t)
+	var m sync.Map
+	m.Store("key", &struct{ hidden []string }{hidden: []string{secret}})
+	var p atomic.Pointer[Announcement]
+	p.Store(&Announcement{ID: secret})
+
+	cases := map[string]interface{}{
+		"sync.Map":       &m,
+		"atomic.Pointer": &p,
+		"unexported map": &struct{ m map[string]*DMMessage }{m: map[string]*DMMessage{"x": {Content: secret}}},
+		"byte slice":     &struct{ b []byte }{b: []byte("prefix " + secret)},
+	}
+	for name, v := range cases {
+		f := &reachFinder{needle: []byte(secret), seen: make(map[uintptr]bool)}
+		if f.find(reflect.ValueOf(v), name) == "" {
+			t.Errorf("%s: secret not found", name)
+		}
+	}
+}
+
+// openWrap decrypts a gift wrap with the recipient's key, returning the seal and the rumor
+func openWrap(t *testing.T, recipient *testutil.Keypair, wrap testutil.Event) (Event, Event) {
+	t.Helper()
+	decrypt := func(peer, payload string) string {
+		peerBytes, _ := hex.DecodeString(peer)
+		key, err := testutil.ConversationKey(recipient.PrivKey, peerBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := testutil.Nip44Decrypt(payload, key)
+		if err != nil {
+			t.Fatalf("decrypt: %v", err)
+		}
+		return plaintext
+	}
+
+	var seal, rumor Event
+	if err := json.Unmarshal([]byte(decrypt(wrap.PubKey, wrap.Content)), &seal); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if err := json.Unmarshal([]byte(decrypt(seal.PubKey, seal.Content)), &rumor); err != nil {
+		t.Fatalf("rumor: %v", err)
+	}
+	return seal, rumor
+}
+
+func TestSendDirectMessageWrapStructure(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("dm-wrap-alice")
+	bob := testutil.NewKeypair("dm-wrap-bob")
+	client, signer := s.login(t, alice)
+	content := secretText(t)
+
+	before := time.Now().Unix()
+	resp, err := client.PostForm(s.URL+"/html/messages/send", url.Values{
+		"csrf_token":   {s.csrfToken(t, client)},
+		"conversation": {bob.PubKey},
+		"content":      {content},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	after := time.Now().Unix()
+
+	for _, method := range []string{"nip44_encrypt", "sign_event"} {
+		if !slices.Contains(signer.Requests(), method) {
+			t.Errorf("signer got %v, want %s requests", signer.Requests(), method)
+		}
+	}
+
+	wrapKeys := make(map[string]bool)
+	var timestamps []int64
+	for _, recipient := range []*testutil.Keypair{bob, alice} {
+		wraps := s.Relay.Query(testutil.Filter{Kinds: []int{kindGiftWrap}, Tags: map[string][]string{"p": {recipient.PubKey}}})
+		if len(wraps) != 1 {
+			t.Fatalf("%d wraps for %s, want 1", len(wraps), shortID(recipient.PubKey))
+		}
+		wrap := wraps[0]
+
+		// Kind 1059, signed by a throwaway key and tagged only with the recipient
+		if wrap.PubKey == alice.PubKey || wrap.PubKey == bob.PubKey || wrapKeys[wrap.PubKey] {
+			t.Errorf("wrap for %s is signed by %s, want a fresh ephemeral key", shortID(recipient.PubKey), shortID(wrap.PubKey))
+		}
+		wrapKeys[wrap.PubKey] = true
+		if len(wrap.Tags) != 1 || wrap.TagValue("p") != recipient.PubKey {
+			t.Errorf("wrap tags = %v, want only the recipient's p tag", wrap.Tags)
+		}
+
+		// Kind 13 seal, signed by Alice with no tags
+		seal, rumor := openWrap(t, recipient, wrap)
+		if seal.Kind != kindSeal || seal.PubKey != alice.PubKey || len(seal.Tags) != 0 {
+			t.Errorf("seal kind %d by %s with tags %v, want an untagged kind 13 by the sender", seal.Kind, shortID(seal.PubKey), seal.Tags)
+		}
+		if calculateEventID(&seal) != seal.ID || !validateEventSignature(&seal) {
+			t.Error("seal signature does not verify")
+		}
+
+		// Kind 14 rumor, unsigned, carrying the message
+		if rumor.Kind != kindDMRumor || rumor.PubKey != alice.PubKey || rumor.Content != content || rumor.Sig != "" {
+			t.Errorf("rumor = kind %d by %s, content %q, sig %q", rumor.Kind, shortID(rumor.PubKey), rumor.Content, rumor.Sig)
+		}
+		if len(rumor.Tags) != 1 || rumor.Tags[0][0] != "p" || rumor.Tags[0][1] != bob.PubKey {
+			t.Errorf("rumor tags = %v, want bob's p tag", rumor.Tags)
+		}
+		if rumor.CreatedAt < before || rumor.CreatedAt > after {
+			t.Errorf("rumor created_at %d outside the send window", rumor.CreatedAt)
+		}
+
+		for _, ts := range []int64{wrap.CreatedAt, seal.CreatedAt} {
+			if ts > after || ts < before-dmTimestampJitter {
+				t.Errorf("created_at %d outside the two-day jitter window", ts)
+			}
+			timestamps = append(timestamps, ts)
+		}
+	}
+
+	// Four independent draws from two days of seconds: all equal to the send time means no jitter
+	randomized := false
+	for _, ts := range timestamps {
+		randomized = randomized || ts < before
+	}
+	if !randomized {
+		t.Errorf("wrap and seal created_at %v all match the send time, want them randomized", timestamps)
+	}
+}
+
+func TestRandomPastTimestamp(t *testing.T) {
+	now := time.Now().Unix()
+	seen := make(map[int64]bool)
+	for i := 0; i < 50; i++ {
+		ts := randomPastTimestamp()
+		if ts > time.Now().Unix() || ts < now-dmTimestampJitter {
+			t.Fatalf("timestamp %d outside [now-2d, now]", ts)
+		}
+		seen[ts] = true
+	}
+	if len(seen) < 40 {
+		t.Errorf("only %d distinct timestamps in 50 draws", len(seen))
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// NIP-32 labeling
+// A kind 1985 event attaches labels ("l" tags) in a namespace ("L" tag) to the
+// events it references with "e" tags. Labels without a namespace mark are "ugc".
+
+const kindLabel = 1985
+
+// defaultLabelNamespace is used when an "l" tag has no namespace mark
+const defaultLabelNamespace = "ugc"
+
+// labelNamespace is the namespace offered for new labels (LABEL_NAMESPACE, default "ugc")
+var labelNamespace = func() string {
+	if ns := strings.TrimSpace(os.Getenv("LABEL_NAMESPACE")); ns != "" {
+		return ns
+	}
+	return defaultLabelNamespace
+}()
+
+// maxLabelLength caps label values and namespaces accepted from forms
+const maxLabelLength = 64
+
+// EventLabel is a single namespaced label
+type EventLabel struct {
+	Namespace string `json:"namespace"`
+	Value     string `json:"value"`
+}
+
+// URL returns the label's feed path, /labels/{namespace}/{label}
+func (l EventLabel) URL() string {
+	return "/labels/" + url.PathEscape(l.Namespace) + "/" + url.PathEscape(l.Value)
+}
+
+// parseLabelEvent returns the labels declared by a kind 1985 event and the event IDs they apply to
+func parseLabelEvent(evt Event) ([]EventLabel, []string) {
+	if evt.Kind != kindLabel {
+		return nil, nil
+	}
+	var labels []EventLabel
+	var targets []string
+	for _, tag := range evt.Tags {
+		if len(tag) < 2 || tag[1] == "" {
+			continue
+		}
+		switch tag[0] {
+		case "l":
+			ns := defaultLabelNamespace
+			if len(tag) >= 3 && tag[2] != "" {
+				ns = tag[2]
+			}
+			labels = append(labels, EventLabel{Namespace: ns, Value: tag[1]})
+		case "e":
+			if isValidEventID(tag[1]) {
+				targets = append(targets, tag[1])
+			}
+		}
+	}
+	return labels, targets
+}
+
+// labelAuthorsFor returns whose labels a viewer sees: the people they follow plus
+// themselves when logged in, or nil (anyone) when logged out
+func labelAuthorsFor(session *BunkerSession, relays []string) []string {
+	if session == nil || !session.Connected || session.UserPubKey == nil {
+		return nil
+	}
+	pubkeyHex := hex.EncodeToString(session.UserPubKey)
+	contacts, ok := contactCache.Get(pubkeyHex)
+	if !ok {
+		contacts = fetchContactList(relays, pubkeyHex)
+		if contacts != nil {
+			contactCache.Set(pubkeyHex, contacts)
+		}
+	}
+	return append(append([]string(nil), contacts...), pubkeyHex)
+}
+
+// fetchLabels fetches the kind 1985 labels attached to eventIDs by labelers (nil for anyone)
+// Returns event ID -> distinct labels, in the order first seen
+func fetchLabels(relays []string, eventIDs []string, labelers []string) map[string][]EventLabel {
+	result := make(map[string][]EventLabel)
+	if len(eventIDs) == 0 {
+		return result
+	}
+
+	filter := Filter{
+		Authors: labelers,
+		Kinds:   []int{kindLabel},
+		Tags:    map[string][]string{"e": eventIDs},
+		Limit:   500,
+	}
+	// Uncached so a label shows up as soon as the curator returns to the page
+	events, _ := fetchEventsFromRelaysWithTimeout(relays, filter, 1500*time.Millisecond)
+
+	wanted := make(map[string]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		wanted[id] = true
+	}
+	seen := make(map[string]bool)
+	for _, evt := range events {
+		labels, targets := parseLabelEvent(evt)
+		for _, id := range targets {
+			if !wanted[id] {
+				continue
+			}
+			for _, label := range labels {
+				key := id + "|" + label.Namespace + "|" + label.Value
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				result[id] = append(result[id], label)
+			}
+		}
+	}
+	return result
+}
+
+// fetchLabeledEventIDs returns the IDs of events labeled ns/value by labelers (nil for anyone),
+// newest label first, plus the created_at of the oldest label event for pagination
+func fetchLabeledEventIDs(relays []string, labelers []string, label EventLabel, limit int, until *int64) ([]string, int64) {
+	tags := map[string][]string{"l": {label.Value}}
+	// "ugc" labels may omit the L tag, so only narrow by namespace for explicit ones
+	if label.Namespace != defaultLabelNamespace {
+		tags["L"] = []string{label.Namespace}
+	}
+	filter := Filter{
+		Authors: labelers,
+		Kinds:   []int{kindLabel},
+		Tags:    tags,
+		Limit:   limit,
+		Until:   until,
+	}
+	events, _ := fetchEventsFromRelaysWithTimeout(relays, filter, 2*time.Second)
+
+	var ids []string
+	var oldest int64
+	seen := make(map[string]bool)
+	for _, evt := range events {
+		if oldest == 0 || evt.CreatedAt < oldest {
+			oldest = evt.CreatedAt
+		}
+		labels, targets := parseLabelEvent(evt)
+		matches := false
+		for _, l := range labels {
+			if l == label {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		for _, id := range targets {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, oldest
+}
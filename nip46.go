@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
@@ -37,8 +38,15 @@ type BunkerSession struct {
 	ConversationKey    []byte    // Cached conversation key
 	Connected          bool
 	CreatedAt          time.Time
-	UserRelayList      *RelayList // User's NIP-65 relay list
+	UserRelayList      *RelayList // User's NIP-65 relay list, set in the background after connecting; hold mu
 	FollowingPubkeys   []string   // Cached list of followed pubkeys (from kind 3)
+	FollowedTags       []string   // Cached followed hashtags (from kind 30015 interest sets)
+	DeckColumns        []DeckColumn // Deck layout (html_deck.go), nil for the default
+	FeedPageSize       int          // Notes per feed page (html_appearance.go), 0 for each feed's default
+	Density            string       // "comfortable" or "compact" (html_appearance.go)
+	FeedWatermarks     map[string]int64 // Newest post read per timeline (html_gap.go)
+	PendingEvents      []PendingEvent   // Unsent events kept for retry (pending_events.go)
+	ComposerDraft      *ComposerDraft   // Rejected composer text to refill its form with (pending_events.go)
 	// Rate limiting for sign operations
 	signRequestTimes []time.Time
 	mu               sync.Mutex
@@ -236,6 +244,30 @@ func (s *BunkerSession) Connect(ctx context.Context) error {
 	return nil
 }
 
+// userRelayList returns the user's NIP-65 relay list, or nil if it hasn't been fetched
+// The list is replaced, never modified, so the caller can read it without the lock
+func (s *BunkerSession) userRelayList() *RelayList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.UserRelayList
+}
+
+// writeRelays returns the user's NIP-65 write relays, or the default write relays
+func (s *BunkerSession) writeRelays() []string {
+	if rl := s.userRelayList(); rl != nil && len(rl.Write) > 0 {
+		return rl.Write
+	}
+	return relaysFor(RelayPurposeWrite)
+}
+
+// readRelays returns the user's NIP-65 read relays, or the default read relays
+func (s *BunkerSession) readRelays() []string {
+	if rl := s.userRelayList(); rl != nil && len(rl.Read) > 0 {
+		return rl.Read
+	}
+	return relaysFor(RelayPurposeRead)
+}
+
 // SignEvent requests the remote signer to sign an event
 func (s *BunkerSession) SignEvent(ctx context.Context, event UnsignedEvent) (*Event, error) {
 	s.mu.Lock()
@@ -245,6 +277,11 @@ func (s *BunkerSession) SignEvent(ctx context.Context, event UnsignedEvent) (*Ev
 		return nil, errors.New("not connected to bunker")
 	}
 
+	// Reject oversized or malformed events before they count against the rate limit
+	if err := prepareEventForSigning(&event); err != nil {
+		return nil, err
+	}
+
 	// Check rate limit
 	if err := s.checkSignRateLimit(); err != nil {
 		return nil, err
@@ -256,9 +293,11 @@ func (s *BunkerSession) SignEvent(ctx context.Context, event UnsignedEvent) (*Ev
 		return nil, err
 	}
 
+	endSign := traceSpan(ctx, "sign event")
 	result, err := s.sendRequest(ctx, "sign_event", []string{string(eventJSON)})
+	endSign()
 	if err != nil {
-		return nil, fmt.Errorf("sign_event failed: %v", err)
+		return nil, fmt.Errorf("sign_event failed: %w", err)
 	}
 
 	// Parse signed event
@@ -270,6 +309,61 @@ func (s *BunkerSession) SignEvent(ctx context.Context, event UnsignedEvent) (*Ev
 	return &signedEvent, nil
 }
 
+// Nip44Decrypt asks the remote signer to decrypt a NIP-44 payload from senderPubKey
+// The user's key never leaves the signer; the plaintext is returned to the caller only
+func (s *BunkerSession) Nip44Decrypt(ctx context.Context, senderPubKey, payload string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Connected {
+		return "", errors.New("not connected to bunker")
+	}
+
+	endDecrypt := traceSpan(ctx, "nip44 decrypt")
+	result, err := s.sendRequest(ctx, "nip44_decrypt", []string{senderPubKey, payload})
+	endDecrypt()
+	if err != nil {
+		return "", fmt.Errorf("nip44_decrypt failed: %w", err)
+	}
+	return result, nil
+}
+
+// Nip44Encrypt asks the remote signer to encrypt plaintext to recipientPubKey
+func (s *BunkerSession) Nip44Encrypt(ctx context.Context, recipientPubKey, plaintext string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Connected {
+		return "", errors.New("not connected to bunker")
+	}
+
+	endEncrypt := traceSpan(ctx, "nip44 encrypt")
+	result, err := s.sendRequest(ctx, "nip44_encrypt", []string{recipientPubKey, plaintext})
+	endEncrypt()
+	if err != nil {
+		return "", fmt.Errorf("nip44_encrypt failed: %w", err)
+	}
+	return result, nil
+}
+
+// SignerAuthError is returned when the signer needs the user to approve a
+// request (NIP-46 auth_url), e.g. the first time a new permission is used
+type SignerAuthError struct {
+	URL string
+}
+
+func (e *SignerAuthError) Error() string {
+	return "signer requires approval at " + e.URL
+}
+
+// SafeURL returns the approval URL if it is an https link, otherwise ""
+func (e *SignerAuthError) SafeURL() string {
+	if u, err := url.Parse(e.URL); err == nil && u.Scheme == "https" && u.Host != "" {
+		return e.URL
+	}
+	return ""
+}
+
 // sendRequest sends a NIP-46 request and waits for response
 func (s *BunkerSession) sendRequest(ctx context.Context, method string, params []string) (string, error) {
 	// Generate request ID
@@ -303,6 +397,11 @@ func (s *BunkerSession) sendRequest(ctx context.Context, method string, params [
 	// Try each relay until we get a response
 	for _, relay := range s.Relays {
 		result, err := s.sendToRelay(ctx, relay, requestEvent, reqID)
+		var authErr *SignerAuthError
+		if errors.As(err, &authErr) {
+			// The signer answered; other relays would get the same challenge
+			return "", err
+		}
 		if err != nil {
 			log.Printf("NIP-46: Relay %s failed: %v", relay, err)
 			continue
@@ -399,6 +498,11 @@ func (s *BunkerSession) sendToRelay(ctx context.Context, relayURL string, event
 					continue
 				}
 
+				// NIP-46 auth challenge: the user must approve this method at a URL
+				if response.Result == "auth_url" {
+					return "", &SignerAuthError{URL: response.Error}
+				}
+
 				if response.Error != "" {
 					return "", errors.New(response.Error)
 				}
@@ -440,16 +544,18 @@ func createNIP46Event(privKey, pubKey, targetPubKey []byte, content string) *Eve
 }
 
 func calculateEventID(event *Event) string {
+	tags := event.Tags
+	if tags == nil {
+		tags = [][]string{}
+	}
 	// [0, pubkey, created_at, kind, tags, content]
-	serialized := fmt.Sprintf(`[0,"%s",%d,%d,%s,"%s"]`,
-		event.PubKey,
-		event.CreatedAt,
-		event.Kind,
-		mustJSON(event.Tags),
-		escapeJSON(event.Content),
-	)
-
-	hash := sha256.Sum256([]byte(serialized))
+	// NIP-01 serialization does not HTML-escape <, > and &
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode([]interface{}{0, event.PubKey, event.CreatedAt, event.Kind, tags, event.Content})
+
+	hash := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
 	return hex.EncodeToString(hash[:])
 }
 
@@ -485,16 +591,6 @@ func mustJSON(v interface{}) string {
 	return string(b)
 }
 
-func escapeJSON(s string) string {
-	b, err := json.Marshal(s)
-	if err != nil || len(b) < 2 {
-		// Fallback: return original string (shouldn't happen for valid strings)
-		return s
-	}
-	// Remove surrounding quotes
-	return string(b[1 : len(b)-1])
-}
-
 // Session store methods
 
 func (store *BunkerSessionStore) Get(sessionID string) *BunkerSession {
@@ -515,6 +611,21 @@ func (store *BunkerSessionStore) Delete(sessionID string) {
 	delete(store.sessions, sessionID)
 }
 
+// DeleteByPubkey removes every session logged in as pubkey and returns how many
+func (store *BunkerSessionStore) DeleteByPubkey(pubkey string) int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	removed := 0
+	for id, session := range store.sessions {
+		if hex.EncodeToString(session.UserPubKey) == pubkey {
+			delete(store.sessions, id)
+			removed++
+		}
+	}
+	return removed
+}
+
 // CleanupExpired removes sessions older than the given duration
 func (store *BunkerSessionStore) CleanupExpired(maxAge time.Duration) {
 	store.mu.Lock()
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Protected events (NIP-70)
+// An event with a bare ["-"] tag may only be published by its author, to relays that
+// authenticate them, so it stays on the relays it was posted to. We cache and show
+// protected events like any other, but label them, don't offer to repost them, and
+// refuse reposts in the handler. The composer's "this relay only" option posts a
+// protected note to one of the user's write relays.
+//
+// Relays that enforce NIP-70 only accept a protected event after NIP-42 AUTH as the
+// author, which the pool doesn't do; such a relay rejects the note, and the rejection
+// shows up in the note's publish receipt.
+
+// isProtectedEvent reports whether tags carry the NIP-70 "-" tag
+func isProtectedEvent(tags [][]string) bool {
+	for _, tag := range tags {
+		if len(tag) == 1 && tag[0] == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// eventIsProtected reports whether the event with this ID is protected, using the
+// ingestion cache and falling back to fetching it
+func eventIsProtected(relays []string, eventID string) bool {
+	if seen, ok := seenEventCache.Get(eventID); ok {
+		return seen.Protected
+	}
+	events := fetchEventByID(relays, eventID)
+	return len(events) > 0 && isProtectedEvent(events[0].Tags)
+}
+
+// composerRelays returns the relays the user can post a relay-only note to
+func composerRelays(session *BunkerSession) []string {
+	return append([]string(nil), session.writeRelays()...)
+}
+
+// htmlPostRelayOnly publishes a protected note to the single relay chosen in the composer
+func htmlPostRelayOnly(w http.ResponseWriter, r *http.Request, session *BunkerSession, content string) {
+	relay := strings.TrimSpace(r.FormValue("relay"))
+	if !slices.Contains(composerRelays(session), relay) {
+		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&error=Choose+one+of+your+write+relays", http.StatusSeeOther)
+		return
+	}
+
+	event := UnsignedEvent{
+		Kind:      1,
+		Content:   content,
+		Tags:      [][]string{{"-"}},
+		CreatedAt: time.Now().Unix(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	draft := PendingEvent{
+		Composer:  "relay-only",
+		Event:     event,
+		Relays:    []string{relay},
+		ReturnURL: "/html/timeline?kinds=1&limit=20",
+	}
+	signedEvent, err := signAndPublish(ctx, session, draft)
+	if err != nil {
+		log.Printf("Failed to sign relay-only note: %v", err)
+		http.Redirect(w, r, signFailureURL(session, draft, "/html/timeline?kinds=1&limit=20", content, err), http.StatusSeeOther)
+		return
+	}
+
+	log.Printf("Published relay-only note %s to %s for %s", shortID(signedEvent.ID), relay, shortID(hex.EncodeToString(session.UserPubKey)))
+	http.Redirect(w, r, "/html/timeline?kinds=1&limit=20&success="+escapeURLParam("Note published to "+relay+" only"), http.StatusSeeOther)
+}
@@ -149,7 +149,8 @@ func GenerateNostrConnectURL(relays []string) (string, string, error) {
 	}
 	q.Set("secret", secret)
 	q.Set("name", "Nostr Hypermedia")
-	q.Set("perms", "sign_event:1") // Only need to sign kind 1 (notes)
+	// Notes, plus NIP-44 and seals (kind 13) for NIP-17 direct messages
+	q.Set("perms", "sign_event:1,sign_event:13,nip44_encrypt,nip44_decrypt")
 	u.RawQuery = q.Encode()
 
 	// Create pending connection
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Unsent events
+// Composers (notes, relay-only notes, replies, quotes, profile edits) publish through
+// signAndPublish. When the signer fails or times out, the unsigned event is kept in the
+// session under a recovery token and the user lands on /html/pending, which shows it with
+// Retry and Discard buttons; when every relay rejects a signed event, it is kept the same
+// way once the last relay answers, and the timeline points to /html/pending. A retry
+// re-signs with a fresh created_at and rebuilds tags that depend on the target (the
+// replied-to or quoted author). Items expire a day after the first failure.
+// An event over its kind's limits (kinds.go) can never be sent, so it isn't kept: the
+// user goes back to the form with the error, and the form is refilled with their text.
+
+const (
+	pendingEventTTL  = 24 * time.Hour
+	maxPendingEvents = 20
+)
+
+// PendingEvent is a composed event that wasn't signed or that no relay accepted
+type PendingEvent struct {
+	Token     string // Recovery token, used by the retry and discard forms
+	Composer  string // Key into pendingComposers
+	Event     UnsignedEvent
+	Target    string   // Replied-to or quoted event ID, "" if none
+	Relays    []string // Where the event goes
+	ReturnURL string   // Where a successful retry lands
+	Error     string   // Why the last attempt failed, for the user
+	FailedAt  time.Time
+}
+
+// pendingComposer describes a composer's retries
+type pendingComposer struct {
+	Label   string
+	Form    string                               // Form that refills rejected text (takeComposerDraft)
+	Success string                               // Flash message after a successful retry
+	Tags    func(draft *PendingEvent) [][]string // Rebuilds context-dependent tags; nil keeps the stored ones
+}
+
+var pendingComposers = map[string]pendingComposer{
+	"note":       {Label: "Note", Form: "note", Success: "Note published"},
+	"relay-only": {Label: "Relay-only note", Form: "note", Success: "Note published"},
+	"reply":      {Label: "Reply", Form: "reply", Success: "Reply published", Tags: pendingReplyTags},
+	"quote":      {Label: "Quote", Form: "quote", Success: "Quote published", Tags: pendingQuoteTags},
+	"profile":    {Label: "Profile update", Form: "profile", Success: "Profile updated"},
+}
+
+// ComposerDraft is text a form submitted that can't be sent as it is
+type ComposerDraft struct {
+	Form   string // pendingComposer.Form
+	Target string // Replied-to or quoted event ID, "" if none
+	Text   string
+}
+
+// pendingTargetAuthor returns the target's author, from the ingestion cache if it has the
+// target and otherwise from the stored p tag
+func pendingTargetAuthor(draft *PendingEvent) string {
+	if seen, ok := seenEventCache.Get(draft.Target); ok {
+		return seen.Event.PubKey
+	}
+	for _, tag := range draft.Event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			return tag[1]
+		}
+	}
+	return ""
+}
+
+// pendingReplyTags rebuilds a reply's NIP-10 tags
+func pendingReplyTags(draft *PendingEvent) [][]string {
+	tags := [][]string{{"e", draft.Target, "", "reply"}}
+	if author := pendingTargetAuthor(draft); author != "" {
+		tags = append(tags, []string{"p", author})
+	}
+	return tags
+}
+
+// pendingQuoteTags rebuilds a quote's NIP-18 tags
+func pendingQuoteTags(draft *PendingEvent) [][]string {
+	tags := [][]string{{"q", draft.Target, ""}}
+	if author := pendingTargetAuthor(draft); author != "" {
+		tags = append(tags, []string{"p", author})
+	}
+	return tags
+}
+
+// signAndPublish signs a composed event and publishes it to draft.Relays
+// If signing fails the draft is kept for retry and the error returned; if no relay
+// accepts the event, the draft is kept once they have all answered. Events the input
+// itself makes unsendable aren't kept (isEventInputError)
+func signAndPublish(ctx context.Context, session *BunkerSession, draft PendingEvent) (*Event, error) {
+	signedEvent, err := session.SignEvent(ctx, draft.Event)
+	if err != nil {
+		if !isEventInputError(err) {
+			draft.Error = sanitizeErrorForUser("Sign event", err)
+			keepPendingEvent(session, draft)
+		}
+		return nil, err
+	}
+
+	accepted := publishEvent(ctx, draft.Relays, signedEvent)
+	go func() {
+		if <-accepted == 0 {
+			log.Printf("No relay accepted %s; keeping it for retry", shortID(signedEvent.ID))
+			draft.Error = "No relay accepted it"
+			keepPendingEvent(session, draft)
+		}
+	}()
+	return signedEvent, nil
+}
+
+// pendingURL is where composers send the user when signing fails
+func pendingURL(errMsg string) string {
+	return "/html/pending?error=" + escapeURLParam(errMsg)
+}
+
+// isEventInputError reports whether signing failed because of the event itself
+// (over its kind's limits), which no retry can fix
+func isEventInputError(err error) bool {
+	var limitErr *EventLimitError
+	return errors.As(err, &limitErr)
+}
+
+// signFailureURL is where a composer sends the user when signAndPublish fails
+// Input errors go back to formURL with the error, keeping text to refill the form;
+// anything else was kept for retry and goes to the pending page
+func signFailureURL(session *BunkerSession, draft PendingEvent, formURL, text string, err error) string {
+	errMsg := sanitizeErrorForUser("Sign event", err)
+	if !isEventInputError(err) {
+		return pendingURL(errMsg)
+	}
+	if text != "" {
+		session.mu.Lock()
+		session.ComposerDraft = &ComposerDraft{Form: pendingComposers[draft.Composer].Form, Target: draft.Target, Text: text}
+		session.mu.Unlock()
+	}
+	sep := "?"
+	if strings.Contains(formURL, "?") {
+		sep = "&"
+	}
+	return formURL + sep + "error=" + escapeURLParam(errMsg)
+}
+
+// takeComposerDraft returns and clears the session's rejected text if it came from this
+// form (and target), so the page can refill the form once
+func takeComposerDraft(session *BunkerSession, form, target string) string {
+	if session == nil {
+		return ""
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	d := session.ComposerDraft
+	if d == nil || d.Form != form || d.Target != target {
+		return ""
+	}
+	session.ComposerDraft = nil
+	return d.Text
+}
+
+// keepPendingEvent adds a draft to the session, or updates it if it's a retry
+func keepPendingEvent(session *BunkerSession, draft PendingEvent) {
+	now := time.Now()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.PendingEvents = prunePendingEvents(session.PendingEvents, now)
+	if draft.Token != "" {
+		for i := range session.PendingEvents {
+			if session.PendingEvents[i].Token == draft.Token {
+				session.PendingEvents[i] = draft
+				return
+			}
+		}
+	} else {
+		draft.Token = generateSessionID()
+	}
+	if draft.FailedAt.IsZero() {
+		draft.FailedAt = now
+	}
+	session.PendingEvents = append(session.PendingEvents, draft)
+	if len(session.PendingEvents) > maxPendingEvents {
+		session.PendingEvents = session.PendingEvents[len(session.PendingEvents)-maxPendingEvents:]
+	}
+}
+
+// prunePendingEvents drops drafts older than pendingEventTTL; callers hold session.mu
+func prunePendingEvents(drafts []PendingEvent, now time.Time) []PendingEvent {
+	return slices.DeleteFunc(drafts, func(d PendingEvent) bool {
+		return now.Sub(d.FailedAt) >= pendingEventTTL
+	})
+}
+
+// pendingEvents returns the session's unexpired drafts, newest first
+func pendingEvents(session *BunkerSession) []PendingEvent {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.PendingEvents = prunePendingEvents(session.PendingEvents, time.Now())
+	drafts := slices.Clone(session.PendingEvents)
+	slices.Reverse(drafts)
+	return drafts
+}
+
+// takePendingEvent removes a draft from the session and returns it
+func takePendingEvent(session *BunkerSession, token string) (PendingEvent, bool) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for i, d := range session.PendingEvents {
+		if d.Token == token {
+			session.PendingEvents = slices.Delete(session.PendingEvents, i, i+1)
+			return d, time.Since(d.FailedAt) < pendingEventTTL
+		}
+	}
+	return PendingEvent{}, false
+}
+
+// HTMLPendingEvent is one unsent event on the pending page
+type HTMLPendingEvent struct {
+	Token     string
+	Label     string
+	Content   string
+	TargetURL string // Thread of the replied-to or quoted note
+	Relays    []string
+	Error     string
+	FailedAgo string
+}
+
+// HTMLPendingData is the data for the unsent events page
+type HTMLPendingData struct {
+	HTMLPageChrome
+	Items []HTMLPendingEvent
+}
+
+func init() {
+	registerPageTemplate("pending", htmlPendingContent)
+}
+
+// htmlPendingHandler lists unsent events (GET) and retries or discards one (POST)
+func htmlPendingHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if !validateCSRFToken(session.ID, r.FormValue("csrf_token")) {
+			http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+			return
+		}
+		htmlPendingAction(w, r, session)
+		return
+	}
+
+	data := HTMLPendingData{HTMLPageChrome: newPageChrome(r, "Unsent")}
+	for _, d := range pendingEvents(session) {
+		item := HTMLPendingEvent{
+			Token:     d.Token,
+			Label:     pendingComposers[d.Composer].Label,
+			Content:   d.Event.Content,
+			Relays:    d.Relays,
+			Error:     d.Error,
+			FailedAgo: formatRelativeTime(d.FailedAt.Unix()),
+		}
+		if d.Target != "" {
+			item.TargetURL = canonicalThreadPath(d.Target)
+		}
+		data.Items = append(data.Items, item)
+	}
+	renderPage(w, "pending", data)
+}
+
+// htmlPendingAction handles the retry and discard forms
+func htmlPendingAction(w http.ResponseWriter, r *http.Request, session *BunkerSession) {
+	draft, ok := takePendingEvent(session, r.FormValue("token"))
+	if !ok {
+		http.Redirect(w, r, "/html/pending?error=That+item+has+expired+or+was+already+sent", http.StatusSeeOther)
+		return
+	}
+	if r.FormValue("action") == "discard" {
+		http.Redirect(w, r, "/html/pending?success=Discarded", http.StatusSeeOther)
+		return
+	}
+
+	// Relay-only notes may only go to one of the user's current write relays
+	if draft.Composer == "relay-only" && (len(draft.Relays) != 1 || !slices.Contains(composerRelays(session), draft.Relays[0])) {
+		draft.Error = "That relay is no longer one of your write relays"
+		keepPendingEvent(session, draft)
+		http.Redirect(w, r, pendingURL(draft.Error), http.StatusSeeOther)
+		return
+	}
+
+	composer := pendingComposers[draft.Composer]
+	draft.Event.CreatedAt = time.Now().Unix()
+	if composer.Tags != nil {
+		draft.Event.Tags = composer.Tags(&draft)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	signedEvent, err := signAndPublish(ctx, session, draft)
+	if err != nil {
+		log.Printf("Failed to sign retried %s: %v", draft.Composer, err)
+		http.Redirect(w, r, pendingURL(sanitizeErrorForUser("Sign event", err)), http.StatusSeeOther)
+		return
+	}
+	if draft.Event.Kind == 0 {
+		profileCache.Delete(hex.EncodeToString(session.UserPubKey))
+	}
+
+	log.Printf("Published retried %s: %s", draft.Composer, signedEvent.ID)
+	returnURL := sanitizeReturnURL(draft.ReturnURL)
+	separator := "?"
+	if strings.Contains(returnURL, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, returnURL+separator+"success="+escapeURLParam(composer.Success), http.StatusSeeOther)
+}
+
+var htmlPendingContent = `{{define "content"}}
+<h1>Unsent</h1>
+<p class="text-muted text-sm">Posts your signer didn't sign, or that no relay accepted. Retrying signs them again with the current time. They're kept for a day.</p>
+{{range .Items}}
+<section class="card pending-item">
+  <h2 class="pending-label">{{.Label}}{{with .TargetURL}} <a href="{{.}}" class="text-sm">(to this note)</a>{{end}}</h2>
+  <textarea class="pending-content" readonly rows="4" aria-label="{{.Label}} content">{{.Content}}</textarea>
+  <p class="text-muted text-sm">{{.Error}} · {{.FailedAgo}}{{if .Relays}} · {{len .Relays}} relay{{if ne (len .Relays) 1}}s{{end}}{{end}}</p>
+  <div class="flex-center gap-md">
+    <form method="POST" action="/html/pending" class="inline-form">
+      <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+      <input type="hidden" name="token" value="{{.Token}}">
+      <input type="hidden" name="action" value="retry">
+      <button type="submit" class="btn">Retry</button>
+    </form>
+    <form method="POST" action="/html/pending" class="inline-form">
+      <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+      <input type="hidden" name="token" value="{{.Token}}">
+      <input type="hidden" name="action" value="discard">
+      <button type="submit" class="btn">Discard</button>
+    </form>
+  </div>
+</section>
+{{else}}
+<p class="text-muted">Nothing unsent.</p>
+{{end}}
+{{end}}
+{{define "styles"}}
+    .pending-label { font-size: 16px; margin: 0 0 8px; }
+    .pending-content { width: 100%; font: inherit; resize: vertical; }
+{{end}}`
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Publish receipts
+// publishEvent records each relay's answer for the user's notes and reposts, keyed by
+// event id, so the author's own profile feed and thread views can show "accepted by 4/5
+// relays" days later and re-publish to the relays that failed. Receipts live in memory
+// for a week; like sessions, they don't survive a restart.
+
+const publishReceiptTTL = 7 * 24 * time.Hour
+
+// publishReceiptKinds are the kinds that get receipts: the ones shown as the author's posts
+var publishReceiptKinds = map[int]bool{1: true, 6: true}
+
+// ReceiptRelayResult is one relay's answer to a publish
+type ReceiptRelayResult struct {
+	Relay    string
+	Pending  bool // No answer yet
+	Accepted bool
+	Message  string // Rejection or error message
+}
+
+// PublishReceipt is the per-relay outcome of publishing one event
+type PublishReceipt struct {
+	Event     *Event // Kept for re-publishing
+	Results   []ReceiptRelayResult
+	UpdatedAt time.Time
+}
+
+// AcceptedCount returns how many relays accepted the event
+func (p *PublishReceipt) AcceptedCount() int {
+	n := 0
+	for _, r := range p.Results {
+		if r.Accepted {
+			n++
+		}
+	}
+	return n
+}
+
+// PendingCount returns how many relays haven't answered yet
+func (p *PublishReceipt) PendingCount() int {
+	n := 0
+	for _, r := range p.Results {
+		if r.Pending {
+			n++
+		}
+	}
+	return n
+}
+
+// FailedRelays returns the relays that rejected the event or couldn't be reached
+func (p *PublishReceipt) FailedRelays() []string {
+	var failed []string
+	for _, r := range p.Results {
+		if !r.Pending && !r.Accepted {
+			failed = append(failed, r.Relay)
+		}
+	}
+	return failed
+}
+
+// MissingRelays returns the failed relays that haven't since delivered the event to us
+// A relay that timed out or errored may have stored the event anyway; once we've read
+// it back from there, re-publishing to it is unnecessary
+func (p *PublishReceipt) MissingRelays() []string {
+	failed := p.FailedRelays()
+	seen, ok := seenEventCache.Get(p.Event.ID)
+	if !ok {
+		return failed
+	}
+	var missing []string
+	for _, relay := range failed {
+		if !slices.ContainsFunc(seen.Sightings, func(s EventSighting) bool { return s.Relay == relay }) {
+			missing = append(missing, relay)
+		}
+	}
+	return missing
+}
+
+// PublishReceiptStore holds publish receipts by event id
+type PublishReceiptStore struct {
+	mu       sync.Mutex
+	receipts map[string]*PublishReceipt
+	ttl      time.Duration
+	maxSize  int
+}
+
+// Global publish receipt store - one week, 10000 events
+var publishReceipts = NewPublishReceiptStore(publishReceiptTTL, 10000)
+
+// NewPublishReceiptStore creates a receipt store and starts its cleanup loop
+func NewPublishReceiptStore(ttl time.Duration, maxSize int) *PublishReceiptStore {
+	s := &PublishReceiptStore{
+		receipts: make(map[string]*PublishReceipt),
+		ttl:      ttl,
+		maxSize:  maxSize,
+	}
+	go s.cleanupLoop()
+	return s
+}
+
+// Start records that event is being published to relays, all pending
+func (s *PublishReceiptStore) Start(event *Event, relays []string) {
+	results := make([]ReceiptRelayResult, len(relays))
+	for i, relay := range relays {
+		results[i] = ReceiptRelayResult{Relay: relay, Pending: true}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.receipts[event.ID]; !ok && len(s.receipts) >= s.maxSize {
+		s.evictOldest()
+	}
+	s.receipts[event.ID] = &PublishReceipt{Event: event, Results: results, UpdatedAt: time.Now()}
+}
+
+// Record stores one relay's answer; err is nil when the relay accepted the event
+func (s *PublishReceiptStore) Record(eventID, relay string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	receipt, ok := s.receipts[eventID]
+	if !ok {
+		return
+	}
+	result := ReceiptRelayResult{Relay: relay, Accepted: err == nil}
+	if err != nil {
+		result.Message = err.Error()
+	}
+	for i := range receipt.Results {
+		if receipt.Results[i].Relay == relay {
+			receipt.Results[i] = result
+			receipt.UpdatedAt = time.Now()
+			return
+		}
+	}
+	receipt.Results = append(receipt.Results, result)
+	receipt.UpdatedAt = time.Now()
+}
+
+// Get returns a copy of the receipt for eventID
+func (s *PublishReceiptStore) Get(eventID string) (*PublishReceipt, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	receipt, ok := s.receipts[eventID]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(receipt.UpdatedAt) > s.ttl {
+		delete(s.receipts, eventID)
+		return nil, false
+	}
+	cp := *receipt
+	cp.Results = append([]ReceiptRelayResult(nil), receipt.Results...)
+	return &cp, true
+}
+
+// evictOldest removes the least recently updated receipt (caller holds mu)
+func (s *PublishReceiptStore) evictOldest() {
+	var oldestID string
+	var oldest time.Time
+	for id, receipt := range s.receipts {
+		if oldestID == "" || receipt.UpdatedAt.Before(oldest) {
+			oldestID, oldest = id, receipt.UpdatedAt
+		}
+	}
+	delete(s.receipts, oldestID)
+}
+
+// cleanupLoop drops expired receipts every hour
+func (s *PublishReceiptStore) cleanupLoop() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		for id, receipt := range s.receipts {
+			if time.Since(receipt.UpdatedAt) > s.ttl {
+				delete(s.receipts, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// DeleteAuthor drops the receipts for events by pubkey
+func (s *PublishReceiptStore) DeleteAuthor(pubkey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, receipt := range s.receipts {
+		if receipt.Event.PubKey == pubkey {
+			delete(s.receipts, id)
+		}
+	}
+}
+
+// Len returns the number of stored receipts
+func (s *PublishReceiptStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.receipts)
+}
+
+// attachPublishReceipts sets Receipt on the items the viewer authored
+func attachPublishReceipts(viewerPubkey string, items ...*HTMLEventItem) {
+	if viewerPubkey == "" {
+		return
+	}
+	for _, item := range items {
+		if item == nil || item.Pubkey != viewerPubkey {
+			continue
+		}
+		if receipt, ok := publishReceipts.Get(item.ID); ok {
+			item.Receipt = receipt
+		}
+	}
+}
+
+// htmlRepublishHandler re-publishes one of the user's own events to the relays that are missing it
+func htmlRepublishHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/html/timeline?kinds=1&limit=20", http.StatusSeeOther)
+		return
+	}
+
+	session := getSessionFromRequest(r)
+	if session == nil || !session.Connected {
+		http.Redirect(w, r, "/html/login?error=Please+login+first", http.StatusSeeOther)
+		return
+	}
+
+	// Validate CSRF token
+	csrfToken := r.FormValue("csrf_token")
+	if !validateCSRFToken(session.ID, csrfToken) {
+		http.Error(w, "Invalid or expired CSRF token", http.StatusForbidden)
+		return
+	}
+
+	eventID := strings.TrimSpace(r.FormValue("event_id"))
+	returnURL := sanitizeReturnURL(strings.TrimSpace(r.FormValue("return_url")))
+	separator := "?"
+	if strings.Contains(returnURL, "?") {
+		separator = "&"
+	}
+
+	// Only the author can re-publish, and only events this instance published for them
+	receipt, ok := publishReceipts.Get(eventID)
+	if !ok || receipt.Event.PubKey != hex.EncodeToString(session.UserPubKey) {
+		http.Redirect(w, r, returnURL+separator+"error=No+publish+record+for+that+note", http.StatusSeeOther)
+		return
+	}
+	missing := receipt.MissingRelays()
+	if len(missing) == 0 {
+		http.Redirect(w, r, returnURL+separator+"success=Nothing+to+re-publish", http.StatusSeeOther)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	accepted := 0
+	for _, res := range publishEventWithResults(ctx, missing, receipt.Event) {
+		publishReceipts.Record(eventID, res.Relay, res.Err)
+		if res.Err == nil {
+			accepted++
+		}
+	}
+
+	log.Printf("Re-published %s: accepted by %d/%d missing relays", shortID(eventID), accepted, len(missing))
+	msg := fmt.Sprintf("Re-published to %d of %d relays", accepted, len(missing))
+	http.Redirect(w, r, returnURL+separator+"success="+escapeURLParam(msg), http.StatusSeeOther)
+}
@@ -43,7 +43,8 @@ type Filter struct {
 	Limit   int
 	Since   *int64
 	Until   *int64
-	PTags   []string // Filter by p-tag (events mentioning these pubkeys)
+	PTags   []string            // Filter by p-tag (events mentioning these pubkeys)
+	Tags    map[string][]string // Other single-letter tag filters, e.g. "e" -> #e
 }
 
 type Event struct {
@@ -104,15 +105,16 @@ func parseEventFromInterface(data interface{}) (Event, bool) {
 		}
 	}
 
-	// Validate signature if present
-	if evt.Sig != "" && !validateEventSignature(&evt) {
-		log.Printf("Event signature validation failed for event %s", shortID(evt.ID))
-		return Event{}, false
-	}
-
 	return evt, evt.ID != ""
 }
 
+// verifyEvent reports whether an event is signed and its ID matches its content
+// A valid signature alone isn't enough: a relay can replay a real ID and signature
+// with different content
+func verifyEvent(evt *Event) bool {
+	return evt.Sig != "" && calculateEventID(evt) == evt.ID && validateEventSignature(evt)
+}
+
 // validateEventSignature verifies that the event signature is valid for the pubkey
 func validateEventSignature(evt *Event) bool {
 	// Signature must be 128 hex characters (64 bytes)
@@ -165,15 +167,24 @@ func fetchEventsFromRelays(relays []string, filter Filter) ([]Event, bool) {
 
 // fetchEventsFromRelaysCached checks cache first, then fetches from relays
 func fetchEventsFromRelaysCached(relays []string, filter Filter) ([]Event, bool) {
+	return fetchEventsFromRelaysCachedCtx(context.Background(), relays, filter)
+}
+
+// fetchEventsFromRelaysCachedCtx is fetchEventsFromRelaysCached carrying the
+// request context, so cache and per-relay timings land in the request trace
+func fetchEventsFromRelaysCachedCtx(ctx context.Context, relays []string, filter Filter) ([]Event, bool) {
 	// Check cache first
-	if events, eose, ok := eventCache.Get(relays, filter); ok {
+	endLookup := traceSpan(ctx, "cache lookup")
+	events, eose, ok := eventCache.Get(relays, filter)
+	endLookup()
+	if ok {
 		log.Printf("Cache hit for query (limit=%d, authors=%d)", filter.Limit, len(filter.Authors))
 		return events, eose
 	}
 
 	// Cache miss - fetch from relays
 	log.Printf("Cache miss for query (limit=%d, authors=%d)", filter.Limit, len(filter.Authors))
-	events, eose := fetchEventsFromRelays(relays, filter)
+	events, eose = fetchEventsFromRelaysCtx(ctx, relays, filter, 1500*time.Millisecond)
 
 	// Store in cache
 	eventCache.Set(relays, filter, events, eose)
@@ -182,7 +193,15 @@ func fetchEventsFromRelaysCached(relays []string, filter Filter) ([]Event, bool)
 }
 
 func fetchEventsFromRelaysWithTimeout(relays []string, filter Filter, timeout time.Duration) ([]Event, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return fetchEventsFromRelaysCtx(context.Background(), relays, filter, timeout)
+}
+
+// fetchEventsFromRelaysCtx fans out to relays with a timeout derived from parent
+// Parent cancellation is ignored so a disconnecting client doesn't truncate
+// results that will be cached; only its values (e.g. the trace) carry over
+func fetchEventsFromRelaysCtx(parent context.Context, relays []string, filter Filter, timeout time.Duration) ([]Event, bool) {
+	defer traceSpan(parent, "relay fan-out")()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), timeout)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -244,6 +263,19 @@ collectLoop:
 			}
 			// If all relays sent EOSE, we're done
 			if eoseCount >= len(relays) {
+				// Events sent before EOSE may still be buffered - select doesn't preserve order
+				for drained := false; !drained; {
+					select {
+					case evt, ok := <-eventChan:
+						if ok && !seenIDs[evt.ID] {
+							seenIDs[evt.ID] = true
+							events = append(events, evt)
+						}
+						drained = !ok
+					default:
+						drained = true
+					}
+				}
 				log.Printf("All %d relays sent EOSE, got %d events", len(relays), len(events))
 				break collectLoop
 			}
@@ -298,6 +330,13 @@ func fetchFromRelay(ctx context.Context, relayURL string, filter Filter, eventCh
 	if len(filter.PTags) > 0 {
 		reqFilter["#p"] = filter.PTags
 	}
+	for name, values := range filter.Tags {
+		if len(values) > 0 {
+			reqFilter["#"+name] = values
+		}
+	}
+
+	defer traceSpan(ctx, "relay "+relayURL)()
 
 	// Subscribe using the pool
 	sub, err := relayPool.Subscribe(ctx, relayURL, subID, reqFilter)
@@ -321,6 +360,19 @@ func fetchFromRelay(ctx context.Context, relayURL string, filter Filter, eventCh
 				return
 			}
 		case <-sub.EOSEChan:
+			// Events delivered before EOSE may still be buffered - select doesn't preserve order
+			for drained := false; !drained; {
+				select {
+				case evt := <-sub.EventChan:
+					select {
+					case eventChan <- evt:
+					case <-ctx.Done():
+						return
+					}
+				default:
+					drained = true
+				}
+			}
 			log.Printf("Received EOSE from %s", relayURL)
 			eoseChan <- true
 			return
@@ -411,12 +463,9 @@ func fetchSingleEvent(ctx context.Context, relayURL string, eventID string, even
 	}
 }
 
-// Specialized relay for profile lookups - has better coverage for kind 0 events
-const profileRelay = "wss://purplepag.es"
-
 // fetchProfiles fetches kind 0 (profile metadata) events for the given pubkeys
 // Uses the global profileCache to avoid redundant relay queries
-// Tries purplepag.es first for faster lookups, falls back to provided relays
+// Tries the configured metadata relays first for faster lookups, falls back to provided relays
 func fetchProfiles(relays []string, pubkeys []string) map[string]*ProfileInfo {
 	if len(pubkeys) == 0 {
 		return nil
@@ -437,10 +486,10 @@ func fetchProfiles(relays []string, pubkeys []string) map[string]*ProfileInfo {
 		Limit:   len(missing),
 	}
 
-	// Try purplepag.es first with a short timeout (specialized profile relay)
+	// Try metadata relays first with a short timeout (specialized profile relays)
 	var events []Event
-	purpleEvents, _ := fetchEventsFromRelaysWithTimeout([]string{profileRelay}, filter, 1500*time.Millisecond)
-	events = append(events, purpleEvents...)
+	metadataEvents, _ := fetchEventsFromRelaysWithTimeout(relaysFor(RelayPurposeMetadata), filter, 1500*time.Millisecond)
+	events = append(events, metadataEvents...)
 
 	// Check which pubkeys we still need
 	foundPubkeys := make(map[string]bool)
@@ -459,7 +508,7 @@ func fetchProfiles(relays []string, pubkeys []string) map[string]*ProfileInfo {
 	}
 
 	if len(stillMissing) > 0 {
-		log.Printf("purplepag.es found %d/%d profiles, falling back to relays for %d", len(foundPubkeys), len(missing), len(stillMissing))
+		log.Printf("Metadata relays found %d/%d profiles, falling back to relays for %d", len(foundPubkeys), len(missing), len(stillMissing))
 		fallbackFilter := Filter{
 			Authors: stillMissing,
 			Kinds:   []int{0},
@@ -468,7 +517,7 @@ func fetchProfiles(relays []string, pubkeys []string) map[string]*ProfileInfo {
 		fallbackEvents, _ := fetchEventsFromRelaysWithTimeout(relays, fallbackFilter, 2000*time.Millisecond)
 		events = append(events, fallbackEvents...)
 	} else {
-		log.Printf("purplepag.es found all %d profiles", len(missing))
+		log.Printf("Metadata relays found all %d profiles", len(missing))
 	}
 
 	// Parse profile content and build map
@@ -591,6 +640,20 @@ func fetchReactions(relays []string, eventIDs []string) map[string]*ReactionsSum
 	return reactions
 }
 
+// fetchReactionsCached returns reaction summaries from the cache, fetching and caching missing ones
+func fetchReactionsCached(relays []string, eventIDs []string) map[string]*ReactionsSummary {
+	reactions, missing := reactionCache.GetMultiple(eventIDs)
+	if len(missing) == 0 {
+		return reactions
+	}
+	fetched := fetchReactions(relays, missing)
+	reactionCache.SetMultiple(missing, fetched)
+	for id, summary := range fetched {
+		reactions[id] = summary
+	}
+	return reactions
+}
+
 // fetchEventsFromRelaysWithETags fetches reactions referencing specific event IDs
 func fetchEventsFromRelaysWithETags(relays []string, eventIDs []string) ([]Event, bool) {
 	// Longer timeout for reactions - they can be slow to query
@@ -671,6 +734,19 @@ func fetchReactionsFromRelay(ctx context.Context, relayURL string, eventIDs []st
 				return
 			}
 		case <-sub.EOSEChan:
+			// Events delivered before EOSE may still be buffered - select doesn't preserve order
+			for drained := false; !drained; {
+				select {
+				case evt := <-sub.EventChan:
+					select {
+					case eventChan <- evt:
+					case <-ctx.Done():
+						return
+					}
+				default:
+					drained = true
+				}
+			}
 			log.Printf("Received EOSE from %s", relayURL)
 			eoseChan <- true
 			return
@@ -755,6 +831,19 @@ func fetchRepliesFromRelay(ctx context.Context, relayURL string, eventIDs []stri
 				return
 			}
 		case <-sub.EOSEChan:
+			// Events delivered before EOSE may still be buffered - select doesn't preserve order
+			for drained := false; !drained; {
+				select {
+				case evt := <-sub.EventChan:
+					select {
+					case eventChan <- evt:
+					case <-ctx.Done():
+						return
+					}
+				default:
+					drained = true
+				}
+			}
 			log.Printf("Received EOSE for replies from %s", relayURL)
 			return
 		}
@@ -838,12 +927,9 @@ func fetchRelayList(pubkey string) *RelayList {
 		return relayList
 	}
 
-	// Use well-known indexer relays to find relay lists
-	indexerRelays := []string{
-		"wss://purplepag.es",
-		"wss://relay.nostr.band",
-		"wss://relay.damus.io",
-	}
+	// Ask the metadata (indexer) relays and the read relays together; the default
+	// metadata set is purplepag.es alone, which misses lists the big relays have
+	indexerRelays := append(relaysFor(RelayPurposeMetadata), relaysFor(RelayPurposeRead)...)
 
 	filter := Filter{
 		Authors: []string{pubkey},
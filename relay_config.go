@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Relay purposes - what the instance uses a configured relay for
+const (
+	RelayPurposeRead     = "read"     // timeline, thread and profile queries
+	RelayPurposeWrite    = "write"    // publishing events for users without a NIP-65 list
+	RelayPurposeMetadata = "metadata" // profiles (kind 0) and relay lists (kind 10002)
+	RelayPurposeSearch   = "search"   // NIP-50 search queries
+	RelayPurposeDM       = "dm"       // direct message inbox/outbox
+)
+
+var validRelayPurposes = map[string]bool{
+	RelayPurposeRead:     true,
+	RelayPurposeWrite:    true,
+	RelayPurposeMetadata: true,
+	RelayPurposeSearch:   true,
+	RelayPurposeDM:       true,
+}
+
+// RelayConfigEntry is a single configured relay
+type RelayConfigEntry struct {
+	URL         string   `json:"url"`
+	Purposes    []string `json:"purposes"`
+	Description string   `json:"description,omitempty"`
+}
+
+// HasPurpose reports whether the relay is tagged with the given purpose
+func (e RelayConfigEntry) HasPurpose(purpose string) bool {
+	for _, p := range e.Purposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// RelayConfig is the instance's default relay configuration
+// Loaded from the JSON file at RELAY_CONFIG, or built-in defaults
+type RelayConfig struct {
+	Relays []RelayConfigEntry `json:"relays"`
+}
+
+// defaultRelayConfig mirrors the relays the server has always used
+func defaultRelayConfig() *RelayConfig {
+	return &RelayConfig{Relays: []RelayConfigEntry{
+		{URL: "wss://relay.damus.io", Purposes: []string{"read", "write", "dm"}, Description: "Large general-purpose relay"},
+		{URL: "wss://relay.nostr.band", Purposes: []string{"read", "write", "search"}, Description: "Aggregator with NIP-50 search"},
+		{URL: "wss://relay.primal.net", Purposes: []string{"read", "write"}, Description: "Primal's caching relay"},
+		{URL: "wss://nos.lol", Purposes: []string{"read", "write", "dm"}, Description: "General-purpose relay"},
+		{URL: "wss://nostr.mom", Purposes: []string{"read"}, Description: "General-purpose relay"},
+		{URL: "wss://purplepag.es", Purposes: []string{"metadata"}, Description: "Profile and relay list indexer"},
+	}}
+}
+
+// Validate checks URLs and purposes, and requires at least one read and one write relay
+func (c *RelayConfig) Validate() error {
+	if len(c.Relays) == 0 {
+		return errors.New("no relays configured")
+	}
+
+	seen := make(map[string]bool)
+	hasRead, hasWrite := false, false
+	for i, r := range c.Relays {
+		u, err := url.Parse(r.URL)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			return fmt.Errorf("relay %d: invalid URL %q", i, r.URL)
+		}
+		if seen[r.URL] {
+			return fmt.Errorf("relay %d: duplicate URL %q", i, r.URL)
+		}
+		seen[r.URL] = true
+
+		if len(r.Purposes) == 0 {
+			return fmt.Errorf("relay %s: no purposes", r.URL)
+		}
+		for _, p := range r.Purposes {
+			if !validRelayPurposes[p] {
+				return fmt.Errorf("relay %s: unknown purpose %q", r.URL, p)
+			}
+		}
+		hasRead = hasRead || r.HasPurpose(RelayPurposeRead)
+		hasWrite = hasWrite || r.HasPurpose(RelayPurposeWrite)
+	}
+
+	if !hasRead {
+		return errors.New("at least one relay must have the read purpose")
+	}
+	if !hasWrite {
+		return errors.New("at least one relay must have the write purpose")
+	}
+	return nil
+}
+
+// URLsFor returns the URLs of relays tagged with the purpose, in config order
+func (c *RelayConfig) URLsFor(purpose string) []string {
+	var urls []string
+	for _, r := range c.Relays {
+		if r.HasPurpose(purpose) {
+			urls = append(urls, r.URL)
+		}
+	}
+	return urls
+}
+
+var (
+	relayConfigMu      sync.RWMutex
+	currentRelayConfig = defaultRelayConfig()
+)
+
+// getRelayConfig returns the active relay configuration
+func getRelayConfig() *RelayConfig {
+	relayConfigMu.RLock()
+	defer relayConfigMu.RUnlock()
+	return currentRelayConfig
+}
+
+// relaysFor returns the configured relay URLs for a purpose
+// Falls back to the read relays when nothing is tagged for metadata, search or dm
+func relaysFor(purpose string) []string {
+	cfg := getRelayConfig()
+	urls := cfg.URLsFor(purpose)
+	if len(urls) == 0 && purpose != RelayPurposeWrite {
+		urls = cfg.URLsFor(RelayPurposeRead)
+	}
+	// Return a copy so callers can't mutate the shared config
+	return append([]string(nil), urls...)
+}
+
+// loadRelayConfig reads and validates a relay configuration file
+func loadRelayConfig(path string) (*RelayConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg RelayConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid relay config %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// initRelayConfig loads RELAY_CONFIG at startup and installs a SIGHUP handler to reload it
+// An invalid file at startup is fatal; an invalid file on reload keeps the previous config
+func initRelayConfig() {
+	path := os.Getenv("RELAY_CONFIG")
+	if path == "" {
+		log.Printf("Using built-in relay configuration (%d relays)", len(getRelayConfig().Relays))
+		return
+	}
+
+	cfg, err := loadRelayConfig(path)
+	if err != nil {
+		log.Fatalf("Failed to load relay config: %v", err)
+	}
+	setRelayConfig(cfg)
+	log.Printf("Loaded relay configuration from %s (%d relays)", path, len(cfg.Relays))
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			cfg, err := loadRelayConfig(path)
+			if err != nil {
+				log.Printf("Relay config reload failed, keeping previous config: %v", err)
+				continue
+			}
+			setRelayConfig(cfg)
+			log.Printf("Reloaded relay configuration from %s (%d relays)", path, len(cfg.Relays))
+		}
+	}()
+}
+
+// setRelayConfig swaps the active configuration and drops pooled
+// connections to relays that are no longer configured
+func setRelayConfig(cfg *RelayConfig) {
+	relayConfigMu.Lock()
+	old := currentRelayConfig
+	currentRelayConfig = cfg
+	relayConfigMu.Unlock()
+
+	keep := make(map[string]bool, len(cfg.Relays))
+	for _, r := range cfg.Relays {
+		keep[r.URL] = true
+	}
+	for _, r := range old.Relays {
+		if !keep[r.URL] {
+			log.Printf("Relay %s removed from config, closing pooled connection", r.URL)
+			relayPool.CloseRelay(r.URL)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDefaultRelayConfig(t *testing.T) {
+	cfg := defaultRelayConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+	// Profile lookups try the metadata relays alone first
+	if got := cfg.URLsFor(RelayPurposeMetadata); !slices.Equal(got, []string{"wss://purplepag.es"}) {
+		t.Errorf("metadata relays = %v, want purplepag.es alone", got)
+	}
+}
+
+func TestRelayConfigSearchPurpose(t *testing.T) {
+	cfg := &RelayConfig{Relays: []RelayConfigEntry{
+		{URL: "wss://search.example.com", Purposes: []string{"read", "write", "search"}},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("config with a search relay was rejected: %v", err)
+	}
+	if got := cfg.URLsFor(RelayPurposeSearch); !slices.Equal(got, []string{"wss://search.example.com"}) {
+		t.Errorf("search relays = %v", got)
+	}
+	if got := defaultRelayConfig().URLsFor(RelayPurposeSearch); len(got) == 0 {
+		t.Error("default config has no search relay")
+	}
+}
+
+func TestRelaysForSearchFallsBackToRead(t *testing.T) {
+	setRelayConfig(&RelayConfig{Relays: []RelayConfigEntry{
+		{URL: "wss://read.example.com", Purposes: []string{"read"}},
+		{URL: "wss://write.example.com", Purposes: []string{"write"}},
+	}})
+	t.Cleanup(func() { setRelayConfig(defaultRelayConfig()) })
+
+	if got := relaysFor(RelayPurposeSearch); !slices.Equal(got, []string{"wss://read.example.com"}) {
+		t.Errorf("search relays = %v, want the read relays", got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"log"
 	"net"
 	"net/url"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -134,14 +136,32 @@ type RelayConn struct {
 	mu            sync.Mutex
 	writeMu       sync.Mutex
 	subscriptions map[string]*Subscription
+	pendingOK     map[string]chan PublishAck // event ID -> waiter for the relay's OK
 	closed        bool
 	lastActivity  time.Time
+	pool          *RelayPool
 }
 
 // RelayPool manages connections to multiple relays
 type RelayPool struct {
 	mu          sync.RWMutex
 	connections map[string]*RelayConn // relayURL -> connection
+
+	healthMu sync.Mutex
+	health   map[string]*RelayHealth // relayURL -> connection history
+}
+
+// RelayHealth counts connection attempts and traffic for a relay
+type RelayHealth struct {
+	URL          string
+	Connected    bool // filled in by Health()
+	Connects     int
+	DialFailures int
+	FailStreak   int // dial failures since the last successful connect
+	Events       int
+	LastError    string
+	LastErrorAt  time.Time
+	LastEventAt  time.Time
 }
 
 // Global relay pool
@@ -151,6 +171,7 @@ var relayPool = NewRelayPool()
 func NewRelayPool() *RelayPool {
 	pool := &RelayPool{
 		connections: make(map[string]*RelayConn),
+		health:      make(map[string]*RelayHealth),
 	}
 	go pool.cleanupLoop()
 	return pool
@@ -184,6 +205,7 @@ func (p *RelayPool) getOrCreateConn(ctx context.Context, relayURL string) (*Rela
 	// Create new connection
 	log.Printf("Pool: creating new connection to %s", relayURL)
 	conn, _, err := websocket.DefaultDialer.DialContext(ctx, relayURL, nil)
+	p.recordDial(relayURL, err)
 	if err != nil {
 		return nil, err
 	}
@@ -192,7 +214,9 @@ func (p *RelayPool) getOrCreateConn(ctx context.Context, relayURL string) (*Rela
 		conn:          conn,
 		relayURL:      relayURL,
 		subscriptions: make(map[string]*Subscription),
+		pendingOK:     make(map[string]chan PublishAck),
 		lastActivity:  time.Now(),
+		pool:          p,
 	}
 
 	p.connections[relayURL] = rc
@@ -265,6 +289,56 @@ func (p *RelayPool) Subscribe(ctx context.Context, relayURL string, subID string
 	return sub, nil
 }
 
+// PublishAck is a relay's OK response to a published event
+type PublishAck struct {
+	Accepted bool
+	Message  string
+}
+
+// Publish sends an event over the pooled connection and waits for the relay's OK
+func (p *RelayPool) Publish(ctx context.Context, relayURL string, event *Event) (PublishAck, error) {
+	rc, err := p.getOrCreateConn(ctx, relayURL)
+	if err != nil {
+		return PublishAck{}, err
+	}
+
+	waiter := make(chan PublishAck, 1)
+	rc.mu.Lock()
+	if rc.closed {
+		rc.mu.Unlock()
+		return PublishAck{}, errors.New("connection closed")
+	}
+	rc.pendingOK[event.ID] = waiter
+	rc.lastActivity = time.Now()
+	rc.mu.Unlock()
+
+	defer func() {
+		rc.mu.Lock()
+		delete(rc.pendingOK, event.ID)
+		rc.mu.Unlock()
+	}()
+
+	rc.writeMu.Lock()
+	rc.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	err = rc.conn.WriteJSON([]interface{}{"EVENT", event})
+	rc.writeMu.Unlock()
+	if err != nil {
+		rc.markClosed()
+		return PublishAck{}, err
+	}
+
+	timeout := time.NewTimer(5 * time.Second)
+	defer timeout.Stop()
+	select {
+	case ack := <-waiter:
+		return ack, nil
+	case <-timeout.C:
+		return PublishAck{}, errors.New("timeout waiting for OK")
+	case <-ctx.Done():
+		return PublishAck{}, ctx.Err()
+	}
+}
+
 // Unsubscribe closes a subscription
 func (p *RelayPool) Unsubscribe(relayURL string, sub *Subscription) {
 	if sub == nil {
@@ -300,6 +374,27 @@ func (p *RelayPool) Unsubscribe(relayURL string, sub *Subscription) {
 	sub.Close()
 }
 
+// ingestEvent checks an event a relay delivered and, the first time it's seen,
+// records it and runs the ingestion hooks. Returns false if the event was dropped:
+// over the per-kind limits we publish with, unsigned, or with an ID that doesn't
+// match its content (the seen cache keeps the first copy, so a forged one would stick)
+func ingestEvent(evt Event, relayURL string) bool {
+	if checkEventLimits(evt.Kind, evt.Content, evt.Tags) != nil {
+		return false
+	}
+	if !verifyEvent(&evt) {
+		log.Printf("Pool: dropped unverified event %s from %s", shortID(evt.ID), relayURL)
+		return false
+	}
+	if seenEventCache.Record(evt, relayURL) {
+		ingestStats.Record(evt)
+		newKeyEmbargo.Record(evt)
+		engagementStats.Record(evt)
+		notifyWebhooksForEvent(evt, relayURL)
+	}
+	return true
+}
+
 // readLoop continuously reads from the connection and routes messages
 func (rc *RelayConn) readLoop() {
 	defer rc.markClosed()
@@ -345,6 +440,10 @@ func (rc *RelayConn) readLoop() {
 				continue
 			}
 			evt.RelaysSeen = []string{rc.relayURL}
+			rc.pool.recordEvent(rc.relayURL)
+			if !ingestEvent(evt, rc.relayURL) {
+				continue
+			}
 
 			rc.mu.Lock()
 			sub := rc.subscriptions[subID]
@@ -394,6 +493,27 @@ func (rc *RelayConn) readLoop() {
 				}
 			}
 
+		case "OK":
+			// ["OK", <event id>, <accepted>, <message>]
+			if len(msg) < 3 {
+				continue
+			}
+			eventID, _ := msg[1].(string)
+			accepted, _ := msg[2].(bool)
+			reason := ""
+			if len(msg) > 3 {
+				reason, _ = msg[3].(string)
+			}
+
+			rc.mu.Lock()
+			waiter := rc.pendingOK[eventID]
+			delete(rc.pendingOK, eventID)
+			rc.mu.Unlock()
+
+			if waiter != nil {
+				waiter <- PublishAck{Accepted: accepted, Message: reason}
+			}
+
 		case "NOTICE":
 			if len(msg) >= 2 {
 				notice, _ := msg[1].(string)
@@ -463,6 +583,79 @@ func (p *RelayPool) CloseRelay(relayURL string) {
 	}
 }
 
+// healthFor returns the health record for a relay (must hold healthMu)
+func (p *RelayPool) healthFor(relayURL string) *RelayHealth {
+	h := p.health[relayURL]
+	if h == nil {
+		h = &RelayHealth{URL: relayURL}
+		p.health[relayURL] = h
+	}
+	return h
+}
+
+// relayCircuitThreshold is how many dial failures in a row count as a relay going down
+// (the relay-circuit-opened webhook fires once per streak)
+const relayCircuitThreshold = 5
+
+// recordDial counts a connection attempt and its outcome
+func (p *RelayPool) recordDial(relayURL string, err error) {
+	p.healthMu.Lock()
+	h := p.healthFor(relayURL)
+	if err != nil {
+		h.DialFailures++
+		h.FailStreak++
+		h.LastError = err.Error()
+		h.LastErrorAt = time.Now()
+	} else {
+		h.Connects++
+		h.FailStreak = 0
+	}
+	opened := err != nil && h.FailStreak == relayCircuitThreshold
+	p.healthMu.Unlock()
+
+	if opened {
+		notifyWebhooks(WebhookRelayCircuitOpened, map[string]string{
+			"relay":    relayURL,
+			"failures": strconv.Itoa(relayCircuitThreshold),
+			"error":    err.Error(),
+		})
+	}
+}
+
+// recordEvent counts an EVENT message received from a relay
+func (p *RelayPool) recordEvent(relayURL string) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+
+	h := p.healthFor(relayURL)
+	h.Events++
+	h.LastEventAt = time.Now()
+}
+
+// Health returns a snapshot of every relay the pool has tried, sorted by URL
+func (p *RelayPool) Health() []RelayHealth {
+	p.mu.RLock()
+	connected := make(map[string]bool, len(p.connections))
+	for url, rc := range p.connections {
+		connected[url] = !rc.closed
+	}
+	p.mu.RUnlock()
+
+	p.healthMu.Lock()
+	result := make([]RelayHealth, 0, len(p.health))
+	for _, h := range p.health {
+		snapshot := *h
+		snapshot.Connected = connected[h.URL]
+		result = append(result, snapshot)
+	}
+	p.healthMu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].URL < result[j].URL
+	})
+	return result
+}
+
 // PooledConn is a compatibility wrapper for code that expects the old interface
 type PooledConn struct {
 	pool     *RelayPool
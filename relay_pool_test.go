@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+func TestReadLoopKeepsLargeContactList(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("pool-large-contacts-alice")
+	follows := make([]string, 15000)
+	for i := range follows {
+		follows[i] = fmt.Sprintf("%064x", i+1)
+	}
+	s.Relay.Publish(testutil.ContactList(alice, time.Now().Unix()-60, follows...))
+
+	got := fetchContactList([]string{s.Relay.URL()}, alice.PubKey)
+	if len(got) != len(follows) {
+		t.Errorf("fetched %d follows, want %d", len(got), len(follows))
+	}
+}
+
+func TestReadLoopDropsEventsOverLimits(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("pool-drop-alice")
+	now := time.Now().Unix()
+
+	tags := make([][]string, limitsForKind(7).MaxTags+1)
+	for i := range tags {
+		tags[i] = []string{"t", fmt.Sprintf("tag%d", i)}
+	}
+	oversized := testutil.MustSign(alice, testutil.Event{Kind: 7, CreatedAt: now - 60, Content: "+", Tags: tags})
+	fine := testutil.Reaction(alice, now-30, "+", oversized)
+	s.Relay.Publish(oversized, fine)
+
+	events, _ := fetchEventsFromRelays([]string{s.Relay.URL()}, Filter{Authors: []string{alice.PubKey}, Kinds: []int{7}, Limit: 10})
+	if len(events) != 1 || events[0].ID != fine.ID {
+		var ids []string
+		for _, evt := range events {
+			ids = append(ids, shortID(evt.ID))
+		}
+		t.Errorf("fetched %v, want only the reaction within limits (%s)", ids, shortID(fine.ID))
+	}
+}
+
+func TestReadLoopDropsUnverifiedEvents(t *testing.T) {
+	s := startTestServer(t)
+	alice := testutil.NewKeypair("pool-unverified-alice")
+	now := time.Now().Unix()
+
+	real := testutil.Note(alice, now-90, "the real note")
+	unsigned := testutil.Note(alice, now-60, "unsigned")
+	unsigned.Sig = ""
+	tampered := testutil.Note(alice, now-30, "original content")
+	tampered.Content = "forged content"
+	signed := testutil.Note(alice, now-10, "<b>signed</b> & sealed")
+	s.Relay.Publish(unsigned, tampered, signed)
+
+	events, _ := fetchEventsFromRelays([]string{s.Relay.URL()}, Filter{Authors: []string{alice.PubKey}, Kinds: []int{1}, Limit: 10})
+	if len(events) != 1 || events[0].ID != signed.ID {
+		var ids []string
+		for _, evt := range events {
+			ids = append(ids, shortID(evt.ID))
+		}
+		t.Errorf("fetched %v, want only the signed note (%s)", ids, shortID(signed.ID))
+	}
+	for name, evt := range map[string]testutil.Event{"unsigned": unsigned, "tampered": tampered} {
+		if _, ok := seenEventCache.Get(evt.ID); ok {
+			t.Errorf("%s event %s was recorded in the seen cache", name, shortID(evt.ID))
+		}
+	}
+	if _, ok := seenEventCache.Get(signed.ID); !ok {
+		t.Errorf("signed event %s was not recorded", shortID(signed.ID))
+	}
+
+	// A real ID and signature replayed over different content
+	forged := Event{ID: real.ID, PubKey: real.PubKey, CreatedAt: real.CreatedAt, Kind: real.Kind, Tags: real.Tags, Content: "forged content", Sig: real.Sig}
+	if ingestEvent(forged, "wss://forger.example") {
+		t.Error("ingestEvent accepted a real ID and signature over forged content")
+	}
+	if _, ok := seenEventCache.Get(real.ID); ok {
+		t.Error("forged copy of the real event was recorded")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// HTML sanitization boundary
+// Event content reaches templates only as RenderedContent, which only the content pipeline
+// (processContentToHTMLFull, renderMarkdown, extractImetaImages) produces. The pipeline
+// escapes all event text and builds its own markup from an allowlist: links, <img>,
+// <video>, <audio>, YouTube iframes, link preview cards and nostr: reference links, with
+// http(s) URLs only. Markdown goes through goldmark's defaults, which omit raw HTML, plus
+// safeMarkdownLinks, which drops links and images with unsafe URLs. Everything else reaches templates as plain
+// strings and is escaped by html/template; no template func marks a string as safe.
+// This file holds the package's only template.HTML conversions outside icon().
+
+// RenderedContent is event content rendered to HTML by the content pipeline
+// Use {{.ContentHTML.HTML}} in templates; the zero value renders nothing
+type RenderedContent struct {
+	html template.HTML
+}
+
+// HTML returns the rendered markup for a template
+func (c RenderedContent) HTML() template.HTML {
+	return c.html
+}
+
+// String returns the rendered markup, for embedding in other pipeline output
+func (c RenderedContent) String() string {
+	return string(c.html)
+}
+
+// renderedContent wraps markup the pipeline built with every piece of event text escaped
+// Only the content pipeline calls this
+func renderedContent(built string) RenderedContent {
+	return RenderedContent{html: template.HTML(built)}
+}
+
+// isSafeMediaURL reports whether an event-supplied URL may go in a src or href attribute
+func isSafeMediaURL(rawURL string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// markdownRenderer renders long-form markdown (see renderMarkdown)
+var markdownRenderer = goldmark.New(goldmark.WithParserOptions(
+	parser.WithASTTransformers(util.Prioritized(safeMarkdownLinks{}, 100)),
+))
+
+// safeMarkdownLinks unwraps links and autolinks and drops images whose URL isn't safe
+// goldmark's own scheme check looks at the destination before entities are decoded, so
+// [x](&#106;avascript:...) would get through it; this checks what the browser will see.
+type safeMarkdownLinks struct{}
+
+func (safeMarkdownLinks) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+	var links, autoLinks, images []ast.Node
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if !isSafeMarkdownLink(string(n.Destination)) {
+				links = append(links, n)
+			}
+		case *ast.AutoLink:
+			// <scheme:...> autolinks take any scheme
+			if !isSafeMarkdownLink(string(n.URL(source))) {
+				autoLinks = append(autoLinks, n)
+			}
+		case *ast.Image:
+			if !isSafeMediaURL(html.UnescapeString(string(n.Destination))) {
+				images = append(images, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	// A link keeps its text
+	for _, n := range links {
+		parent := n.Parent()
+		for child := n.FirstChild(); child != nil; child = n.FirstChild() {
+			parent.InsertBefore(parent, n, child)
+		}
+		parent.RemoveChild(parent, n)
+	}
+	for _, n := range autoLinks {
+		parent := n.Parent()
+		parent.ReplaceChild(parent, n, ast.NewString(n.(*ast.AutoLink).Label(source)))
+	}
+	for _, n := range images {
+		n.Parent().RemoveChild(n.Parent(), n)
+	}
+}
+
+// isSafeMarkdownLink reports whether a markdown link destination is http(s), mailto or
+// relative once entities are decoded and the whitespace browsers ignore is removed
+func isSafeMarkdownLink(dest string) bool {
+	dest = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, html.UnescapeString(dest))
+	colon := strings.IndexByte(dest, ':')
+	if colon < 0 || strings.ContainsAny(dest[:colon], "/?#") {
+		return true
+	}
+	switch strings.ToLower(dest[:colon]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// handlerFragment embeds the output of one of our own handlers in another page
+// Only for bodies rendered by html/template (deck columns); never for event content
+func handlerFragment(body string) template.HTML {
+	return template.HTML(body)
+}
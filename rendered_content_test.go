@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"testing"
+)
+
+// Hostile event content for the content pipeline. Every output must contain only the
+// markup the pipeline builds itself: allowlisted tags and attributes, double-quoted
+// values and http(s) or same-site URLs.
+var hostileContent = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=//evil.example/x.js></SCRIPT>`,
+	`<scr<script>ipt>alert(1)</script>`,
+	`<iframe src="javascript:alert(1)"></iframe>`,
+	`<iframe srcdoc="<script>alert(1)</script>">`,
+	`<img src=x onerror=alert(1)>`,
+	`<svg/onload=alert(1)>`,
+	`<a href="javascript:alert(1)">click</a>`,
+	`<!--<img src=x onerror=alert(1)>-->`,
+	`<![CDATA[<script>alert(1)</script>]]>`,
+	`<?xml version="1.0"?><x/>`,
+	`javascript:alert(1)`,
+	`JaVaScRiPt:alert(document.cookie)`,
+	`data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==`,
+	`https://example.com/"onmouseover="alert(1)`,
+	`https://example.com/x.jpg"><script>alert(1)</script>`,
+	`https://example.com/'onerror='alert(1)'.png`,
+	`https://example.com/a.png?x="><img src=x onerror=alert(1)>`,
+	`https://www.youtube.com/watch?v="><script>alert(1)</script>`,
+	`https://youtu.be/abc" onload="alert(1)`,
+	`https://example.com/video.mp4#"onplay="alert(1)`,
+	`https://example.com/<script>`,
+	"https://example.com/\x00<script>alert(1)</script>",
+	"\x00NOSTR_0\x00<script>alert(1)</script>",
+	`nostr:npub1"><script>alert(1)</script>`,
+	`nostr:note1<img src=x onerror=alert(1)>`,
+	`&lt;script&gt;alert(1)&lt;/script&gt;`,
+	`&#60;script&#62;alert(1)&#60;/script&#62;`,
+	`[click](javascript:alert(1))`,
+	`[click](JAVASCRIPT:alert(1))`,
+	`[click](&#106;avascript:alert(1))`,
+	`[click](java&#x09;script:alert(1))`,
+	`[click](vbscript:msgbox(1))`,
+	`[click](data:text/html,<script>alert(1)</script>)`,
+	`![x](javascript:alert(1))`,
+	`![x" onerror="alert(1)](https://example.com/x.png)`,
+	`[x](https://example.com/ "title\" onmouseover=\"alert(1)")`,
+	`<https://example.com/"onclick="alert(1)>`,
+	`<javascript:alert(1)>`,
+	`<vbscript:msgbox(1)>`,
+	"```\n<script>alert(1)</script>\n```",
+	"<div>\n<script>alert(1)</script>\n</div>",
+	"# <img src=x onerror=alert(1)>",
+	"\xff\xfe<script>alert(1)</script>",
+	"<scr\xffipt>alert(1)</script>",
+	"https://example.com/\xc3\x28.png",
+	"\xed\xa0\x80<img src=x onerror=alert(1)>",
+	"‮<script>alert(1)</script>",
+	"<script>alert(1)</script>",
+	"<<script>>alert(1)<</script>>",
+	strings.Repeat("<", 500) + "script>",
+}
+
+var (
+	allowedTags = map[string]bool{
+		"a": true, "img": true, "video": true, "audio": true, "iframe": true, "div": true,
+		"p": true, "br": true, "hr": true, "em": true, "strong": true, "code": true, "pre": true,
+		"ul": true, "ol": true, "li": true, "blockquote": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	}
+	allowedAttrs = map[string]bool{
+		"href": true, "src": true, "alt": true, "title": true, "class": true, "loading": true,
+		"controls": true, "preload": true, "frameborder": true, "allow": true,
+		"allowfullscreen": true, "target": true, "rel": true, "start": true,
+	}
+)
+
+// liveMarkupError returns why out has markup the pipeline didn't build, or ""
+// It reads the output the way a browser's tokenizer would: any "<" followed by a letter,
+// "/", "!" or "?" starts markup, and every tag must be one of ours with attributes we set.
+func liveMarkupError(out string) string {
+	for i := 0; i < len(out); i++ {
+		if out[i] != '<' {
+			continue
+		}
+		rest := out[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "/"):
+			name, n := scanName(rest[1:])
+			if !allowedTags[name] || !strings.HasPrefix(rest[1+n:], ">") {
+				return fmt.Sprintf("end tag %q", truncateString(out[i:], 40))
+			}
+		case len(rest) > 0 && isASCIILetter(rest[0]):
+			if err := checkStartTag(rest); err != "" {
+				return err + " in " + fmt.Sprintf("%q", truncateString(out[i:], 60))
+			}
+		case strings.HasPrefix(rest, "!-- raw HTML omitted -->"):
+			// goldmark's placeholder for raw HTML it dropped
+		case strings.HasPrefix(rest, "!"), strings.HasPrefix(rest, "?"):
+			return fmt.Sprintf("markup declaration %q", truncateString(out[i:], 40))
+		}
+	}
+	return ""
+}
+
+// checkStartTag checks a start tag (after the "<") against the allowlists
+func checkStartTag(s string) string {
+	name, n := scanName(s)
+	if !allowedTags[name] {
+		return "tag <" + name + ">"
+	}
+	s = s[n:]
+	for {
+		s = strings.TrimLeft(s, " \t\n")
+		if strings.HasPrefix(s, ">") || strings.HasPrefix(s, "/>") {
+			return ""
+		}
+		attr, n := scanName(s)
+		if n == 0 {
+			return "malformed tag"
+		}
+		if !allowedAttrs[attr] {
+			return "attribute " + attr
+		}
+		s = s[n:]
+		if !strings.HasPrefix(s, "=") {
+			continue
+		}
+		if !strings.HasPrefix(s, `="`) {
+			return "unquoted " + attr
+		}
+		end := strings.IndexByte(s[2:], '"')
+		if end < 0 {
+			return "unterminated " + attr
+		}
+		value := html.UnescapeString(s[2 : 2+end])
+		if err := checkAttrValue(name, attr, value); err != "" {
+			return err
+		}
+		s = s[2+end+1:]
+	}
+}
+
+// checkAttrValue checks URL attributes the way a browser resolves them: entities decoded,
+// and tabs, newlines and leading control characters ignored in the scheme
+func checkAttrValue(tag, attr, value string) string {
+	if attr != "href" && attr != "src" {
+		return ""
+	}
+	stripped := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, value)
+	if tag == "iframe" && !strings.HasPrefix(stripped, "https://www.youtube-nocookie.com/embed/") {
+		return "iframe src " + value
+	}
+	// Anything before the first colon is a scheme unless it has a character schemes can't
+	scheme := ""
+	if colon := strings.IndexByte(stripped, ':'); colon > 0 {
+		scheme = strings.ToLower(stripped[:colon])
+		for _, c := range scheme {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+				scheme = ""
+				break
+			}
+		}
+	}
+	switch scheme {
+	case "", "http", "https":
+		return ""
+	case "mailto":
+		if attr == "href" {
+			return ""
+		}
+	}
+	return attr + " " + value
+}
+
+func scanName(s string) (string, int) {
+	n := 0
+	for n < len(s) && (isASCIILetter(s[n]) || s[n] >= '0' && s[n] <= '9' || s[n] == '-') {
+		n++
+	}
+	return strings.ToLower(s[:n]), n
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// renderAllPipelines runs content through every path that produces RenderedContent
+func renderAllPipelines(content string) map[string]string {
+	previews := map[string]*LinkPreview{}
+	for _, u := range urlRegex.FindAllString(content, -1) {
+		previews[u] = &LinkPreview{URL: u, Title: content, Description: content, SiteName: content, Image: u}
+	}
+	return map[string]string{
+		"content":  processContentToHTML(content).String(),
+		"previews": processContentToHTMLFull(content, nil, nil, previews).String(),
+		"markdown": renderMarkdown(content).String(),
+		"imeta":    extractImetaImages([][]string{{"imeta", "url " + content, "alt " + content}, {"imeta", "url https://example.com/a.png", "alt " + content}}).String(),
+	}
+}
+
+func TestRenderContentHostileInputs(t *testing.T) {
+	for _, content := range hostileContent {
+		for pipeline, out := range renderAllPipelines(content) {
+			if err := liveMarkupError(out); err != "" {
+				t.Errorf("%s(%q): %s\noutput: %s", pipeline, content, err, out)
+			}
+		}
+	}
+}
+
+func TestRenderContentKeepsOwnMarkup(t *testing.T) {
+	// The checker must pass the pipeline's own markup, or the hostile tests prove nothing
+	content := "hello https://example.com/a.png https://example.com/b.jpg\nhttps://example.com/v.mp4 https://www.youtube.com/watch?v=dQw4w9WgXcQ https://example.com/page"
+	for pipeline, out := range renderAllPipelines(content) {
+		if err := liveMarkupError(out); err != "" {
+			t.Errorf("%s: %s\noutput: %s", pipeline, err, out)
+		}
+	}
+	for _, bad := range []string{
+		`<script>x</script>`,
+		`<a href="javascript:x">`,
+		`<a href="java&#x09;script:x">`,
+		`<img src="x" onerror="x">`,
+		`<a href=x>`,
+		`<iframe src="https://evil.example/">`,
+		`<!-- x -->`,
+	} {
+		if liveMarkupError(bad) == "" {
+			t.Errorf("checker passed %s", bad)
+		}
+	}
+}
+
+func FuzzRenderContent(f *testing.F) {
+	for _, content := range hostileContent {
+		f.Add(content)
+	}
+	f.Fuzz(func(t *testing.T, content string) {
+		for pipeline, out := range renderAllPipelines(content) {
+			if err := liveMarkupError(out); err != "" {
+				t.Errorf("%s(%q): %s\noutput: %s", pipeline, content, err, out)
+			}
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// serverStartTime is used for the uptime shown on the stats page
+var serverStartTime = time.Now()
+
+// statsWindowHours is how far back ingestion counters reach (one week)
+const statsWindowHours = 7 * 24
+
+// IngestStats counts newly ingested events per kind in hourly buckets,
+// and when each author was last seen, for the last week
+type IngestStats struct {
+	mu      sync.Mutex
+	buckets [statsWindowHours]ingestBucket
+	authors map[string]int64 // pubkey -> hour last seen
+}
+
+type ingestBucket struct {
+	hour  int64 // unix hour this bucket holds; stale buckets are reset on reuse
+	kinds map[int]int
+}
+
+// Global ingestion counters, fed by the relay pool read loop
+var ingestStats = &IngestStats{authors: make(map[string]int64)}
+
+// Record counts a newly ingested event
+func (s *IngestStats) Record(evt Event) {
+	hour := time.Now().Unix() / 3600
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := &s.buckets[hour%statsWindowHours]
+	if b.hour != hour {
+		// New hour: reuse the bucket and drop authors older than the window
+		b.hour = hour
+		b.kinds = make(map[int]int)
+		for pubkey, seen := range s.authors {
+			if hour-seen >= statsWindowHours {
+				delete(s.authors, pubkey)
+			}
+		}
+	}
+	b.kinds[evt.Kind]++
+	s.authors[evt.PubKey] = hour
+}
+
+// KindCount is the number of events of a kind ingested in the last day and week
+type KindCount struct {
+	Kind int
+	Day  int
+	Week int
+}
+
+// IngestSnapshot is a point-in-time summary of the ingestion counters
+type IngestSnapshot struct {
+	Kinds       []KindCount // sorted by weekly count, highest first
+	TotalDay    int
+	TotalWeek   int
+	AuthorsDay  int
+	AuthorsWeek int
+}
+
+// Snapshot summarises the last 24 hours and the last week
+func (s *IngestStats) Snapshot() IngestSnapshot {
+	hour := time.Now().Unix() / 3600
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byKind := make(map[int]*KindCount)
+	var snap IngestSnapshot
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		age := hour - b.hour
+		if b.kinds == nil || age < 0 || age >= statsWindowHours {
+			continue
+		}
+		for kind, n := range b.kinds {
+			kc := byKind[kind]
+			if kc == nil {
+				kc = &KindCount{Kind: kind}
+				byKind[kind] = kc
+			}
+			kc.Week += n
+			snap.TotalWeek += n
+			if age < 24 {
+				kc.Day += n
+				snap.TotalDay += n
+			}
+		}
+	}
+	for _, seen := range s.authors {
+		age := hour - seen
+		if age < 24 {
+			snap.AuthorsDay++
+		}
+		if age < statsWindowHours {
+			snap.AuthorsWeek++
+		}
+	}
+
+	for _, kc := range byKind {
+		snap.Kinds = append(snap.Kinds, *kc)
+	}
+	sort.Slice(snap.Kinds, func(i, j int) bool {
+		if snap.Kinds[i].Week != snap.Kinds[j].Week {
+			return snap.Kinds[i].Week > snap.Kinds[j].Week
+		}
+		return snap.Kinds[i].Kind < snap.Kinds[j].Kind
+	})
+	return snap
+}
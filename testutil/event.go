@@ -0,0 +1,185 @@
+// Package testutil provides an in-process Nostr relay and event helpers
+// for exercising the server without touching the public network.
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// Event mirrors the NIP-01 event structure
+type Event struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// TagValue returns the first value of the named tag, or "" if absent
+func (e *Event) TagValue(name string) string {
+	for _, tag := range e.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			return tag[1]
+		}
+	}
+	return ""
+}
+
+// Keypair holds a secp256k1 key used to sign seeded events
+type Keypair struct {
+	PrivKey *btcec.PrivateKey
+	PubKey  string // x-only hex pubkey
+}
+
+// NewKeypair derives a deterministic keypair from a seed string,
+// so fixtures produce the same pubkeys on every run
+func NewKeypair(seed string) *Keypair {
+	sum := sha256.Sum256([]byte("nostr-hypermedia-testutil:" + seed))
+	priv, _ := btcec.PrivKeyFromBytes(sum[:])
+	return &Keypair{
+		PrivKey: priv,
+		PubKey:  hex.EncodeToString(schnorr.SerializePubKey(priv.PubKey())),
+	}
+}
+
+// PrivKeyHex returns the hex-encoded private key
+func (k *Keypair) PrivKeyHex() string {
+	return hex.EncodeToString(k.PrivKey.Serialize())
+}
+
+// Sign sets the pubkey, computes the event ID and signs the event in place
+func (k *Keypair) Sign(evt *Event) error {
+	evt.PubKey = k.PubKey
+	if evt.Tags == nil {
+		evt.Tags = [][]string{}
+	}
+	evt.ID = ComputeEventID(evt)
+
+	idBytes, err := hex.DecodeString(evt.ID)
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.Sign(k.PrivKey, idBytes)
+	if err != nil {
+		return err
+	}
+	evt.Sig = hex.EncodeToString(sig.Serialize())
+	return nil
+}
+
+// ComputeEventID returns the NIP-01 event ID (sha256 of the serialized event)
+func ComputeEventID(evt *Event) string {
+	tags := evt.Tags
+	if tags == nil {
+		tags = [][]string{}
+	}
+	// NIP-01 serialization does not HTML-escape <, > and &
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode([]interface{}{0, evt.PubKey, evt.CreatedAt, evt.Kind, tags, evt.Content})
+	hash := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// Filter is a NIP-01 subscription filter. Tag filters ("#e", "#p", ...)
+// are collected into Tags keyed by the tag letter.
+type Filter struct {
+	IDs     []string            `json:"ids,omitempty"`
+	Authors []string            `json:"authors,omitempty"`
+	Kinds   []int               `json:"kinds,omitempty"`
+	Since   *int64              `json:"since,omitempty"`
+	Until   *int64              `json:"until,omitempty"`
+	Limit   int                 `json:"limit,omitempty"`
+	Tags    map[string][]string `json:"-"`
+}
+
+// parseFilter decodes a raw REQ filter object including its tag filters
+func parseFilter(raw json.RawMessage) (Filter, error) {
+	var f Filter
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return f, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return f, err
+	}
+	for key, value := range fields {
+		if len(key) != 2 || key[0] != '#' {
+			continue
+		}
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			continue
+		}
+		if f.Tags == nil {
+			f.Tags = make(map[string][]string)
+		}
+		f.Tags[key[1:]] = values
+	}
+	return f, nil
+}
+
+// Matches reports whether the event satisfies every condition of the filter
+func (f *Filter) Matches(evt *Event) bool {
+	if len(f.IDs) > 0 && !containsString(f.IDs, evt.ID) {
+		return false
+	}
+	if len(f.Authors) > 0 && !containsString(f.Authors, evt.PubKey) {
+		return false
+	}
+	if len(f.Kinds) > 0 {
+		found := false
+		for _, k := range f.Kinds {
+			if k == evt.Kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Since != nil && evt.CreatedAt < *f.Since {
+		return false
+	}
+	if f.Until != nil && evt.CreatedAt > *f.Until {
+		return false
+	}
+	for name, values := range f.Tags {
+		found := false
+		for _, tag := range evt.Tags {
+			if len(tag) >= 2 && tag[0] == name && containsString(values, tag[1]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
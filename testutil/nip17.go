@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// NIP-17 fixtures: a kind 14 rumor sealed (kind 13) by the sender and
+// gift-wrapped (kind 1059) to a recipient with a throwaway key.
+
+// Rumor builds an unsigned kind 14 direct message from sender to recipients
+func Rumor(sender *Keypair, createdAt int64, content string, recipients ...string) Event {
+	tags := make([][]string, 0, len(recipients))
+	for _, pk := range recipients {
+		tags = append(tags, []string{"p", pk})
+	}
+	rumor := Event{Kind: 14, PubKey: sender.PubKey, CreatedAt: createdAt, Content: content, Tags: tags}
+	rumor.ID = ComputeEventID(&rumor)
+	return rumor
+}
+
+// GiftWrap seals the rumor with the sender's key and wraps it for wrapFor
+// Call once per recipient, and once for the sender's own copy
+func GiftWrap(sender *Keypair, rumor Event, wrapFor string) (Event, error) {
+	recipient, err := hex.DecodeString(wrapFor)
+	if err != nil {
+		return Event{}, err
+	}
+
+	rumorJSON, _ := json.Marshal(struct {
+		ID        string     `json:"id"`
+		PubKey    string     `json:"pubkey"`
+		CreatedAt int64      `json:"created_at"`
+		Kind      int        `json:"kind"`
+		Tags      [][]string `json:"tags"`
+		Content   string     `json:"content"`
+	}{rumor.ID, rumor.PubKey, rumor.CreatedAt, rumor.Kind, rumor.Tags, rumor.Content})
+
+	sealKey, err := ConversationKey(sender.PrivKey, recipient)
+	if err != nil {
+		return Event{}, err
+	}
+	sealContent, err := Nip44Encrypt(string(rumorJSON), sealKey)
+	if err != nil {
+		return Event{}, err
+	}
+	seal := Event{Kind: 13, CreatedAt: rumor.CreatedAt, Content: sealContent, Tags: [][]string{}}
+	if err := sender.Sign(&seal); err != nil {
+		return Event{}, err
+	}
+
+	// Deterministic throwaway key per seal and recipient
+	ephemeral := NewKeypair("giftwrap:" + seal.ID + ":" + wrapFor)
+	wrapKey, err := ConversationKey(ephemeral.PrivKey, recipient)
+	if err != nil {
+		return Event{}, err
+	}
+	wrapContent, err := Nip44Encrypt(mustJSON(seal), wrapKey)
+	if err != nil {
+		return Event{}, err
+	}
+	wrap := Event{Kind: 1059, CreatedAt: rumor.CreatedAt, Content: wrapContent, Tags: [][]string{{"p", wrapFor}}}
+	if err := ephemeral.Sign(&wrap); err != nil {
+		return Event{}, err
+	}
+	return wrap, nil
+}
@@ -0,0 +1,129 @@
+package testutil
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// NIP-44 v2 for the mock signer. This mirrors nip44.go in the server
+// package, which can't be imported from here.
+
+// ConversationKey derives the NIP-44 conversation key between two parties
+func ConversationKey(priv *btcec.PrivateKey, pubKeyBytes []byte) ([]byte, error) {
+	pubKey, err := btcec.ParsePubKey(append([]byte{0x02}, pubKeyBytes...))
+	if err != nil {
+		return nil, errors.New("invalid public key")
+	}
+
+	sharedX, _ := pubKey.ToECDSA().Curve.ScalarMult(pubKey.X(), pubKey.Y(), priv.Serialize())
+	shared := make([]byte, 32)
+	raw := sharedX.Bytes()
+	copy(shared[32-len(raw):], raw)
+
+	return hkdf.Extract(sha256.New, shared, []byte("nip44-v2")), nil
+}
+
+// Nip44Encrypt encrypts plaintext with a random nonce
+func Nip44Encrypt(plaintext string, conversationKey []byte) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	size := len(plaintext)
+	if size < 1 || size > 65535 {
+		return "", errors.New("invalid plaintext length")
+	}
+	padded := make([]byte, 2+nip44PaddedLen(size))
+	binary.BigEndian.PutUint16(padded[0:2], uint16(size))
+	copy(padded[2:], plaintext)
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.XORKeyStream(ciphertext, padded)
+
+	out := append([]byte{2}, nonce...)
+	out = append(out, ciphertext...)
+	out = append(out, nip44MAC(hmacKey, ciphertext, nonce)...)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Nip44Decrypt decrypts a NIP-44 v2 payload
+func Nip44Decrypt(payload string, conversationKey []byte) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", errors.New("invalid base64")
+	}
+	if len(data) < 99 || data[0] != 2 {
+		return "", errors.New("invalid payload")
+	}
+
+	nonce := data[1:33]
+	ciphertext := data[33 : len(data)-32]
+	mac := data[len(data)-32:]
+
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal(nip44MAC(hmacKey, ciphertext, nonce), mac) {
+		return "", errors.New("invalid MAC")
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	padded := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(padded, ciphertext)
+
+	size := int(binary.BigEndian.Uint16(padded[0:2]))
+	if size == 0 || len(padded) != 2+nip44PaddedLen(size) {
+		return "", errors.New("invalid padding")
+	}
+	return string(padded[2 : 2+size]), nil
+}
+
+func nip44MessageKeys(conversationKey, nonce []byte) (chachaKey, chachaNonce, hmacKey []byte, err error) {
+	keys := make([]byte, 76)
+	if _, err := hkdf.Expand(sha256.New, conversationKey, nonce).Read(keys); err != nil {
+		return nil, nil, nil, err
+	}
+	return keys[0:32], keys[32:44], keys[44:76], nil
+}
+
+func nip44PaddedLen(size int) int {
+	if size <= 32 {
+		return 32
+	}
+	nextPower := 1 << int(math.Floor(math.Log2(float64(size-1)))+1)
+	chunk := 32
+	if nextPower > 256 {
+		chunk = nextPower / 8
+	}
+	return chunk * ((size-1)/chunk + 1)
+}
+
+func nip44MAC(key, message, aad []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(aad)
+	h.Write(message)
+	return h.Sum(nil)
+}
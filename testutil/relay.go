@@ -0,0 +1,340 @@
+package testutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/gorilla/websocket"
+)
+
+// Relay is an in-memory Nostr relay served over a local websocket.
+// It speaks REQ/EVENT/EOSE/OK/CLOSE and keeps every accepted event in memory.
+type Relay struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu      sync.RWMutex
+	events  []Event
+	clients map[*relayClient]bool
+
+	// VerifySignatures rejects events with a bad ID or signature (default true)
+	VerifySignatures bool
+}
+
+// relayClient is a single websocket connection and its open subscriptions
+type relayClient struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	subsMu  sync.Mutex
+	subs    map[string][]Filter
+}
+
+// NewRelay starts an in-memory relay on a random loopback port
+func NewRelay() *Relay {
+	r := newRelay()
+	r.server = httptest.NewServer(http.HandlerFunc(r.handle))
+	return r
+}
+
+// NewRelayAt starts an in-memory relay listening on a fixed address,
+// for use as a long-running development relay
+func NewRelayAt(addr string) (*Relay, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := newRelay()
+	r.server = httptest.NewUnstartedServer(http.HandlerFunc(r.handle))
+	r.server.Listener.Close()
+	r.server.Listener = ln
+	r.server.Start()
+	return r, nil
+}
+
+func newRelay() *Relay {
+	return &Relay{
+		clients:          make(map[*relayClient]bool),
+		VerifySignatures: true,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+}
+
+// URL returns the ws:// URL clients should connect to
+func (r *Relay) URL() string {
+	return "ws" + strings.TrimPrefix(r.server.URL, "http")
+}
+
+// Close disconnects all clients and stops the relay
+func (r *Relay) Close() {
+	r.mu.Lock()
+	for c := range r.clients {
+		c.conn.Close()
+	}
+	r.mu.Unlock()
+	r.server.Close()
+}
+
+// Publish stores events directly, bypassing the websocket, and delivers
+// them to any matching live subscriptions
+func (r *Relay) Publish(events ...Event) {
+	for _, evt := range events {
+		r.store(evt)
+	}
+}
+
+// Events returns a copy of every stored event
+func (r *Relay) Events() []Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Event, len(r.events))
+	copy(out, r.events)
+	return out
+}
+
+// Query returns stored events matching the filter, newest first
+func (r *Relay) Query(filter Filter) []Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []Event
+	for i := range r.events {
+		if filter.Matches(&r.events[i]) {
+			matched = append(matched, r.events[i])
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt != matched[j].CreatedAt {
+			return matched[i].CreatedAt > matched[j].CreatedAt
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched
+}
+
+// Reset removes all stored events
+func (r *Relay) Reset() {
+	r.mu.Lock()
+	r.events = nil
+	r.mu.Unlock()
+}
+
+// store saves an event, applying replaceable-event semantics, and
+// broadcasts it to live subscribers. Returns false for duplicates or
+// events older than the replaceable version already stored.
+func (r *Relay) store(evt Event) bool {
+	r.mu.Lock()
+	for i := range r.events {
+		existing := &r.events[i]
+		if existing.ID == evt.ID {
+			r.mu.Unlock()
+			return false
+		}
+		if replaces(existing, &evt) {
+			if existing.CreatedAt > evt.CreatedAt {
+				r.mu.Unlock()
+				return false
+			}
+			r.events = append(r.events[:i], r.events[i+1:]...)
+			break
+		}
+	}
+	r.events = append(r.events, evt)
+	clients := make([]*relayClient, 0, len(r.clients))
+	for c := range r.clients {
+		clients = append(clients, c)
+	}
+	r.mu.Unlock()
+
+	for _, c := range clients {
+		c.deliver(evt)
+	}
+	return true
+}
+
+// replaces reports whether newer supersedes existing under NIP-01
+// replaceable (0, 3, 10000-19999) or addressable (30000-39999) rules
+func replaces(existing, newer *Event) bool {
+	if existing.Kind != newer.Kind || existing.PubKey != newer.PubKey {
+		return false
+	}
+	k := newer.Kind
+	if k == 0 || k == 3 || (k >= 10000 && k < 20000) {
+		return true
+	}
+	if k >= 30000 && k < 40000 {
+		return existing.TagValue("d") == newer.TagValue("d")
+	}
+	return false
+}
+
+func (r *Relay) handle(w http.ResponseWriter, req *http.Request) {
+	conn, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+
+	c := &relayClient{conn: conn, subs: make(map[string][]Filter)}
+	r.mu.Lock()
+	r.clients[c] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.clients, c)
+		r.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg []json.RawMessage
+		if err := json.Unmarshal(data, &msg); err != nil || len(msg) < 2 {
+			c.send("NOTICE", "invalid message")
+			continue
+		}
+
+		var msgType string
+		json.Unmarshal(msg[0], &msgType)
+
+		switch msgType {
+		case "REQ":
+			r.handleReq(c, msg)
+		case "EVENT":
+			r.handleEvent(c, msg[1])
+		case "CLOSE":
+			var subID string
+			json.Unmarshal(msg[1], &subID)
+			c.subsMu.Lock()
+			delete(c.subs, subID)
+			c.subsMu.Unlock()
+		default:
+			c.send("NOTICE", "unknown message type: "+msgType)
+		}
+	}
+}
+
+func (r *Relay) handleReq(c *relayClient, msg []json.RawMessage) {
+	var subID string
+	if err := json.Unmarshal(msg[1], &subID); err != nil || subID == "" {
+		c.send("NOTICE", "invalid subscription id")
+		return
+	}
+
+	var filters []Filter
+	for _, raw := range msg[2:] {
+		f, err := parseFilter(raw)
+		if err != nil {
+			c.send("CLOSED", subID, "error: invalid filter")
+			return
+		}
+		filters = append(filters, f)
+	}
+
+	c.subsMu.Lock()
+	c.subs[subID] = filters
+	c.subsMu.Unlock()
+
+	sent := make(map[string]bool)
+	for _, f := range filters {
+		for _, evt := range r.Query(f) {
+			if sent[evt.ID] {
+				continue
+			}
+			sent[evt.ID] = true
+			c.send("EVENT", subID, evt)
+		}
+	}
+	c.send("EOSE", subID)
+}
+
+func (r *Relay) handleEvent(c *relayClient, raw json.RawMessage) {
+	var evt Event
+	if err := json.Unmarshal(raw, &evt); err != nil {
+		c.send("NOTICE", "invalid event")
+		return
+	}
+
+	if r.VerifySignatures {
+		if ok, reason := verifyEvent(&evt); !ok {
+			c.send("OK", evt.ID, false, reason)
+			return
+		}
+	}
+
+	if !r.store(evt) {
+		c.send("OK", evt.ID, true, "duplicate: already have this event")
+		return
+	}
+	c.send("OK", evt.ID, true, "")
+}
+
+// deliver sends an event to every open subscription whose filters match
+func (c *relayClient) deliver(evt Event) {
+	c.subsMu.Lock()
+	var matched []string
+	for subID, filters := range c.subs {
+		for i := range filters {
+			if filters[i].Matches(&evt) {
+				matched = append(matched, subID)
+				break
+			}
+		}
+	}
+	c.subsMu.Unlock()
+
+	for _, subID := range matched {
+		c.send("EVENT", subID, evt)
+	}
+}
+
+func (c *relayClient) send(parts ...interface{}) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteJSON(parts)
+}
+
+// verifyEvent checks the event ID and Schnorr signature
+func verifyEvent(evt *Event) (bool, string) {
+	if ComputeEventID(evt) != evt.ID {
+		return false, "invalid: event id does not match"
+	}
+
+	sigBytes, err := hex.DecodeString(evt.Sig)
+	if err != nil {
+		return false, "invalid: bad signature encoding"
+	}
+	pubKeyBytes, err := hex.DecodeString(evt.PubKey)
+	if err != nil {
+		return false, "invalid: bad pubkey encoding"
+	}
+	idBytes, _ := hex.DecodeString(evt.ID)
+
+	sig, err := schnorr.ParseSignature(sigBytes)
+	if err != nil {
+		return false, "invalid: bad signature"
+	}
+	pubKey, err := schnorr.ParsePubKey(pubKeyBytes)
+	if err != nil {
+		return false, "invalid: bad pubkey"
+	}
+	if !sig.Verify(idBytes, pubKey) {
+		return false, "invalid: signature verification failed"
+	}
+	return true, ""
+}
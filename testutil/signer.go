@@ -0,0 +1,297 @@
+package testutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Signer is a mock NIP-46 remote signer. It listens for kind 24133
+// requests on a relay and answers connect, get_public_key, sign_event,
+// nip44_encrypt, nip44_decrypt and ping using its own keypair.
+type Signer struct {
+	Key    *Keypair
+	Secret string // optional connect secret; empty accepts any
+
+	mu       sync.Mutex
+	latency  time.Duration
+	failures map[string]string // method -> error message
+	authURLs map[string]string // method -> auth_url challenge
+	requests []string          // methods received, in order
+
+	relayURL string
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	done     chan struct{}
+}
+
+// nip46Request and nip46Response mirror the JSON-RPC payloads used by the server
+type nip46Request struct {
+	ID     string   `json:"id"`
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+type nip46Response struct {
+	ID     string `json:"id"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewSigner connects a mock signer for the keypair to the relay and starts
+// answering requests. The signer key is also the user key.
+func NewSigner(relayURL string, key *Keypair) (*Signer, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(relayURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", relayURL, err)
+	}
+
+	s := &Signer{
+		Key:      key,
+		failures: make(map[string]string),
+		authURLs: make(map[string]string),
+		relayURL: relayURL,
+		conn:     conn,
+		done:     make(chan struct{}),
+	}
+
+	sub := []interface{}{"REQ", "signer", map[string]interface{}{
+		"kinds": []int{24133},
+		"#p":    []string{key.PubKey},
+		"since": time.Now().Unix() - 10,
+	}}
+	if err := s.write(sub); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go s.loop()
+	return s, nil
+}
+
+// BunkerURL returns the bunker:// URL a client uses to log in with this signer
+func (s *Signer) BunkerURL() string {
+	u := "bunker://" + s.Key.PubKey + "?relay=" + url.QueryEscape(s.relayURL)
+	if s.Secret != "" {
+		u += "&secret=" + url.QueryEscape(s.Secret)
+	}
+	return u
+}
+
+// SetLatency delays every response by d
+func (s *Signer) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	s.latency = d
+	s.mu.Unlock()
+}
+
+// FailMethod makes the signer answer the method with an error, e.g.
+// FailMethod("sign_event", "permission denied"). An empty message clears it.
+func (s *Signer) FailMethod(method, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if message == "" {
+		delete(s.failures, method)
+		return
+	}
+	s.failures[method] = message
+}
+
+// RequireAuth makes the signer answer the method with a NIP-46 auth_url
+// challenge, as signers do when a permission hasn't been granted yet.
+// An empty URL clears it.
+func (s *Signer) RequireAuth(method, authURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if authURL == "" {
+		delete(s.authURLs, method)
+		return
+	}
+	s.authURLs[method] = authURL
+}
+
+// Requests returns the methods the signer has received so far
+func (s *Signer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Close disconnects the signer from the relay
+func (s *Signer) Close() {
+	s.conn.Close()
+	<-s.done
+}
+
+func (s *Signer) loop() {
+	defer close(s.done)
+	for {
+		var msg []json.RawMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if len(msg) < 3 {
+			continue
+		}
+		var msgType string
+		json.Unmarshal(msg[0], &msgType)
+		if msgType != "EVENT" {
+			continue
+		}
+		var evt Event
+		if err := json.Unmarshal(msg[2], &evt); err != nil {
+			continue
+		}
+		go s.handle(evt)
+	}
+}
+
+// handle decrypts one request event and publishes the encrypted response
+func (s *Signer) handle(evt Event) {
+	clientPubKey, err := hex.DecodeString(evt.PubKey)
+	if err != nil {
+		return
+	}
+	convKey, err := ConversationKey(s.Key.PrivKey, clientPubKey)
+	if err != nil {
+		return
+	}
+	plaintext, err := Nip44Decrypt(evt.Content, convKey)
+	if err != nil {
+		return
+	}
+	var req nip46Request
+	if err := json.Unmarshal([]byte(plaintext), &req); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req.Method)
+	latency := s.latency
+	failure := s.failures[req.Method]
+	authURL := s.authURLs[req.Method]
+	s.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	resp := nip46Response{ID: req.ID}
+	if authURL != "" {
+		resp.Result, resp.Error = "auth_url", authURL
+	} else if failure != "" {
+		resp.Error = failure
+	} else if result, err := s.dispatch(req); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Result = result
+	}
+
+	payload, _ := json.Marshal(resp)
+	content, err := Nip44Encrypt(string(payload), convKey)
+	if err != nil {
+		return
+	}
+	reply := Event{
+		Kind:      24133,
+		CreatedAt: time.Now().Unix(),
+		Content:   content,
+		Tags:      [][]string{{"p", evt.PubKey}},
+	}
+	if err := s.Key.Sign(&reply); err != nil {
+		return
+	}
+	s.write([]interface{}{"EVENT", reply})
+}
+
+func (s *Signer) dispatch(req nip46Request) (string, error) {
+	switch req.Method {
+	case "connect":
+		if s.Secret != "" && (len(req.Params) < 2 || req.Params[1] != s.Secret) {
+			return "", errors.New("invalid secret")
+		}
+		return "ack", nil
+	case "get_public_key":
+		return s.Key.PubKey, nil
+	case "ping":
+		return "pong", nil
+	case "sign_event":
+		if len(req.Params) < 1 {
+			return "", errors.New("missing event")
+		}
+		var evt Event
+		if err := json.Unmarshal([]byte(req.Params[0]), &evt); err != nil {
+			return "", errors.New("invalid event")
+		}
+		if err := s.Key.Sign(&evt); err != nil {
+			return "", err
+		}
+		signed, _ := json.Marshal(evt)
+		return string(signed), nil
+	case "nip44_encrypt", "nip44_decrypt":
+		if len(req.Params) < 2 {
+			return "", errors.New("missing params")
+		}
+		peer, err := hex.DecodeString(req.Params[0])
+		if err != nil {
+			return "", errors.New("invalid pubkey")
+		}
+		convKey, err := ConversationKey(s.Key.PrivKey, peer)
+		if err != nil {
+			return "", err
+		}
+		if req.Method == "nip44_encrypt" {
+			return Nip44Encrypt(req.Params[1], convKey)
+		}
+		return Nip44Decrypt(req.Params[1], convKey)
+	default:
+		return "", fmt.Errorf("unsupported method: %s", req.Method)
+	}
+}
+
+func (s *Signer) write(v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(v)
+}
+
+// Login submits the signer's bunker URL to a running server's login form
+// and returns a cookie jar holding the authenticated session cookie
+func Login(serverURL string, signer *Signer) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: 90 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.PostForm(strings.TrimSuffix(serverURL, "/")+"/html/login", url.Values{
+		"bunker_url": {signer.BunkerURL()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	u, _ := url.Parse(serverURL)
+	for _, c := range jar.Cookies(u) {
+		if c.Name == "nostr_session" {
+			return jar, nil
+		}
+	}
+	return nil, fmt.Errorf("login failed: redirected to %s", resp.Header.Get("Location"))
+}
@@ -0,0 +1,76 @@
+package main
+
+import "sort"
+
+// Thread summaries
+// Large threads get a summary block above the replies: counts, the most active
+// participants (each linking to their first reply) and jump links. Built from the
+// replies already fetched for the page. Reply anchors are "reply-{event id}", so links
+// stay valid however the reply list is ordered or split.
+
+// threadSummaryMinReplies is the reply count at which the summary block is shown
+const threadSummaryMinReplies = 20
+
+// threadSummaryMaxParticipants caps the avatars in the summary row
+const threadSummaryMaxParticipants = 8
+
+// ThreadParticipant is one author in a thread summary
+type ThreadParticipant struct {
+	Pubkey       string
+	NpubShort    string
+	Profile      *ProfileInfo
+	ReplyCount   int
+	FirstReplyID string
+}
+
+// ThreadSummary describes a large thread's replies
+type ThreadSummary struct {
+	ReplyCount       int
+	ParticipantCount int
+	TopParticipants  []ThreadParticipant
+	NewestReplyID    string
+	FirstOPReplyID   string // first reply by the root note's author, empty if none
+}
+
+// buildThreadSummary aggregates replies (oldest first) into a summary
+// Returns nil for threads below threadSummaryMinReplies
+func buildThreadSummary(rootPubkey string, replies []HTMLEventItem) *ThreadSummary {
+	if len(replies) < threadSummaryMinReplies {
+		return nil
+	}
+
+	summary := &ThreadSummary{ReplyCount: len(replies)}
+	byAuthor := make(map[string]*ThreadParticipant)
+	var order []*ThreadParticipant
+	for _, reply := range replies {
+		p, ok := byAuthor[reply.Pubkey]
+		if !ok {
+			p = &ThreadParticipant{
+				Pubkey:       reply.Pubkey,
+				NpubShort:    reply.NpubShort,
+				Profile:      reply.AuthorProfile,
+				FirstReplyID: reply.ID,
+			}
+			byAuthor[reply.Pubkey] = p
+			order = append(order, p)
+		}
+		p.ReplyCount++
+		if reply.Pubkey == rootPubkey && summary.FirstOPReplyID == "" {
+			summary.FirstOPReplyID = reply.ID
+		}
+		summary.NewestReplyID = reply.ID
+	}
+	summary.ParticipantCount = len(order)
+
+	// Most replies first; ties go to whoever joined the thread earlier
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].ReplyCount > order[j].ReplyCount
+	})
+	if len(order) > threadSummaryMaxParticipants {
+		order = order[:threadSummaryMaxParticipants]
+	}
+	for _, p := range order {
+		summary.TopParticipants = append(summary.TopParticipants, *p)
+	}
+	return summary
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// NIP-35 torrents (kind 2003)
+// The torrent is identified by its BitTorrent v1 info hash ("x" tag); "file" tags list
+// [name, size in bytes] and "tracker" tags list announce URLs. Some publishers add a
+// ready-made "magnet" tag instead, which is only used after parsing and validation.
+
+// maxTorrentFiles caps the rendered file list
+const maxTorrentFiles = 200
+
+// TorrentFile is one file in a torrent
+type TorrentFile struct {
+	Name string
+	Size string // human-readable, empty if unknown
+}
+
+// TorrentInfo holds parsed data from a kind 2003 torrent event
+type TorrentInfo struct {
+	Title     string
+	InfoHash  string
+	Magnet    string // rebuilt from validated parts, empty if there's no valid info hash
+	TotalSize string
+	Files     []TorrentFile
+	FileCount int
+	Trackers  []string
+}
+
+// parseTorrent extracts torrent information from a kind 2003 event's tags
+func parseTorrent(tags [][]string) *TorrentInfo {
+	info := &TorrentInfo{}
+	var totalBytes uint64
+	sizeKnown := false
+	var magnetTag string
+
+	for _, tag := range tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "title":
+			info.Title = tag[1]
+		case "x":
+			if info.InfoHash == "" {
+				info.InfoHash = normalizeInfoHash(tag[1])
+			}
+		case "magnet":
+			magnetTag = tag[1]
+		case "tracker":
+			if isValidTrackerURL(tag[1]) {
+				info.Trackers = append(info.Trackers, tag[1])
+			}
+		case "file":
+			info.FileCount++
+			file := TorrentFile{Name: tag[1]}
+			if len(tag) >= 3 {
+				if n, err := strconv.ParseUint(tag[2], 10, 64); err == nil {
+					totalBytes += n
+					sizeKnown = true
+					file.Size = formatBytes(n)
+				}
+			}
+			if len(info.Files) < maxTorrentFiles {
+				info.Files = append(info.Files, file)
+			}
+		}
+	}
+
+	// Fall back to the magnet tag's info hash, trackers and name
+	if magnetTag != "" {
+		if hash, name, trackers, ok := parseMagnetURI(magnetTag); ok {
+			if info.InfoHash == "" {
+				info.InfoHash = hash
+			}
+			if info.Title == "" {
+				info.Title = name
+			}
+			if len(info.Trackers) == 0 {
+				info.Trackers = trackers
+			}
+		}
+	}
+
+	if sizeKnown {
+		info.TotalSize = formatBytes(totalBytes)
+	}
+	if info.InfoHash != "" {
+		info.Magnet = buildMagnetURI(info.InfoHash, info.Title, info.Trackers)
+	}
+	return info
+}
+
+// normalizeInfoHash returns a lowercase 40-char hex or uppercase 32-char base32 info hash,
+// or "" if the value is neither
+func normalizeInfoHash(hash string) string {
+	hash = strings.TrimSpace(hash)
+	switch len(hash) {
+	case 40:
+		hash = strings.ToLower(hash)
+		for _, c := range hash {
+			if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+				return ""
+			}
+		}
+		return hash
+	case 32:
+		hash = strings.ToUpper(hash)
+		for _, c := range hash {
+			if !((c >= 'A' && c <= 'Z') || (c >= '2' && c <= '7')) {
+				return ""
+			}
+		}
+		return hash
+	}
+	return ""
+}
+
+// isValidTrackerURL accepts http(s), udp and wss announce URLs with a host
+func isValidTrackerURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "udp", "wss":
+		return true
+	}
+	return false
+}
+
+// parseMagnetURI extracts the BitTorrent info hash, display name and valid trackers from a magnet URI
+func parseMagnetURI(s string) (hash, name string, trackers []string, ok bool) {
+	rest, found := strings.CutPrefix(s, "magnet:?")
+	if !found {
+		return "", "", nil, false
+	}
+	values, err := url.ParseQuery(rest)
+	if err != nil {
+		return "", "", nil, false
+	}
+	for _, xt := range values["xt"] {
+		if strings.HasPrefix(strings.ToLower(xt), "urn:btih:") {
+			hash = normalizeInfoHash(xt[len("urn:btih:"):])
+			break
+		}
+	}
+	if hash == "" {
+		return "", "", nil, false
+	}
+	for _, tr := range values["tr"] {
+		if isValidTrackerURL(tr) {
+			trackers = append(trackers, tr)
+		}
+	}
+	return hash, values.Get("dn"), trackers, true
+}
+
+// buildMagnetURI builds a magnet URI from a validated info hash, escaping every other part
+func buildMagnetURI(infoHash, name string, trackers []string) string {
+	var sb strings.Builder
+	sb.WriteString("magnet:?xt=urn:btih:")
+	sb.WriteString(infoHash)
+	if name != "" {
+		sb.WriteString("&dn=")
+		sb.WriteString(url.QueryEscape(name))
+	}
+	for _, tr := range trackers {
+		sb.WriteString("&tr=")
+		sb.WriteString(url.QueryEscape(tr))
+	}
+	return sb.String()
+}
+
+// magnetHref marks a magnet URI built by buildMagnetURI as safe for an href
+// html/template would otherwise replace the magnet: scheme with #ZgotmplZ
+func magnetHref(magnet string) template.URL {
+	if !strings.HasPrefix(magnet, "magnet:?xt=urn:btih:") {
+		return ""
+	}
+	return template.URL(magnet)
+}
+
+// formatBytes formats a byte count as "1.4 GB"
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugTiming enables per-request tracing (DEBUG_TIMING=true)
+// When disabled, no trace is attached to requests and traceSpan is a no-op
+var debugTiming = os.Getenv("DEBUG_TIMING") == "true"
+
+// traceExporter is called with every finished trace when set
+// (see tracing_otlp.go, built with -tags otlp)
+var traceExporter func(t *requestTrace)
+
+type traceContextKey struct{}
+
+// requestTrace accumulates named spans for a single HTTP request
+type requestTrace struct {
+	Method string
+	Path   string
+	Start  time.Time
+	End    time.Time
+
+	mu    sync.Mutex
+	spans []traceSpanRecord
+}
+
+// traceSpanRecord is a completed span within a request
+type traceSpanRecord struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// traceFromContext returns the request trace, or nil if tracing is disabled
+func traceFromContext(ctx context.Context) *requestTrace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(traceContextKey{}).(*requestTrace)
+	return t
+}
+
+// traceSpan starts a named span and returns a function that ends it
+// Usage: defer traceSpan(ctx, "fetch profiles")()
+func traceSpan(ctx context.Context, name string) func() {
+	t := traceFromContext(ctx)
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.mu.Lock()
+		t.spans = append(t.spans, traceSpanRecord{Name: name, Start: start, Duration: time.Since(start)})
+		t.mu.Unlock()
+	}
+}
+
+// Spans returns a copy of the recorded spans in completion order
+func (t *requestTrace) Spans() []traceSpanRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]traceSpanRecord, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+// Summary formats the trace as a single line for logging
+func (t *requestTrace) Summary() string {
+	spans := t.Spans()
+	parts := make([]string, 0, len(spans))
+	for _, s := range spans {
+		parts = append(parts, fmt.Sprintf("%s=%s", s.Name, s.Duration.Round(time.Millisecond)))
+	}
+	return fmt.Sprintf("%s %s %s [%s]", t.Method, t.Path, t.End.Sub(t.Start).Round(time.Millisecond), strings.Join(parts, ", "))
+}
+
+// htmlComment formats the trace as an HTML comment appended to rendered pages
+func (t *requestTrace) htmlComment() string {
+	var b strings.Builder
+	b.WriteString("\n<!-- timing breakdown (DEBUG_TIMING)\n")
+	for _, s := range t.Spans() {
+		// Relay URLs and kind names never contain "--", but be safe inside a comment
+		name := strings.ReplaceAll(s.Name, "--", "- -")
+		fmt.Fprintf(&b, "  %-40s %8s (at +%s)\n", name, s.Duration.Round(time.Millisecond), s.Start.Sub(t.Start).Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, "  %-40s %8s\n-->\n", "total", time.Since(t.Start).Round(time.Millisecond))
+	return b.String()
+}
+
+// traceResponseWriter remembers whether the handler produced an HTML page
+type traceResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *traceResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// canAppendComment reports whether the response is an HTML page that can take trailing
+// bytes: a declared Content-Length or an encoded body would be corrupted by them
+func (w *traceResponseWriter) canAppendComment() bool {
+	h := w.Header()
+	return (w.status == 0 || w.status == http.StatusOK) &&
+		strings.HasPrefix(h.Get("Content-Type"), "text/html") &&
+		h.Get("Content-Length") == "" && h.Get("Content-Encoding") == ""
+}
+
+// traceRequests attaches a trace to each request, logs the span summary
+// with the request line, and appends the breakdown to HTML pages sent without a length
+// Returns the handler unchanged when DEBUG_TIMING is not enabled
+func traceRequests(next http.Handler) http.Handler {
+	if !debugTiming {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &requestTrace{Method: r.Method, Path: r.URL.Path, Start: time.Now()}
+		ctx := context.WithValue(r.Context(), traceContextKey{}, t)
+		tw := &traceResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(tw, r.WithContext(ctx))
+
+		t.End = time.Now()
+		if r.Method != http.MethodHead && tw.canAppendComment() {
+			tw.Write([]byte(t.htmlComment()))
+		}
+		log.Printf("Timing: %s", t.Summary())
+		if traceExporter != nil {
+			go traceExporter(t)
+		}
+	})
+}
+
+// Flush passes through to the underlying writer so streamed pages still flush
+func (w *traceResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
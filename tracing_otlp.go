@@ -0,0 +1,98 @@
+//go:build otlp
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLP/HTTP JSON export of request traces, for operators running a collector
+// Build with: go build -tags otlp
+// Endpoint: OTEL_EXPORTER_OTLP_ENDPOINT (default http://localhost:4318)
+
+var otlpClient = &http.Client{Timeout: 5 * time.Second}
+
+func init() {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+
+	traceExporter = func(t *requestTrace) {
+		if err := exportOTLP(endpoint, t); err != nil {
+			log.Printf("OTLP export failed: %v", err)
+		}
+	}
+}
+
+func otlpRandomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// exportOTLP sends the request as a root span with one child span per trace span
+func exportOTLP(endpoint string, t *requestTrace) error {
+	traceID := otlpRandomID(16)
+	rootID := otlpRandomID(8)
+
+	spans := []map[string]interface{}{{
+		"traceId":           traceID,
+		"spanId":            rootID,
+		"name":              t.Method + " " + t.Path,
+		"kind":              2, // SPAN_KIND_SERVER
+		"startTimeUnixNano": otlpTime(t.Start),
+		"endTimeUnixNano":   otlpTime(t.End),
+	}}
+	for _, s := range t.Spans() {
+		spans = append(spans, map[string]interface{}{
+			"traceId":           traceID,
+			"spanId":            otlpRandomID(8),
+			"parentSpanId":      rootID,
+			"name":              s.Name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": otlpTime(s.Start),
+			"endTimeUnixNano":   otlpTime(s.Start.Add(s.Duration)),
+		})
+	}
+
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []interface{}{map[string]interface{}{
+					"key":   "service.name",
+					"value": map[string]string{"stringValue": "nostr-hypermedia"},
+				}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "nostr-hypermedia"},
+				"spans": spans,
+			}},
+		}},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := otlpClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTraceCommentOnlyOnUnsizedHTML(t *testing.T) {
+	debugTiming = true
+	t.Cleanup(func() { debugTiming = false })
+
+	const page = "<html><body>page</body></html>"
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		sized       bool
+		wantComment bool
+	}{
+		{"rendered page", http.MethodGet, "text/html; charset=utf-8", false, true},
+		{"page with a length", http.MethodGet, "text/html; charset=utf-8", true, false},
+		{"JSON", http.MethodGet, "application/json", false, false},
+		{"HEAD", http.MethodHead, "text/html; charset=utf-8", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := traceRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.sized {
+					w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+				}
+				w.Write([]byte(page))
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/html/timeline", nil))
+
+			body := rec.Body.String()
+			if got := strings.Contains(body, "timing breakdown"); got != tt.wantComment {
+				t.Errorf("timing comment appended = %v, want %v:\n%s", got, tt.wantComment, body)
+			}
+			if !tt.wantComment && body != page {
+				t.Errorf("body = %q, want it unchanged", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outgoing webhooks
+// WEBHOOK_CONFIG points at a JSON file listing operator endpoints and the events each
+// one wants. Notifications are queued per endpoint and POSTed as JSON by a background
+// worker, signed with HMAC-SHA256 over the body using the endpoint's secret. Each
+// endpoint has its own worker so one that's down doesn't hold up the others.
+
+// Webhook event types
+const (
+	WebhookReportReceived     = "report-received"      // a kind 1984 report reached the instance
+	WebhookAdminMentioned     = "admin-mentioned"      // an event p-tagging the admin pubkey reached the instance
+	WebhookRelayCircuitOpened = "relay-circuit-opened" // a relay failed relayCircuitThreshold dials in a row
+)
+
+var validWebhookEvents = map[string]bool{
+	WebhookReportReceived:     true,
+	WebhookAdminMentioned:     true,
+	WebhookRelayCircuitOpened: true,
+}
+
+const (
+	webhookQueueSize   = 64 // pending notifications per endpoint
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 4
+	webhookRecentLimit = 20  // deliveries kept for the stats page
+	webhookMaxContent  = 500 // characters of event content included in payloads
+)
+
+// webhookInitialBackoff is the wait before the first retry, doubling after each (a var for tests)
+var webhookInitialBackoff = 2 * time.Second
+
+// WebhookEndpoint is a single configured webhook receiver
+type WebhookEndpoint struct {
+	Name   string   `json:"name"` // shown on the stats page instead of the URL
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+// wants reports whether the endpoint subscribes to the event type
+func (e WebhookEndpoint) wants(event string) bool {
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookConfig is the operator's webhook configuration
+type WebhookConfig struct {
+	AdminPubkey  string            `json:"admin_pubkey,omitempty"`  // hex or npub, for admin-mentioned
+	AllowPrivate bool              `json:"allow_private,omitempty"` // allow endpoints on private/loopback addresses
+	Endpoints    []WebhookEndpoint `json:"endpoints"`
+}
+
+// Validate checks endpoints and events, and normalizes the admin pubkey to hex
+func (c *WebhookConfig) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return errors.New("no endpoints configured")
+	}
+
+	if c.AdminPubkey != "" {
+		if strings.HasPrefix(c.AdminPubkey, "npub1") {
+			pubkey, err := decodeBech32Pubkey(c.AdminPubkey)
+			if err != nil {
+				return fmt.Errorf("invalid admin_pubkey: %v", err)
+			}
+			c.AdminPubkey = pubkey
+		}
+		if !isValidEventID(c.AdminPubkey) {
+			return errors.New("admin_pubkey must be hex or npub")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, e := range c.Endpoints {
+		if e.Name == "" {
+			return fmt.Errorf("endpoint %d: missing name", i)
+		}
+		if seen[e.Name] {
+			return fmt.Errorf("endpoint %d: duplicate name %q", i, e.Name)
+		}
+		seen[e.Name] = true
+
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint %s: invalid URL", e.Name)
+		}
+		if !c.AllowPrivate && !isURLSafeForSSRF(e.URL) {
+			return fmt.Errorf("endpoint %s: private or internal address (set allow_private to permit)", e.Name)
+		}
+
+		if len(e.Events) == 0 {
+			return fmt.Errorf("endpoint %s: no events", e.Name)
+		}
+		for _, ev := range e.Events {
+			if !validWebhookEvents[ev] {
+				return fmt.Errorf("endpoint %s: unknown event %q", e.Name, ev)
+			}
+		}
+		if e.wants(WebhookAdminMentioned) && c.AdminPubkey == "" {
+			return fmt.Errorf("endpoint %s: %s needs admin_pubkey", e.Name, WebhookAdminMentioned)
+		}
+	}
+	return nil
+}
+
+// loadWebhookConfig reads and validates a webhook configuration file
+func loadWebhookConfig(path string) (*WebhookConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg WebhookConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid webhook config %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// WebhookPayload is the JSON body POSTed to endpoints
+type WebhookPayload struct {
+	Event     string            `json:"event"`
+	CreatedAt int64             `json:"created_at"`
+	Data      map[string]string `json:"data"`
+}
+
+// WebhookDelivery is the outcome of one notification to one endpoint
+type WebhookDelivery struct {
+	Endpoint   string
+	Event      string
+	Delivered  bool
+	StatusCode int // last HTTP status, 0 if no response
+	Attempts   int
+	Error      string // short reason; details (which may include the URL) are only logged
+	At         time.Time
+}
+
+type webhookJob struct {
+	endpoint WebhookEndpoint
+	event    string
+	body     []byte
+}
+
+// WebhookDispatcher queues notifications and delivers them in the background
+type WebhookDispatcher struct {
+	cfg    *WebhookConfig
+	client *http.Client
+	queues map[string]chan webhookJob // endpoint name -> pending notifications
+
+	mu     sync.Mutex
+	recent []WebhookDelivery // newest first
+}
+
+// webhooks is nil unless WEBHOOK_CONFIG is set
+var webhooks *WebhookDispatcher
+
+// newWebhookDispatcher builds a dispatcher whose HTTP client applies the SSRF rules
+// at connection time unless the config allows private addresses
+func newWebhookDispatcher(cfg *WebhookConfig) *WebhookDispatcher {
+	transport := &http.Transport{
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if !cfg.AllowPrivate {
+		transport.DialContext = ssrfSafeDialContext
+	}
+	queues := make(map[string]chan webhookJob, len(cfg.Endpoints))
+	for _, e := range cfg.Endpoints {
+		queues[e.Name] = make(chan webhookJob, webhookQueueSize)
+	}
+	return &WebhookDispatcher{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: transport,
+			// Redirects aren't followed; a 3xx counts as a failed delivery
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		queues: queues,
+	}
+}
+
+// initWebhooks loads WEBHOOK_CONFIG and starts a delivery worker per endpoint
+// An invalid file is fatal, like RELAY_CONFIG
+func initWebhooks() {
+	path := os.Getenv("WEBHOOK_CONFIG")
+	if path == "" {
+		return
+	}
+	cfg, err := loadWebhookConfig(path)
+	if err != nil {
+		log.Fatalf("Failed to load webhook config: %v", err)
+	}
+	webhooks = newWebhookDispatcher(cfg)
+	for _, queue := range webhooks.queues {
+		go webhooks.worker(queue)
+	}
+	log.Printf("Loaded webhook configuration from %s (%d endpoints)", path, len(cfg.Endpoints))
+}
+
+// notifyWebhooks queues a notification for every endpoint subscribed to the event
+// Never blocks: when the queue is full the notification is dropped and recorded
+func notifyWebhooks(event string, data map[string]string) {
+	d := webhooks
+	if d == nil {
+		return
+	}
+	body, err := json.Marshal(WebhookPayload{Event: event, CreatedAt: time.Now().Unix(), Data: data})
+	if err != nil {
+		log.Printf("Webhook payload for %s failed to encode: %v", event, err)
+		return
+	}
+	for _, e := range d.cfg.Endpoints {
+		if !e.wants(event) {
+			continue
+		}
+		select {
+		case d.queues[e.Name] <- webhookJob{endpoint: e, event: event, body: body}:
+		default:
+			d.record(WebhookDelivery{Endpoint: e.Name, Event: event, Error: "queue full, dropped", At: time.Now()})
+		}
+	}
+}
+
+// notifyWebhooksForEvent checks a newly ingested event for report and mention notifications
+// Only called from ingestEvent, after the signature check, so a relay can't forge a report
+func notifyWebhooksForEvent(evt Event, relayURL string) {
+	d := webhooks
+	if d == nil {
+		return
+	}
+
+	if evt.Kind == 1984 {
+		data := map[string]string{
+			"event_id": evt.ID,
+			"pubkey":   evt.PubKey,
+			"content":  truncateString(evt.Content, webhookMaxContent),
+			"relay":    relayURL,
+		}
+		// NIP-56: the report type is the third element of the p or e tag
+		for _, tag := range evt.Tags {
+			if len(tag) < 2 {
+				continue
+			}
+			switch tag[0] {
+			case "p":
+				data["reported_pubkey"] = tag[1]
+			case "e":
+				data["reported_event"] = tag[1]
+			}
+			if len(tag) >= 3 && (tag[0] == "p" || tag[0] == "e") && tag[2] != "" {
+				data["report_type"] = tag[2]
+			}
+		}
+		notifyWebhooks(WebhookReportReceived, data)
+		return
+	}
+
+	admin := d.cfg.AdminPubkey
+	if admin == "" || evt.PubKey == admin {
+		return
+	}
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "p" && tag[1] == admin {
+			notifyWebhooks(WebhookAdminMentioned, map[string]string{
+				"event_id": evt.ID,
+				"pubkey":   evt.PubKey,
+				"kind":     strconv.Itoa(evt.Kind),
+				"content":  truncateString(evt.Content, webhookMaxContent),
+				"relay":    relayURL,
+			})
+			return
+		}
+	}
+}
+
+// worker delivers an endpoint's queued notifications one at a time
+func (d *WebhookDispatcher) worker(queue chan webhookJob) {
+	for job := range queue {
+		d.record(d.deliver(job))
+	}
+}
+
+// deliver POSTs a notification, retrying network errors, 429 and 5xx with exponential backoff
+func (d *WebhookDispatcher) deliver(job webhookJob) WebhookDelivery {
+	result := WebhookDelivery{Endpoint: job.endpoint.Name, Event: job.event}
+	backoff := webhookInitialBackoff
+	var err error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		result.Attempts = attempt
+		var retry bool
+		retry, err = d.post(job, &result)
+		if result.Delivered || !retry {
+			break
+		}
+		if attempt < webhookMaxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	result.At = time.Now()
+	if !result.Delivered {
+		log.Printf("Webhook %s to %s failed after %d attempts: %v", job.event, job.endpoint.Name, result.Attempts, err)
+	}
+	return result
+}
+
+// post makes one delivery attempt, returning whether a failure is worth retrying and its error
+func (d *WebhookDispatcher) post(job webhookJob, result *WebhookDelivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, job.endpoint.URL, bytes.NewReader(job.body))
+	if err != nil {
+		result.Error = "invalid request"
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nostr-hypermedia-webhooks")
+	req.Header.Set("X-Webhook-Event", job.event)
+	if job.endpoint.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+signWebhookBody(job.endpoint.Secret, job.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.StatusCode = 0
+		result.Error = "connection failed"
+		if ue, ok := err.(*url.Error); ok && ue.Timeout() {
+			result.Error = "timeout"
+		}
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		result.Delivered = true
+		result.Error = ""
+		return false, nil
+	}
+	result.Error = resp.Status
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, errors.New(resp.Status)
+}
+
+// signWebhookBody returns the hex HMAC-SHA256 of the body with the endpoint secret
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// record keeps a delivery result for the admin status page
+func (d *WebhookDispatcher) record(delivery WebhookDelivery) {
+	if delivery.At.IsZero() {
+		delivery.At = time.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recent = append([]WebhookDelivery{delivery}, d.recent...)
+	if len(d.recent) > webhookRecentLimit {
+		d.recent = d.recent[:webhookRecentLimit]
+	}
+}
+
+// Recent returns the latest delivery results, newest first
+func (d *WebhookDispatcher) Recent() []WebhookDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]WebhookDelivery(nil), d.recent...)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nostr-hypermedia/testutil"
+)
+
+// webhookRequest is what a test endpoint received
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// startWebhookEndpoint serves a test webhook receiver that answers with status(n) for
+// the nth request (from 1) and hands every request to the returned channel
+func startWebhookEndpoint(t *testing.T, status func(n int32) int) (*httptest.Server, <-chan webhookRequest, *atomic.Int32) {
+	t.Helper()
+	received := make(chan webhookRequest, 16)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- webhookRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status(hits.Add(1)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received, &hits
+}
+
+// useWebhooks installs a dispatcher for cfg with its workers running
+func useWebhooks(t *testing.T, cfg *WebhookConfig) *WebhookDispatcher {
+	t.Helper()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	d := newWebhookDispatcher(cfg)
+	for _, queue := range d.queues {
+		go d.worker(queue)
+	}
+	previous := webhooks
+	webhooks = d
+	t.Cleanup(func() {
+		webhooks = previous
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	})
+	return d
+}
+
+// fastWebhookRetries shortens the retry backoff for the test
+func fastWebhookRetries(t *testing.T) {
+	previous := webhookInitialBackoff
+	webhookInitialBackoff = time.Millisecond
+	t.Cleanup(func() { webhookInitialBackoff = previous })
+}
+
+func waitForWebhook(t *testing.T, received <-chan webhookRequest) webhookRequest {
+	t.Helper()
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a webhook delivery")
+		return webhookRequest{}
+	}
+}
+
+func TestWebhookSignsExactBody(t *testing.T) {
+	srv, received, _ := startWebhookEndpoint(t, func(int32) int { return http.StatusNoContent })
+	useWebhooks(t, &WebhookConfig{AllowPrivate: true, Endpoints: []WebhookEndpoint{
+		{Name: "ops", URL: srv.URL, Secret: "s3cret", Events: []string{WebhookRelayCircuitOpened}},
+	}})
+
+	notifyWebhooks(WebhookRelayCircuitOpened, map[string]string{"relay": "wss://relay.example.com", "note": `<a&b> "quoted"`})
+	req := waitForWebhook(t, received)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(req.body)
+	if got, want := req.header.Get("X-Webhook-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+	}
+	if got := req.header.Get("X-Webhook-Event"); got != WebhookRelayCircuitOpened {
+		t.Errorf("X-Webhook-Event = %q, want %q", got, WebhookRelayCircuitOpened)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("body isn't a payload: %v\n%s", err, req.body)
+	}
+	if payload.Event != WebhookRelayCircuitOpened || payload.Data["relay"] != "wss://relay.example.com" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	fastWebhookRetries(t)
+	tests := []struct {
+		name          string
+		status        func(n int32) int
+		wantDelivered bool
+		wantAttempts  int
+	}{
+		{"recovers after server errors", func(n int32) int {
+			if n < 3 {
+				return http.StatusServiceUnavailable
+			}
+			return http.StatusOK
+		}, true, 3},
+		{"gives up", func(int32) int { return http.StatusInternalServerError }, false, webhookMaxAttempts},
+		{"client errors aren't retried", func(int32) int { return http.StatusBadRequest }, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _, hits := startWebhookEndpoint(t, tt.status)
+			endpoint := WebhookEndpoint{Name: "ops", URL: srv.URL, Events: []string{WebhookReportReceived}}
+			d := newWebhookDispatcher(&WebhookConfig{AllowPrivate: true, Endpoints: []WebhookEndpoint{endpoint}})
+
+			result := d.deliver(webhookJob{endpoint: endpoint, event: WebhookReportReceived, body: []byte(`{}`)})
+			if result.Delivered != tt.wantDelivered || result.Attempts != tt.wantAttempts {
+				t.Errorf("delivered %v after %d attempts, want %v after %d", result.Delivered, result.Attempts, tt.wantDelivered, tt.wantAttempts)
+			}
+			if int(hits.Load()) != tt.wantAttempts {
+				t.Errorf("endpoint got %d requests, want %d", hits.Load(), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWebhookRefusesPrivateEndpoints(t *testing.T) {
+	fastWebhookRetries(t)
+	srv, _, hits := startWebhookEndpoint(t, func(int32) int { return http.StatusOK })
+	endpoint := WebhookEndpoint{Name: "ops", URL: srv.URL, Events: []string{WebhookReportReceived}}
+
+	cfg := &WebhookConfig{Endpoints: []WebhookEndpoint{endpoint}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("loopback endpoint accepted without allow_private")
+	}
+	// Checked again when connecting, for names that resolve to private addresses later
+	result := newWebhookDispatcher(cfg).deliver(webhookJob{endpoint: endpoint, event: WebhookReportReceived, body: []byte(`{}`)})
+	if result.Delivered || hits.Load() != 0 {
+		t.Errorf("delivered to a loopback endpoint without allow_private (%d requests)", hits.Load())
+	}
+
+	cfg.AllowPrivate = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("loopback endpoint refused with allow_private: %v", err)
+	}
+	if result := newWebhookDispatcher(cfg).deliver(webhookJob{endpoint: endpoint, event: WebhookReportReceived, body: []byte(`{}`)}); !result.Delivered {
+		t.Errorf("not delivered with allow_private: %+v", result)
+	}
+}
+
+func TestWebhookReportReceivedOnlyForVerifiedReports(t *testing.T) {
+	srv, received, _ := startWebhookEndpoint(t, func(int32) int { return http.StatusOK })
+	useWebhooks(t, &WebhookConfig{AllowPrivate: true, Endpoints: []WebhookEndpoint{
+		{Name: "moderation", URL: srv.URL, Events: []string{WebhookReportReceived}},
+	}})
+
+	reporter := testutil.NewKeypair("webhook-reporter")
+	spammer := testutil.NewKeypair("webhook-spammer")
+	now := time.Now().Unix()
+	asEvent := func(e testutil.Event) Event {
+		return Event{ID: e.ID, PubKey: e.PubKey, CreatedAt: e.CreatedAt, Kind: e.Kind, Tags: e.Tags, Content: e.Content, Sig: e.Sig}
+	}
+	note := testutil.Note(reporter, now-60, "spam again", []string{"p", spammer.PubKey, "spam"})
+	report := testutil.MustSign(reporter, testutil.Event{Kind: 1984, CreatedAt: now - 30, Content: "buy my coin",
+		Tags: [][]string{{"p", spammer.PubKey, "spam"}}})
+	forged := asEvent(testutil.MustSign(reporter, testutil.Event{Kind: 1984, CreatedAt: now - 20, Content: "original",
+		Tags: [][]string{{"p", spammer.PubKey, "spam"}}}))
+	forged.Content = "forged report"
+
+	// The worker delivers in order, so anything wrongly queued before the real report arrives first
+	ingestEvent(asEvent(note), "wss://relay.example.com")
+	ingestEvent(forged, "wss://forger.example")
+	ingestEvent(asEvent(report), "wss://relay.example.com")
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(waitForWebhook(t, received).body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != WebhookReportReceived || payload.Data["event_id"] != report.ID {
+		t.Fatalf("first delivery = %+v, want the signed report %s", payload, shortID(report.ID))
+	}
+	if payload.Data["reported_pubkey"] != spammer.PubKey || payload.Data["report_type"] != "spam" {
+		t.Errorf("report data = %v", payload.Data)
+	}
+}